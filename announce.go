@@ -0,0 +1,118 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Announcer announces the runs which changed the schema on the database
+// itself, e.g. with a NOTIFY or an outbox row, so the running instances of
+// the application react to the change, refreshing prepared statements or
+// caches, without polling the migration table.
+type Announcer interface {
+	// Announce is called after a run which applied migrations succeeded, on
+	// the connection of the run.
+	Announce(ctx context.Context, db SqlExecutor, a Announcement) error
+}
+
+// Announcement describes a run which changed the schema.
+type Announcement struct {
+	Direction MigrationDirection
+	// Migrations are the ids of the migrations of the run, in order.
+	Migrations []string
+	// Version is the version of the latest migration applied after the run,
+	// e.g. 20240115123000, its id when it has no version, or empty when no
+	// migration is applied anymore.
+	Version string
+}
+
+// PostgresNotify announces the runs with NOTIFY on Channel, with the Version
+// as payload, e.g. NOTIFY schema_migrated, '20240115123000'.
+type PostgresNotify struct {
+	// Channel is the channel notified, schema_migrated when empty.
+	Channel string
+}
+
+func (p PostgresNotify) Announce(ctx context.Context, db SqlExecutor, a Announcement) error {
+	channel := p.Channel
+	if channel == "" {
+		channel = "schema_migrated"
+	}
+
+	_, err := db.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, a.Version)
+
+	return err
+}
+
+// Outbox announces the runs with a row in the outbox table of the
+// application, for a relay to publish. Query inserts the row with two
+// arguments, Topic and the announcement as JSON, e.g.
+//
+//	INSERT INTO outbox (topic, payload) VALUES ($1, $2)
+//
+// with the placeholders of the dialect.
+type Outbox struct {
+	Query string
+	Topic string
+}
+
+// outboxPayload is the JSON of an announcement in the outbox.
+type outboxPayload struct {
+	Direction  string   `json:"direction"`
+	Migrations []string `json:"migrations"`
+	Version    string   `json:"version"`
+}
+
+func (o Outbox) Announce(ctx context.Context, db SqlExecutor, a Announcement) error {
+	payload, err := json.Marshal(outboxPayload{Direction: a.Direction.String(), Migrations: a.Migrations, Version: a.Version})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, o.Query, o.Topic, string(payload))
+
+	return err
+}
+
+// announce announces a successful run. The migrations are applied already,
+// so a failure is logged and doesn't fail the run.
+func (ex *MigrationExecutor) announce(ctx context.Context, conn SqlDB, dir MigrationDirection, plan *MigrationPlan) {
+	a := Announcement{Direction: dir, Migrations: make([]string, len(plan.Migrations))}
+	for i, migration := range plan.Migrations {
+		a.Migrations[i] = migration.Id
+	}
+
+	ids, err := plan.rep.ListMigrationIds(ctx)
+	if err == nil {
+		a.Version = latestVersion(ids)
+		err = ex.Announcer.Announce(ctx, conn, a)
+	}
+
+	if err != nil {
+		logWith(ctx, ex.Logger, LevelError, fmt.Sprintf("Failed to announce the migrations: %v", err),
+			Field{"direction", dir}, Field{"error", err})
+	}
+}
+
+// latestVersion returns the version of the latest of the migrations, or its
+// id when it has no version.
+func latestVersion(ids []string) string {
+	var latest *Migration
+
+	for _, id := range ids {
+		if m := (&Migration{Id: id}); latest == nil || latest.Less(m) {
+			latest = m
+		}
+	}
+
+	if latest == nil {
+		return ""
+	}
+
+	if version, ok := latest.versionPrefix(); ok {
+		return version
+	}
+
+	return latest.Id
+}
@@ -0,0 +1,60 @@
+package migrate
+
+import (
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func (s *SqliteMigrateSuite) TestAnnounceOutbox(c *C) {
+	_, err := s.db.Exec("CREATE TABLE outbox (topic text, payload text)")
+	c.Assert(err, IsNil)
+
+	s.ex.Announcer = Outbox{Query: "INSERT INTO outbox (topic, payload) VALUES (?, ?)", Topic: "schema"}
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	n, err := s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	// Nothing applied, nothing announced.
+	n, err = s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 0)
+
+	n, err = s.ex.ExecMax(s.db, s.dialect, source, Down, 1)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	rows, err := s.db.Query("SELECT topic, payload FROM outbox ORDER BY rowid")
+	c.Assert(err, IsNil)
+	defer rows.Close()
+
+	var payloads []string
+	for rows.Next() {
+		var topic, payload string
+		c.Assert(rows.Scan(&topic, &payload), IsNil)
+		c.Assert(topic, Equals, "schema")
+		payloads = append(payloads, payload)
+	}
+
+	c.Assert(rows.Err(), IsNil)
+	c.Assert(payloads, DeepEquals, []string{
+		`{"direction":"up","migrations":["123","124"],"version":"124"}`,
+		`{"direction":"down","migrations":["124"],"version":"123"}`,
+	})
+}
+
+func (s *SqliteMigrateSuite) TestAnnounceFailure(c *C) {
+	// The outbox table is missing, the run succeeds regardless.
+	s.ex.Announcer = Outbox{Query: "INSERT INTO outbox (topic, payload) VALUES (?, ?)"}
+
+	n, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations), Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+}
+
+func (*SqliteMigrateSuite) TestLatestVersion(c *C) {
+	c.Assert(latestVersion(nil), Equals, "")
+	c.Assert(latestVersion([]string{"2_b.sql", "10_a.sql", "1_c.sql"}), Equals, "10")
+	c.Assert(latestVersion([]string{"init.sql"}), Equals, "init.sql")
+}
@@ -0,0 +1,258 @@
+package migrate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ErrAtlasSum is returned when the atlas.sum file of an Atlas migration
+// directory doesn't match its files, e.g. after a migration was edited by
+// hand or migrations were merged without running atlas migrate hash.
+var ErrAtlasSum = errors.New("atlas.sum doesn't match the migration directory")
+
+const atlasSumName = "atlas.sum"
+
+var _ MigrationSource = (*AtlasMigrationSource)(nil)
+
+var _ MigrationIdLister = (*AtlasMigrationSource)(nil)
+
+// AtlasMigrationSource reads a migration directory written by Atlas: a file
+// per migration, without Up and Down sections, and the atlas.sum file, which
+// FindMigrations checks against the files before returning any migration.
+// Atlas migrations only go up; reverting one removes its record and runs no
+// statement.
+//
+// The statements end with a semicolon at the end of a line, like in the
+// files of sql-migrate. A file with the header directive
+// "-- atlas:txmode none" runs without a transaction; custom delimiters of
+// "-- atlas:delimiter" aren't supported.
+type AtlasMigrationSource struct {
+	fs   http.FileSystem
+	root string
+}
+
+// NewAtlasMigrationSource reads the Atlas migration directory dir.
+func NewAtlasMigrationSource(dir string) *AtlasMigrationSource {
+	return &AtlasMigrationSource{
+		fs:   http.Dir(dir),
+		root: "/",
+	}
+}
+
+// NewAtlasEmbedMigrationSource reads the Atlas migration directory root of
+// an embed.FS.
+func NewAtlasEmbedMigrationSource(fs embed.FS, root string) *AtlasMigrationSource {
+	return &AtlasMigrationSource{
+		fs:   http.FS(fs),
+		root: root,
+	}
+}
+
+// FindMigrationIds returns the ids of the migrations, without reading the
+// files or checking atlas.sum.
+func (a *AtlasMigrationSource) FindMigrationIds() ([]string, error) {
+	files, err := readSqlDir(a.fs, a.root)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(files))
+	for i, info := range files {
+		names[i] = info.Name()
+	}
+
+	return sortIds(names), nil
+}
+
+func (a *AtlasMigrationSource) FindMigrations() ([]*Migration, error) {
+	files, err := readSqlDir(a.fs, a.root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Atlas hashes the files in the order of their names.
+	names := make([]string, len(files))
+	for i, info := range files {
+		names[i] = info.Name()
+	}
+
+	sort.Strings(names)
+
+	contents := make([][]byte, len(names))
+	for i, name := range names {
+		contents[i], err = a.readFile(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sum, err := a.readFile(atlasSumName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s is missing, run atlas migrate hash", ErrAtlasSum, atlasSumName)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := checkAtlasSum(sum, atlasHash(names, contents)); err != nil {
+		return nil, err
+	}
+
+	migrations := make([]*Migration, 0, len(names))
+
+	for i, name := range names {
+		migration, err := atlasMigration(name, contents[i])
+		if err != nil {
+			return nil, fmt.Errorf("Error while parsing %s: %w", name, err)
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	sort.Sort(byId(migrations))
+
+	return migrations, nil
+}
+
+func (a *AtlasMigrationSource) readFile(name string) ([]byte, error) {
+	file, err := a.fs.Open(path.Join(a.root, name))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = file.Close() }()
+
+	return io.ReadAll(file)
+}
+
+// atlasMigration parses the Atlas migration file as the Up section of a
+// migration.
+func atlasMigration(id string, content []byte) (*Migration, error) {
+	if _, ok := atlasDirective(content, "delimiter"); ok {
+		return nil, errors.New("the atlas:delimiter directive isn't supported, enclose the statements in -- +migrate StatementBegin and StatementEnd")
+	}
+
+	header := "-- +migrate Up\n"
+	if mode, _ := atlasDirective(content, "txmode"); mode == "none" {
+		header = "-- +migrate Up notransaction\n"
+	}
+
+	migration, err := parseMigration(id, bytes.NewReader(append([]byte(header), content...)))
+	if err != nil {
+		return nil, err
+	}
+
+	// The lines are those of the file, without the header.
+	for i := range migration.UpLines {
+		migration.UpLines[i].First--
+		migration.UpLines[i].Last--
+	}
+
+	return migration, nil
+}
+
+// atlasDirective returns the value of the directive "-- atlas:<name> <value>"
+// of the comments heading the file.
+func atlasDirective(content []byte, name string) (string, bool) {
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+
+		value, ok := strings.CutPrefix(line, "-- atlas:"+name)
+		if ok && (value == "" || value[0] == ' ') {
+			return strings.TrimSpace(value), true
+		}
+	}
+
+	return "", false
+}
+
+// atlasFileHash is a line of atlas.sum, a file and its hash.
+type atlasFileHash struct {
+	Name string
+	Hash string
+}
+
+// atlasHash hashes the files like atlas migrate hash: the hash of a file is
+// the hash of the names and contents of all the files up to and including
+// it, so editing a file changes the hashes of the later ones too. Files with
+// the "-- atlas:sum ignore" directive are left out, except for their name.
+func atlasHash(names []string, contents [][]byte) []atlasFileHash {
+	var hashes []atlasFileHash
+
+	h := sha256.New()
+
+	for i, name := range names {
+		h.Write([]byte(name))
+
+		if mode, ok := atlasDirective(contents[i], "sum"); ok && mode == "ignore" {
+			continue
+		}
+
+		h.Write(contents[i])
+		hashes = append(hashes, atlasFileHash{Name: name, Hash: base64.StdEncoding.EncodeToString(h.Sum(nil))})
+	}
+
+	return hashes
+}
+
+// atlasSum returns the hash of the hashes, the first line of atlas.sum.
+func atlasSum(hashes []atlasFileHash) string {
+	h := sha256.New()
+	for _, file := range hashes {
+		h.Write([]byte(file.Name))
+		h.Write([]byte(file.Hash))
+	}
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// checkAtlasSum checks the content of atlas.sum against the hashes of the
+// files, and names the first file which doesn't match.
+func checkAtlasSum(content []byte, hashes []atlasFileHash) error {
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+
+	sum, ok := strings.CutPrefix(lines[0], "h1:")
+	if !ok {
+		return fmt.Errorf("%w: %s doesn't start with its sum", ErrAtlasSum, atlasSumName)
+	}
+
+	recorded := make([]atlasFileHash, 0, len(lines)-1)
+
+	for _, line := range lines[1:] {
+		name, hash, ok := strings.Cut(line, " h1:")
+		if !ok {
+			return fmt.Errorf("%w: invalid line %q of %s", ErrAtlasSum, line, atlasSumName)
+		}
+
+		recorded = append(recorded, atlasFileHash{Name: name, Hash: hash})
+	}
+
+	if atlasSum(recorded) != sum {
+		return fmt.Errorf("%w: %s was edited by hand", ErrAtlasSum, atlasSumName)
+	}
+
+	for i := 0; i < len(recorded) || i < len(hashes); i++ {
+		switch {
+		case i >= len(hashes) || (i < len(recorded) && recorded[i].Name < hashes[i].Name):
+			return fmt.Errorf("%w: %s is missing from the directory", ErrAtlasSum, recorded[i].Name)
+		case i >= len(recorded) || recorded[i].Name != hashes[i].Name:
+			return fmt.Errorf("%w: %s isn't in %s", ErrAtlasSum, hashes[i].Name, atlasSumName)
+		case recorded[i].Hash != hashes[i].Hash:
+			return fmt.Errorf("%w: %s was edited", ErrAtlasSum, hashes[i].Name)
+		}
+	}
+
+	return nil
+}
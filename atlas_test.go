@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+type AtlasSuite struct{}
+
+var _ = Suite(&AtlasSuite{})
+
+// formatAtlasSum returns the content of atlas.sum for the hashes, like atlas
+// migrate hash writes it.
+func formatAtlasSum(hashes []atlasFileHash) []byte {
+	var b strings.Builder
+
+	b.WriteString("h1:" + atlasSum(hashes) + "\n")
+
+	for _, file := range hashes {
+		b.WriteString(file.Name + " h1:" + file.Hash + "\n")
+	}
+
+	return []byte(b.String())
+}
+
+// writeAtlasDir writes the files and their atlas.sum to a new directory.
+func writeAtlasDir(c *C, files map[string]string) string {
+	dir := c.MkDir()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	contents := make([][]byte, len(names))
+	for i, name := range names {
+		contents[i] = []byte(files[name])
+		c.Assert(os.WriteFile(filepath.Join(dir, name), contents[i], 0o600), IsNil)
+	}
+
+	c.Assert(os.WriteFile(filepath.Join(dir, atlasSumName), formatAtlasSum(atlasHash(names, contents)), 0o600), IsNil)
+
+	return dir
+}
+
+var atlasFiles = map[string]string{
+	"20240101000000_people.sql": "-- Create \"people\" table\nCREATE TABLE people (id int);\n",
+	"20240102000000_pets.sql":   "CREATE TABLE pets (id int);\n\nCREATE INDEX pets_id ON pets (id);\n",
+}
+
+func (*AtlasSuite) TestFindMigrations(c *C) {
+	dir := writeAtlasDir(c, atlasFiles)
+
+	migrations, err := NewAtlasMigrationSource(dir).FindMigrations()
+	c.Assert(err, IsNil)
+	c.Assert(migrations, HasLen, 2)
+
+	c.Assert(migrations[0].Id, Equals, "20240101000000_people.sql")
+	c.Assert(migrations[0].Up, DeepEquals, []string{"CREATE TABLE people (id int);\n"})
+	c.Assert(migrations[0].UpLines, DeepEquals, []sqlparse.LineRange{{First: 2, Last: 2}})
+	c.Assert(migrations[0].Down, HasLen, 0)
+	c.Assert(migrations[0].DisableTransactionUp, Equals, false)
+
+	c.Assert(migrations[1].Up, HasLen, 2)
+	c.Assert(migrations[1].UpLines[1], DeepEquals, sqlparse.LineRange{First: 3, Last: 3})
+
+	ids, err := NewAtlasMigrationSource(dir).FindMigrationIds()
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, []string{"20240101000000_people.sql", "20240102000000_pets.sql"})
+}
+
+func (*AtlasSuite) TestTxModeNone(c *C) {
+	dir := writeAtlasDir(c, map[string]string{
+		"1_index.sql": "-- atlas:txmode none\n\nCREATE INDEX CONCURRENTLY people_id ON people (id);\n",
+	})
+
+	migrations, err := NewAtlasMigrationSource(dir).FindMigrations()
+	c.Assert(err, IsNil)
+	c.Assert(migrations[0].DisableTransactionUp, Equals, true)
+	c.Assert(migrations[0].Up, HasLen, 1)
+}
+
+func (*AtlasSuite) TestDelimiter(c *C) {
+	dir := writeAtlasDir(c, map[string]string{
+		"1_function.sql": "-- atlas:delimiter -- end\n\nCREATE FUNCTION f() ...\n-- end\n",
+	})
+
+	_, err := NewAtlasMigrationSource(dir).FindMigrations()
+	c.Assert(err, ErrorMatches, "Error while parsing 1_function.sql: the atlas:delimiter directive isn't supported.*")
+}
+
+func (*AtlasSuite) TestSumIgnore(c *C) {
+	dir := writeAtlasDir(c, map[string]string{
+		"1_people.sql": "CREATE TABLE people (id int);\n",
+		"2_seed.sql":   "-- atlas:sum ignore\nINSERT INTO people VALUES (1);\n",
+	})
+
+	// The content of an ignored file can change.
+	c.Assert(os.WriteFile(filepath.Join(dir, "2_seed.sql"), []byte("-- atlas:sum ignore\nINSERT INTO people VALUES (2);\n"), 0o600), IsNil)
+
+	migrations, err := NewAtlasMigrationSource(dir).FindMigrations()
+	c.Assert(err, IsNil)
+	c.Assert(migrations, HasLen, 2)
+	c.Assert(migrations[1].Up, DeepEquals, []string{"INSERT INTO people VALUES (2);\n"})
+}
+
+func (*AtlasSuite) TestSumMismatch(c *C) {
+	for _, t := range []struct {
+		change func(dir string) error
+		err    string
+	}{
+		{
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "20240101000000_people.sql"), []byte("CREATE TABLE people (id bigint);\n"), 0o600)
+			},
+			err: "atlas.sum doesn't match the migration directory: 20240101000000_people.sql was edited",
+		},
+		{
+			change: func(dir string) error {
+				return os.Remove(filepath.Join(dir, atlasSumName))
+			},
+			err: "atlas.sum doesn't match the migration directory: atlas.sum is missing, run atlas migrate hash",
+		},
+		{
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "20240103000000_toys.sql"), []byte("CREATE TABLE toys (id int);\n"), 0o600)
+			},
+			err: "atlas.sum doesn't match the migration directory: 20240103000000_toys.sql isn't in atlas.sum",
+		},
+		{
+			change: func(dir string) error {
+				return os.Remove(filepath.Join(dir, "20240102000000_pets.sql"))
+			},
+			err: "atlas.sum doesn't match the migration directory: 20240102000000_pets.sql is missing from the directory",
+		},
+		{
+			change: func(dir string) error {
+				sum, err := os.ReadFile(filepath.Join(dir, atlasSumName))
+				if err != nil {
+					return err
+				}
+
+				sum = []byte(strings.Replace(string(sum), "_pets.sql h1:", "_pets.sql h1:x", 1))
+
+				return os.WriteFile(filepath.Join(dir, atlasSumName), sum, 0o600)
+			},
+			err: "atlas.sum doesn't match the migration directory: atlas.sum was edited by hand",
+		},
+	} {
+		dir := writeAtlasDir(c, atlasFiles)
+		c.Assert(t.change(dir), IsNil)
+
+		_, err := NewAtlasMigrationSource(dir).FindMigrations()
+		c.Assert(err, ErrorMatches, t.err)
+		c.Assert(errors.Is(err, ErrAtlasSum), Equals, true)
+	}
+}
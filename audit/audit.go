@@ -0,0 +1,219 @@
+// Package audit appends a JSON Lines record per migration applied or rolled
+// back, keeping a trail of the schema changes independent of the database.
+//
+//	log, err := audit.OpenFile("migrations.jsonl", audit.WithTarget(audit.DSNHost(dsn)))
+//	if err != nil {
+//		return err
+//	}
+//	defer log.Close()
+//
+//	ex := migrate.NewMigrationExecutor()
+//	ex.Observer = log
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+// Record is a line of the audit log.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Id        string    `json:"id"`
+	Direction string    `json:"direction"`
+	Checksum  string    `json:"checksum"`
+	Duration  float64   `json:"duration_seconds"`
+	Actor     string    `json:"actor"`
+	Target    string    `json:"target,omitempty"`
+	// Error is set for a migration which failed and was rolled back.
+	Error string `json:"error,omitempty"`
+}
+
+// Option configures the Log.
+type Option func(*Log)
+
+// WithActor names who runs the migrations, CurrentUser by default.
+func WithActor(actor string) Option {
+	return func(l *Log) {
+		l.actor = actor
+	}
+}
+
+// WithTarget names the database migrated, e.g. the host of its DSN.
+func WithTarget(target string) Option {
+	return func(l *Log) {
+		l.target = target
+	}
+}
+
+// WithErrorHandler is called when a record can't be written, as the migration
+// is applied already.
+func WithErrorHandler(handler func(error)) Option {
+	return func(l *Log) {
+		l.onError = handler
+	}
+}
+
+var _ migrate.Observer = (*Log)(nil)
+
+// Log implements migrate.Observer by writing a Record per migration.
+type Log struct {
+	mu      sync.Mutex
+	w       io.Writer
+	closer  io.Closer
+	actor   string
+	target  string
+	onError func(error)
+}
+
+// New returns a Log writing to w.
+func New(w io.Writer, opts ...Option) *Log {
+	l := &Log{
+		w:       w,
+		actor:   CurrentUser(),
+		onError: func(error) {},
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// OpenFile returns a Log appending to the file at path, created if needed.
+func OpenFile(path string, opts ...Option) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, err
+	}
+
+	l := New(f, opts...)
+	l.closer = f
+
+	return l, nil
+}
+
+// Close closes the file opened by OpenFile. The writer given to New is left open.
+func (l *Log) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+
+	return l.closer.Close()
+}
+
+func (*Log) StartRun(ctx context.Context, _ migrate.RunInfo) (context.Context, func(migrate.RunResult)) {
+	return ctx, func(migrate.RunResult) {}
+}
+
+func (l *Log) StartMigration(ctx context.Context, m migrate.MigrationInfo) (context.Context, func(error)) {
+	started := time.Now()
+
+	return ctx, func(err error) {
+		record := Record{
+			Time:      started.UTC(),
+			Id:        m.Id,
+			Direction: m.Direction.String(),
+			Checksum:  m.Checksum,
+			Duration:  time.Since(started).Seconds(),
+			Actor:     l.actor,
+			Target:    l.target,
+		}
+
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		if err := l.write(record); err != nil {
+			l.onError(err)
+		}
+	}
+}
+
+func (*Log) StartStatement(ctx context.Context, _ string) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+
+// write writes the record with a single write, so concurrent appends to the
+// same file don't interleave.
+func (l *Log) write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.w.Write(append(line, '\n'))
+
+	return err
+}
+
+// CurrentUser identifies who runs the process, as user@host.
+func CurrentUser() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		name += "@" + host
+	}
+
+	return name
+}
+
+var mysqlAddress = regexp.MustCompile(`@\w+\(([^)]*)\)`)
+
+// DSNHost returns the host and port of a connection string, without the
+// credentials, or an empty string for a DSN without a host, e.g. sqlite.
+// It understands URLs, the mysql user:password@tcp(host:port)/db form and
+// key=value lists with a host or server key.
+func DSNHost(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		if u, err := url.Parse(dsn); err == nil {
+			return u.Host
+		}
+
+		return ""
+	}
+
+	if m := mysqlAddress.FindStringSubmatch(dsn); m != nil {
+		return m[1]
+	}
+
+	var host, port string
+
+	for _, field := range strings.FieldsFunc(dsn, func(r rune) bool { return r == ' ' || r == ';' }) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "host", "server":
+			host = strings.Trim(value, `'"`)
+		case "port":
+			port = strings.Trim(value, `'"`)
+		}
+	}
+
+	if host != "" && port != "" {
+		return net.JoinHostPort(host, port)
+	}
+
+	return host
+}
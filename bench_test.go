@@ -0,0 +1,127 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// benchMigrations is the size of the sources of the benchmarks, as large
+// monorepos reach it.
+const benchMigrations = 10000
+
+func benchSource(b *testing.B) []*Migration {
+	b.Helper()
+
+	migrations := make([]*Migration, benchMigrations)
+	for i := range migrations {
+		migrations[i] = &Migration{
+			Id:   fmt.Sprintf("%d_table.sql", i+1),
+			Up:   []string{"SELECT 1"},
+			Down: []string{"SELECT 2"},
+		}
+	}
+
+	return migrations
+}
+
+func benchDB(b *testing.B) *sql.DB {
+	b.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// Every connection would open another in-memory database.
+	db.SetMaxOpenConns(1)
+	b.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func benchExecutor() *MigrationExecutor {
+	ex := NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = nullLogger{}
+
+	return ex
+}
+
+func BenchmarkFindMigrations(b *testing.B) {
+	dir := b.TempDir()
+
+	for i := 1; i <= benchMigrations; i++ {
+		content := fmt.Sprintf("-- +migrate Up\nCREATE TABLE t%d (id int);\n\n-- +migrate Down\nDROP TABLE t%d;\n", i, i)
+
+		err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d_table.sql", i)), []byte(content), 0o600)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	source := NewFileMigrationSource(dir)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := source.FindMigrations()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPlanMigration(b *testing.B) {
+	db := benchDB(b)
+	ex := benchExecutor()
+	source := NewMemoryMigrationSource(benchSource(b))
+
+	// All but the last ten migrations are applied.
+	_, err := ex.SkipMax(context.Background(), db, dialect.NewSqliteDialect(), source, Up, benchMigrations-10)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		migrations, _, err := ex.PlanMigration(context.Background(), db, dialect.NewSqliteDialect(), source, Up, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(migrations) != 10 {
+			b.Fatalf("planned %d migrations", len(migrations))
+		}
+	}
+}
+
+func BenchmarkExec(b *testing.B) {
+	source := NewMemoryMigrationSource(benchSource(b))
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		db := benchDB(b)
+		ex := benchExecutor()
+		b.StartTimer()
+
+		n, err := ex.Exec(db, dialect.NewSqliteDialect(), source, Up)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if n != benchMigrations {
+			b.Fatalf("applied %d migrations", n)
+		}
+	}
+}
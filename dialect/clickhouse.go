@@ -7,21 +7,39 @@ import (
 
 var _ Dialect = (*ClickhouseDialect)(nil)
 
+var _ IdSelector = (*ClickhouseDialect)(nil)
+
+var _ TableLocker = (*ClickhouseDialect)(nil)
+
 type ClickhouseEngine string
 
 const (
+	// TinyLogEngine can't delete the records of the migrations rolled back,
+	// the rollbacks fail with it.
 	TinyLogEngine ClickhouseEngine = "TinyLog"
+	// MergeTreeEngine deletes the records of the migrations rolled back with
+	// mutations. The other engines of the MergeTree family, e.g.
+	// ReplicatedMergeTree with its parameters, work alike.
+	MergeTreeEngine ClickhouseEngine = "MergeTree"
 )
 
 type ClickhouseDialect struct {
 	engine      ClickhouseEngine
 	clusterName string
+
+	// MutationsSync is the mutations_sync setting of the deletes, of the
+	// records of the migrations rolled back and of the migration lock: 1, the
+	// default, waits for the mutation on the replica, 2 on all the replicas.
+	// Zero doesn't wait, the deleted rows are then still read for a while.
+	// The mutations of the lock row always wait.
+	MutationsSync int
 }
 
 func NewClickhouseDialect(clusterName string, engine ClickhouseEngine) *ClickhouseDialect {
 	return &ClickhouseDialect{
-		clusterName: clusterName,
-		engine:      engine,
+		clusterName:   clusterName,
+		engine:        engine,
+		MutationsSync: 1,
 	}
 }
 
@@ -30,21 +48,35 @@ func (c *ClickhouseDialect) QueryCreateMigrateSchema(databaseName string) string
 }
 
 func (c *ClickhouseDialect) QueryCreateMigrateTable(database, tableName string) string {
+	engine := string(c.engine)
+	if c.isMergeTree() {
+		engine += " ORDER BY id"
+	}
+
 	if c.clusterName != "" {
 		return fmt.Sprintf(
 			"CREATE TABLE IF NOT EXISTS %s ON CLUSTER %s (id String, applied_at DateTime) ENGINE = %s;",
-			c.quotedTableForQuery(database, tableName), c.clusterName, c.engine,
+			c.quotedTableForQuery(database, tableName), c.clusterName, engine,
 		)
 	}
 
 	return fmt.Sprintf(
 		"CREATE TABLE IF NOT EXISTS %s (id String, applied_at DateTime) ENGINE = %s;",
-		c.quotedTableForQuery(database, tableName), c.engine,
+		c.quotedTableForQuery(database, tableName), engine,
 	)
 }
 
+// QueryDeleteMigrate The record is deleted with a mutation, which only the
+// engines of the MergeTree family support: with TinyLog, ClickHouse refuses
+// it and the rollback fails instead of leaving the record behind.
 func (c *ClickhouseDialect) QueryDeleteMigrate(database, tableName string) string {
-	return ";"
+	if c.clusterName != "" {
+		return fmt.Sprintf("ALTER TABLE %s ON CLUSTER %s DELETE WHERE id = ?%s",
+			c.quotedTableForQuery(database, tableName), c.clusterName, c.mutationSettings())
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s DELETE WHERE id = ?%s",
+		c.quotedTableForQuery(database, tableName), c.mutationSettings())
 }
 
 func (c *ClickhouseDialect) QuerySelectMigrate(database, tableName string) string {
@@ -54,11 +86,113 @@ func (c *ClickhouseDialect) QuerySelectMigrate(database, tableName string) strin
 	)
 }
 
+func (c *ClickhouseDialect) QuerySelectMigrateIds(database, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT id FROM %s ORDER BY id ASC",
+		c.quotedTableForQuery(database, tableName),
+	)
+}
+
+func (c *ClickhouseDialect) QuerySelectMigrateId(database, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT 1 FROM %s WHERE id = ?",
+		c.quotedTableForQuery(database, tableName),
+	)
+}
+
 func (c *ClickhouseDialect) QueryInsertMigrate(database, tableName string) string {
 	return fmt.Sprintf("INSERT INTO %s(id, applied_at) VALUES (?, ?)",
 		c.quotedTableForQuery(database, tableName))
 }
 
+// QueryCreateLockTable The lock table always uses the MergeTree engine,
+// as the configured engine may be TinyLog, which does not support deletes.
+// On a cluster, its replicas share a single path in ZooKeeper, whatever their
+// shard, so that every node sees the lock taken on another one; the {replica}
+// macro must then be unique across the cluster.
+func (c *ClickhouseDialect) QueryCreateLockTable(database, tableName string) string {
+	if c.clusterName != "" {
+		return fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s ON CLUSTER %s (lock_key String, owner String, acquired_at DateTime) "+
+				"ENGINE = ReplicatedMergeTree('/clickhouse/sql-migrate/{database}/{table}', '{replica}') ORDER BY lock_key;",
+			c.quotedTableForQuery(database, tableName), c.clusterName,
+		)
+	}
+
+	return fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (lock_key String, owner String, acquired_at DateTime) ENGINE = MergeTree ORDER BY lock_key;",
+		c.quotedTableForQuery(database, tableName),
+	)
+}
+
+// QueryInsertLock ClickHouse has no unique constraints, so the row is inserted
+// only when no row with the same key is present. Racing inserts are resolved by
+// QuerySelectLock, which deterministically picks the earliest row.
+func (c *ClickhouseDialect) QueryInsertLock(database, tableName string) string {
+	table := c.quotedTableForQuery(database, tableName)
+
+	return fmt.Sprintf(
+		"INSERT INTO %s(lock_key, owner, acquired_at) SELECT lock_key, owner, acquired_at FROM (SELECT ? AS lock_key, ? AS owner, ? AS acquired_at) AS s "+
+			"WHERE s.lock_key NOT IN (SELECT lock_key FROM %s)",
+		table, table)
+}
+
+// QuerySelectLock The earliest row is picked by its owner, as the lease of
+// the winner of racing inserts is renewed.
+func (c *ClickhouseDialect) QuerySelectLock(database, tableName string) string {
+	return fmt.Sprintf("SELECT owner, acquired_at FROM %s WHERE lock_key = ? ORDER BY owner ASC LIMIT 1",
+		c.quotedTableForQuery(database, tableName))
+}
+
+func (c *ClickhouseDialect) QueryRenewLock(database, tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s%s UPDATE acquired_at = ? WHERE lock_key = ? AND owner = ?%s",
+		c.quotedTableForQuery(database, tableName), c.onCluster(), c.lockMutationSettings())
+}
+
+// QueryDeleteLock The delete waits for its mutation, so that the lock is free
+// when the run ends.
+func (c *ClickhouseDialect) QueryDeleteLock(database, tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s%s DELETE WHERE lock_key = ? AND owner = ?%s",
+		c.quotedTableForQuery(database, tableName), c.onCluster(), c.lockMutationSettings())
+}
+
+// isMergeTree tells whether the engine is of the MergeTree family, whose
+// tables need an ORDER BY and support deletes.
+func (c *ClickhouseDialect) isMergeTree() bool {
+	return strings.Contains(string(c.engine), "MergeTree")
+}
+
+// mutationSettings returns the SETTINGS clause of the deletes.
+func (c *ClickhouseDialect) mutationSettings() string {
+	if c.MutationsSync <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" SETTINGS mutations_sync = %d", c.MutationsSync)
+}
+
+// lockMutationSettings returns the SETTINGS clause of the mutations of the
+// lock row, which always wait: for all the replicas on a cluster.
+func (c *ClickhouseDialect) lockMutationSettings() string {
+	sync := c.MutationsSync
+	if c.clusterName != "" {
+		sync = 2
+	} else if sync < 1 {
+		sync = 1
+	}
+
+	return fmt.Sprintf(" SETTINGS mutations_sync = %d", sync)
+}
+
+// onCluster returns the ON CLUSTER clause of the DDL, empty without a cluster.
+func (c *ClickhouseDialect) onCluster() string {
+	if c.clusterName == "" {
+		return ""
+	}
+
+	return " ON CLUSTER " + c.clusterName
+}
+
 func (c *ClickhouseDialect) quoteField(f string) string {
 	return `"` + f + `"`
 }
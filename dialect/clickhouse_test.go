@@ -0,0 +1,55 @@
+package dialect
+
+import (
+	"testing"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ClickhouseSuite struct{}
+
+var _ = Suite(&ClickhouseSuite{})
+
+func (*ClickhouseSuite) TestCreateMigrateTable(c *C) {
+	c.Assert(NewClickhouseDialect("", MergeTreeEngine).QueryCreateMigrateTable("db", "migrations"), Equals,
+		`CREATE TABLE IF NOT EXISTS "db"."migrations" (id String, applied_at DateTime) ENGINE = MergeTree ORDER BY id;`)
+	c.Assert(NewClickhouseDialect("main", "ReplicatedMergeTree").QueryCreateMigrateTable("", "migrations"), Equals,
+		`CREATE TABLE IF NOT EXISTS "migrations" ON CLUSTER main (id String, applied_at DateTime) ENGINE = ReplicatedMergeTree ORDER BY id;`)
+	c.Assert(NewClickhouseDialect("", TinyLogEngine).QueryCreateMigrateTable("", "migrations"), Equals,
+		`CREATE TABLE IF NOT EXISTS "migrations" (id String, applied_at DateTime) ENGINE = TinyLog;`)
+}
+
+func (*ClickhouseSuite) TestDeleteMigrate(c *C) {
+	d := NewClickhouseDialect("", MergeTreeEngine)
+	c.Assert(d.QueryDeleteMigrate("", "migrations"), Equals,
+		`ALTER TABLE "migrations" DELETE WHERE id = ? SETTINGS mutations_sync = 1`)
+
+	d.MutationsSync = 0
+	c.Assert(d.QueryDeleteMigrate("", "migrations"), Equals, `ALTER TABLE "migrations" DELETE WHERE id = ?`)
+
+	d = NewClickhouseDialect("main", "ReplicatedMergeTree")
+	d.MutationsSync = 2
+	c.Assert(d.QueryDeleteMigrate("db", "migrations"), Equals,
+		`ALTER TABLE "db"."migrations" ON CLUSTER main DELETE WHERE id = ? SETTINGS mutations_sync = 2`)
+}
+
+func (*ClickhouseSuite) TestLock(c *C) {
+	d := NewClickhouseDialect("", TinyLogEngine)
+	d.MutationsSync = 0
+	c.Assert(d.QueryCreateLockTable("", "migrations_lock"), Equals,
+		`CREATE TABLE IF NOT EXISTS "migrations_lock" (lock_key String, owner String, acquired_at DateTime) ENGINE = MergeTree ORDER BY lock_key;`)
+	c.Assert(d.QueryDeleteLock("", "migrations_lock"), Equals,
+		`ALTER TABLE "migrations_lock" DELETE WHERE lock_key = ? AND owner = ? SETTINGS mutations_sync = 1`)
+
+	d = NewClickhouseDialect("main", "ReplicatedMergeTree")
+	c.Assert(d.QueryCreateLockTable("", "migrations_lock"), Equals,
+		`CREATE TABLE IF NOT EXISTS "migrations_lock" ON CLUSTER main (lock_key String, owner String, acquired_at DateTime) `+
+			`ENGINE = ReplicatedMergeTree('/clickhouse/sql-migrate/{database}/{table}', '{replica}') ORDER BY lock_key;`)
+	c.Assert(d.QueryDeleteLock("", "migrations_lock"), Equals,
+		`ALTER TABLE "migrations_lock" ON CLUSTER main DELETE WHERE lock_key = ? AND owner = ? SETTINGS mutations_sync = 2`)
+	c.Assert(d.QueryRenewLock("", "migrations_lock"), Equals,
+		`ALTER TABLE "migrations_lock" ON CLUSTER main UPDATE acquired_at = ? WHERE lock_key = ? AND owner = ? SETTINGS mutations_sync = 2`)
+}
@@ -0,0 +1,150 @@
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var _ Dialect = (*CockroachDialect)(nil)
+
+var _ IdSelector = (*CockroachDialect)(nil)
+
+var _ MigrateIndexer = (*CockroachDialect)(nil)
+
+var _ ColumnRecorder = (*CockroachDialect)(nil)
+
+var _ TableLocker = (*CockroachDialect)(nil)
+
+var _ SchemaSelector = (*CockroachDialect)(nil)
+
+var _ SchemaDropper = (*CockroachDialect)(nil)
+
+var _ TxRetrier = (*CockroachDialect)(nil)
+
+// CockroachDialect Implementation of Dialect for CockroachDB, over the
+// postgres wire protocol. Its pg_advisory_lock doesn't lock, so the migration
+// lock is a row of a lock table, and the transactions aborted by conflicts
+// are retried.
+type CockroachDialect struct {
+	postgres PostgresDialect
+}
+
+func NewCockroachDialect() *CockroachDialect {
+	return &CockroachDialect{}
+}
+
+func (d *CockroachDialect) QueryCreateMigrateSchema(schemaName string) string {
+	return d.postgres.QueryCreateMigrateSchema(schemaName)
+}
+
+func (d *CockroachDialect) QueryCreateMigrateTable(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (id STRING PRIMARY KEY, applied_at TIMESTAMP NOT NULL);",
+		d.postgres.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+func (d *CockroachDialect) QueryDeleteMigrate(schemaName, tableName string) string {
+	return d.postgres.QueryDeleteMigrate(schemaName, tableName)
+}
+
+func (d *CockroachDialect) QuerySelectMigrate(schemaName, tableName string) string {
+	return d.postgres.QuerySelectMigrate(schemaName, tableName)
+}
+
+func (d *CockroachDialect) QuerySelectMigrateIds(schemaName, tableName string) string {
+	return d.postgres.QuerySelectMigrateIds(schemaName, tableName)
+}
+
+func (d *CockroachDialect) QuerySelectMigrateId(schemaName, tableName string) string {
+	return d.postgres.QuerySelectMigrateId(schemaName, tableName)
+}
+
+func (d *CockroachDialect) QueryInsertMigrate(schemaName, tableName string) string {
+	return d.postgres.QueryInsertMigrate(schemaName, tableName)
+}
+
+func (d *CockroachDialect) QueryCreateMigrateIndex(schemaName, tableName string) string {
+	return d.postgres.QueryCreateMigrateIndex(schemaName, tableName)
+}
+
+func (d *CockroachDialect) QuerySelectLastMigrate(schemaName, tableName string) string {
+	return d.postgres.QuerySelectLastMigrate(schemaName, tableName)
+}
+
+func (d *CockroachDialect) QueryProbeMigrateColumn(schemaName, tableName, column string) string {
+	return d.postgres.QueryProbeMigrateColumn(schemaName, tableName, column)
+}
+
+func (d *CockroachDialect) QueryAddMigrateColumn(schemaName, tableName, column string, columnType ColumnType) string {
+	columnDef := "STRING"
+	if columnType == ColumnInteger {
+		columnDef = "INT8"
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s",
+		d.postgres.quotedTableForQuery(schemaName, tableName), column, columnDef)
+}
+
+func (d *CockroachDialect) QuerySelectMigrateColumns(schemaName, tableName string, columns []string) string {
+	return d.postgres.QuerySelectMigrateColumns(schemaName, tableName, columns)
+}
+
+func (d *CockroachDialect) QueryInsertMigrateColumns(schemaName, tableName string, columns []string) string {
+	return d.postgres.QueryInsertMigrateColumns(schemaName, tableName, columns)
+}
+
+func (d *CockroachDialect) IsUndefinedColumn(err error) bool {
+	return d.postgres.IsUndefinedColumn(err)
+}
+
+func (d *CockroachDialect) QueryCreateLockTable(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (lock_key STRING PRIMARY KEY, owner STRING NOT NULL, acquired_at TIMESTAMP NOT NULL);",
+		d.postgres.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+func (d *CockroachDialect) QueryInsertLock(schemaName, tableName string) string {
+	return fmt.Sprintf("INSERT INTO %s(lock_key, owner, acquired_at) VALUES ($1, $2, $3) ON CONFLICT (lock_key) DO NOTHING",
+		d.postgres.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *CockroachDialect) QuerySelectLock(schemaName, tableName string) string {
+	return fmt.Sprintf("SELECT owner, acquired_at FROM %s WHERE lock_key = $1",
+		d.postgres.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *CockroachDialect) QueryRenewLock(schemaName, tableName string) string {
+	return fmt.Sprintf("UPDATE %s SET acquired_at = $1 WHERE lock_key = $2 AND owner = $3",
+		d.postgres.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *CockroachDialect) QueryDeleteLock(schemaName, tableName string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE lock_key = $1 AND owner = $2",
+		d.postgres.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *CockroachDialect) QuerySelectSchema(schemaName string) string {
+	return d.postgres.QuerySelectSchema(schemaName)
+}
+
+func (d *CockroachDialect) QueryResetSchema() string {
+	return d.postgres.QueryResetSchema()
+}
+
+func (d *CockroachDialect) QueryDropSchema(schemaName string) string {
+	return d.postgres.QueryDropSchema(schemaName)
+}
+
+// IsRetryableTx CockroachDB aborts the transactions it can't serialize with
+// the SQLSTATE 40001, asking to restart them.
+func (d *CockroachDialect) IsRetryableTx(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		return state.SQLState() == "40001"
+	}
+
+	return err != nil && strings.Contains(err.Error(), "restart transaction")
+}
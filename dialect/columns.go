@@ -0,0 +1,65 @@
+package dialect
+
+import (
+	"errors"
+	"strings"
+)
+
+// ColumnType is the type of an extra column of the migration table.
+type ColumnType int
+
+const (
+	// ColumnText holds short strings such as checksums.
+	ColumnText ColumnType = iota
+	// ColumnInteger holds 64-bit integers.
+	ColumnInteger
+)
+
+// ColumnRecorder is implemented by dialects whose migration table can store
+// metadata of the applied migrations in extra nullable columns next to id and
+// applied_at. Columns missing from an existing table are added in place.
+type ColumnRecorder interface {
+	// QueryProbeMigrateColumn returns the query - select the column of the migration table without returning rows,
+	// failing when the column does not exist
+	QueryProbeMigrateColumn(schemaName, tableName, column string) string
+	// QueryAddMigrateColumn returns the query - add a nullable column to the migration table
+	QueryAddMigrateColumn(schemaName, tableName, column string, columnType ColumnType) string
+	// QuerySelectMigrateColumns returns the query - select id, applied_at and the columns order by id ASC
+	QuerySelectMigrateColumns(schemaName, tableName string, columns []string) string
+	// QueryInsertMigrateColumns returns the query - insert migration, args are id, applied_at and the columns
+	QueryInsertMigrateColumns(schemaName, tableName string, columns []string) string
+	// IsUndefinedColumn tells whether the error of QueryProbeMigrateColumn
+	// reports the column as missing, rather than e.g. a lost connection
+	IsUndefinedColumn(err error) bool
+}
+
+// sqlState returns the SQLSTATE of the error of drivers exposing it, e.g.
+// lib/pq and pgx, or an empty string.
+func sqlState(err error) string {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		return state.SQLState()
+	}
+
+	return ""
+}
+
+// migrateColumns returns the column list of the migration table holding id, applied_at and the columns.
+func migrateColumns(columns []string) string {
+	return strings.Join(append([]string{"id", "applied_at"}, columns...), ", ")
+}
+
+// placeholders returns the bind parameters for id, applied_at and the columns.
+// placeholder receives the 1-based position of the parameter.
+func placeholders(columns []string, placeholder func(i int) string) string {
+	params := make([]string, 0, len(columns)+2)
+	for i := 1; i <= len(columns)+2; i++ {
+		params = append(params, placeholder(i))
+	}
+
+	return strings.Join(params, ", ")
+}
+
+func questionMark(int) string {
+	return "?"
+}
@@ -0,0 +1,11 @@
+package dialect
+
+// DDLDetector is implemented by dialects whose database can't roll back the
+// DDL statements of a transaction, committing them on the spot. The executor
+// applies the migrations with a DDL statement without a transaction, as if
+// marked notransaction, rather than recording a half-applied migration as
+// rolled back.
+type DDLDetector interface {
+	// IsDDL tells whether the statement changes the schema
+	IsDDL(stmt string) bool
+}
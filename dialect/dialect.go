@@ -14,3 +14,34 @@ type Dialect interface {
 	// QueryInsertMigrate returns the query - insert migration
 	QueryInsertMigrate(schemaName, tableName string) string
 }
+
+// IdSelector is implemented by dialects which select the ids of the applied
+// migrations alone, so planning doesn't read the whole migration table.
+type IdSelector interface {
+	// QuerySelectMigrateIds returns the query - select the id of all migrations order by id ASC
+	QuerySelectMigrateIds(schemaName, tableName string) string
+}
+
+// IdFinder is implemented by dialects which check a single migration alone,
+// so re-checking a failed migration doesn't read the whole migration table.
+type IdFinder interface {
+	// QuerySelectMigrateId returns the query - select 1 when the migration with the given id is recorded
+	QuerySelectMigrateId(schemaName, tableName string) string
+}
+
+// SchemaSelector is implemented by dialects which can change the default schema
+// of a session, so that unqualified names in migrations resolve to that schema.
+type SchemaSelector interface {
+	// QuerySelectSchema returns the query - make schemaName the default schema of the session
+	QuerySelectSchema(schemaName string) string
+	// QueryResetSchema returns the query - restore the default schema of the session,
+	// or an empty string when the session can't be restored and must be discarded
+	QueryResetSchema() string
+}
+
+// SchemaDropper is implemented by dialects which can drop a schema with all
+// its objects, e.g. to remove the temporary schema of a sandbox.
+type SchemaDropper interface {
+	// QueryDropSchema returns the query - drop schema if exists, with its objects
+	QueryDropSchema(schemaName string) string
+}
@@ -0,0 +1,16 @@
+package dialect
+
+// MigrateIndexer is implemented by dialects which index the migration table by
+// applied_at, so the latest applied migration is found without reading a long
+// history. The index is created with the migration table.
+type MigrateIndexer interface {
+	// QueryCreateMigrateIndex returns the query - create the index on applied_at if not exists
+	QueryCreateMigrateIndex(schemaName, tableName string) string
+	// QuerySelectLastMigrate returns the query - select id and applied_at of the latest applied migration
+	QuerySelectLastMigrate(schemaName, tableName string) string
+}
+
+// migrateIndexName returns the name of the index on applied_at of the migration table.
+func migrateIndexName(tableName string) string {
+	return tableName + "_applied_at_idx"
+}
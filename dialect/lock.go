@@ -0,0 +1,30 @@
+package dialect
+
+// AdvisoryLocker is implemented by dialects whose database provides
+// session-level advisory locks. Both queries receive the lock key as their
+// only argument.
+type AdvisoryLocker interface {
+	// QueryTryAdvisoryLock returns the query - try to acquire the advisory lock without blocking,
+	// selecting a single truthy column when the lock was acquired
+	QueryTryAdvisoryLock() string
+	// QueryAdvisoryUnlock returns the query - release the advisory lock
+	QueryAdvisoryUnlock() string
+}
+
+// TableLocker is implemented by dialects whose database has no advisory locks.
+// The lock is represented by a row in a dedicated lock table which is inserted
+// only when no row for the same key exists yet. Its acquired_at is the start
+// of the lease of the lock, renewed while it's held.
+type TableLocker interface {
+	// QueryCreateLockTable returns the query - create lock table if not exists
+	QueryCreateLockTable(schemaName, tableName string) string
+	// QueryInsertLock returns the query - insert the lock row unless a row with the same key exists,
+	// args are lock_key, owner, acquired_at
+	QueryInsertLock(schemaName, tableName string) string
+	// QuerySelectLock returns the query - select the owner and acquired_at of the lock row, args are lock_key
+	QuerySelectLock(schemaName, tableName string) string
+	// QueryRenewLock returns the query - set acquired_at of the lock row, args are acquired_at, lock_key, owner
+	QueryRenewLock(schemaName, tableName string) string
+	// QueryDeleteLock returns the query - delete the lock row, args are lock_key, owner
+	QueryDeleteLock(schemaName, tableName string) string
+}
@@ -7,6 +7,18 @@ import (
 
 var _ Dialect = (*MySQLDialect)(nil)
 
+var _ IdSelector = (*MySQLDialect)(nil)
+
+var _ ColumnRecorder = (*MySQLDialect)(nil)
+
+var _ AdvisoryLocker = (*MySQLDialect)(nil)
+
+var _ SchemaSelector = (*MySQLDialect)(nil)
+
+var _ SchemaDropper = (*MySQLDialect)(nil)
+
+var _ WarningReader = (*MySQLDialect)(nil)
+
 // MySQLDialect Implementation of Dialect for MySQL databases.
 type MySQLDialect struct {
 	// engine is the storage engine to use "InnoDB" vs "MyISAM" for example
@@ -50,11 +62,82 @@ func (d *MySQLDialect) QuerySelectMigrate(schemaName, tableName string) string {
 	)
 }
 
+func (d *MySQLDialect) QuerySelectMigrateIds(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT id FROM %s ORDER BY id ASC",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+func (d *MySQLDialect) QuerySelectMigrateId(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT 1 FROM %s WHERE id = ?",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
 func (d *MySQLDialect) QueryInsertMigrate(schemaName, tableName string) string {
 	return fmt.Sprintf("INSERT INTO %s(id, applied_at) VALUES (?, ?)",
 		d.quotedTableForQuery(schemaName, tableName))
 }
 
+func (d *MySQLDialect) QueryProbeMigrateColumn(schemaName, tableName, column string) string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0",
+		column, d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *MySQLDialect) QueryAddMigrateColumn(schemaName, tableName, column string, columnType ColumnType) string {
+	columnDef := "varchar(255)"
+	if columnType == ColumnInteger {
+		columnDef = "bigint"
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s NULL",
+		d.quotedTableForQuery(schemaName, tableName), column, columnDef)
+}
+
+func (d *MySQLDialect) QuerySelectMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("SELECT %s FROM %s ORDER BY id ASC",
+		migrateColumns(columns), d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *MySQLDialect) QueryInsertMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("INSERT INTO %s(%s) VALUES (%s)",
+		d.quotedTableForQuery(schemaName, tableName), migrateColumns(columns), placeholders(columns, questionMark))
+}
+
+// IsUndefinedColumn MySQL reports the error 1054, Unknown column.
+func (d *MySQLDialect) IsUndefinedColumn(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "Error 1054") || strings.Contains(err.Error(), "Unknown column"))
+}
+
+// QueryTryAdvisoryLock MySQL limits lock names to 64 characters, so the key is hashed.
+func (d *MySQLDialect) QueryTryAdvisoryLock() string {
+	return "SELECT GET_LOCK(SHA1(?), 0)"
+}
+
+func (d *MySQLDialect) QueryAdvisoryUnlock() string {
+	return "SELECT RELEASE_LOCK(SHA1(?))"
+}
+
+func (d *MySQLDialect) QuerySelectSchema(schemaName string) string {
+	return "USE " + d.quoteField(strings.ReplaceAll(schemaName, "`", "``"))
+}
+
+// QueryResetSchema MySQL can't unselect a database, so the connection is discarded.
+func (d *MySQLDialect) QueryResetSchema() string {
+	return ""
+}
+
+// QueryDropSchema MySQL schemas are databases.
+func (d *MySQLDialect) QueryDropSchema(schemaName string) string {
+	return "DROP DATABASE IF EXISTS " + d.quoteField(strings.ReplaceAll(schemaName, "`", "``"))
+}
+
+func (d *MySQLDialect) QuerySelectWarnings() string {
+	return "SHOW WARNINGS"
+}
+
 func (d *MySQLDialect) quoteField(f string) string {
 	return "`" + f + "`"
 }
@@ -0,0 +1,9 @@
+package dialect
+
+// WarningReader is implemented by dialects whose database keeps the warnings
+// of the last statement of the session until the next one, which the
+// executor reads after each statement of a migration.
+type WarningReader interface {
+	// QuerySelectWarnings returns the query - select the level, code and message of the warnings of the last statement
+	QuerySelectWarnings() string
+}
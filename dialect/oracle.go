@@ -7,6 +7,10 @@ import (
 
 var _ Dialect = (*OracleDialect)(nil)
 
+var _ IdSelector = (*OracleDialect)(nil)
+
+var _ ColumnRecorder = (*OracleDialect)(nil)
+
 // OracleDialect Implementation of Dialect for Oracle databases.
 type OracleDialect struct{}
 
@@ -41,11 +45,55 @@ func (d *OracleDialect) QuerySelectMigrate(schemaName, tableName string) string
 	)
 }
 
+func (d *OracleDialect) QuerySelectMigrateIds(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT id FROM %s ORDER BY id ASC",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+func (d *OracleDialect) QuerySelectMigrateId(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT 1 FROM %s WHERE id = :1",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
 func (d *OracleDialect) QueryInsertMigrate(schemaName, tableName string) string {
 	return fmt.Sprintf("INSERT INTO %s(id, applied_at) VALUES (:1, :2)",
 		d.quotedTableForQuery(schemaName, tableName))
 }
 
+func (d *OracleDialect) QueryProbeMigrateColumn(schemaName, tableName, column string) string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0",
+		column, d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *OracleDialect) QueryAddMigrateColumn(schemaName, tableName, column string, columnType ColumnType) string {
+	columnDef := "varchar2(255)"
+	if columnType == ColumnInteger {
+		columnDef = "number(19)"
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD (%s %s)",
+		d.quotedTableForQuery(schemaName, tableName), column, columnDef)
+}
+
+func (d *OracleDialect) QuerySelectMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("SELECT %s FROM %s ORDER BY id ASC",
+		migrateColumns(columns), d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *OracleDialect) QueryInsertMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("INSERT INTO %s(%s) VALUES (%s)",
+		d.quotedTableForQuery(schemaName, tableName), migrateColumns(columns), placeholders(columns, func(i int) string { return fmt.Sprintf(":%d", i) }))
+}
+
+// IsUndefinedColumn Oracle reports ORA-00904, invalid identifier.
+func (d *OracleDialect) IsUndefinedColumn(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "ORA-00904")
+}
+
 func (d *OracleDialect) quoteField(f string) string {
 	return `"` + strings.ToUpper(f) + `"`
 }
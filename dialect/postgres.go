@@ -7,6 +7,18 @@ import (
 
 var _ Dialect = (*PostgresDialect)(nil)
 
+var _ IdSelector = (*PostgresDialect)(nil)
+
+var _ MigrateIndexer = (*PostgresDialect)(nil)
+
+var _ ColumnRecorder = (*PostgresDialect)(nil)
+
+var _ AdvisoryLocker = (*PostgresDialect)(nil)
+
+var _ SchemaSelector = (*PostgresDialect)(nil)
+
+var _ SchemaDropper = (*PostgresDialect)(nil)
+
 // PostgresDialect Implementation of Dialect for PostgreSQL databases.
 type PostgresDialect struct {
 }
@@ -42,11 +54,91 @@ func (d *PostgresDialect) QuerySelectMigrate(schemaName, tableName string) strin
 	)
 }
 
+func (d *PostgresDialect) QuerySelectMigrateIds(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT id FROM %s ORDER BY id ASC",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+func (d *PostgresDialect) QuerySelectMigrateId(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT 1 FROM %s WHERE id = $1",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
 func (d *PostgresDialect) QueryInsertMigrate(schemaName, tableName string) string {
 	return fmt.Sprintf("INSERT INTO %s(id, applied_at) VALUES ($1, $2)",
 		d.quotedTableForQuery(schemaName, tableName))
 }
 
+func (d *PostgresDialect) QueryCreateMigrateIndex(schemaName, tableName string) string {
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (applied_at);",
+		d.quoteField(migrateIndexName(tableName)), d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *PostgresDialect) QuerySelectLastMigrate(schemaName, tableName string) string {
+	return fmt.Sprintf("SELECT id, applied_at FROM %s ORDER BY applied_at DESC, id DESC LIMIT 1",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *PostgresDialect) QueryProbeMigrateColumn(schemaName, tableName, column string) string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0",
+		column, d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *PostgresDialect) QueryAddMigrateColumn(schemaName, tableName, column string, columnType ColumnType) string {
+	columnDef := "text"
+	if columnType == ColumnInteger {
+		columnDef = "bigint"
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s",
+		d.quotedTableForQuery(schemaName, tableName), column, columnDef)
+}
+
+func (d *PostgresDialect) QuerySelectMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("SELECT %s FROM %s ORDER BY id ASC",
+		migrateColumns(columns), d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *PostgresDialect) QueryInsertMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("INSERT INTO %s(%s) VALUES (%s)",
+		d.quotedTableForQuery(schemaName, tableName), migrateColumns(columns), placeholders(columns, func(i int) string { return fmt.Sprintf("$%d", i) }))
+}
+
+// IsUndefinedColumn The drivers report the SQLSTATE 42703, undefined_column.
+func (d *PostgresDialect) IsUndefinedColumn(err error) bool {
+	if state := sqlState(err); state != "" {
+		return state == "42703"
+	}
+
+	return err != nil && strings.Contains(err.Error(), "does not exist") && strings.Contains(err.Error(), "column")
+}
+
+func (d *PostgresDialect) QueryTryAdvisoryLock() string {
+	return "SELECT pg_try_advisory_lock(('x' || substr(md5($1), 1, 16))::bit(64)::bigint)"
+}
+
+func (d *PostgresDialect) QueryAdvisoryUnlock() string {
+	return "SELECT pg_advisory_unlock(('x' || substr(md5($1), 1, 16))::bit(64)::bigint)"
+}
+
+// QuerySelectSchema keeps public on the search path, as it usually holds
+// extensions and objects shared by all tenants.
+func (d *PostgresDialect) QuerySelectSchema(schemaName string) string {
+	return fmt.Sprintf("SET search_path TO %s, public", d.quoteField(strings.ReplaceAll(schemaName, `"`, `""`)))
+}
+
+func (d *PostgresDialect) QueryResetSchema() string {
+	return "RESET search_path"
+}
+
+func (d *PostgresDialect) QueryDropSchema(schemaName string) string {
+	return fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", d.quoteField(strings.ReplaceAll(schemaName, `"`, `""`)))
+}
+
 func (d *PostgresDialect) quoteField(f string) string {
 	return `"` + f + `"`
 }
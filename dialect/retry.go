@@ -0,0 +1,11 @@
+package dialect
+
+// TxRetrier is implemented by dialects whose database aborts the transactions
+// conflicting with others, expecting them to be run again, e.g. on
+// serialization failures. The executor runs the migrations aborted so again,
+// with a backoff.
+type TxRetrier interface {
+	// IsRetryableTx tells whether the error aborted the transaction, which
+	// succeeds when run again
+	IsRetryableTx(err error) bool
+}
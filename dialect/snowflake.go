@@ -7,6 +7,12 @@ import (
 
 var _ Dialect = (*SnowflakeDialect)(nil)
 
+var _ IdSelector = (*SnowflakeDialect)(nil)
+
+var _ ColumnRecorder = (*SnowflakeDialect)(nil)
+
+var _ TableLocker = (*SnowflakeDialect)(nil)
+
 type SnowflakeDialect struct {
 }
 
@@ -41,11 +47,86 @@ func (d *SnowflakeDialect) QuerySelectMigrate(schemaName, tableName string) stri
 	)
 }
 
+func (d *SnowflakeDialect) QuerySelectMigrateIds(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT id FROM %s ORDER BY id ASC",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+func (d *SnowflakeDialect) QuerySelectMigrateId(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT 1 FROM %s WHERE id = ?",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
 func (d *SnowflakeDialect) QueryInsertMigrate(schemaName, tableName string) string {
 	return fmt.Sprintf("INSERT INTO %s(id, applied_at) VALUES (?, ?)",
 		d.quotedTableForQuery(schemaName, tableName))
 }
 
+func (d *SnowflakeDialect) QueryProbeMigrateColumn(schemaName, tableName, column string) string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0",
+		column, d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SnowflakeDialect) QueryAddMigrateColumn(schemaName, tableName, column string, columnType ColumnType) string {
+	columnDef := "varchar"
+	if columnType == ColumnInteger {
+		columnDef = "number"
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s",
+		d.quotedTableForQuery(schemaName, tableName), column, columnDef)
+}
+
+func (d *SnowflakeDialect) QuerySelectMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("SELECT %s FROM %s ORDER BY id ASC",
+		migrateColumns(columns), d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SnowflakeDialect) QueryInsertMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("INSERT INTO %s(%s) VALUES (%s)",
+		d.quotedTableForQuery(schemaName, tableName), migrateColumns(columns), placeholders(columns, questionMark))
+}
+
+// IsUndefinedColumn Snowflake reports the error 000904, invalid identifier.
+func (d *SnowflakeDialect) IsUndefinedColumn(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "000904") || strings.Contains(err.Error(), "invalid identifier"))
+}
+
+func (d *SnowflakeDialect) QueryCreateLockTable(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (lock_key varchar(255) primary key, owner varchar(255) not null, acquired_at timestamp not null);",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+// QueryInsertLock Snowflake does not enforce primary keys, so the row is
+// inserted through a MERGE which only inserts when the key is not matched.
+func (d *SnowflakeDialect) QueryInsertLock(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"MERGE INTO %s t USING (SELECT ? AS lock_key, ? AS owner, ? AS acquired_at) s ON t.lock_key = s.lock_key "+
+			"WHEN NOT MATCHED THEN INSERT (lock_key, owner, acquired_at) VALUES (s.lock_key, s.owner, s.acquired_at)",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SnowflakeDialect) QuerySelectLock(schemaName, tableName string) string {
+	return fmt.Sprintf("SELECT owner, acquired_at FROM %s WHERE lock_key = ? ORDER BY acquired_at ASC, owner ASC LIMIT 1",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SnowflakeDialect) QueryRenewLock(schemaName, tableName string) string {
+	return fmt.Sprintf("UPDATE %s SET acquired_at = ? WHERE lock_key = ? AND owner = ?",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SnowflakeDialect) QueryDeleteLock(schemaName, tableName string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE lock_key = ? AND owner = ?",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
 func (d *SnowflakeDialect) quoteField(f string) string {
 	return `"` + f + `"`
 }
@@ -2,10 +2,19 @@ package dialect
 
 import (
 	"fmt"
+	"strings"
 )
 
 var _ Dialect = (*SqliteDialect)(nil)
 
+var _ IdSelector = (*SqliteDialect)(nil)
+
+var _ MigrateIndexer = (*SqliteDialect)(nil)
+
+var _ ColumnRecorder = (*SqliteDialect)(nil)
+
+var _ TableLocker = (*SqliteDialect)(nil)
+
 type SqliteDialect struct {
 }
 
@@ -38,11 +47,91 @@ func (d *SqliteDialect) QuerySelectMigrate(schemaName, tableName string) string
 	)
 }
 
+func (d *SqliteDialect) QuerySelectMigrateIds(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT id FROM %s ORDER BY id ASC",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+func (d *SqliteDialect) QuerySelectMigrateId(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT 1 FROM %s WHERE id = ?",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
 func (d *SqliteDialect) QueryInsertMigrate(schemaName, tableName string) string {
 	return fmt.Sprintf("INSERT INTO %s(id, applied_at) VALUES (?, ?)",
 		d.quotedTableForQuery(schemaName, tableName))
 }
 
+func (d *SqliteDialect) QueryCreateMigrateIndex(schemaName, tableName string) string {
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (applied_at);",
+		d.quoteField(migrateIndexName(tableName)), d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SqliteDialect) QuerySelectLastMigrate(schemaName, tableName string) string {
+	return fmt.Sprintf("SELECT id, applied_at FROM %s ORDER BY applied_at DESC, id DESC LIMIT 1",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SqliteDialect) QueryProbeMigrateColumn(schemaName, tableName, column string) string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0",
+		column, d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SqliteDialect) QueryAddMigrateColumn(schemaName, tableName, column string, columnType ColumnType) string {
+	columnDef := "text"
+	if columnType == ColumnInteger {
+		columnDef = "integer"
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s",
+		d.quotedTableForQuery(schemaName, tableName), column, columnDef)
+}
+
+func (d *SqliteDialect) QuerySelectMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("SELECT %s FROM %s ORDER BY id ASC",
+		migrateColumns(columns), d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SqliteDialect) QueryInsertMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("INSERT INTO %s(%s) VALUES (%s)",
+		d.quotedTableForQuery(schemaName, tableName), migrateColumns(columns), placeholders(columns, questionMark))
+}
+
+func (d *SqliteDialect) IsUndefinedColumn(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "no such column")
+}
+
+func (d *SqliteDialect) QueryCreateLockTable(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (lock_key text primary key, owner text not null, acquired_at datetime not null);",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+func (d *SqliteDialect) QueryInsertLock(schemaName, tableName string) string {
+	return fmt.Sprintf("INSERT OR IGNORE INTO %s(lock_key, owner, acquired_at) VALUES (?, ?, ?)",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SqliteDialect) QuerySelectLock(schemaName, tableName string) string {
+	return fmt.Sprintf("SELECT owner, acquired_at FROM %s WHERE lock_key = ?",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SqliteDialect) QueryRenewLock(schemaName, tableName string) string {
+	return fmt.Sprintf("UPDATE %s SET acquired_at = ? WHERE lock_key = ? AND owner = ?",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SqliteDialect) QueryDeleteLock(schemaName, tableName string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE lock_key = ? AND owner = ?",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
 func (d *SqliteDialect) quoteField(f string) string {
 	return `"` + f + `"`
 }
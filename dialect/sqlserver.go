@@ -1,12 +1,19 @@
 package dialect
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
 var _ Dialect = (*SqlServerDialect)(nil)
 
+var _ IdSelector = (*SqlServerDialect)(nil)
+
+var _ MigrateIndexer = (*SqlServerDialect)(nil)
+
+var _ ColumnRecorder = (*SqlServerDialect)(nil)
+
 type SqlServerDialect struct {
 }
 
@@ -14,10 +21,11 @@ func NewSqlServerDialect() *SqlServerDialect {
 	return &SqlServerDialect{}
 }
 
+// QueryCreateMigrateSchema CREATE SCHEMA must be the only statement of a batch, hence the EXEC.
 func (d *SqlServerDialect) QueryCreateMigrateSchema(schemaName string) string {
 	return fmt.Sprintf(
-		"if schema_id(N'%s') is null CREATE SCHEMA IF NOT EXISTS %s;",
-		schemaName, schemaName)
+		"if schema_id(N'%s') is null EXEC('CREATE SCHEMA %s');",
+		schemaName, d.quoteField(schemaName))
 }
 
 func (d *SqlServerDialect) QueryCreateMigrateTable(schemaName, tableName string) string {
@@ -27,7 +35,7 @@ func (d *SqlServerDialect) QueryCreateMigrateTable(schemaName, tableName string)
 	}
 
 	return fmt.Sprintf(
-		"if object_id('%s%s') is null CREATE TABLE IF NOT EXISTS %s (id nvarchar(255) primary key, applied_at datetime2 not null);",
+		"if object_id(N'%s%s') is null CREATE TABLE %s (id nvarchar(255) primary key, applied_at datetime2 not null);",
 		schemaClause, tableName,
 		d.quotedTableForQuery(schemaName, tableName),
 	)
@@ -47,11 +55,78 @@ func (d *SqlServerDialect) QuerySelectMigrate(schemaName, tableName string) stri
 	)
 }
 
+func (d *SqlServerDialect) QuerySelectMigrateIds(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT id FROM %s ORDER BY id ASC",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+func (d *SqlServerDialect) QuerySelectMigrateId(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"SELECT 1 FROM %s WHERE id = ?",
+		d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
 func (d *SqlServerDialect) QueryInsertMigrate(schemaName, tableName string) string {
 	return fmt.Sprintf("INSERT INTO %s(id, applied_at) VALUES (?, ?)",
 		d.quotedTableForQuery(schemaName, tableName))
 }
 
+func (d *SqlServerDialect) QueryCreateMigrateIndex(schemaName, tableName string) string {
+	var schemaClause string
+	if strings.TrimSpace(schemaName) != "" {
+		schemaClause = fmt.Sprintf("%s.", schemaName)
+	}
+
+	return fmt.Sprintf(
+		"if not exists (select * from sys.indexes where name = N'%s' and object_id = object_id(N'%s%s')) CREATE INDEX %s ON %s (applied_at);",
+		migrateIndexName(tableName), schemaClause, tableName,
+		d.quoteField(migrateIndexName(tableName)), d.quotedTableForQuery(schemaName, tableName),
+	)
+}
+
+func (d *SqlServerDialect) QuerySelectLastMigrate(schemaName, tableName string) string {
+	return fmt.Sprintf("SELECT TOP 1 id, applied_at FROM %s ORDER BY applied_at DESC, id DESC",
+		d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SqlServerDialect) QueryProbeMigrateColumn(schemaName, tableName, column string) string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0",
+		column, d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SqlServerDialect) QueryAddMigrateColumn(schemaName, tableName, column string, columnType ColumnType) string {
+	columnDef := "nvarchar(255)"
+	if columnType == ColumnInteger {
+		columnDef = "bigint"
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD %s %s NULL",
+		d.quotedTableForQuery(schemaName, tableName), column, columnDef)
+}
+
+func (d *SqlServerDialect) QuerySelectMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("SELECT %s FROM %s ORDER BY id ASC",
+		migrateColumns(columns), d.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *SqlServerDialect) QueryInsertMigrateColumns(schemaName, tableName string, columns []string) string {
+	return fmt.Sprintf("INSERT INTO %s(%s) VALUES (%s)",
+		d.quotedTableForQuery(schemaName, tableName), migrateColumns(columns), placeholders(columns, questionMark))
+}
+
+// IsUndefinedColumn SQL Server reports the error 207, Invalid column name.
+func (d *SqlServerDialect) IsUndefinedColumn(err error) bool {
+	var number interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &number) {
+		return number.SQLErrorNumber() == 207
+	}
+
+	return err != nil && strings.Contains(err.Error(), "Invalid column name")
+}
+
 func (d *SqlServerDialect) quoteField(f string) string {
 	return "[" + strings.Replace(f, "]", "]]", -1) + "]"
 }
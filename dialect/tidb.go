@@ -0,0 +1,156 @@
+package dialect
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var _ Dialect = (*TiDBDialect)(nil)
+
+var _ IdSelector = (*TiDBDialect)(nil)
+
+var _ MigrateIndexer = (*TiDBDialect)(nil)
+
+var _ ColumnRecorder = (*TiDBDialect)(nil)
+
+var _ TableLocker = (*TiDBDialect)(nil)
+
+var _ SchemaSelector = (*TiDBDialect)(nil)
+
+var _ SchemaDropper = (*TiDBDialect)(nil)
+
+var _ WarningReader = (*TiDBDialect)(nil)
+
+var _ DDLDetector = (*TiDBDialect)(nil)
+
+// TiDBDialect Implementation of Dialect for TiDB, over the MySQL protocol.
+// TiDB commits DDL statements on the spot, so the migrations running DDL are
+// applied without a transaction. Unlike MySQL, it creates indexes and adds
+// columns with IF NOT EXISTS. The migration lock is a row of a lock table.
+type TiDBDialect struct {
+	mysql MySQLDialect
+}
+
+func NewTiDBDialect() *TiDBDialect {
+	return &TiDBDialect{mysql: MySQLDialect{engine: "InnoDB", encoding: "utf8mb4"}}
+}
+
+func (d *TiDBDialect) QueryCreateMigrateSchema(schemaName string) string {
+	return fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s;", schemaName)
+}
+
+func (d *TiDBDialect) QueryCreateMigrateTable(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (id varchar(255) primary key, applied_at datetime not null) charset=%s;",
+		d.mysql.quotedTableForQuery(schemaName, tableName), d.mysql.encoding,
+	)
+}
+
+func (d *TiDBDialect) QueryDeleteMigrate(schemaName, tableName string) string {
+	return d.mysql.QueryDeleteMigrate(schemaName, tableName)
+}
+
+func (d *TiDBDialect) QuerySelectMigrate(schemaName, tableName string) string {
+	return d.mysql.QuerySelectMigrate(schemaName, tableName)
+}
+
+func (d *TiDBDialect) QuerySelectMigrateIds(schemaName, tableName string) string {
+	return d.mysql.QuerySelectMigrateIds(schemaName, tableName)
+}
+
+func (d *TiDBDialect) QuerySelectMigrateId(schemaName, tableName string) string {
+	return d.mysql.QuerySelectMigrateId(schemaName, tableName)
+}
+
+func (d *TiDBDialect) QueryInsertMigrate(schemaName, tableName string) string {
+	return d.mysql.QueryInsertMigrate(schemaName, tableName)
+}
+
+func (d *TiDBDialect) QueryCreateMigrateIndex(schemaName, tableName string) string {
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (applied_at);",
+		d.mysql.quoteField(migrateIndexName(tableName)), d.mysql.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *TiDBDialect) QuerySelectLastMigrate(schemaName, tableName string) string {
+	return fmt.Sprintf("SELECT id, applied_at FROM %s ORDER BY applied_at DESC, id DESC LIMIT 1",
+		d.mysql.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *TiDBDialect) QueryProbeMigrateColumn(schemaName, tableName, column string) string {
+	return d.mysql.QueryProbeMigrateColumn(schemaName, tableName, column)
+}
+
+func (d *TiDBDialect) QueryAddMigrateColumn(schemaName, tableName, column string, columnType ColumnType) string {
+	columnDef := "varchar(255)"
+	if columnType == ColumnInteger {
+		columnDef = "bigint"
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s NULL",
+		d.mysql.quotedTableForQuery(schemaName, tableName), column, columnDef)
+}
+
+func (d *TiDBDialect) QuerySelectMigrateColumns(schemaName, tableName string, columns []string) string {
+	return d.mysql.QuerySelectMigrateColumns(schemaName, tableName, columns)
+}
+
+func (d *TiDBDialect) QueryInsertMigrateColumns(schemaName, tableName string, columns []string) string {
+	return d.mysql.QueryInsertMigrateColumns(schemaName, tableName, columns)
+}
+
+func (d *TiDBDialect) IsUndefinedColumn(err error) bool {
+	return d.mysql.IsUndefinedColumn(err)
+}
+
+func (d *TiDBDialect) QueryCreateLockTable(schemaName, tableName string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (lock_key varchar(255) primary key, owner varchar(255) not null, acquired_at datetime not null) charset=%s;",
+		d.mysql.quotedTableForQuery(schemaName, tableName), d.mysql.encoding,
+	)
+}
+
+func (d *TiDBDialect) QueryInsertLock(schemaName, tableName string) string {
+	return fmt.Sprintf("INSERT IGNORE INTO %s(lock_key, owner, acquired_at) VALUES (?, ?, ?)",
+		d.mysql.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *TiDBDialect) QuerySelectLock(schemaName, tableName string) string {
+	return fmt.Sprintf("SELECT owner, acquired_at FROM %s WHERE lock_key = ?",
+		d.mysql.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *TiDBDialect) QueryRenewLock(schemaName, tableName string) string {
+	return fmt.Sprintf("UPDATE %s SET acquired_at = ? WHERE lock_key = ? AND owner = ?",
+		d.mysql.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *TiDBDialect) QueryDeleteLock(schemaName, tableName string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE lock_key = ? AND owner = ?",
+		d.mysql.quotedTableForQuery(schemaName, tableName))
+}
+
+func (d *TiDBDialect) QuerySelectSchema(schemaName string) string {
+	return d.mysql.QuerySelectSchema(schemaName)
+}
+
+func (d *TiDBDialect) QueryResetSchema() string {
+	return d.mysql.QueryResetSchema()
+}
+
+func (d *TiDBDialect) QueryDropSchema(schemaName string) string {
+	return d.mysql.QueryDropSchema(schemaName)
+}
+
+func (d *TiDBDialect) QuerySelectWarnings() string {
+	return d.mysql.QuerySelectWarnings()
+}
+
+// tidbDDL matches the statements TiDB commits on the spot, after leading
+// comments.
+var tidbDDL = regexp.MustCompile(`(?is)^(?:\s+|--[^\n]*(?:\n|$)|/\*.*?\*/)*(?:CREATE|ALTER|DROP|TRUNCATE|RENAME|FLASHBACK|RECOVER)\b`)
+
+// IsDDL TiDB commits the transaction before a DDL statement and the DDL
+// itself, which can't be rolled back.
+func (d *TiDBDialect) IsDDL(stmt string) bool {
+	return tidbDDL.MatchString(stmt)
+}
@@ -0,0 +1,256 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+// DryRun holds the SQL a run would execute, see ExecDryRun.
+type DryRun struct {
+	// Setup are the statements creating the migration table, or adding its
+	// missing columns, with CreateTable.
+	Setup []string
+	// Migrations are the planned migrations, in order.
+	Migrations []MigrationSQL
+}
+
+// MigrationSQL is the SQL of a planned migration.
+type MigrationSQL struct {
+	Id                 string
+	Direction          MigrationDirection
+	DisableTransaction bool
+	StatementTimeout   time.Duration
+	// Statements are the statements of the migration, then the statement
+	// recording it in, or deleting it from, the migration table, with its
+	// arguments inlined. The applied_at recorded is the time of the dry run.
+	Statements []string
+}
+
+// ExecDryRun plans at most max migrations, 0 for no limit, and returns the
+// SQL ExecMax would execute, without changing the database, e.g. to review
+// what would run against production.
+func (ex *MigrationExecutor) ExecDryRun(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	dir MigrationDirection,
+	max int,
+) (*DryRun, error) {
+	return ex.dryRun(ctx, db, dialect, source, dir, max, -1)
+}
+
+// ExecDryRunToVersion returns the SQL ExecVersion would execute, without
+// changing the database.
+func (ex *MigrationExecutor) ExecDryRunToVersion(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	dir MigrationDirection,
+	version int64,
+) (*DryRun, error) {
+	return ex.dryRun(ctx, db, dialect, source, dir, 0, version)
+}
+
+func (ex *MigrationExecutor) dryRun(
+	ctx context.Context,
+	db *sql.DB,
+	d dialect.Dialect,
+	source MigrationSource,
+	dir MigrationDirection,
+	max int,
+	version int64,
+) (*DryRun, error) {
+	rep := ex.newRepository(db, d)
+	run := &DryRun{}
+
+	recorder, _ := d.(dialect.ColumnRecorder)
+
+	var appliedIds []string
+
+	// The table is missing when even its id can't be selected, it would be
+	// created empty with all the columns.
+	if ex.CreateTable && recorder != nil && rep.probeColumn(ctx, recorder, "id") != nil {
+		if ex.CreateSchema && strings.TrimSpace(ex.SchemaName) != "" {
+			run.Setup = append(run.Setup, d.QueryCreateMigrateSchema(ex.SchemaName))
+		}
+
+		run.Setup = append(run.Setup, d.QueryCreateMigrateTable(ex.SchemaName, ex.TableName))
+		if indexer, ok := d.(dialect.MigrateIndexer); ok {
+			run.Setup = append(run.Setup, indexer.QueryCreateMigrateIndex(ex.SchemaName, ex.TableName))
+		}
+	} else {
+		if err := rep.DetectColumns(ctx, false); err != nil {
+			return nil, err
+		}
+
+		var err error
+
+		appliedIds, err = rep.ListMigrationIds(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ex.CreateTable && recorder != nil {
+		for _, column := range migrateColumns {
+			if !rep.hasExtraColumn(column.name) {
+				run.Setup = append(run.Setup, recorder.QueryAddMigrateColumn(ex.SchemaName, ex.TableName, column.name, column.columnType))
+				rep.columns = append(rep.columns, column.name)
+			}
+		}
+
+		rep.renderQueries()
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := ex.planApplied(rep, migrations, appliedIds, dir, max, version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range plan.Migrations {
+		m := MigrationSQL{
+			Id:                 migration.Id,
+			Direction:          dir,
+			DisableTransaction: migration.DisableTransaction,
+			StatementTimeout:   migration.StatementTimeout,
+		}
+
+		err := migration.Statements(func(stmt string, _ sqlparse.LineRange) error {
+			m.Statements = append(m.Statements, strings.TrimSuffix(strings.TrimRight(stmt, " \n"), ";"))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch dir {
+		case Up:
+			record := ex.newRecord(migration, ex.now(), 0)
+			m.Statements = append(m.Statements, inlineArgs(rep.insertQuery(), rep.insertArgs(record)))
+		case Down:
+			m.Statements = append(m.Statements, inlineArgs(rep.queries.delete, []any{migration.Id}))
+		}
+
+		run.Migrations = append(run.Migrations, m)
+	}
+
+	return run, nil
+}
+
+// WriteTo writes the SQL as a script, the statements of each migration
+// under a comment naming it.
+func (d *DryRun) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	if len(d.Setup) > 0 {
+		b.WriteString("-- Migration table\n")
+
+		for _, stmt := range d.Setup {
+			b.WriteString(strings.TrimSuffix(strings.TrimSpace(stmt), ";") + ";\n")
+		}
+	}
+
+	for i, m := range d.Migrations {
+		if i > 0 || len(d.Setup) > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "-- Migration %s (%s)\n", m.Id, m.Direction)
+
+		if m.DisableTransaction {
+			b.WriteString("-- without transaction\n")
+		}
+
+		if m.StatementTimeout > 0 {
+			fmt.Fprintf(&b, "-- statement timeout %s\n", m.StatementTimeout)
+		}
+
+		for _, stmt := range m.Statements {
+			b.WriteString(stmt + ";\n")
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+
+	return int64(n), err
+}
+
+// inlineArgs replaces the placeholders of the query, ?, $1 or :1, with the
+// arguments as SQL literals. The placeholders in quotes are left alone.
+func inlineArgs(query string, args []any) string {
+	var (
+		b     strings.Builder
+		next  int
+		quote byte
+	)
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?' && next < len(args):
+			b.WriteString(sqlLiteral(args[next]))
+			next++
+
+			continue
+		case c == '$' || c == ':':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+
+			n, err := strconv.Atoi(query[i+1 : j])
+			if err == nil && n >= 1 && n <= len(args) {
+				b.WriteString(sqlLiteral(args[n-1]))
+				i = j - 1
+
+				continue
+			}
+		}
+
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// sqlLiteral renders the argument as an SQL literal.
+func sqlLiteral(arg any) string {
+	switch v := argValue(arg).(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case time.Time:
+		return "'" + v.UTC().Format("2006-01-02 15:04:05.000000") + "'"
+	case bool:
+		if v {
+			return "1"
+		}
+
+		return "0"
+	default:
+		return fmt.Sprint(v)
+	}
+}
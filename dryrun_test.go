@@ -0,0 +1,65 @@
+package migrate
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func (s *SqliteMigrateSuite) TestExecDryRun(c *C) {
+	ctx := context.Background()
+	source := NewMemoryMigrationSource(sqliteMigrations)
+	s.ex.Clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	// The table would be created, but isn't.
+	run, err := s.ex.ExecDryRun(ctx, s.db, s.dialect, source, Up, 1)
+	c.Assert(err, IsNil)
+	c.Assert(run.Setup, DeepEquals, []string{
+		`CREATE TABLE IF NOT EXISTS "migrations" (id text primary key, applied_at datetime not null);`,
+		`CREATE INDEX IF NOT EXISTS "migrations_applied_at_idx" ON "migrations" (applied_at);`,
+		`ALTER TABLE "migrations" ADD COLUMN checksum text`,
+		`ALTER TABLE "migrations" ADD COLUMN duration_ms integer`,
+		`ALTER TABLE "migrations" ADD COLUMN applied_by text`,
+	})
+	c.Assert(run.Migrations, DeepEquals, []MigrationSQL{{
+		Id:        "123",
+		Direction: Up,
+		Statements: []string{
+			"CREATE TABLE people (id int)",
+			`INSERT INTO "migrations"(id, applied_at, checksum, duration_ms, applied_by) VALUES ('123', '2024-01-02 03:04:05.000000', '` + sqliteMigrations[0].Checksum() + `', NULL, NULL)`,
+		},
+	}})
+
+	var out strings.Builder
+	_, err = run.WriteTo(&out)
+	c.Assert(err, IsNil)
+	c.Assert(out.String(), Matches, `-- Migration table\nCREATE TABLE .*;\nCREATE INDEX .*;\n(ALTER TABLE .*;\n){3}\n-- Migration 123 \(up\)\nCREATE TABLE people \(id int\);\nINSERT INTO .*;\n`)
+
+	_, err = s.db.Exec(`SELECT 1 FROM migrations`)
+	c.Assert(err, ErrorMatches, "no such table: migrations")
+
+	n, err := s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	run, err = s.ex.ExecDryRunToVersion(ctx, s.db, s.dialect, source, Down, 124)
+	c.Assert(err, IsNil)
+	c.Assert(run.Setup, HasLen, 0)
+	c.Assert(run.Migrations, DeepEquals, []MigrationSQL{{
+		Id:         "124",
+		Direction:  Down,
+		Statements: []string{"SELECT 0", `DELETE FROM "migrations" WHERE id = '124'`},
+	}})
+
+	records, err := s.ex.GetMigrationRecords(ctx, s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+}
+
+func (*SqliteMigrateSuite) TestInlineArgs(c *C) {
+	c.Assert(inlineArgs("INSERT INTO t VALUES (?, ?, '?')", []any{"it's", nil}), Equals, `INSERT INTO t VALUES ('it''s', NULL, '?')`)
+	c.Assert(inlineArgs("DELETE FROM t WHERE id = $1 AND n = :2 AND x::text = $3", []any{"a", 2}), Equals, "DELETE FROM t WHERE id = 'a' AND n = 2 AND x::text = $3")
+}
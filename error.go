@@ -42,3 +42,7 @@ func newTxError(migration *PlannedMigration, err error) error {
 func (e *TxError) Error() string {
 	return e.Err.Error() + " handling " + e.Migration.Id
 }
+
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	`time`
+)
+
+// MigrationEvent is an event of the progress of a run, sent to the EventSink
+// of the executor: a PlanComputed, MigrationStarted, StatementExecuted,
+// MigrationFinished or RunFinished.
+type MigrationEvent interface {
+	migrationEvent()
+}
+
+// PlanComputed is sent once the migrations to apply are known.
+type PlanComputed struct {
+	Direction MigrationDirection
+	// Migrations are the ids of the migrations to apply, in order.
+	Migrations []string
+}
+
+// MigrationStarted is sent before the statements of a migration run.
+type MigrationStarted struct {
+	Id        string
+	Direction MigrationDirection
+	// Statements is the number of statements of the migration, -1 when it's
+	// unknown: the statements of a migration with a Stream are only read
+	// while they run.
+	Statements int
+}
+
+// StatementExecuted is sent after each statement of a migration.
+type StatementExecuted struct {
+	MigrationId string
+	// Index is the position of the statement in the migration, from 0.
+	Index int
+	// Statement describes the statement by its lines in the migration file,
+	// e.g. "lines 4-6", or by its position when the lines aren't known.
+	Statement string
+	Duration  time.Duration
+	// Notices are the notices and warnings the database raised.
+	Notices []Notice
+	Err     error
+}
+
+// MigrationFinished is sent when a migration is applied or failed.
+type MigrationFinished struct {
+	Id        string
+	Direction MigrationDirection
+	Duration  time.Duration
+	// Notices are the notices and warnings raised by all the statements.
+	Notices []Notice
+	// Err is also set when another migrator applied the migration
+	// concurrently, after which the run goes on.
+	Err error
+}
+
+// RunFinished is sent at the end of a run, also when it failed before
+// planning, e.g. on the migration lock.
+type RunFinished struct {
+	Direction MigrationDirection
+	Planned   int
+	Applied   int
+	Duration  time.Duration
+	Err       error
+}
+
+func (PlanComputed) migrationEvent()      {}
+func (MigrationStarted) migrationEvent()  {}
+func (StatementExecuted) migrationEvent() {}
+func (MigrationFinished) migrationEvent() {}
+func (RunFinished) migrationEvent()       {}
+
+// emit sends the event to the EventSink, if any.
+func (ex *MigrationExecutor) emit(event MigrationEvent) {
+	if ex.EventSink != nil {
+		ex.EventSink(event)
+	}
+}
+
+// Sinks combines event sinks, e.g. Hooks.Sink with a sink of its own, into an
+// EventSink calling each of them in order. Nil sinks are skipped.
+func Sinks(sinks ...func(MigrationEvent)) func(MigrationEvent) {
+	var combined []func(MigrationEvent)
+
+	for _, sink := range sinks {
+		if sink != nil {
+			combined = append(combined, sink)
+		}
+	}
+
+	switch len(combined) {
+	case 0:
+		return nil
+	case 1:
+		return combined[0]
+	default:
+		return func(event MigrationEvent) {
+			for _, sink := range combined {
+				sink(event)
+			}
+		}
+	}
+}
+
+// Hooks calls a function per kind of event, e.g. to drive a progress bar,
+// once set as EventSink, or combined with another sink with Sinks:
+//
+//	ex.EventSink = migrate.Sinks(ex.EventSink, migrate.Hooks{
+//		AfterMigration: func(e migrate.MigrationFinished) { bar.Increment() },
+//	}.Sink)
+//
+// Nil functions are skipped.
+type Hooks struct {
+	// BeforeMigration is called before the statements of a migration run.
+	BeforeMigration func(MigrationStarted)
+	// AfterMigration is called when a migration is applied or failed.
+	AfterMigration func(MigrationFinished)
+	// OnStatement is called after each statement, also when it failed.
+	OnStatement func(StatementExecuted)
+	// OnError is called when a run failed, after AfterMigration for the
+	// migration which failed, if any.
+	OnError func(RunFinished)
+}
+
+// Sink calls the hook of the event.
+func (h Hooks) Sink(event MigrationEvent) {
+	switch e := event.(type) {
+	case MigrationStarted:
+		if h.BeforeMigration != nil {
+			h.BeforeMigration(e)
+		}
+	case MigrationFinished:
+		if h.AfterMigration != nil {
+			h.AfterMigration(e)
+		}
+	case StatementExecuted:
+		if h.OnStatement != nil {
+			h.OnStatement(e)
+		}
+	case RunFinished:
+		if h.OnError != nil && e.Err != nil {
+			h.OnError(e)
+		}
+	}
+}
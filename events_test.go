@@ -0,0 +1,103 @@
+package migrate
+
+import (
+	"fmt"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func (s *SqliteMigrateSuite) TestEvents(c *C) {
+	events := make(chan MigrationEvent, 100)
+	s.ex.EventSink = func(event MigrationEvent) { events <- event }
+
+	n, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations), Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+	close(events)
+
+	var kinds []string
+	for event := range events {
+		kinds = append(kinds, fmt.Sprintf("%T", event))
+
+		switch e := event.(type) {
+		case PlanComputed:
+			c.Assert(e.Migrations, DeepEquals, []string{"123", "124"})
+		case StatementExecuted:
+			c.Assert(e.Err, IsNil)
+			c.Assert(e.Statement, Equals, "statement 1")
+		case RunFinished:
+			c.Assert(e.Planned, Equals, 2)
+			c.Assert(e.Applied, Equals, 2)
+			c.Assert(e.Err, IsNil)
+		}
+	}
+
+	c.Assert(kinds, DeepEquals, []string{
+		"migrate.PlanComputed",
+		"migrate.MigrationStarted",
+		"migrate.StatementExecuted",
+		"migrate.MigrationFinished",
+		"migrate.MigrationStarted",
+		"migrate.StatementExecuted",
+		"migrate.MigrationFinished",
+		"migrate.RunFinished",
+	})
+}
+
+func (s *SqliteMigrateSuite) TestHooks(c *C) {
+	var calls []string
+
+	s.ex.EventSink = Hooks{
+		BeforeMigration: func(e MigrationStarted) { calls = append(calls, "before "+e.Id) },
+		AfterMigration:  func(e MigrationFinished) { calls = append(calls, fmt.Sprintf("after %s %v", e.Id, e.Err != nil)) },
+		OnStatement:     func(e StatementExecuted) { calls = append(calls, fmt.Sprintf("statement %s %d", e.MigrationId, e.Index)) },
+		OnError:         func(e RunFinished) { calls = append(calls, fmt.Sprintf("error %d", e.Applied)) },
+	}.Sink
+
+	source := NewMemoryMigrationSource([]*Migration{
+		sqliteMigrations[0],
+		{Id: "125", Up: []string{"INSERT INTO missing VALUES (1)"}},
+	})
+
+	n, err := s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, NotNil)
+	c.Assert(n, Equals, 1)
+	c.Assert(calls, DeepEquals, []string{
+		"before 123",
+		"statement 123 0",
+		"after 123 false",
+		"before 125",
+		"statement 125 0",
+		"after 125 true",
+		"error 1",
+	})
+
+	// A run which succeeds calls no OnError.
+	calls = nil
+	s.ex.EventSink = Hooks{OnError: func(RunFinished) { calls = append(calls, "error") }}.Sink
+
+	_, err = s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations[:1]), Down)
+	c.Assert(err, IsNil)
+	c.Assert(calls, HasLen, 0)
+}
+
+func (s *SqliteMigrateSuite) TestSinks(c *C) {
+	var calls []string
+
+	c.Assert(Sinks(nil, nil), IsNil)
+
+	s.ex.EventSink = Sinks(
+		func(event MigrationEvent) {
+			if _, ok := event.(RunFinished); ok {
+				calls = append(calls, "sink")
+			}
+		},
+		nil,
+		Hooks{AfterMigration: func(e MigrationFinished) { calls = append(calls, "after "+e.Id) }}.Sink,
+	)
+
+	_, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations[:1]), Up)
+	c.Assert(err, IsNil)
+	c.Assert(calls, DeepEquals, []string{"after 123", "sink"})
+}
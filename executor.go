@@ -3,12 +3,15 @@ package migrate
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	`github.com/kva3umoda/sql-migrate/dialect`
+	`github.com/kva3umoda/sql-migrate/sqlparse`
 )
 
 type MigrationDirection int
@@ -18,6 +21,17 @@ const (
 	Down
 )
 
+func (d MigrationDirection) String() string {
+	switch d {
+	case Up:
+		return "up"
+	case Down:
+		return "down"
+	default:
+		return fmt.Sprintf("MigrationDirection(%d)", int(d))
+	}
+}
+
 const (
 	defaultTableName = "migrations"
 )
@@ -37,8 +51,87 @@ type MigrationExecutor struct {
 	CreateTable bool
 	// CreateSchema disable the creation of the migration schema
 	CreateSchema bool
+	// Lock serializes concurrent migrators with an advisory lock, or with a row
+	// in the lock table for databases without advisory locks.
+	Lock bool
+	// LockKey overrides the key of the migration lock. It defaults to a key derived
+	// from SchemaName and TableName, so migrations of different schemas don't block each other.
+	LockKey string
+	// LockTimeout bounds how long to wait for the migration lock. Zero waits until the context is done.
+	LockTimeout time.Duration
+	// LockLease is how long the lock row of the dialects without advisory
+	// locks stays valid unless renewed, DefaultLockLease when zero. It's
+	// renewed every third of it while held, on a connection of the pool of
+	// its own, and a run takes over a row whose lease expired, left by a
+	// migrator which crashed.
+	LockLease time.Duration
+	// SingleFlight coalesces concurrent Exec calls of this process against the same
+	// database, source, schema and table: only one run happens and the other callers
+	// wait for it and share its result.
+	SingleFlight bool
+	// Observer is notified of the runs, migrations and statements, e.g. for
+	// tracing or metrics. Nil observes nothing.
+	Observer Observer
+	// SlowStatement is the duration above which a statement of a migration is
+	// reported as slow, with a warning logged while it still runs. Zero disables it.
+	SlowStatement time.Duration
+	// ArgRedaction hides the bind arguments of the traced queries, which are
+	// logged verbatim by default.
+	ArgRedaction ArgRedaction
+	// EventSink receives the events of the runs, e.g. to render their progress
+	// live. It's called synchronously by the migrating goroutine, so it should
+	// hand slow work off, e.g. to a buffered channel. Nil drops the events.
+	// Hooks.Sink calls a function per kind of event, Sinks combines sinks.
+	EventSink func(MigrationEvent)
+	// Notices collects the notices the driver pushes while a statement runs,
+	// e.g. RAISE NOTICE of postgres, which are logged and sent with the
+	// events of the statement and the migration. The warnings of dialects
+	// implementing dialect.WarningReader, e.g. mysql, are read without it.
+	Notices *NoticeBuffer
+	// SingleConnection runs everything on a single connection taken from the
+	// pool: the migration lock, the planning and the migrations. It's needed
+	// behind a pooler in transaction mode, e.g. PgBouncer, which hands the
+	// statements of separate pool connections to any server connection.
+	SingleConnection bool
+	// Clock returns the time recorded as applied_at of the migrations, e.g. a
+	// fixed time in tests. Nil uses time.Now.
+	Clock func() time.Time
+	// Runner runs the statements of the migrations on the driver connection
+	// instead of through database/sql, e.g. pgxmigrate.Runner for COPY and
+	// batches with pgx. Everything runs on a single connection then, as with
+	// SingleConnection.
+	Runner StatementRunner
+	// Announcer announces the runs which applied migrations on the database,
+	// e.g. PostgresNotify, while the lock is held. A failed announcement is
+	// logged and doesn't fail the run.
+	Announcer Announcer
+	// RequireDownConfirmation refuses the Down runs, and the Up runs with
+	// statements dropping or truncating, unless confirmed with
+	// AllowDestructive, e.g. against a rollback of production by a wrong
+	// direction flag. They fail with a DestructiveError holding the token.
+	// Migrations with a Stream of their own count as dropping, their
+	// statements aren't read before they run.
+	RequireDownConfirmation bool
+	// AppliedBy identifies who applies the migrations, e.g. a user or a
+	// deployment, and is recorded with each migration next to the time it
+	// took, for the dialects implementing dialect.ColumnRecorder.
+	AppliedBy string
+	// ParallelMigrations applies up to that many Up migrations at once, each
+	// in its own transaction on its own connection, after the migrations of
+	// its DependsOn, e.g. backfills of unrelated tables. A migration without
+	// DependsOn waits for all the migrations before it. The EventSink and the
+	// Observer are then called from several goroutines. It can't be combined
+	// with SingleConnection or a Runner. Down migrations are rolled back one
+	// by one. Zero or one apply the migrations one by one, in order.
+	ParallelMigrations int
 
 	Logger Logger
+
+	// destructiveToken is the token given to AllowDestructive.
+	destructiveToken string
+	// columns caches the extra columns detected per database, nil doesn't
+	// cache them.
+	columns *columnCache
 }
 
 func NewMigrationExecutor() *MigrationExecutor {
@@ -49,7 +142,17 @@ func NewMigrationExecutor() *MigrationExecutor {
 		CreateTable:   false,
 		CreateSchema:  false,
 		Logger:        DefaultLogger(),
+		columns:       &columnCache{},
+	}
+}
+
+// now returns the time of the Clock in UTC.
+func (ex *MigrationExecutor) now() time.Time {
+	if ex.Clock != nil {
+		return ex.Clock().UTC()
 	}
+
+	return time.Now().UTC()
 }
 
 // Exec Returns the number of applied migrations.
@@ -93,12 +196,7 @@ func (ex *MigrationExecutor) ExecMaxContext(
 	dir MigrationDirection,
 	max int,
 ) (int, error) {
-	migrations, rep, err := ex.PlanMigration(ctx, db, dialect, source, dir, max)
-	if err != nil {
-		return 0, err
-	}
-
-	return ex.applyMigrations(ctx, dir, rep, migrations)
+	return ex.exec(ctx, db, db, dialect, source, dir, max, -1)
 }
 
 // ExecVersion Returns the number of applied migrations.
@@ -120,41 +218,327 @@ func (ex *MigrationExecutor) ExecVersionContext(
 	dir MigrationDirection,
 	version int64,
 ) (int, error) {
-	migrations, rep, err := ex.PlanMigrationToVersion(ctx, db, dialect, source, dir, version)
+	return ex.exec(ctx, db, db, dialect, source, dir, 0, version)
+}
+
+// exec plans and applies migrations on conn while holding the migration lock,
+// which is acquired through db.
+func (ex *MigrationExecutor) exec(
+	ctx context.Context,
+	db *sql.DB,
+	conn SqlDB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	dir MigrationDirection,
+	max int,
+	version int64,
+) (int, error) {
+	if ex.SingleFlight {
+		return execFlights.do(ctx, ex.flightKey(conn, source, dir, max, version), func() (int, error) {
+			return ex.execLocked(ctx, db, conn, dialect, source, dir, max, version)
+		})
+	}
+
+	return ex.execLocked(ctx, db, conn, dialect, source, dir, max, version)
+}
+
+func (ex *MigrationExecutor) execLocked(
+	ctx context.Context,
+	db *sql.DB,
+	conn SqlDB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	dir MigrationDirection,
+	max int,
+	version int64,
+) (int, error) {
+	return ex.run(ctx, db, conn, dialect, dir, max, version, func(ctx context.Context, conn SqlDB) (*MigrationPlan, error) {
+		return ex.plan(ctx, conn, dialect, source, dir, max, version)
+	})
+}
+
+// run applies the migrations which planner returns for conn, while holding the
+// migration lock acquired through db.
+func (ex *MigrationExecutor) run(
+	ctx context.Context,
+	db *sql.DB,
+	conn SqlDB,
+	dialect dialect.Dialect,
+	dir MigrationDirection,
+	max int,
+	version int64,
+	planner func(ctx context.Context, conn SqlDB) (*MigrationPlan, error),
+) (applied int, err error) {
+	var planned int
+
+	started := time.Now()
+	ctx, done := ex.startRun(ctx, dialect, dir, max, version)
+
+	defer func() {
+		done(RunResult{Planned: planned, Applied: applied, Err: err})
+		ex.emit(RunFinished{Direction: dir, Planned: planned, Applied: applied, Duration: time.Since(started), Err: err})
+	}()
+
+	locked := SqlDB(db)
+
+	if ex.SingleConnection || ex.Runner != nil {
+		var release func()
+
+		conn, release, err = session(ctx, conn)
+		if err != nil {
+			return 0, err
+		}
+
+		defer release()
+
+		locked = conn
+	}
+
+	unlock, err := ex.lock(ctx, db, locked, dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	defer unlock()
+
+	plan, err := planner(ctx, conn)
 	if err != nil {
 		return 0, err
 	}
 
-	return ex.applyMigrations(ctx, dir, rep, migrations)
+	if err := ex.addColumns(ctx, plan.rep); err != nil {
+		return 0, err
+	}
+
+	planned = len(plan.Migrations)
+
+	if ex.EventSink != nil {
+		ids := make([]string, len(plan.Migrations))
+		for i, migration := range plan.Migrations {
+			ids[i] = migration.Id
+		}
+
+		ex.emit(PlanComputed{Direction: dir, Migrations: ids})
+	}
+
+	if err := ex.confirmDestructive(dir, plan.Migrations); err != nil {
+		return 0, err
+	}
+
+	if ex.ParallelMigrations > 1 && dir == Up {
+		applied, err = ex.applyParallel(ctx, dir, plan.rep, plan.Migrations)
+	} else {
+		applied, err = ex.applyMigrations(ctx, dir, plan.rep, plan.Migrations)
+	}
+
+	if err == nil && applied > 0 && ex.Announcer != nil {
+		ex.announce(ctx, conn, dir, plan)
+	}
+
+	return applied, err
 }
 
 // SkipMax Skip a set of migrations
 // Will skip at most `max` migrations. Pass 0 for no limit.
+// The migrations are recorded in a single transaction. When it fails, those
+// recorded by another migrator meanwhile are left out and the others are
+// recorded again at once; otherwise the error is returned and none is recorded.
 // Returns the number of skipped migrations.
 func (ex *MigrationExecutor) SkipMax(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource, dir MigrationDirection, max int) (int, error) {
-	migrations, rep, err := ex.PlanMigration(ctx, db, dialect, m, dir, max)
+	conn, release, err := ex.connection(ctx, db)
 	if err != nil {
 		return 0, err
 	}
 
-	// Skip migrations
-	applied := 0
+	defer release()
+
+	unlock, err := ex.lock(ctx, db, conn, dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	defer unlock()
+
+	migrations, rep, err := ex.planMigrationCommon(ctx, conn, dialect, m, dir, max, -1)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ex.addColumns(ctx, rep); err != nil {
+		return 0, err
+	}
+
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+
+	// Record all the migrations at once, which only fails as a whole, e.g.
+	// when another migrator recorded some of them meanwhile. The others are
+	// then recorded at once again.
+	err = ex.saveMigrations(ctx, rep, migrations)
+	if err != nil {
+		remaining := ex.unrecordedMigrations(ctx, rep, migrations)
+
+		if len(remaining) == len(migrations) {
+			logWith(ctx, ex.Logger, LevelError, fmt.Sprintf("Failed to save the skipped migrations: %v", err), Field{"error", err})
+
+			return 0, err
+		}
+
+		logWith(ctx, ex.Logger, LevelInfo,
+			fmt.Sprintf("%d of the skipped migrations were recorded by another migrator, recording the others: %v", len(migrations)-len(remaining), err),
+			Field{"error", err})
+
+		migrations = remaining
+
+		err = ex.saveMigrations(ctx, rep, migrations)
+		if err != nil {
+			logWith(ctx, ex.Logger, LevelError, fmt.Sprintf("Failed to save the skipped migrations: %v", err), Field{"error", err})
+
+			return 0, err
+		}
+	}
+
+	for _, migration := range migrations {
+		logWith(ctx, ex.Logger, LevelInfo, fmt.Sprintf("Skipped migration %s", migration.Id),
+			Field{"migration_id", migration.Id}, Field{"direction", dir})
+	}
+
+	return len(migrations), nil
+}
+
+// Baseline records all migrations up to and including version as applied
+// without running them, to adopt migrations on an existing database. The
+// migration table must not hold any migration yet, and version must be the
+// version of exactly one migration, so the migrations recorded are never in
+// doubt. All records are written in a single transaction. Returns the number
+// of recorded migrations.
+func (ex *MigrationExecutor) Baseline(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource, version int64) (int, error) {
+	if version < 0 {
+		return 0, fmt.Errorf("baseline: invalid version %d", version)
+	}
+
+	conn, release, err := ex.connection(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	defer release()
+
+	unlock, err := ex.lock(ctx, db, conn, dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	defer unlock()
+
+	records, err := ex.getMigrationRecords(ctx, conn, dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(records) > 0 {
+		return 0, fmt.Errorf("baseline: the migration table already holds %d migrations", len(records))
+	}
+
+	migrations, rep, err := ex.planMigrationCommon(ctx, conn, dialect, m, Up, 0, version)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ex.addColumns(ctx, rep); err != nil {
+		return 0, err
+	}
+
+	// The plan stops at the first migration of the version, a second one
+	// would be left out.
+	var matching []string
+
+	ids, err := findMigrationIds(m)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if migration := (&Migration{Id: id}); migration.isNumeric() && migration.VersionInt() == version {
+			matching = append(matching, id)
+		}
+	}
+
+	if len(matching) > 1 {
+		return 0, fmt.Errorf("baseline: version %d matches several migrations: %s", version, strings.Join(matching, ", "))
+	}
+
+	if err := ex.saveMigrations(ctx, rep, migrations); err != nil {
+		return 0, err
+	}
 
 	for _, migration := range migrations {
+		logWith(ctx, ex.Logger, LevelInfo, fmt.Sprintf("Baselined migration %s", migration.Id),
+			Field{"migration_id", migration.Id})
+	}
+
+	return len(migrations), nil
+}
+
+// Init creates the schema and the migration table, as configured by
+// CreateSchema and CreateTable, without applying any migration. It fails when
+// the migration table can't be read afterwards, e.g. when CreateTable is
+// disabled and the table is missing.
+func (ex *MigrationExecutor) Init(ctx context.Context, db *sql.DB, dialect dialect.Dialect) error {
+	conn, release, err := ex.connection(ctx, db)
+	if err != nil {
+		return err
+	}
 
-		err := ex.saveMigration(rep, migration)
+	defer release()
+
+	unlock, err := ex.lock(ctx, db, conn, dialect)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	rep, err := ex.getMigrationRepository(ctx, conn, dialect)
+	if err != nil {
+		return err
+	}
+
+	if err := ex.addColumns(ctx, rep); err != nil {
+		return err
+	}
+
+	_, err = rep.ListMigration(ctx)
+
+	return err
+}
+
+// saveMigrations records the migrations in a single transaction.
+func (ex *MigrationExecutor) saveMigrations(ctx context.Context, rep *MigrationRepository, migrations []*PlannedMigration) (err error) {
+	tx, ctx, err := rep.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
 		if err != nil {
-			ex.Logger.Errorf("Failed to save migration %s: %v", migration.Id, err)
+			_ = tx.Rollback()
 
-			return applied, err
+			return
 		}
 
-		ex.Logger.Infof("Skipped migration %s", migration.Id)
+		err = tx.Commit()
+	}()
+
+	now := ex.now()
+	records := make([]MigrationRecord, len(migrations))
 
-		applied++
+	for i, migration := range migrations {
+		records[i] = ex.newRecord(migration, now, 0)
 	}
 
-	return applied, nil
+	return rep.SaveMigrations(ctx, records)
 }
 
 func (ex *MigrationExecutor) saveMigration(rep *MigrationRepository, migration *PlannedMigration) (err error) {
@@ -180,7 +564,7 @@ func (ex *MigrationExecutor) saveMigration(rep *MigrationRepository, migration *
 		}()
 	}
 
-	err = rep.SaveMigration(ctx, MigrationRecord{Id: migration.Id, AppliedAt: time.Now().UTC()})
+	err = rep.SaveMigration(ctx, ex.newRecord(migration, ex.now(), 0))
 	if err != nil {
 		return newTxError(migration, err)
 	}
@@ -188,6 +572,18 @@ func (ex *MigrationExecutor) saveMigration(rep *MigrationRepository, migration *
 	return nil
 }
 
+// newRecord returns the record of the applied migration, which took duration
+// to apply, zero when it was only recorded.
+func (ex *MigrationExecutor) newRecord(migration *PlannedMigration, appliedAt time.Time, duration time.Duration) MigrationRecord {
+	return MigrationRecord{
+		Id:        migration.Id,
+		AppliedAt: appliedAt,
+		Checksum:  migration.Checksum(),
+		Duration:  duration,
+		AppliedBy: ex.AppliedBy,
+	}
+}
+
 // Applies the planned migrations and returns the number of applied migrations.
 func (ex *MigrationExecutor) applyMigrations(
 	ctx context.Context,
@@ -197,32 +593,113 @@ func (ex *MigrationExecutor) applyMigrations(
 ) (int, error) {
 	applied := 0
 	for _, migration := range migrations {
-		err := ex.applyMigration(ctx, dir, rep, migration)
+		ok, err := ex.applyPlanned(ctx, dir, rep, migration)
 		if err != nil {
-			ex.Logger.Errorf("Failed to apply migration %s: %v", migration.Id, err)
-
 			return applied, err
 		}
 
-		ex.Logger.Infof("Applied migration %s", migration.Id)
-
-		applied++
+		if ok {
+			applied++
+		}
 	}
 
 	return applied, nil
 }
 
+// applyPlanned applies the migration and reports whether it was applied by
+// this executor rather than by another migrator.
+func (ex *MigrationExecutor) applyPlanned(
+	ctx context.Context,
+	dir MigrationDirection,
+	rep *MigrationRepository,
+	migration *PlannedMigration,
+) (bool, error) {
+	started := time.Now()
+	ex.emit(MigrationStarted{Id: migration.Id, Direction: dir, Statements: migration.statementCount()})
+	migrationCtx, done := ex.startMigration(ctx, dir, migration)
+	notices, err := ex.retryMigration(migrationCtx, dir, rep, migration)
+	done(err)
+	ex.emit(MigrationFinished{Id: migration.Id, Direction: dir, Duration: time.Since(started), Notices: notices, Err: err})
+
+	fields := []Field{
+		{"migration_id", migration.Id},
+		{"direction", dir},
+		{"duration", time.Since(started)},
+		{"statements", migration.statementCount()},
+	}
+
+	if err != nil && ex.appliedConcurrently(ctx, rep, dir, migration) {
+		logWith(ctx, ex.Logger, LevelInfo, fmt.Sprintf("Migration %s was already handled by another migrator", migration.Id), fields...)
+
+		return false, nil
+	}
+
+	if err != nil {
+		logWith(ctx, ex.Logger, LevelError, fmt.Sprintf("Failed to apply migration %s: %v", migration.Id, err),
+			append(fields, Field{"error", err})...)
+
+		return false, err
+	}
+
+	logWith(ctx, ex.Logger, LevelInfo, fmt.Sprintf("Applied migration %s", migration.Id), fields...)
+
+	return true, nil
+}
+
+// txRetries and txRetryBackoff bound the retries of the migrations whose
+// transaction was aborted by a conflict, with a dialect.TxRetrier. The
+// backoff doubles with each retry.
+var (
+	txRetries      = 5
+	txRetryBackoff = 50 * time.Millisecond
+)
+
+// retryMigration applies the migration, and applies it again when the
+// dialect tells its transaction was aborted to be retried.
+func (ex *MigrationExecutor) retryMigration(
+	ctx context.Context,
+	dir MigrationDirection,
+	rep *MigrationRepository,
+	migration *PlannedMigration,
+) ([]Notice, error) {
+	retrier, _ := rep.dialect.(dialect.TxRetrier)
+	backoff := txRetryBackoff
+
+	for retry := 1; ; retry++ {
+		notices, err := ex.applyMigration(ctx, dir, rep, migration)
+		if err == nil || retrier == nil || migration.DisableTransaction || retry > txRetries || !retrier.IsRetryableTx(err) {
+			return notices, err
+		}
+
+		logWith(ctx, ex.Logger, LevelWarn, fmt.Sprintf("Retrying migration %s in %s after a conflict: %v", migration.Id, backoff, err),
+			Field{"migration_id", migration.Id}, Field{"retry", retry}, Field{"error", err})
+
+		select {
+		case <-ctx.Done():
+			return notices, err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
 func (ex *MigrationExecutor) applyMigration(
 	ctx context.Context,
 	dir MigrationDirection,
 	rep *MigrationRepository,
 	migration *PlannedMigration,
-) (err error) {
+) (notices []Notice, err error) {
+	// Drop the notices of the queries before the migration, e.g. of the lock.
+	ex.Notices.take()
+
+	started := time.Now()
+
 	if !migration.DisableTransaction {
 		var tx *sql.Tx
 		tx, ctx, err = rep.BeginTx(ctx)
 		if err != nil {
-			return newTxError(migration, err)
+			return nil, newTxError(migration, err)
 		}
 
 		defer func() {
@@ -239,21 +716,91 @@ func (ex *MigrationExecutor) applyMigration(
 		}()
 	}
 
-	for _, stmt := range migration.Queries {
+	if ex.Runner != nil {
+		err = ex.runStatements(ctx, rep, migration)
+		if err != nil {
+			return nil, newTxError(migration, err)
+		}
+
+		return nil, ex.recordMigration(ctx, dir, rep, migration, time.Since(started))
+	}
+
+	i := 0
+
+	err = migration.Statements(func(stmt string, lines sqlparse.LineRange) error {
 		// remove the semicolon from stmt, fix ORA-00922 issue in database oracle
 		stmt = strings.TrimSuffix(stmt, "\n")
 		stmt = strings.TrimSuffix(stmt, " ")
 		stmt = strings.TrimSuffix(stmt, ";")
 
-		_, err = rep.ExecContext(ctx, stmt)
-		if err != nil {
-			return newTxError(migration, err)
-		}
+		name := statementName(i, lines)
+		started := time.Now()
+		timeoutCtx, cancel := statementContext(ctx, migration, 1)
+		stmtCtx, done := ex.startStatement(timeoutCtx, stmt)
+		finished := ex.watchStatement(ctx, migration, name)
+		_, err := rep.ExecContext(stmtCtx, stmt)
+		err = statementTimeout(ctx, timeoutCtx, migration, err)
+		cancel()
+		finished()
+		done(err)
+
+		stmtNotices := ex.statementNotices(ctx, rep, migration, name, err)
+		notices = append(notices, stmtNotices...)
+
+		ex.emit(StatementExecuted{
+			MigrationId: migration.Id,
+			Index:       i,
+			Statement:   name,
+			Duration:    time.Since(started),
+			Notices:     stmtNotices,
+			Err:         err,
+		})
+
+		i++
+
+		return err
+	})
+	if err != nil {
+		return notices, newTxError(migration, err)
 	}
 
+	return notices, ex.recordMigration(ctx, dir, rep, migration, time.Since(started))
+}
+
+// statementContext bounds ctx by the StatementTimeout of the migration, for n
+// statements run at once.
+func statementContext(ctx context.Context, migration *PlannedMigration, n int) (context.Context, context.CancelFunc) {
+	if migration.StatementTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, time.Duration(max(n, 1))*migration.StatementTimeout)
+}
+
+// statementTimeout tells the error of a statement apart when it was canceled
+// by the StatementTimeout of the migration, rather than by ctx.
+func statementTimeout(ctx, timeoutCtx context.Context, migration *PlannedMigration, err error) error {
+	if err == nil || ctx.Err() != nil || !errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	return fmt.Errorf("statement timeout of %s exceeded: %w", migration.StatementTimeout, err)
+}
+
+// recordMigration records the migration as applied, which took duration, or
+// removes its record after it was rolled back.
+func (ex *MigrationExecutor) recordMigration(
+	ctx context.Context,
+	dir MigrationDirection,
+	rep *MigrationRepository,
+	migration *PlannedMigration,
+	duration time.Duration,
+) error {
+	var err error
+
 	switch dir {
 	case Up:
-		err = rep.SaveMigration(ctx, MigrationRecord{Id: migration.Id, AppliedAt: time.Now().UTC()})
+		err = rep.SaveMigration(ctx, ex.newRecord(migration, ex.now(), duration))
 	case Down:
 		err = rep.DeleteMigration(ctx, migration.Id)
 	default:
@@ -267,6 +814,54 @@ func (ex *MigrationExecutor) applyMigration(
 	return nil
 }
 
+// appliedConcurrently re-checks the state of a migration which failed to apply.
+// When two migrators race, the loser typically fails on a unique constraint
+// violation while recording the migration, or on statements conflicting with
+// the already committed changes. If the migration has meanwhile been recorded
+// (or for Down, removed) by the other migrator, the failure is harmless.
+func (ex *MigrationExecutor) appliedConcurrently(
+	ctx context.Context,
+	rep *MigrationRepository,
+	dir MigrationDirection,
+	migration *PlannedMigration,
+) bool {
+	exists, err := rep.HasMigration(ctx, migration.Id)
+	if err != nil {
+		return false
+	}
+
+	return exists == (dir == Up)
+}
+
+// unrecordedMigrations returns the migrations which failed to be recorded at
+// once and haven't meanwhile been recorded by another migrator, reading the
+// ids of the recorded migrations once.
+func (ex *MigrationExecutor) unrecordedMigrations(
+	ctx context.Context,
+	rep *MigrationRepository,
+	migrations []*PlannedMigration,
+) []*PlannedMigration {
+	ids, err := rep.ListMigrationIds(ctx)
+	if err != nil {
+		return migrations
+	}
+
+	recorded := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		recorded[id] = struct{}{}
+	}
+
+	var remaining []*PlannedMigration
+
+	for _, migration := range migrations {
+		if _, ok := recorded[migration.Id]; !ok {
+			remaining = append(remaining, migration)
+		}
+	}
+
+	return remaining
+}
+
 // PlanMigration Plan a migration.
 func (ex *MigrationExecutor) PlanMigration(
 	ctx context.Context,
@@ -294,50 +889,84 @@ func (ex *MigrationExecutor) PlanMigrationToVersion(
 // planMigrationCommon A common method to plan a migration.
 func (ex *MigrationExecutor) planMigrationCommon(
 	ctx context.Context,
-	db *sql.DB,
+	db SqlDB,
 	dialect dialect.Dialect,
 	source MigrationSource,
 	dir MigrationDirection,
 	max int,
 	version int64,
 ) ([]*PlannedMigration, *MigrationRepository, error) {
-	rep, err := ex.getMigrationRepository(ctx, db, dialect)
+	plan, err := ex.plan(ctx, db, dialect, source, dir, max, version)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	return plan.Migrations, plan.rep, nil
+}
+
+func (ex *MigrationExecutor) plan(
+	ctx context.Context,
+	db SqlDB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	dir MigrationDirection,
+	max int,
+	version int64,
+) (*MigrationPlan, error) {
+	rep, err := ex.getMigrationRepository(ctx, db, dialect)
+	if err != nil {
+		return nil, err
+	}
+
 	migrations, err := source.FindMigrations()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	migrationRecords, err := rep.ListMigration(ctx)
+	// Planning only needs the ids of the applied migrations.
+	appliedIds, err := rep.ListMigrationIds(ctx)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	// Sort migrations that have been run by Id.
-	var existingMigrations []*Migration
-	for _, migrationRecord := range migrationRecords {
+	return ex.planApplied(rep, migrations, appliedIds, dir, max, version)
+}
+
+// planApplied plans the migrations found given the ids of the applied ones.
+func (ex *MigrationExecutor) planApplied(
+	rep *MigrationRepository,
+	migrations []*Migration,
+	appliedIds []string,
+	dir MigrationDirection,
+	max int,
+	version int64,
+) (*MigrationPlan, error) {
+	// Index the applied and the found migrations by id once, the lookups below
+	// are linear in the number of migrations then.
+	applied := make(map[string]struct{}, len(appliedIds))
+	existingMigrations := make([]*Migration, 0, len(appliedIds))
+
+	for _, id := range appliedIds {
+		applied[id] = struct{}{}
 		existingMigrations = append(existingMigrations, &Migration{
-			Id: migrationRecord.Id,
+			Id: id,
 		})
 	}
 
+	// Sort migrations that have been run by Id.
 	sort.Sort(byId(existingMigrations))
 
 	// Make sure all migrations in the database are among the found migrations which
 	// are to be applied.
 	if !ex.IgnoreUnknown {
-		migrationsSearch := make(map[string]struct{})
-
+		found := make(map[string]struct{}, len(migrations))
 		for _, migration := range migrations {
-			migrationsSearch[migration.Id] = struct{}{}
+			found[migration.Id] = struct{}{}
 		}
 
 		for _, existingMigration := range existingMigrations {
-			if _, ok := migrationsSearch[existingMigration.Id]; !ok {
-				return nil, nil, newPlanError(existingMigration, "unknown migration in database")
+			if _, ok := found[existingMigration.Id]; !ok {
+				return nil, newPlanError(existingMigration, "unknown migration in database")
 			}
 		}
 	}
@@ -353,7 +982,7 @@ func (ex *MigrationExecutor) planMigrationCommon(
 	// Add missing migrations up to the last run migration.
 	// This can happen for example when merges happened.
 	if len(existingMigrations) > 0 {
-		result = append(result, toCatchup(migrations, existingMigrations, record)...)
+		result = append(result, toCatchup(migrations, applied, record)...)
 	}
 
 	// Figure out which migrations to apply
@@ -366,7 +995,7 @@ func (ex *MigrationExecutor) planMigrationCommon(
 			tempVersion := toApply[targetIndex].VersionInt()
 
 			if dir == Up && tempVersion > version || dir == Down && tempVersion < version {
-				return nil, nil, newPlanError(&Migration{}, fmt.Errorf("unknown migration with version id %d in database", version).Error())
+				return nil, newPlanError(&Migration{}, fmt.Errorf("unknown migration with version id %d in database", version).Error())
 			}
 
 			if tempVersion == version {
@@ -378,7 +1007,7 @@ func (ex *MigrationExecutor) planMigrationCommon(
 		}
 
 		if targetIndex == len(toApply) {
-			return nil, nil, newPlanError(&Migration{}, fmt.Errorf("unknown migration with version id %d in database", version).Error())
+			return nil, newPlanError(&Migration{}, fmt.Errorf("unknown migration with version id %d in database", version).Error())
 		}
 	} else if max > 0 && max < toApplyCount {
 		toApplyCount = max
@@ -389,21 +1018,53 @@ func (ex *MigrationExecutor) planMigrationCommon(
 			result = append(result, &PlannedMigration{
 				Migration:          v,
 				Queries:            v.Up,
+				Lines:              v.UpLines,
 				DisableTransaction: v.DisableTransactionUp,
+				StatementTimeout:   v.StatementTimeoutUp,
+				direction:          Up,
 			})
 		} else if dir == Down {
 			result = append(result, &PlannedMigration{
 				Migration:          v,
 				Queries:            v.Down,
+				Lines:              v.DownLines,
 				DisableTransaction: v.DisableTransactionDown,
+				StatementTimeout:   v.StatementTimeoutDown,
+				direction:          Down,
 			})
 		}
 	}
 
-	return result, rep, nil
+	if err := disableDDLTransactions(rep.dialect, result); err != nil {
+		return nil, err
+	}
+
+	return &MigrationPlan{
+		Direction:  dir,
+		Migrations: result,
+		rep:        rep,
+		max:        max,
+		version:    version,
+		applied:    applied,
+	}, nil
 }
 
 func (ex *MigrationExecutor) GetMigrationRecords(ctx context.Context, db *sql.DB, dialect dialect.Dialect) ([]MigrationRecord, error) {
+	return ex.getMigrationRecords(ctx, db, dialect)
+}
+
+// LastMigration returns the latest applied migration, or nil when none is
+// applied, without reading the whole migration table where it's indexed.
+func (ex *MigrationExecutor) LastMigration(ctx context.Context, db *sql.DB, dialect dialect.Dialect) (*MigrationRecord, error) {
+	rep, err := ex.getMigrationRepository(ctx, db, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	return rep.LastMigration(ctx)
+}
+
+func (ex *MigrationExecutor) getMigrationRecords(ctx context.Context, db SqlDB, dialect dialect.Dialect) ([]MigrationRecord, error) {
 	rep, err := ex.getMigrationRepository(ctx, db, dialect)
 	if err != nil {
 		return nil, err
@@ -417,9 +1078,9 @@ func (ex *MigrationExecutor) GetMigrationRecords(ctx context.Context, db *sql.DB
 	return records, nil
 }
 
-func (ex *MigrationExecutor) getMigrationRepository(ctx context.Context, db *sql.DB, dialect dialect.Dialect) (*MigrationRepository, error) {
+func (ex *MigrationExecutor) getMigrationRepository(ctx context.Context, db SqlDB, dialect dialect.Dialect) (*MigrationRepository, error) {
 	// Create migration database map
-	rep := NewMigrationRepository(db, dialect, ex.SchemaName, ex.TableName, ex.Logger)
+	rep := ex.newRepository(db, dialect)
 
 	if ex.CreateSchema && strings.TrimSpace(ex.SchemaName) != "" {
 		err := rep.CreateSchema(ctx)
@@ -435,30 +1096,182 @@ func (ex *MigrationExecutor) getMigrationRepository(ctx context.Context, db *sql
 		}
 	}
 
+	pool, _ := db.(*sql.DB)
+	key := columnKey{db: pool, schemaName: ex.SchemaName, tableName: ex.TableName}
+
+	if columns, ok := ex.columns.load(key); ok {
+		rep.columns = columns
+		rep.renderQueries()
+
+		return rep, nil
+	}
+
+	// Missing columns are only added by addColumns, under the migration
+	// lock, and read as empty until then.
+	err := rep.DetectColumns(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Missing columns are probed again, they may be added by then.
+	if len(rep.columns) == len(migrateColumns) {
+		ex.columns.store(key, rep.columns)
+	}
+
 	return rep, nil
 }
 
-func toCatchup(migrations, existingMigrations []*Migration, lastRun *Migration) []*PlannedMigration {
+// addColumns adds the extra columns missing from the migration table when
+// CreateTable is set. It's called by the runs writing to the migration table
+// once they hold the migration lock, so that concurrent migrators and the
+// commands only reading the table don't alter it at once.
+func (ex *MigrationExecutor) addColumns(ctx context.Context, rep *MigrationRepository) error {
+	if !ex.CreateTable || len(rep.columns) == len(migrateColumns) {
+		return nil
+	}
+
+	err := rep.DetectColumns(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	if pool, ok := rep.db.(*sql.DB); ok && len(rep.columns) == len(migrateColumns) {
+		ex.columns.store(columnKey{db: pool, schemaName: ex.SchemaName, tableName: ex.TableName}, rep.columns)
+	}
+
+	return nil
+}
+
+// columnKey identifies a migration table of a database.
+type columnKey struct {
+	db         *sql.DB
+	schemaName string
+	tableName  string
+}
+
+// columnCache holds the extra columns detected in the migration tables, so
+// that they're probed once per executor rather than on every call. Only the
+// tables of a *sql.DB having all the columns are cached, not those reached
+// through a connection or a transaction.
+type columnCache struct {
+	mu      sync.Mutex
+	columns map[columnKey][]string
+}
+
+func (c *columnCache) load(key columnKey) ([]string, bool) {
+	if c == nil || key.db == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	columns, ok := c.columns[key]
+
+	// The repository may append to its columns, e.g. in a dry run.
+	return append([]string(nil), columns...), ok
+}
+
+func (c *columnCache) store(key columnKey, columns []string) {
+	if c == nil || key.db == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.columns == nil {
+		c.columns = make(map[columnKey][]string)
+	}
+
+	c.columns[key] = append([]string(nil), columns...)
+}
+
+// newRepository returns a repository of the migration table of the executor.
+func (ex *MigrationExecutor) newRepository(db SqlDB, dialect dialect.Dialect) *MigrationRepository {
+	rep := NewMigrationRepository(db, dialect, ex.SchemaName, ex.TableName, ex.Logger)
+	rep.SetArgRedaction(ex.ArgRedaction)
+
+	return rep
+}
+
+// connection returns the connection to run on: a single connection of the
+// pool with SingleConnection, released by the returned function, or db.
+func (ex *MigrationExecutor) connection(ctx context.Context, db *sql.DB) (SqlDB, func(), error) {
+	if !ex.SingleConnection {
+		return db, func() {}, nil
+	}
+
+	return session(ctx, db)
+}
+
+// session returns a connection of db keeping its session, e.g. for an
+// advisory lock: db itself when it's a *sql.Conn already, or a connection
+// taken from the pool, released by the returned function.
+func session(ctx context.Context, db SqlDB) (SqlDB, func(), error) {
+	pool, ok := db.(*sql.DB)
+	if !ok {
+		return db, func() {}, nil
+	}
+
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, func() { _ = conn.Close() }, nil
+}
+
+// toCatchup returns the migrations before the last one run which aren't
+// applied, to apply them Up.
+func toCatchup(migrations []*Migration, applied map[string]struct{}, lastRun *Migration) []*PlannedMigration {
 	missing := make([]*PlannedMigration, 0)
 	for _, migration := range migrations {
-		found := false
-		for _, existing := range existingMigrations {
-			if existing.Id == migration.Id {
-				found = true
-				break
-			}
-		}
-		if !found && migration.Less(lastRun) {
+		if _, ok := applied[migration.Id]; !ok && migration.Less(lastRun) {
 			missing = append(missing, &PlannedMigration{
 				Migration:          migration,
 				Queries:            migration.Up,
+				Lines:              migration.UpLines,
 				DisableTransaction: migration.DisableTransactionUp,
+				StatementTimeout:   migration.StatementTimeoutUp,
+				direction:          Up,
 			})
 		}
 	}
 	return missing
 }
 
+// disableDDLTransactions runs the migrations with a DDL statement without a
+// transaction, for dialects implementing dialect.DDLDetector, whose database
+// would commit the DDL anyway. Streamed migrations, whose statements are only
+// read while they run, always run without a transaction then.
+func disableDDLTransactions(d dialect.Dialect, migrations []*PlannedMigration) error {
+	detector, ok := d.(dialect.DDLDetector)
+	if !ok {
+		return nil
+	}
+
+	for _, migration := range migrations {
+		if migration.Stream != nil {
+			migration.DisableTransaction = true
+		}
+
+		if migration.DisableTransaction {
+			continue
+		}
+
+		err := migration.Statements(func(stmt string, _ sqlparse.LineRange) error {
+			migration.DisableTransaction = migration.DisableTransaction || detector.IsDDL(stmt)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // toApplyMigrations Filter a slice of migrations into ones that should be applied.
 func toApplyMigrations(migrations []*Migration, current string, direction MigrationDirection) []*Migration {
 	index := -1
@@ -0,0 +1,211 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+// golangMigrateSeed is the file of an export to golang-migrate which records
+// the applied version. golang-migrate skips it, its name has no version.
+const golangMigrateSeed = "schema_migrations.sql"
+
+// ExportReport describes the files written by an export to the layout of
+// another migration tool.
+type ExportReport struct {
+	// Files lists the names of the files written in the directory, in order.
+	Files []string
+	// Version is the latest applied version, -1 when none is applied.
+	Version int64
+}
+
+// ExportToGolangMigrate writes the migrations of the source to dir in the
+// layout of golang-migrate, each split into a N_name.up.sql and a
+// N_name.down.sql file, e.g. 3_add_email.up.sql for 3_add_email.sql, for a
+// project handed over to teams using that tool. It also writes
+// schema_migrations.sql, which creates the table of golang-migrate and
+// records the latest applied version, to run once on the database.
+//
+// golang-migrate only records a version, so the export fails when a migration
+// of the source is pending before an applied one, when migrations aren't
+// numbered or share a version, and when an applied migration isn't in the
+// source unless IgnoreUnknown is set. Existing files aren't overwritten.
+func (ex *MigrationExecutor) ExportToGolangMigrate(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	dir string,
+) (ExportReport, error) {
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return ExportReport{}, err
+	}
+
+	records, err := ex.getMigrationRecords(ctx, db, dialect)
+	if err != nil {
+		return ExportReport{}, err
+	}
+
+	report := ExportReport{Version: -1}
+
+	applied := make(map[string]bool, len(records))
+	for _, record := range records {
+		applied[record.Id] = true
+	}
+
+	versions := make(map[int64]string, len(migrations))
+	pending := ""
+
+	for _, migration := range migrations {
+		if !migration.isNumeric() {
+			return ExportReport{}, fmt.Errorf("golang-migrate: migration %s has no version", migration.Id)
+		}
+
+		version := migration.VersionInt()
+		if other, ok := versions[version]; ok {
+			return ExportReport{}, fmt.Errorf("golang-migrate: migrations %s and %s have the same version %d", other, migration.Id, version)
+		}
+
+		versions[version] = migration.Id
+
+		if !applied[migration.Id] {
+			if pending == "" {
+				pending = migration.Id
+			}
+
+			continue
+		}
+
+		if pending != "" {
+			return ExportReport{}, fmt.Errorf("golang-migrate: migration %s is pending before the applied %s", pending, migration.Id)
+		}
+
+		report.Version = version
+		delete(applied, migration.Id)
+	}
+
+	if len(applied) > 0 && !ex.IgnoreUnknown {
+		for _, record := range records {
+			if applied[record.Id] {
+				return ExportReport{}, fmt.Errorf("golang-migrate: applied migration %s is not in the source", record.Id)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return ExportReport{}, err
+	}
+
+	for _, migration := range migrations {
+		base := fmt.Sprintf("%d_%s", migration.VersionInt(), golangMigrateName(migration))
+
+		for _, direction := range []MigrationDirection{Up, Down} {
+			content, err := renderStatements(migration, direction)
+			if err != nil {
+				return report, fmt.Errorf("golang-migrate: cannot read migration %s: %w", migration.Id, err)
+			}
+
+			name := base + "." + direction.String() + ".sql"
+			if err := writeNewFile(filepath.Join(dir, name), content); err != nil {
+				return report, err
+			}
+
+			report.Files = append(report.Files, name)
+		}
+	}
+
+	if err := writeNewFile(filepath.Join(dir, golangMigrateSeed), golangMigrateSeedSQL(dialect, report.Version)); err != nil {
+		return report, err
+	}
+
+	report.Files = append(report.Files, golangMigrateSeed)
+
+	return report, nil
+}
+
+// golangMigrateName returns the name of the migration without its version
+// and extension, e.g. add_email for 3_add_email.sql.
+func golangMigrateName(migration *Migration) string {
+	version, _ := migration.versionPrefix()
+
+	name := strings.TrimSuffix(strings.TrimPrefix(migration.Id, version), ".sql")
+	name = strings.Trim(name, "_-. ")
+
+	if name == "" {
+		return "migration"
+	}
+
+	return name
+}
+
+// renderStatements renders the statements of the direction, one per line
+// and terminated by a semicolon.
+func renderStatements(migration *Migration, dir MigrationDirection) (string, error) {
+	var b strings.Builder
+
+	err := migration.statements(dir, func(stmt string, _ sqlparse.LineRange) error {
+		stmt = strings.TrimSpace(stmt)
+		if !strings.HasSuffix(stmt, ";") {
+			stmt += ";"
+		}
+
+		b.WriteString(stmt + "\n")
+
+		return nil
+	})
+
+	return b.String(), err
+}
+
+// golangMigrateSeedSQL creates the table of golang-migrate and records the
+// version, or leaves the table empty when the version is -1.
+func golangMigrateSeedSQL(d dialect.Dialect, version int64) string {
+	var b strings.Builder
+
+	b.WriteString("-- The state of golang-migrate, exported by sql-migrate. Run it once on the database.\n")
+
+	clean := "false"
+
+	if _, ok := d.(*dialect.SqlServerDialect); ok {
+		b.WriteString("IF OBJECT_ID('" + golangMigrateTable + "') IS NULL CREATE TABLE " + golangMigrateTable +
+			" (version bigint NOT NULL PRIMARY KEY, dirty bit NOT NULL);\n")
+
+		clean = "0"
+	} else {
+		b.WriteString("CREATE TABLE IF NOT EXISTS " + golangMigrateTable +
+			" (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL);\n")
+	}
+
+	if version >= 0 {
+		fmt.Fprintf(&b, "INSERT INTO %s (version, dirty) VALUES (%d, %s);\n", golangMigrateTable, version, clean)
+	}
+
+	return b.String()
+}
+
+// writeNewFile writes content to path, failing when the file exists.
+func writeNewFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func (s *SqliteMigrateSuite) TestExportToGolangMigrate(c *C) {
+	migrations := []*Migration{
+		{Id: "1_people.sql", Up: []string{"CREATE TABLE people (id int);"}, Down: []string{"DROP TABLE people;"}},
+		{Id: "2", Up: []string{"ALTER TABLE people ADD COLUMN first_name text"}, Down: []string{"SELECT 0"}},
+	}
+	source := NewMemoryMigrationSource(migrations)
+
+	_, err := s.ex.ExecMaxContext(context.Background(), s.db, s.dialect, source, Up, 1)
+	c.Assert(err, IsNil)
+
+	dir := filepath.Join(c.MkDir(), "golang-migrate")
+
+	report, err := s.ex.ExportToGolangMigrate(context.Background(), s.db, s.dialect, source, dir)
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ExportReport{
+		Files: []string{
+			"1_people.up.sql", "1_people.down.sql",
+			"2_migration.up.sql", "2_migration.down.sql",
+			"schema_migrations.sql",
+		},
+		Version: 1,
+	})
+
+	up, err := os.ReadFile(filepath.Join(dir, "2_migration.up.sql"))
+	c.Assert(err, IsNil)
+	c.Assert(string(up), Equals, "ALTER TABLE people ADD COLUMN first_name text;\n")
+
+	seed, err := os.ReadFile(filepath.Join(dir, "schema_migrations.sql"))
+	c.Assert(err, IsNil)
+
+	_, err = s.db.Exec(string(seed))
+	c.Assert(err, IsNil)
+
+	// The seed reads back as the state of golang-migrate.
+	other := NewMigrationExecutor()
+	other.TableName = "imported"
+	other.CreateTable = true
+	other.Logger = nullLogger{}
+
+	imported, err := other.ImportFromGolangMigrate(context.Background(), s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(imported.Imported, DeepEquals, []string{"1_people.sql"})
+
+	_, err = s.ex.ExportToGolangMigrate(context.Background(), s.db, s.dialect, source, dir)
+	c.Assert(err, ErrorMatches, ".*1_people.up.sql already exists")
+}
+
+func (s *SqliteMigrateSuite) TestExportToGolangMigrateErrors(c *C) {
+	source := NewMemoryMigrationSource([]*Migration{
+		{Id: "1_a.sql", Up: []string{"SELECT 1"}},
+		{Id: "2_b.sql", Up: []string{"SELECT 2"}},
+	})
+
+	_, err := s.ex.SkipMax(context.Background(), s.db, s.dialect, source, Up, 0)
+	c.Assert(err, IsNil)
+
+	_, err = s.db.Exec("DELETE FROM migrations WHERE id = '1_a.sql'")
+	c.Assert(err, IsNil)
+
+	_, err = s.ex.ExportToGolangMigrate(context.Background(), s.db, s.dialect, source, c.MkDir())
+	c.Assert(err, ErrorMatches, "golang-migrate: migration 1_a.sql is pending before the applied 2_b.sql")
+
+	_, err = s.ex.SkipMax(context.Background(), s.db, s.dialect, source, Up, 0)
+	c.Assert(err, IsNil)
+
+	source.Migrations = append(source.Migrations, &Migration{Id: "2_c.sql", Up: []string{"SELECT 3"}})
+
+	_, err = s.ex.ExportToGolangMigrate(context.Background(), s.db, s.dialect, source, c.MkDir())
+	c.Assert(err, ErrorMatches, "golang-migrate: migrations 2_b.sql and 2_c.sql have the same version 2")
+}
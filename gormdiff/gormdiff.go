@@ -0,0 +1,235 @@
+// Package gormdiff drafts sql-migrate migrations from GORM models, so the
+// schema of the models is changed through versioned migrations instead of
+// AutoMigrate in production:
+//
+//	draft, err := gormdiff.Draft(ctx, db, dialect, "public", []any{&User{}, &Order{}})
+//	if err != nil {
+//		return err
+//	}
+//
+// The tables are those GORM creates on PostgreSQL; see the schemadiff
+// package for the caveats of the drafts.
+package gormdiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/schema"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/schemadiff"
+)
+
+type options struct {
+	namer schema.Namer
+}
+
+// Option configures how the models are mapped to tables.
+type Option func(*options)
+
+// WithNamer names the tables, columns and indexes like the NamingStrategy
+// of the gorm.Config of the application. The default is schema.NamingStrategy{}.
+func WithNamer(namer schema.Namer) Option {
+	return func(o *options) {
+		o.namer = namer
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{namer: schema.NamingStrategy{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// Schema returns the tables of the models, as GORM creates them on PostgreSQL.
+func Schema(models []any, opts ...Option) (*schemadiff.Schema, error) {
+	o := newOptions(opts)
+	cache := &sync.Map{}
+
+	s := &schemadiff.Schema{}
+
+	for _, model := range models {
+		parsed, err := schema.Parse(model, cache, o.namer)
+		if err != nil {
+			return nil, fmt.Errorf("gormdiff: cannot parse %T: %w", model, err)
+		}
+
+		table := schemadiff.Table{Name: parsed.Table}
+
+		for _, field := range parsed.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+
+			table.Columns = append(table.Columns, column(field))
+
+			if field.PrimaryKey {
+				table.PrimaryKey = append(table.PrimaryKey, field.DBName)
+			}
+
+			if field.Unique {
+				table.Indexes = append(table.Indexes, schemadiff.Index{
+					Name:    o.namer.UniqueName(parsed.Table, field.DBName),
+					Columns: []string{field.DBName},
+					Unique:  true,
+				})
+			}
+		}
+
+		for _, index := range parsed.ParseIndexes() {
+			columns := make([]string, 0, len(index.Fields))
+			for _, f := range index.Fields {
+				if f.Expression != "" {
+					columns = append(columns, f.Expression)
+				} else {
+					columns = append(columns, f.DBName)
+				}
+			}
+
+			table.Indexes = append(table.Indexes, schemadiff.Index{
+				Name:    index.Name,
+				Columns: columns,
+				Unique:  index.Class == "UNIQUE",
+			})
+		}
+
+		s.Tables = append(s.Tables, table)
+	}
+
+	s.Sort()
+
+	return s, nil
+}
+
+// Draft drafts the migration bringing the tables of the models in the schema
+// of the database to the models, or returns "" when they match. Tables which
+// aren't those of a model are left alone.
+func Draft(ctx context.Context, db *sql.DB, d dialect.Dialect, schemaName string, models []any, opts ...Option) (string, error) {
+	desired, err := Schema(models, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	current, err := schemadiff.Introspect(ctx, db, d, schemaName)
+	if err != nil {
+		return "", err
+	}
+
+	var others []string
+
+	for i := range current.Tables {
+		table := &current.Tables[i]
+
+		if desired.Table(table.Name) == nil {
+			others = append(others, table.Name)
+		}
+
+		for j := range table.Columns {
+			normalize(&table.Columns[j])
+		}
+	}
+
+	current.Exclude(others...)
+
+	return schemadiff.Draft(schemadiff.Diff(current, desired)), nil
+}
+
+// column maps a field like the PostgreSQL dialector of GORM.
+func column(field *schema.Field) schemadiff.Column {
+	c := schemadiff.Column{
+		Name:     field.DBName,
+		Type:     dataType(field),
+		Nullable: !field.NotNull && !field.PrimaryKey,
+	}
+
+	switch value := field.DefaultValueInterface.(type) {
+	case string:
+		c.Default = quote(value)
+	case time.Time:
+		c.Default = quote(field.DefaultValue)
+	default:
+		if !strings.EqualFold(field.DefaultValue, "null") {
+			c.Default = field.DefaultValue
+		}
+	}
+
+	return c
+}
+
+// dataType returns the type of the field, as PostgreSQL reports it.
+func dataType(field *schema.Field) string {
+	switch field.DataType {
+	case schema.Bool:
+		return "boolean"
+	case schema.Int, schema.Uint:
+		switch {
+		case field.Size > 0 && field.Size <= 16:
+			return serialType("smallint", field.AutoIncrement)
+		case field.Size > 0 && field.Size <= 32:
+			return serialType("integer", field.AutoIncrement)
+		default:
+			return serialType("bigint", field.AutoIncrement)
+		}
+	case schema.Float:
+		if field.Precision > 0 {
+			if field.Scale > 0 {
+				return fmt.Sprintf("numeric(%d,%d)", field.Precision, field.Scale)
+			}
+
+			return fmt.Sprintf("numeric(%d)", field.Precision)
+		}
+
+		return "numeric"
+	case schema.String:
+		if field.Size > 0 {
+			return fmt.Sprintf("character varying(%d)", field.Size)
+		}
+
+		return "text"
+	case schema.Time:
+		if field.Precision > 0 {
+			return fmt.Sprintf("timestamp(%d) with time zone", field.Precision)
+		}
+
+		return "timestamp with time zone"
+	case schema.Bytes:
+		return "bytea"
+	default:
+		return string(field.DataType)
+	}
+}
+
+var serials = map[string]string{"smallint": "smallserial", "integer": "serial", "bigint": "bigserial"}
+
+func serialType(typ string, serial bool) string {
+	if serial {
+		return serials[typ]
+	}
+
+	return typ
+}
+
+var castSuffix = regexp.MustCompile(`^('(?:[^']|'')*')::[a-z ]+$`)
+
+// normalize maps a column read from PostgreSQL to its definition by GORM:
+// a sequence default is a serial type, and literal defaults have no cast.
+func normalize(c *schemadiff.Column) {
+	if serial, ok := serials[c.Type]; ok && strings.HasPrefix(c.Default, "nextval(") {
+		c.Type, c.Default = serial, ""
+	}
+
+	c.Default = castSuffix.ReplaceAllString(c.Default, "$1")
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
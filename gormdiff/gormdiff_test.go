@@ -0,0 +1,104 @@
+package gormdiff
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/schemadiff"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type GormDiffSuite struct{}
+
+var _ = Suite(&GormDiffSuite{})
+
+type User struct {
+	ID        uint
+	Email     string `gorm:"size:255;not null;uniqueIndex"`
+	Name      string `gorm:"default:anonymous"`
+	Admin     bool   `gorm:"default:false"`
+	Balance   float64
+	CreatedAt time.Time
+	Orders    []Order
+}
+
+type Order struct {
+	ID     uint
+	UserID uint `gorm:"index"`
+	Total  float64 `gorm:"precision:10;scale:2"`
+}
+
+func (*GormDiffSuite) TestSchema(c *C) {
+	s, err := Schema([]any{&User{}, &Order{}})
+	c.Assert(err, IsNil)
+
+	c.Assert(s, DeepEquals, &schemadiff.Schema{Tables: []schemadiff.Table{
+		{
+			Name: "orders",
+			Columns: []schemadiff.Column{
+				{Name: "id", Type: "bigserial"},
+				{Name: "user_id", Type: "bigint", Nullable: true},
+				{Name: "total", Type: "numeric(10,2)", Nullable: true},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes:    []schemadiff.Index{{Name: "idx_orders_user_id", Columns: []string{"user_id"}}},
+		},
+		{
+			Name: "users",
+			Columns: []schemadiff.Column{
+				{Name: "id", Type: "bigserial"},
+				{Name: "email", Type: "character varying(255)"},
+				{Name: "name", Type: "text", Nullable: true, Default: "'anonymous'"},
+				{Name: "admin", Type: "boolean", Nullable: true, Default: "false"},
+				{Name: "balance", Type: "numeric", Nullable: true},
+				{Name: "created_at", Type: "timestamp with time zone", Nullable: true},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes:    []schemadiff.Index{{Name: "idx_users_email", Columns: []string{"email"}, Unique: true}},
+		},
+	}})
+}
+
+func (*GormDiffSuite) TestNormalize(c *C) {
+	column := schemadiff.Column{Name: "id", Type: "bigint", Default: "nextval('users_id_seq'::regclass)"}
+	normalize(&column)
+	c.Assert(column, Equals, schemadiff.Column{Name: "id", Type: "bigserial"})
+
+	column = schemadiff.Column{Name: "name", Type: "text", Default: "'it''s'::character varying"}
+	normalize(&column)
+	c.Assert(column.Default, Equals, "'it''s'")
+}
+
+func (*GormDiffSuite) TestDraft(c *C) {
+	db, err := sql.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE orders (id bigserial PRIMARY KEY, user_id bigint, total numeric(10,2));
+		CREATE INDEX idx_orders_user_id ON orders (user_id);
+		CREATE TABLE migrations (id text PRIMARY KEY)`)
+	c.Assert(err, IsNil)
+
+	draft, err := Draft(context.Background(), db, &dialect.SqliteDialect{}, "", []any{&Order{}})
+	c.Assert(err, IsNil)
+	c.Assert(draft, Equals, "")
+
+	draft, err = Draft(context.Background(), db, &dialect.SqliteDialect{}, "", []any{&User{}, &Order{}})
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(draft, `CREATE TABLE "users" (`), Equals, true)
+	c.Assert(strings.Contains(draft, `CREATE UNIQUE INDEX "idx_users_email" ON "users" (email);`), Equals, true)
+	c.Assert(strings.Contains(draft, "migrations"), Equals, false)
+}
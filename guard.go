@@ -0,0 +1,112 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+// DestructiveError is returned by the runs RequireDownConfirmation refuses:
+// Down runs, and Up runs with statements dropping or truncating, which
+// weren't confirmed with AllowDestructive and the Token.
+type DestructiveError struct {
+	Direction MigrationDirection
+	// Migrations are the destructive migrations of the plan.
+	Migrations []string
+	// Token confirms the plan, see AllowDestructive.
+	Token string
+}
+
+func (e *DestructiveError) Error() string {
+	return fmt.Sprintf("refusing to run the destructive migrations %s (%s) without confirmation, confirm them with the token %s",
+		strings.Join(e.Migrations, ", "), e.Direction, e.Token)
+}
+
+// AllowDestructive confirms the destructive runs, with RequireDownConfirmation,
+// whose plan has the token of the DestructiveError. The token is derived from
+// the ids of the planned migrations, so it doesn't confirm any other plan, and
+// it's the same for rolling back migrations and applying them again.
+func (ex *MigrationExecutor) AllowDestructive(token string) {
+	ex.destructiveToken = token
+}
+
+// destructive matches the statements dropping or truncating, after the
+// leading comments.
+var destructive = regexp.MustCompile(`(?is)^(?:\s+|--[^\n]*(?:\n|$)|/\*.*?\*/)*(?:DROP|TRUNCATE|ALTER\s+TABLE\s[^;]*\bDROP)\b`)
+
+// IsDestructive tells whether the statement drops or truncates something,
+// e.g. DROP TABLE, TRUNCATE or ALTER TABLE ... DROP COLUMN.
+func IsDestructive(stmt string) bool {
+	return destructive.MatchString(stmt)
+}
+
+// confirmDestructive returns a DestructiveError if the planned migrations
+// are destructive and the run wasn't confirmed for them.
+func (ex *MigrationExecutor) confirmDestructive(dir MigrationDirection, migrations []*PlannedMigration) error {
+	if !ex.RequireDownConfirmation || len(migrations) == 0 {
+		return nil
+	}
+
+	var ids, destructiveIds []string
+
+	for _, migration := range migrations {
+		ids = append(ids, migration.Id)
+
+		if dir == Down {
+			destructiveIds = append(destructiveIds, migration.Id)
+
+			continue
+		}
+
+		dropping := false
+
+		if migration.Stream != nil {
+			// The stream is only read when the migration runs, those not
+			// scanned while parsing them are taken for destructive.
+			dropping = !migration.scanned || migration.destructiveUp
+		} else {
+			err := migration.Statements(func(stmt string, _ sqlparse.LineRange) error {
+				dropping = dropping || IsDestructive(stmt)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if dropping {
+			destructiveIds = append(destructiveIds, migration.Id)
+		}
+	}
+
+	if len(destructiveIds) == 0 {
+		return nil
+	}
+
+	token := confirmationToken(ids)
+	if ex.destructiveToken == token {
+		return nil
+	}
+
+	return &DestructiveError{Direction: dir, Migrations: destructiveIds, Token: token}
+}
+
+// confirmationToken derives the token confirming the migrations from their
+// ids, in any order.
+func confirmationToken(ids []string) string {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, id := range sorted {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:8]
+}
@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+func (*SqliteMigrateSuite) TestIsDestructive(c *C) {
+	c.Assert(IsDestructive("DROP TABLE people"), Equals, true)
+	c.Assert(IsDestructive("-- cleanup\ntruncate people"), Equals, true)
+	c.Assert(IsDestructive("ALTER TABLE people DROP COLUMN name"), Equals, true)
+	c.Assert(IsDestructive("ALTER TABLE people ADD COLUMN dropped bool"), Equals, false)
+	c.Assert(IsDestructive("CREATE TABLE drops (id int)"), Equals, false)
+}
+
+func (s *SqliteMigrateSuite) TestRequireDownConfirmation(c *C) {
+	s.ex.RequireDownConfirmation = true
+	source := NewMemoryMigrationSource(append(sqliteMigrations, &Migration{
+		Id:   "125",
+		Up:   []string{"ALTER TABLE people DROP COLUMN first_name"},
+		Down: []string{"ALTER TABLE people ADD COLUMN first_name text"},
+	}))
+
+	n, err := s.ex.ExecMax(s.db, s.dialect, source, Up, 2)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	n, err = s.ex.ExecMax(s.db, s.dialect, source, Down, 2)
+	c.Assert(n, Equals, 0)
+
+	var refused *DestructiveError
+	c.Assert(errors.As(err, &refused), Equals, true)
+	c.Assert(refused.Migrations, DeepEquals, []string{"124", "123"})
+	c.Assert(err, ErrorMatches, "refusing to run the destructive migrations 124, 123 \\(down\\) without confirmation, confirm them with the token "+refused.Token)
+
+	// The token of another plan doesn't confirm it.
+	s.ex.AllowDestructive(refused.Token)
+	n, err = s.ex.ExecMax(s.db, s.dialect, source, Down, 1)
+	c.Assert(err, FitsTypeOf, &DestructiveError{})
+	c.Assert(n, Equals, 0)
+
+	n, err = s.ex.ExecMax(s.db, s.dialect, source, Down, 2)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	// Up runs without drops need no confirmation, the third migration drops a column.
+	n, err = s.ex.ExecMax(s.db, s.dialect, source, Up, 2)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	n, err = s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, ErrorMatches, "refusing to run the destructive migrations 125 \\(up\\).*")
+	c.Assert(n, Equals, 0)
+}
+
+func (s *SqliteMigrateSuite) TestRequireConfirmationOfStreams(c *C) {
+	s.ex.RequireDownConfirmation = true
+
+	// Streamed files are checked while parsed, other streams count as dropping.
+	scanned, err := streamedMigration("1_people.sql", func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("-- +migrate Up\nCREATE TABLE people (id int);\n-- +migrate Down\nDROP TABLE people;\n")), nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(scanned.scanned, Equals, true)
+	c.Assert(scanned.destructiveUp, Equals, false)
+
+	n, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource([]*Migration{scanned}), Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	source := NewMemoryMigrationSource([]*Migration{scanned, {
+		Id: "2_backfill.sql",
+		Stream: func(_ MigrationDirection, fn func(string, sqlparse.LineRange) error) error {
+			return fn("INSERT INTO people VALUES (1)", sqlparse.LineRange{})
+		},
+	}})
+
+	_, err = s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, ErrorMatches, "refusing to run the destructive migrations 2_backfill.sql \\(up\\).*")
+}
@@ -0,0 +1,473 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// ImportReport describes the migrations recorded by an import of the state
+// of another migration tool.
+type ImportReport struct {
+	// Imported lists the ids of the migrations recorded as applied, in order.
+	Imported []string
+	// Existing lists the ids which were recorded already and left alone.
+	Existing []string
+	// Unmatched lists the versions applied by the other tool which no
+	// migration of the source has.
+	Unmatched []string
+}
+
+type importOptions struct {
+	table     string
+	dryRun    bool
+	checksums bool
+}
+
+// ImportOption configures the imports of the state of other migration tools.
+type ImportOption func(*importOptions)
+
+func newImportOptions(table string, opts []ImportOption) importOptions {
+	options := importOptions{table: table}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// WithImportTable reads the state of the other tool from table instead of
+// its default table, e.g. when its table was renamed.
+func WithImportTable(table string) ImportOption {
+	return func(o *importOptions) {
+		o.table = table
+	}
+}
+
+// WithImportDryRun reports the migrations the import would record, and the
+// unmatched versions, without recording any.
+func WithImportDryRun() ImportOption {
+	return func(o *importOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithImportChecksums records the checksums of the other tool, prefixed
+// with its name, e.g. flyway:1234, instead of the checksums of the migrations
+// of the source. Such checksums aren't verified, see MigrationRecord.Verifiable.
+func WithImportChecksums() ImportOption {
+	return func(o *importOptions) {
+		o.checksums = true
+	}
+}
+
+// golangMigrateTable is the default table of golang-migrate.
+const golangMigrateTable = "schema_migrations"
+
+// ImportFromGolangMigrate records the migrations applied by golang-migrate,
+// so a project can switch tools without resetting its databases. It reads
+// the version of the schema_migrations table and records every migration of
+// the source up to and including the one with that version, matched on the
+// numeric prefix of the ids, e.g. 3_add_email.sql for version 3. It fails
+// when the version is dirty or no migration of the source has it. Migrations
+// recorded already are left alone.
+func (ex *MigrationExecutor) ImportFromGolangMigrate(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	opts ...ImportOption,
+) (ImportReport, error) {
+	options := newImportOptions(golangMigrateTable, opts)
+
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT version, dirty FROM %s", options.table)).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return ImportReport{}, nil
+	}
+
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("golang-migrate: cannot read %s: %w", options.table, err)
+	}
+
+	if dirty {
+		return ImportReport{}, fmt.Errorf("golang-migrate: version %d is dirty, fix the database and force the version first", version)
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	last := -1
+
+	for i, migration := range migrations {
+		if migration.isNumeric() && migration.VersionInt() == version {
+			last = i
+		}
+	}
+
+	if last < 0 {
+		return ImportReport{}, fmt.Errorf("golang-migrate: no migration of the source has version %d", version)
+	}
+
+	now := ex.now()
+	records := make([]MigrationRecord, 0, last+1)
+
+	for _, migration := range migrations[:last+1] {
+		records = append(records, MigrationRecord{Id: migration.Id, AppliedAt: now, Checksum: migration.Checksum()})
+	}
+
+	return ex.importRecords(ctx, db, dialect, records, options)
+}
+
+// gooseTable is the default table of goose.
+const gooseTable = "goose_db_version"
+
+// ImportFromGoose records the migrations applied by goose. It replays the
+// history of the goose_db_version table, where the latest row of a version
+// tells whether it's applied or rolled back, and records the migrations of
+// the source whose numeric prefix matches an applied version, with the time
+// goose applied them. It fails, recording nothing, when applied versions
+// match no migration of the source; WithImportDryRun reports them instead.
+// Migrations recorded already are left alone.
+func (ex *MigrationExecutor) ImportFromGoose(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	opts ...ImportOption,
+) (ImportReport, error) {
+	options := newImportOptions(gooseTable, opts)
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT version_id, is_applied, tstamp FROM %s ORDER BY id", options.table))
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("goose: cannot read %s: %w", options.table, err)
+	}
+
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+
+	for rows.Next() {
+		var (
+			version   int64
+			isApplied bool
+			tstamp    time.Time
+		)
+
+		err = rows.Scan(&version, &isApplied, &tstamp)
+		if err != nil {
+			return ImportReport{}, fmt.Errorf("goose: cannot read %s: %w", options.table, err)
+		}
+
+		if isApplied {
+			applied[version] = tstamp.UTC()
+		} else {
+			delete(applied, version)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return ImportReport{}, fmt.Errorf("goose: cannot read %s: %w", options.table, err)
+	}
+
+	// Goose inserts version 0 when it creates its table.
+	delete(applied, 0)
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var records []MigrationRecord
+
+	for _, migration := range migrations {
+		if !migration.isNumeric() {
+			continue
+		}
+
+		appliedAt, ok := applied[migration.VersionInt()]
+		if !ok {
+			continue
+		}
+
+		records = append(records, MigrationRecord{Id: migration.Id, AppliedAt: appliedAt, Checksum: migration.Checksum()})
+		delete(applied, migration.VersionInt())
+	}
+
+	var versions []int64
+	for version := range applied {
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	unmatched := make([]string, 0, len(versions))
+	for _, version := range versions {
+		unmatched = append(unmatched, strconv.FormatInt(version, 10))
+	}
+
+	return ex.importMatched(ctx, db, dialect, "goose", records, unmatched, options)
+}
+
+// flywayTable is the default table of Flyway.
+const flywayTable = "flyway_schema_history"
+
+// flywayMigration is a version applied by Flyway.
+type flywayMigration struct {
+	installedOn time.Time
+	checksum    sql.NullInt64
+}
+
+// ImportFromFlyway records the migrations applied by Flyway. It replays the
+// rows of the flyway_schema_history table in the order Flyway installed
+// them, skipping the failed ones and the repeatable migrations, and records
+// the migrations of the source whose numeric prefix matches an applied
+// version, with the time Flyway applied them. A baseline row stands for all
+// the migrations up to its version. It fails, recording nothing, when applied
+// versions match no migration of the source, e.g. dotted versions such as
+// 1.1; WithImportDryRun reports them instead. WithImportChecksums keeps the
+// checksums of Flyway. Migrations recorded already are left alone.
+func (ex *MigrationExecutor) ImportFromFlyway(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	opts ...ImportOption,
+) (ImportReport, error) {
+	options := newImportOptions(flywayTable, opts)
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT version, type, checksum, installed_on, success FROM %s ORDER BY installed_rank", options.table))
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("flyway: cannot read %s: %w", options.table, err)
+	}
+
+	defer rows.Close()
+
+	applied := make(map[string]flywayMigration)
+	baseline, hasBaseline := int64(0), false
+	baselineOn := time.Time{}
+
+	var order []string
+
+	for rows.Next() {
+		var (
+			version     sql.NullString
+			kind        string
+			checksum    sql.NullInt64
+			installedOn time.Time
+			success     bool
+		)
+
+		err = rows.Scan(&version, &kind, &checksum, &installedOn, &success)
+		if err != nil {
+			return ImportReport{}, fmt.Errorf("flyway: cannot read %s: %w", options.table, err)
+		}
+
+		if !success || !version.Valid || kind == "SCHEMA" {
+			continue
+		}
+
+		key := flywayVersion(version.String)
+
+		switch {
+		case kind == "BASELINE":
+			v, err := strconv.ParseInt(key, 10, 64)
+			if err != nil {
+				return ImportReport{}, fmt.Errorf("flyway: cannot read the baseline version %s: %w", version.String, err)
+			}
+
+			baseline, hasBaseline, baselineOn = v, true, installedOn.UTC()
+		case strings.HasPrefix(kind, "UNDO_"):
+			delete(applied, key)
+		default:
+			if _, ok := applied[key]; !ok {
+				order = append(order, key)
+			}
+
+			applied[key] = flywayMigration{installedOn: installedOn.UTC(), checksum: checksum}
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return ImportReport{}, fmt.Errorf("flyway: cannot read %s: %w", options.table, err)
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var records []MigrationRecord
+
+	for _, migration := range migrations {
+		if !migration.isNumeric() {
+			continue
+		}
+
+		record := MigrationRecord{Id: migration.Id, Checksum: migration.Checksum()}
+		key := strconv.FormatInt(migration.VersionInt(), 10)
+
+		if m, ok := applied[key]; ok {
+			record.AppliedAt = m.installedOn
+			if options.checksums && m.checksum.Valid {
+				record.Checksum = "flyway:" + strconv.FormatInt(m.checksum.Int64, 10)
+			}
+
+			delete(applied, key)
+		} else if hasBaseline && migration.VersionInt() <= baseline {
+			record.AppliedAt = baselineOn
+		} else {
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	var unmatched []string
+
+	for _, key := range order {
+		if _, ok := applied[key]; ok {
+			unmatched = append(unmatched, key)
+		}
+	}
+
+	return ex.importMatched(ctx, db, dialect, "flyway", records, unmatched, options)
+}
+
+// flywayVersion normalizes a Flyway version, e.g. 001 to 1, to match the
+// numeric prefixes of the migrations. Dotted versions are kept as is.
+func flywayVersion(version string) string {
+	v, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return version
+	}
+
+	return strconv.FormatInt(v, 10)
+}
+
+// importMatched records the migrations matched to the versions of the tool,
+// unless some versions are unmatched and the import isn't a dry run.
+func (ex *MigrationExecutor) importMatched(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	tool string,
+	records []MigrationRecord,
+	unmatched []string,
+	options importOptions,
+) (ImportReport, error) {
+	if len(unmatched) == 0 {
+		unmatched = nil
+	}
+
+	if len(unmatched) > 0 && !options.dryRun {
+		return ImportReport{Unmatched: unmatched}, fmt.Errorf("%s: no migration of the source has versions %v", tool, unmatched)
+	}
+
+	report, err := ex.importRecords(ctx, db, dialect, records, options)
+	report.Unmatched = unmatched
+
+	return report, err
+}
+
+// importRecords records the migrations missing from the migration table in a
+// single transaction, holding the migration lock. A dry run only reports them.
+func (ex *MigrationExecutor) importRecords(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	records []MigrationRecord,
+	options importOptions,
+) (ImportReport, error) {
+	var report ImportReport
+
+	conn, release, err := ex.connection(ctx, db)
+	if err != nil {
+		return report, err
+	}
+
+	defer release()
+
+	unlock, err := ex.lock(ctx, db, conn, dialect)
+	if err != nil {
+		return report, err
+	}
+
+	defer unlock()
+
+	rep, err := ex.getMigrationRepository(ctx, conn, dialect)
+	if err != nil {
+		return report, err
+	}
+
+	if err := ex.addColumns(ctx, rep); err != nil {
+		return report, err
+	}
+
+	ids, err := rep.ListMigrationIds(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	existing := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		existing[id] = struct{}{}
+	}
+
+	missing := make([]MigrationRecord, 0, len(records))
+
+	for _, record := range records {
+		if _, ok := existing[record.Id]; ok {
+			report.Existing = append(report.Existing, record.Id)
+		} else {
+			missing = append(missing, record)
+		}
+	}
+
+	if options.dryRun {
+		for _, record := range missing {
+			report.Imported = append(report.Imported, record.Id)
+		}
+
+		return report, nil
+	}
+
+	tx, txCtx, err := rep.BeginTx(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	err = rep.SaveMigrations(txCtx, missing)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return report, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return report, err
+	}
+
+	for _, record := range missing {
+		report.Imported = append(report.Imported, record.Id)
+
+		logWith(ctx, ex.Logger, LevelInfo, fmt.Sprintf("Imported migration %s", record.Id),
+			Field{"migration_id", record.Id})
+	}
+
+	return report, nil
+}
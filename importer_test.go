@@ -0,0 +1,162 @@
+package migrate
+
+import (
+	"context"
+	"time"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func (s *SqliteMigrateSuite) TestImportFromGolangMigrate(c *C) {
+	_, err := s.db.Exec("CREATE TABLE schema_migrations (version bigint not null primary key, dirty boolean not null)")
+	c.Assert(err, IsNil)
+
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	report, err := s.ex.ImportFromGolangMigrate(context.Background(), s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ImportReport{})
+
+	_, err = s.db.Exec("INSERT INTO schema_migrations VALUES (123, false)")
+	c.Assert(err, IsNil)
+
+	report, err = s.ex.ImportFromGolangMigrate(context.Background(), s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ImportReport{Imported: []string{"123"}})
+
+	_, err = s.db.Exec("UPDATE schema_migrations SET version = 124")
+	c.Assert(err, IsNil)
+
+	report, err = s.ex.ImportFromGolangMigrate(context.Background(), s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ImportReport{Imported: []string{"124"}, Existing: []string{"123"}})
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[1].Checksum, Equals, sqliteMigrations[1].Checksum())
+}
+
+func (s *SqliteMigrateSuite) TestImportFromGolangMigrateErrors(c *C) {
+	_, err := s.db.Exec("CREATE TABLE old_migrations (version bigint not null primary key, dirty boolean not null)")
+	c.Assert(err, IsNil)
+
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	_, err = s.db.Exec("INSERT INTO old_migrations VALUES (124, true)")
+	c.Assert(err, IsNil)
+
+	_, err = s.ex.ImportFromGolangMigrate(context.Background(), s.db, s.dialect, source, WithImportTable("old_migrations"))
+	c.Assert(err, ErrorMatches, "golang-migrate: version 124 is dirty.*")
+
+	_, err = s.db.Exec("UPDATE old_migrations SET version = 125, dirty = false")
+	c.Assert(err, IsNil)
+
+	_, err = s.ex.ImportFromGolangMigrate(context.Background(), s.db, s.dialect, source, WithImportTable("old_migrations"))
+	c.Assert(err, ErrorMatches, "golang-migrate: no migration of the source has version 125")
+
+	_, err = s.ex.ImportFromGolangMigrate(context.Background(), s.db, s.dialect, source)
+	c.Assert(err, ErrorMatches, "golang-migrate: cannot read schema_migrations: .*")
+}
+
+func (s *SqliteMigrateSuite) TestImportFromGoose(c *C) {
+	_, err := s.db.Exec(`CREATE TABLE goose_db_version (id integer primary key autoincrement, version_id bigint not null,
+		is_applied boolean not null, tstamp timestamp default current_timestamp)`)
+	c.Assert(err, IsNil)
+
+	_, err = s.db.Exec(`INSERT INTO goose_db_version (version_id, is_applied, tstamp) VALUES
+		(0, true, '2024-01-01 00:00:00'),
+		(123, true, '2024-01-02 00:00:00'),
+		(124, true, '2024-01-03 00:00:00'),
+		(124, false, '2024-01-04 00:00:00'),
+		(200, true, '2024-01-05 00:00:00')`)
+	c.Assert(err, IsNil)
+
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	report, err := s.ex.ImportFromGoose(context.Background(), s.db, s.dialect, source)
+	c.Assert(err, ErrorMatches, `goose: no migration of the source has versions \[200\]`)
+	c.Assert(report, DeepEquals, ImportReport{Unmatched: []string{"200"}})
+
+	report, err = s.ex.ImportFromGoose(context.Background(), s.db, s.dialect, source, WithImportDryRun())
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ImportReport{Imported: []string{"123"}, Unmatched: []string{"200"}})
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 0)
+
+	_, err = s.db.Exec("DELETE FROM goose_db_version WHERE version_id = 200")
+	c.Assert(err, IsNil)
+
+	report, err = s.ex.ImportFromGoose(context.Background(), s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ImportReport{Imported: []string{"123"}})
+
+	records, err = s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].Id, Equals, "123")
+	c.Assert(records[0].AppliedAt.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)), Equals, true)
+}
+
+func (s *SqliteMigrateSuite) TestImportFromFlyway(c *C) {
+	_, err := s.db.Exec(`CREATE TABLE flyway_schema_history (installed_rank int primary key, version varchar(50),
+		type varchar(20) not null, checksum int, installed_on timestamp not null, success boolean not null)`)
+	c.Assert(err, IsNil)
+
+	_, err = s.db.Exec(`INSERT INTO flyway_schema_history (installed_rank, version, type, checksum, installed_on, success) VALUES
+		(1, '0123', 'SQL', 42, '2024-01-02 00:00:00', true),
+		(2, '124', 'SQL', 43, '2024-01-03 00:00:00', false),
+		(3, NULL, 'SQL', 44, '2024-01-04 00:00:00', true),
+		(4, '1.1', 'SQL', 45, '2024-01-05 00:00:00', true)`)
+	c.Assert(err, IsNil)
+
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	report, err := s.ex.ImportFromFlyway(context.Background(), s.db, s.dialect, source)
+	c.Assert(err, ErrorMatches, `flyway: no migration of the source has versions \[1.1\]`)
+	c.Assert(report, DeepEquals, ImportReport{Unmatched: []string{"1.1"}})
+
+	report, err = s.ex.ImportFromFlyway(context.Background(), s.db, s.dialect, source, WithImportDryRun())
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ImportReport{Imported: []string{"123"}, Unmatched: []string{"1.1"}})
+
+	_, err = s.db.Exec("DELETE FROM flyway_schema_history WHERE version = '1.1'")
+	c.Assert(err, IsNil)
+
+	report, err = s.ex.ImportFromFlyway(context.Background(), s.db, s.dialect, source, WithImportChecksums())
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ImportReport{Imported: []string{"123"}})
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].Id, Equals, "123")
+	c.Assert(records[0].Checksum, Equals, "flyway:42")
+	c.Assert(records[0].Verifiable(), Equals, false)
+	c.Assert(records[0].AppliedAt.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)), Equals, true)
+}
+
+func (s *SqliteMigrateSuite) TestImportFromFlywayBaseline(c *C) {
+	_, err := s.db.Exec(`CREATE TABLE flyway_schema_history (installed_rank int primary key, version varchar(50),
+		type varchar(20) not null, checksum int, installed_on timestamp not null, success boolean not null)`)
+	c.Assert(err, IsNil)
+
+	_, err = s.db.Exec(`INSERT INTO flyway_schema_history (installed_rank, version, type, checksum, installed_on, success) VALUES
+		(1, '123', 'BASELINE', NULL, '2024-01-01 00:00:00', true),
+		(2, '124', 'SQL', 43, '2024-01-03 00:00:00', true),
+		(3, '124', 'UNDO_SQL', 44, '2024-01-04 00:00:00', true)`)
+	c.Assert(err, IsNil)
+
+	report, err := s.ex.ImportFromFlyway(context.Background(), s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations))
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ImportReport{Imported: []string{"123"}})
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].Checksum, Equals, sqliteMigrations[0].Checksum())
+	c.Assert(records[0].AppliedAt.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)), Equals, true)
+}
@@ -0,0 +1,313 @@
+// Package k8smigrate applies the migrations from a Kubernetes Job, or an init
+// container: it waits for the database, holds the migration lock, logs a JSON
+// line per progress event and writes the summary of the run to the
+// termination message of the container, where `kubectl get pod` and the Job
+// controller find it:
+//
+//	opts := k8smigrate.DefaultOptions()
+//	opts.RegisterFlags(pflag.CommandLine)
+//	pflag.Parse()
+//
+//	result := k8smigrate.Apply(ctx, migrate.NewMigrationExecutor(), db, dialect, source, opts)
+//	os.Exit(opts.ExitCode(result))
+package k8smigrate
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// Exit codes of ExitCode. Without DetailedExitCode, a run which applied
+// migrations exits with ExitNoop too, as Kubernetes takes any other code
+// for a failure of the Job.
+const (
+	ExitNoop    = 0
+	ExitFailed  = 1
+	ExitApplied = 2
+)
+
+// DefaultTerminationLog is the default terminationMessagePath of a container.
+const DefaultTerminationLog = "/dev/termination-log"
+
+// maxTerminationMessage is the size above which Kubernetes truncates the
+// termination message.
+const maxTerminationMessage = 4096
+
+const (
+	waitMinBackoff = 500 * time.Millisecond
+	waitMaxBackoff = 10 * time.Second
+)
+
+// Options configures Apply.
+type Options struct {
+	// WaitTimeout bounds how long Apply retries reaching the database before
+	// migrating, e.g. while its pod starts. Zero doesn't retry.
+	WaitTimeout time.Duration
+	// TerminationLog is the file the result is written to as JSON, the
+	// terminationMessagePath of the container. Empty doesn't write it.
+	TerminationLog string
+	// DetailedExitCode makes ExitCode tell a run which applied migrations,
+	// ExitApplied, from a run with nothing to apply, ExitNoop.
+	DetailedExitCode bool
+	// Progress receives the progress events, a JSON object per line. Nil
+	// discards them.
+	Progress io.Writer
+}
+
+// DefaultOptions waits two minutes for the database, writes the result to
+// DefaultTerminationLog and the progress to the standard output.
+func DefaultOptions() Options {
+	return Options{
+		WaitTimeout:    2 * time.Minute,
+		TerminationLog: DefaultTerminationLog,
+		Progress:       os.Stdout,
+	}
+}
+
+// RegisterFlags registers the flags of the options, with their current
+// values as defaults, so every binary running as a Job takes the same flags.
+func (o *Options) RegisterFlags(f *pflag.FlagSet) {
+	f.DurationVar(&o.WaitTimeout, "wait-timeout", o.WaitTimeout, "how long to retry reaching the database (0 = don't retry)")
+	f.StringVar(&o.TerminationLog, "termination-log", o.TerminationLog, "file the result is written to, the terminationMessagePath of the container (empty = none)")
+	f.BoolVar(&o.DetailedExitCode, "detailed-exit-code", o.DetailedExitCode,
+		fmt.Sprintf("exit with %d when migrations were applied, %d when there was nothing to apply and %d on failure", ExitApplied, ExitNoop, ExitFailed))
+}
+
+// ExitCode returns the exit code of the process for the result.
+func (o Options) ExitCode(r Summary) int {
+	switch r.Outcome {
+	case Failed:
+		return ExitFailed
+	case Applied:
+		if o.DetailedExitCode {
+			return ExitApplied
+		}
+	}
+
+	return ExitNoop
+}
+
+// Outcome is the outcome of a run.
+type Outcome string
+
+const (
+	// Noop is a run with no migration to apply.
+	Noop Outcome = "noop"
+	// Applied is a run which applied all its migrations.
+	Applied Outcome = "applied"
+	// Failed is a run which failed, after applying the migrations of
+	// Migrations.
+	Failed Outcome = "failed"
+)
+
+// Summary sums a run up, as written to the termination log.
+type Summary struct {
+	Outcome Outcome `json:"outcome"`
+	// Planned is the number of migrations to apply.
+	Planned int `json:"planned"`
+	// Applied is the number of migrations applied.
+	Applied int `json:"applied"`
+	// Migrations are the ids of the applied migrations, in order. They are
+	// left out of the termination log when it would be too long.
+	Migrations []string `json:"migrations,omitempty"`
+	// FailedMigration is the id of the migration which failed, if any.
+	FailedMigration string  `json:"failed_migration,omitempty"`
+	Duration        float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Apply waits for the database, then applies the migrations of the source up,
+// holding the migration lock whatever the Lock of the executor, and writes
+// the result to the termination log. The executor itself is left unchanged.
+func Apply(ctx context.Context, ex *migrate.MigrationExecutor, db *sql.DB, d dialect.Dialect, source migrate.MigrationSource, opts Options) Summary {
+	started := time.Now()
+	p := &progress{w: opts.Progress}
+
+	result := run(ctx, ex, db, d, source, opts, p)
+	result.Duration = time.Since(started).Seconds()
+
+	p.write(event{
+		Event:      "finished",
+		Outcome:    result.Outcome,
+		Migrations: result.Migrations,
+		Duration:   result.Duration,
+		Error:      result.Error,
+	})
+
+	if opts.TerminationLog != "" {
+		if err := WriteTerminationLog(opts.TerminationLog, result); err != nil {
+			p.write(event{Event: "termination_log", Error: err.Error()})
+		}
+	}
+
+	return result
+}
+
+func run(ctx context.Context, ex *migrate.MigrationExecutor, db *sql.DB, d dialect.Dialect, source migrate.MigrationSource, opts Options, p *progress) Summary {
+	var result Summary
+
+	fail := func(err error) Summary {
+		result.Outcome = Failed
+		result.Error = err.Error()
+
+		return result
+	}
+
+	if err := waitForDB(ctx, db, opts.WaitTimeout, p); err != nil {
+		return fail(err)
+	}
+
+	job := *ex
+	job.Lock = true
+	job.EventSink = func(e migrate.MigrationEvent) {
+		switch e := e.(type) {
+		case migrate.PlanComputed:
+			result.Planned = len(e.Migrations)
+			p.write(event{Event: "planned", Migrations: e.Migrations})
+		case migrate.MigrationStarted:
+			// An unknown count, of a streamed migration, is left out.
+			p.write(event{Event: "migration_started", Id: e.Id, Statements: max(e.Statements, 0)})
+		case migrate.MigrationFinished:
+			finished := event{Event: "migration_finished", Id: e.Id, Duration: e.Duration.Seconds()}
+			if e.Err != nil {
+				result.FailedMigration = e.Id
+				finished.Error = e.Err.Error()
+			} else {
+				result.Migrations = append(result.Migrations, e.Id)
+			}
+
+			p.write(finished)
+		}
+
+		if ex.EventSink != nil {
+			ex.EventSink(e)
+		}
+	}
+
+	n, err := job.ExecContext(ctx, db, d, source, migrate.Up)
+	result.Applied = n
+
+	switch {
+	case err != nil:
+		return fail(err)
+	case n == 0:
+		result.Outcome = Noop
+	default:
+		result.Outcome = Applied
+	}
+
+	// A migration applied concurrently by another migrator doesn't fail the run.
+	result.FailedMigration = ""
+
+	return result
+}
+
+// waitForDB pings the database until it answers, backing off exponentially
+// between attempts, and gives up after timeout.
+func waitForDB(ctx context.Context, db *sql.DB, timeout time.Duration, p *progress) error {
+	if timeout <= 0 {
+		if err := db.PingContext(ctx); err != nil {
+			return fmt.Errorf("database not ready: %w", err)
+		}
+
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := waitMinBackoff
+
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		p.write(event{Event: "waiting", Duration: backoff.Seconds(), Error: err.Error()})
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not ready after %s: %w", timeout, err)
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, waitMaxBackoff)
+	}
+}
+
+// WriteTerminationLog writes the result to the file as JSON, leaving the ids
+// of the migrations, and then the end of the error, out when it would exceed
+// the size Kubernetes keeps of a termination message. It's also meant for
+// failures before Apply, e.g. of the configuration.
+func WriteTerminationLog(path string, r Summary) error {
+	content, err := terminationMessage(r)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0o644)
+}
+
+func terminationMessage(r Summary) ([]byte, error) {
+	content, err := json.Marshal(r)
+	if err != nil || len(content) <= maxTerminationMessage {
+		return content, err
+	}
+
+	r.Migrations = nil
+
+	content, err = json.Marshal(r)
+	if err != nil || len(content) <= maxTerminationMessage {
+		return content, err
+	}
+
+	// The error is escaped in JSON, cut it by more than the excess.
+	excess := len(content) - maxTerminationMessage
+	r.Error = strings.ToValidUTF8(r.Error[:max(0, len(r.Error)-2*excess-len("..."))], "") + "..."
+
+	return json.Marshal(r)
+}
+
+// event is a progress event, written as a JSON line.
+type event struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	Id         string    `json:"id,omitempty"`
+	Outcome    Outcome   `json:"outcome,omitempty"`
+	Migrations []string  `json:"migrations,omitempty"`
+	Statements int       `json:"statements,omitempty"`
+	Duration   float64   `json:"duration_seconds,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// progress writes the progress events to w.
+type progress struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func (p *progress) write(e event) {
+	if p.w == nil {
+		return
+	}
+
+	e.Time = time.Now().UTC()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_ = json.NewEncoder(p.w).Encode(e)
+}
@@ -0,0 +1,194 @@
+package k8smigrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type JobSuite struct {
+	db  *sql.DB
+	ex  *migrate.MigrationExecutor
+	dir string
+}
+
+var _ = Suite(&JobSuite{})
+
+type nullLogger struct{}
+
+func (nullLogger) Tracef(string, ...any) {}
+func (nullLogger) Infof(string, ...any)  {}
+func (nullLogger) Errorf(string, ...any) {}
+
+func (s *JobSuite) SetUpTest(c *C) {
+	var err error
+
+	s.dir = c.MkDir()
+
+	s.db, err = sql.Open("sqlite3", filepath.Join(s.dir, "test.db"))
+	c.Assert(err, IsNil)
+
+	s.ex = migrate.NewMigrationExecutor()
+	s.ex.CreateTable = true
+	s.ex.Logger = nullLogger{}
+}
+
+func (s *JobSuite) TearDownTest(*C) {
+	_ = s.db.Close()
+}
+
+func (s *JobSuite) options(progress *bytes.Buffer) Options {
+	return Options{TerminationLog: filepath.Join(s.dir, "termination-log"), Progress: progress}
+}
+
+func events(c *C, progress *bytes.Buffer) []event {
+	var result []event
+
+	for _, line := range strings.Split(strings.TrimSpace(progress.String()), "\n") {
+		var e event
+		c.Assert(json.Unmarshal([]byte(line), &e), IsNil)
+		result = append(result, e)
+	}
+
+	return result
+}
+
+func terminationLog(c *C, path string) Summary {
+	content, err := os.ReadFile(path)
+	c.Assert(err, IsNil)
+
+	var result Summary
+	c.Assert(json.Unmarshal(content, &result), IsNil)
+
+	return result
+}
+
+func (s *JobSuite) TestRun(c *C) {
+	source := migrate.NewMemoryMigrationSource([]*migrate.Migration{
+		{Id: "1_people.sql", Up: []string{"CREATE TABLE people (id int)"}},
+		{Id: "2_pets.sql", Up: []string{"CREATE TABLE pets (id int)"}},
+	})
+
+	var progress bytes.Buffer
+
+	opts := s.options(&progress)
+
+	result := Apply(context.Background(), s.ex, s.db, dialect.NewSqliteDialect(), source, opts)
+	c.Assert(result.Outcome, Equals, Applied)
+	c.Assert(result.Planned, Equals, 2)
+	c.Assert(result.Applied, Equals, 2)
+	c.Assert(result.Migrations, DeepEquals, []string{"1_people.sql", "2_pets.sql"})
+	c.Assert(result.Error, Equals, "")
+
+	c.Assert(opts.ExitCode(result), Equals, ExitNoop)
+
+	opts.DetailedExitCode = true
+	c.Assert(opts.ExitCode(result), Equals, ExitApplied)
+
+	// The executor of the caller isn't changed.
+	c.Assert(s.ex.Lock, Equals, false)
+	c.Assert(s.ex.EventSink, IsNil)
+
+	var names []string
+	for _, e := range events(c, &progress) {
+		names = append(names, e.Event+" "+e.Id)
+	}
+
+	c.Assert(names, DeepEquals, []string{
+		"planned ",
+		"migration_started 1_people.sql",
+		"migration_finished 1_people.sql",
+		"migration_started 2_pets.sql",
+		"migration_finished 2_pets.sql",
+		"finished ",
+	})
+
+	logged := terminationLog(c, opts.TerminationLog)
+	logged.Duration = result.Duration
+	c.Assert(logged, DeepEquals, result)
+
+	progress.Reset()
+
+	result = Apply(context.Background(), s.ex, s.db, dialect.NewSqliteDialect(), source, opts)
+	c.Assert(result.Outcome, Equals, Noop)
+	c.Assert(result.Applied, Equals, 0)
+	c.Assert(opts.ExitCode(result), Equals, ExitNoop)
+	c.Assert(terminationLog(c, opts.TerminationLog).Outcome, Equals, Noop)
+}
+
+func (s *JobSuite) TestRunFailed(c *C) {
+	source := migrate.NewMemoryMigrationSource([]*migrate.Migration{
+		{Id: "1_people.sql", Up: []string{"CREATE TABLE people (id int)"}},
+		{Id: "2_broken.sql", Up: []string{"CREATE TABLE people (id int)"}},
+	})
+
+	var progress bytes.Buffer
+
+	opts := s.options(&progress)
+
+	result := Apply(context.Background(), s.ex, s.db, dialect.NewSqliteDialect(), source, opts)
+	c.Assert(result.Outcome, Equals, Failed)
+	c.Assert(result.Applied, Equals, 1)
+	c.Assert(result.Migrations, DeepEquals, []string{"1_people.sql"})
+	c.Assert(result.FailedMigration, Equals, "2_broken.sql")
+	c.Assert(result.Error, Matches, ".*table people already exists.*")
+	c.Assert(opts.ExitCode(result), Equals, ExitFailed)
+
+	all := events(c, &progress)
+	c.Assert(all[4].Event, Equals, "migration_finished")
+	c.Assert(all[4].Error, Not(Equals), "")
+	c.Assert(all[5].Outcome, Equals, Failed)
+
+	c.Assert(terminationLog(c, opts.TerminationLog).FailedMigration, Equals, "2_broken.sql")
+}
+
+func (s *JobSuite) TestRunUnreachable(c *C) {
+	var progress bytes.Buffer
+
+	c.Assert(s.db.Close(), IsNil)
+
+	opts := s.options(&progress)
+
+	result := Apply(context.Background(), s.ex, s.db, dialect.NewSqliteDialect(), migrate.NewMemoryMigrationSource(nil), opts)
+	c.Assert(result.Outcome, Equals, Failed)
+	c.Assert(result.Error, Matches, "database not ready: .*")
+	c.Assert(terminationLog(c, opts.TerminationLog).Outcome, Equals, Failed)
+}
+
+func (*JobSuite) TestTerminationMessage(c *C) {
+	result := Summary{Outcome: Failed, Error: strings.Repeat("é", 3000)}
+	for i := 0; i < 200; i++ {
+		result.Migrations = append(result.Migrations, "20240101000000_a_rather_long_migration_name.sql")
+	}
+
+	content, err := terminationMessage(result)
+	c.Assert(err, IsNil)
+	c.Assert(len(content) <= maxTerminationMessage, Equals, true)
+
+	var logged Summary
+	c.Assert(json.Unmarshal(content, &logged), IsNil)
+	c.Assert(logged.Migrations, IsNil)
+	c.Assert(strings.HasSuffix(logged.Error, "..."), Equals, true)
+
+	result.Error = "failed"
+
+	content, err = terminationMessage(result)
+	c.Assert(err, IsNil)
+	c.Assert(json.Unmarshal(content, &logged), IsNil)
+	c.Assert(logged.Error, Equals, "failed")
+}
@@ -0,0 +1,745 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// liquibaseTable is the default table of Liquibase.
+const liquibaseTable = "DATABASECHANGELOG"
+
+// LiquibaseChangeSet is a changeset of a Liquibase changelog, converted to a
+// migration.
+type LiquibaseChangeSet struct {
+	Id     string
+	Author string
+	// File is the changelog declaring the changeset as Liquibase records it,
+	// relative to the search path, or its logicalFilePath.
+	File string
+	// MigrationId is the id of the converted migration, its position in the
+	// changelog and the id of the changeset, e.g. 0003_add_email.sql.
+	MigrationId   string
+	NoTransaction bool
+	Up            []string
+	// Down are the statements of the rollback of the changeset, if any.
+	Down []string
+}
+
+// String returns the changeset in the notation of Liquibase, file::id::author.
+func (c LiquibaseChangeSet) String() string {
+	return c.File + "::" + c.Id + "::" + c.Author
+}
+
+// LiquibaseChangelog is a Liquibase changelog and the changelogs it includes,
+// read by ReadLiquibaseChangelog.
+type LiquibaseChangelog struct {
+	// ChangeSets are the changesets in the order Liquibase runs them.
+	ChangeSets []LiquibaseChangeSet
+}
+
+// ReadLiquibaseChangelog reads the Liquibase changelog, an XML, YAML or JSON
+// file of fsys, and the changelogs it includes, e.g.
+//
+//	ReadLiquibaseChangelog(os.DirFS("src/main/resources"), "db/changelog/db.changelog-master.xml")
+//
+// fsys is the search path of Liquibase, the paths of the changelog and of the
+// includes which aren't relative to their changelog are relative to it.
+//
+// Only the sql and sqlFile changes convert. Changesets with other changes,
+// e.g. createTable, preconditions, contexts, labels, dbms or runAlways fail
+// the conversion, as sql-migrate has no equivalent; liquibase update-sql
+// renders the other changes as SQL. Custom end delimiters are matched as
+// text, not as regular expressions.
+func ReadLiquibaseChangelog(fsys fs.FS, changelog string) (*LiquibaseChangelog, error) {
+	r := liquibaseReader{fsys: fsys, reading: make(map[string]bool)}
+
+	if err := r.read(liquibasePath(changelog)); err != nil {
+		return nil, err
+	}
+
+	width := max(4, len(strconv.Itoa(len(r.changeSets))))
+
+	for i := range r.changeSets {
+		r.changeSets[i].MigrationId = fmt.Sprintf("%0*d_%s.sql", width, i+1, liquibaseSlug(r.changeSets[i].Id))
+	}
+
+	return &LiquibaseChangelog{ChangeSets: r.changeSets}, nil
+}
+
+// WriteMigrations writes a migration file per changeset to dir and returns
+// their names. Files identical to the migrations are left alone, so a
+// conversion can be run again, other existing files aren't overwritten.
+func (l *LiquibaseChangelog) WriteMigrations(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(l.ChangeSets))
+
+	for _, changeSet := range l.ChangeSets {
+		content := changeSet.render()
+		file := filepath.Join(dir, changeSet.MigrationId)
+
+		existing, err := os.ReadFile(file)
+		if err == nil && string(existing) == content {
+			names = append(names, changeSet.MigrationId)
+			continue
+		}
+
+		if err := writeNewFile(file, content); err != nil {
+			return names, err
+		}
+
+		names = append(names, changeSet.MigrationId)
+	}
+
+	return names, nil
+}
+
+// render returns the migration file of the changeset.
+func (c LiquibaseChangeSet) render() string {
+	var b strings.Builder
+
+	option := ""
+	if c.NoTransaction {
+		option = " notransaction"
+	}
+
+	fmt.Fprintf(&b, "-- Converted from the Liquibase changeset %s.\n\n", c)
+
+	b.WriteString("-- +migrate Up" + option + "\n")
+	renderLiquibaseStatements(&b, c.Up)
+
+	b.WriteString("\n-- +migrate Down" + option + "\n")
+	renderLiquibaseStatements(&b, c.Down)
+
+	return b.String()
+}
+
+// renderLiquibaseStatements writes the statements terminated by a semicolon,
+// or between StatementBegin and StatementEnd when they contain lines ending
+// with a semicolon, e.g. the bodies of functions.
+func renderLiquibaseStatements(b *strings.Builder, statements []string) {
+	for _, stmt := range statements {
+		if containsStatementEnd(stmt) {
+			b.WriteString("-- +migrate StatementBegin\n" + stmt + "\n-- +migrate StatementEnd\n")
+		} else {
+			b.WriteString(stmt + ";\n")
+		}
+	}
+}
+
+func containsStatementEnd(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		if i := strings.Index(line, "--"); i >= 0 {
+			line = line[:i]
+		}
+
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			return true
+		}
+	}
+
+	return false
+}
+
+var liquibaseSlugSanitizer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// liquibaseSlug returns the id of the changeset as part of a file name.
+func liquibaseSlug(id string) string {
+	slug := strings.Trim(liquibaseSlugSanitizer.ReplaceAllString(strings.ToLower(id), "_"), "_")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "_")
+	}
+
+	if slug == "" {
+		return "changeset"
+	}
+
+	return slug
+}
+
+// liquibasePath returns the path of a changelog relative to the search path,
+// as Liquibase records it.
+func liquibasePath(p string) string {
+	p = strings.TrimPrefix(filepath.ToSlash(p), "classpath:")
+
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// liquibaseReader reads the changesets of a changelog and its includes.
+type liquibaseReader struct {
+	fsys       fs.FS
+	reading    map[string]bool
+	changeSets []LiquibaseChangeSet
+}
+
+// liquibaseEntry is an entry of a changelog: an include, an includeAll or a
+// changeset.
+type liquibaseEntry struct {
+	kind     string
+	file     string
+	relative bool
+	attrs    map[string]string
+	changes  []liquibaseChange
+	rollback []liquibaseChange
+	// unsupported lists the elements of the changeset without an equivalent.
+	unsupported []string
+}
+
+// liquibaseChange is a sql or sqlFile change.
+type liquibaseChange struct {
+	kind  string
+	sql   string
+	attrs map[string]string
+}
+
+func (r *liquibaseReader) read(file string) error {
+	if r.reading[file] {
+		return fmt.Errorf("liquibase: %s includes itself", file)
+	}
+
+	r.reading[file] = true
+	defer delete(r.reading, file)
+
+	content, err := fs.ReadFile(r.fsys, file)
+	if err != nil {
+		return fmt.Errorf("liquibase: %w", err)
+	}
+
+	var (
+		entries     []liquibaseEntry
+		logicalPath string
+	)
+
+	switch strings.ToLower(path.Ext(file)) {
+	case ".xml":
+		entries, logicalPath, err = parseLiquibaseXML(content)
+	case ".yaml", ".yml", ".json":
+		entries, logicalPath, err = parseLiquibaseYAML(content)
+	default:
+		return fmt.Errorf("liquibase: %s isn't an XML, YAML or JSON changelog", file)
+	}
+
+	if err != nil {
+		return fmt.Errorf("liquibase: cannot read %s: %w", file, err)
+	}
+
+	if logicalPath == "" {
+		logicalPath = file
+	}
+
+	for _, entry := range entries {
+		switch entry.kind {
+		case "include":
+			if err := r.read(r.resolve(file, entry.file, entry.relative)); err != nil {
+				return err
+			}
+		case "includeAll":
+			if err := r.readAll(r.resolve(file, entry.file, entry.relative)); err != nil {
+				return err
+			}
+		case "changeSet":
+			changeSet, err := r.changeSet(file, logicalPath, entry)
+			if err != nil {
+				return err
+			}
+
+			r.changeSets = append(r.changeSets, changeSet)
+		}
+	}
+
+	return nil
+}
+
+// readAll reads the changelogs of dir in the order of their names, like
+// includeAll.
+func (r *liquibaseReader) readAll(dir string) error {
+	entries, err := fs.ReadDir(r.fsys, dir)
+	if err != nil {
+		return fmt.Errorf("liquibase: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := r.read(path.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (*liquibaseReader) resolve(changelog, file string, relative bool) string {
+	if relative {
+		return liquibasePath(path.Join(path.Dir(changelog), file))
+	}
+
+	return liquibasePath(file)
+}
+
+func (r *liquibaseReader) changeSet(file, logicalPath string, entry liquibaseEntry) (LiquibaseChangeSet, error) {
+	changeSet := LiquibaseChangeSet{
+		Id:     entry.attrs["id"],
+		Author: entry.attrs["author"],
+		File:   logicalPath,
+	}
+
+	if p := entry.attrs["logicalFilePath"]; p != "" {
+		changeSet.File = p
+	}
+
+	if changeSet.Id == "" {
+		return changeSet, fmt.Errorf("liquibase: a changeset of %s has no id", file)
+	}
+
+	unsupported := entry.unsupported
+
+	for _, attr := range []string{"context", "contextFilter", "labels", "dbms"} {
+		if entry.attrs[attr] != "" {
+			unsupported = append(unsupported, attr)
+		}
+	}
+
+	if entry.attrs["runAlways"] == "true" {
+		unsupported = append(unsupported, "runAlways")
+	}
+
+	if len(unsupported) > 0 {
+		return changeSet, fmt.Errorf("liquibase: changeset %s uses %s, which sql-migrate has no equivalent for",
+			changeSet, strings.Join(unsupported, ", "))
+	}
+
+	changeSet.NoTransaction = entry.attrs["runInTransaction"] == "false"
+
+	var err error
+
+	changeSet.Up, err = r.statements(file, entry.changes)
+	if err != nil {
+		return changeSet, fmt.Errorf("liquibase: changeset %s: %w", changeSet, err)
+	}
+
+	changeSet.Down, err = r.statements(file, entry.rollback)
+	if err != nil {
+		return changeSet, fmt.Errorf("liquibase: rollback of changeset %s: %w", changeSet, err)
+	}
+
+	return changeSet, nil
+}
+
+// statements returns the statements of the changes, split like Liquibase
+// splits them.
+func (r *liquibaseReader) statements(file string, changes []liquibaseChange) ([]string, error) {
+	var statements []string
+
+	for _, change := range changes {
+		sql := change.sql
+
+		if change.kind == "sqlFile" {
+			p := r.resolve(file, change.attrs["path"], change.attrs["relativeToChangelogFile"] == "true")
+
+			content, err := fs.ReadFile(r.fsys, p)
+			if err != nil {
+				return nil, err
+			}
+
+			sql = string(content)
+		}
+
+		statements = append(statements, splitLiquibaseSQL(sql, change.attrs["endDelimiter"], change.attrs["splitStatements"] != "false")...)
+	}
+
+	return statements, nil
+}
+
+// splitLiquibaseSQL splits the SQL of a change into statements. By default, a
+// statement ends with a semicolon at the end of a line, or before a line
+// with GO. The delimiters are removed.
+func splitLiquibaseSQL(sql, delimiter string, split bool) []string {
+	sql = strings.ReplaceAll(sql, "\r\n", "\n")
+
+	if !split {
+		if stmt := strings.TrimSpace(sql); stmt != "" {
+			return []string{stmt}
+		}
+
+		return nil
+	}
+
+	var (
+		statements []string
+		current    []string
+	)
+
+	flush := func() {
+		if stmt := strings.TrimSpace(strings.Join(current, "\n")); stmt != "" {
+			statements = append(statements, stmt)
+		}
+
+		current = nil
+	}
+
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case delimiter == "" && strings.EqualFold(trimmed, "GO"):
+			flush()
+		case delimiter == "" && strings.HasSuffix(trimmed, ";"):
+			current = append(current, strings.TrimSuffix(strings.TrimRight(line, " \t"), ";"))
+			flush()
+		case delimiter != "" && strings.HasSuffix(trimmed, delimiter):
+			current = append(current, strings.TrimSuffix(strings.TrimRight(line, " \t"), delimiter))
+			flush()
+		default:
+			current = append(current, line)
+		}
+	}
+
+	flush()
+
+	return statements
+}
+
+// liquibaseXMLNode is an element of an XML changelog.
+type liquibaseXMLNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr         `xml:",any,attr"`
+	Text     string             `xml:",chardata"`
+	Children []liquibaseXMLNode `xml:",any"`
+}
+
+func (n liquibaseXMLNode) attrs() map[string]string {
+	attrs := make(map[string]string, len(n.Attrs))
+	for _, attr := range n.Attrs {
+		attrs[attr.Name.Local] = attr.Value
+	}
+
+	return attrs
+}
+
+func parseLiquibaseXML(content []byte) ([]liquibaseEntry, string, error) {
+	var root liquibaseXMLNode
+	if err := xml.NewDecoder(bytes.NewReader(content)).Decode(&root); err != nil {
+		return nil, "", err
+	}
+
+	if root.XMLName.Local != "databaseChangeLog" {
+		return nil, "", fmt.Errorf("the root element is %s, not databaseChangeLog", root.XMLName.Local)
+	}
+
+	var entries []liquibaseEntry
+
+	for _, node := range root.Children {
+		attrs := node.attrs()
+
+		switch node.XMLName.Local {
+		case "include":
+			entries = append(entries, liquibaseEntry{kind: "include", file: attrs["file"], relative: attrs["relativeToChangelogFile"] == "true"})
+		case "includeAll":
+			entries = append(entries, liquibaseEntry{kind: "includeAll", file: attrs["path"], relative: attrs["relativeToChangelogFile"] == "true"})
+		case "changeSet":
+			entry := liquibaseEntry{kind: "changeSet", attrs: attrs}
+
+			for _, child := range node.Children {
+				switch child.XMLName.Local {
+				case "comment", "validCheckSum":
+				case "sql", "sqlFile":
+					entry.changes = append(entry.changes, liquibaseChange{kind: child.XMLName.Local, sql: child.Text, attrs: child.attrs()})
+				case "rollback":
+					rollback, unsupported := parseLiquibaseXMLRollback(child)
+					entry.rollback = rollback
+					entry.unsupported = append(entry.unsupported, unsupported...)
+				default:
+					entry.unsupported = append(entry.unsupported, child.XMLName.Local)
+				}
+			}
+
+			entries = append(entries, entry)
+		case "property", "preConditions":
+			return nil, "", fmt.Errorf("%s isn't supported", node.XMLName.Local)
+		}
+	}
+
+	return entries, root.attrs()["logicalFilePath"], nil
+}
+
+// parseLiquibaseXMLRollback returns the changes of a rollback element, which
+// either holds SQL or sql and sqlFile changes.
+func parseLiquibaseXMLRollback(node liquibaseXMLNode) ([]liquibaseChange, []string) {
+	if node.attrs()["changeSetId"] != "" {
+		return nil, []string{"a rollback to another changeset"}
+	}
+
+	if len(node.Children) == 0 {
+		return []liquibaseChange{{kind: "sql", sql: node.Text}}, nil
+	}
+
+	var (
+		changes     []liquibaseChange
+		unsupported []string
+	)
+
+	for _, child := range node.Children {
+		switch child.XMLName.Local {
+		case "comment":
+		case "sql", "sqlFile":
+			changes = append(changes, liquibaseChange{kind: child.XMLName.Local, sql: child.Text, attrs: child.attrs()})
+		default:
+			unsupported = append(unsupported, "a rollback with "+child.XMLName.Local)
+		}
+	}
+
+	return changes, unsupported
+}
+
+func parseLiquibaseYAML(content []byte) ([]liquibaseEntry, string, error) {
+	var root struct {
+		DatabaseChangeLog []map[string]any `yaml:"databaseChangeLog"`
+	}
+
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, "", err
+	}
+
+	var (
+		entries     []liquibaseEntry
+		logicalPath string
+	)
+
+	for _, item := range root.DatabaseChangeLog {
+		for kind, value := range item {
+			fields, _ := value.(map[string]any)
+
+			switch kind {
+			case "logicalFilePath":
+				logicalPath = yamlString(value)
+			case "include":
+				entries = append(entries, liquibaseEntry{kind: "include", file: yamlString(fields["file"]), relative: yamlString(fields["relativeToChangelogFile"]) == "true"})
+			case "includeAll":
+				entries = append(entries, liquibaseEntry{kind: "includeAll", file: yamlString(fields["path"]), relative: yamlString(fields["relativeToChangelogFile"]) == "true"})
+			case "changeSet":
+				entries = append(entries, parseLiquibaseYAMLChangeSet(fields))
+			case "property", "preConditions":
+				return nil, "", fmt.Errorf("%s isn't supported", kind)
+			}
+		}
+	}
+
+	return entries, logicalPath, nil
+}
+
+func parseLiquibaseYAMLChangeSet(fields map[string]any) liquibaseEntry {
+	entry := liquibaseEntry{kind: "changeSet", attrs: make(map[string]string)}
+
+	for key, value := range fields {
+		switch key {
+		case "changes":
+			changes, unsupported := parseLiquibaseYAMLChanges(value)
+			entry.changes = changes
+			entry.unsupported = append(entry.unsupported, unsupported...)
+		case "rollback":
+			if sql, ok := value.(string); ok {
+				entry.rollback = []liquibaseChange{{kind: "sql", sql: sql}}
+				continue
+			}
+
+			changes, unsupported := parseLiquibaseYAMLChanges(value)
+			entry.rollback = changes
+
+			for _, kind := range unsupported {
+				entry.unsupported = append(entry.unsupported, "a rollback with "+kind)
+			}
+		case "comment", "validCheckSum":
+		case "preConditions":
+			entry.unsupported = append(entry.unsupported, key)
+		default:
+			entry.attrs[key] = yamlString(value)
+		}
+	}
+
+	return entry
+}
+
+// parseLiquibaseYAMLChanges returns the sql and sqlFile changes of a list of
+// changes, or of a single change, and the kinds of the other changes.
+func parseLiquibaseYAMLChanges(value any) ([]liquibaseChange, []string) {
+	var items []any
+
+	switch value := value.(type) {
+	case []any:
+		items = value
+	case map[string]any:
+		items = []any{value}
+	}
+
+	var (
+		changes     []liquibaseChange
+		unsupported []string
+	)
+
+	for _, item := range items {
+		change, _ := item.(map[string]any)
+
+		for kind, value := range change {
+			if kind != "sql" && kind != "sqlFile" {
+				unsupported = append(unsupported, kind)
+				continue
+			}
+
+			c := liquibaseChange{kind: kind, attrs: make(map[string]string)}
+
+			if sql, ok := value.(string); ok {
+				c.sql = sql
+			}
+
+			if fields, ok := value.(map[string]any); ok {
+				for key, v := range fields {
+					c.attrs[key] = yamlString(v)
+				}
+
+				c.sql = c.attrs["sql"]
+			}
+
+			changes = append(changes, c)
+		}
+	}
+
+	return changes, unsupported
+}
+
+// yamlString returns a scalar of a YAML changelog as a string, e.g. the
+// numeric ids of changesets.
+func yamlString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// ImportFromLiquibase records the migrations converted from the changesets
+// of the changelog which Liquibase applied, with the time it applied them. It
+// reads the DATABASECHANGELOG table, skipping the failed and skipped
+// changesets, and matches its rows to the changesets on their file, id and
+// author, or on their id and author alone when the file differs, e.g. when
+// Liquibase ran from another search path. It fails, recording nothing, when
+// applied changesets match no migration of the source; WithImportDryRun
+// reports them instead, as file::id::author. Migrations recorded already are
+// left alone.
+func (ex *MigrationExecutor) ImportFromLiquibase(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	changelog *LiquibaseChangelog,
+	opts ...ImportOption,
+) (ImportReport, error) {
+	options := newImportOptions(liquibaseTable, opts)
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT ID, AUTHOR, FILENAME, DATEEXECUTED, EXECTYPE FROM %s ORDER BY DATEEXECUTED, ORDEREXECUTED", options.table))
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("liquibase: cannot read %s: %w", options.table, err)
+	}
+
+	defer rows.Close()
+
+	byFile := make(map[string]LiquibaseChangeSet, len(changelog.ChangeSets))
+	byId := make(map[string][]LiquibaseChangeSet, len(changelog.ChangeSets))
+
+	for _, changeSet := range changelog.ChangeSets {
+		byFile[liquibasePath(changeSet.File)+"::"+changeSet.Id+"::"+changeSet.Author] = changeSet
+		byId[changeSet.Id+"::"+changeSet.Author] = append(byId[changeSet.Id+"::"+changeSet.Author], changeSet)
+	}
+
+	applied := make(map[string]time.Time)
+
+	var unmatched []string
+
+	for rows.Next() {
+		var (
+			id, author, file, execType string
+			executed                   time.Time
+		)
+
+		err = rows.Scan(&id, &author, &file, &executed, &execType)
+		if err != nil {
+			return ImportReport{}, fmt.Errorf("liquibase: cannot read %s: %w", options.table, err)
+		}
+
+		if execType == "FAILED" || execType == "SKIPPED" {
+			continue
+		}
+
+		changeSet, ok := byFile[liquibasePath(file)+"::"+id+"::"+author]
+		if !ok && len(byId[id+"::"+author]) == 1 {
+			changeSet, ok = byId[id+"::"+author][0], true
+		}
+
+		if !ok {
+			unmatched = append(unmatched, file+"::"+id+"::"+author)
+			continue
+		}
+
+		applied[changeSet.MigrationId] = executed.UTC()
+	}
+
+	if err = rows.Err(); err != nil {
+		return ImportReport{}, fmt.Errorf("liquibase: cannot read %s: %w", options.table, err)
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var records []MigrationRecord
+
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Id]
+		if !ok {
+			continue
+		}
+
+		records = append(records, MigrationRecord{Id: migration.Id, AppliedAt: appliedAt, Checksum: migration.Checksum()})
+		delete(applied, migration.Id)
+	}
+
+	// The changesets whose migration isn't in the source, e.g. written to
+	// another directory.
+	for _, changeSet := range changelog.ChangeSets {
+		if _, ok := applied[changeSet.MigrationId]; ok {
+			unmatched = append(unmatched, changeSet.String())
+		}
+	}
+
+	return ex.importMatched(ctx, db, dialect, "liquibase", records, unmatched, options)
+}
@@ -0,0 +1,188 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+	"time"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type LiquibaseSuite struct{}
+
+var _ = Suite(&LiquibaseSuite{})
+
+var liquibaseFiles = fstest.MapFS{
+	"db/changelog/master.xml": {Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<databaseChangeLog xmlns="http://www.liquibase.org/xml/ns/dbchangelog">
+    <changeSet id="1" author="alice">
+        <comment>Create people</comment>
+        <sql>
+            CREATE TABLE people (id int);
+            CREATE INDEX people_id ON people (id);
+        </sql>
+        <rollback>DROP TABLE people;</rollback>
+    </changeSet>
+    <include file="changes/002-pets.yaml" relativeToChangelogFile="true"/>
+    <changeSet id="add-function" author="bob" runInTransaction="false">
+        <sql splitStatements="false"><![CDATA[CREATE FUNCTION one() RETURNS int AS $$
+BEGIN
+  RETURN 1;
+END;
+$$ LANGUAGE plpgsql]]></sql>
+        <rollback>
+            <sql>DROP FUNCTION one()</sql>
+        </rollback>
+    </changeSet>
+</databaseChangeLog>
+`)},
+	"db/changelog/changes/002-pets.yaml": {Data: []byte(`databaseChangeLog:
+  - changeSet:
+      id: 2
+      author: alice
+      changes:
+        - sqlFile:
+            path: sql/pets.sql
+            relativeToChangelogFile: true
+      rollback:
+        - sql:
+            sql: DROP TABLE pets
+`)},
+	"db/changelog/changes/sql/pets.sql": {Data: []byte("CREATE TABLE pets (id int)\nGO\nINSERT INTO pets VALUES (1);\n")},
+}
+
+func (*LiquibaseSuite) TestReadChangelog(c *C) {
+	changelog, err := ReadLiquibaseChangelog(liquibaseFiles, "classpath:/db/changelog/master.xml")
+	c.Assert(err, IsNil)
+	c.Assert(changelog.ChangeSets, DeepEquals, []LiquibaseChangeSet{
+		{
+			Id:          "1",
+			Author:      "alice",
+			File:        "db/changelog/master.xml",
+			MigrationId: "0001_1.sql",
+			Up:          []string{"CREATE TABLE people (id int)", "CREATE INDEX people_id ON people (id)"},
+			Down:        []string{"DROP TABLE people"},
+		},
+		{
+			Id:          "2",
+			Author:      "alice",
+			File:        "db/changelog/changes/002-pets.yaml",
+			MigrationId: "0002_2.sql",
+			Up:          []string{"CREATE TABLE pets (id int)", "INSERT INTO pets VALUES (1)"},
+			Down:        []string{"DROP TABLE pets"},
+		},
+		{
+			Id:            "add-function",
+			Author:        "bob",
+			File:          "db/changelog/master.xml",
+			MigrationId:   "0003_add_function.sql",
+			NoTransaction: true,
+			Up:            []string{"CREATE FUNCTION one() RETURNS int AS $$\nBEGIN\n  RETURN 1;\nEND;\n$$ LANGUAGE plpgsql"},
+			Down:          []string{"DROP FUNCTION one()"},
+		},
+	})
+}
+
+func (*LiquibaseSuite) TestWriteMigrations(c *C) {
+	changelog, err := ReadLiquibaseChangelog(liquibaseFiles, "db/changelog/master.xml")
+	c.Assert(err, IsNil)
+
+	dir := c.MkDir()
+
+	names, err := changelog.WriteMigrations(dir)
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{"0001_1.sql", "0002_2.sql", "0003_add_function.sql"})
+
+	// The files parse back to the statements of the changesets.
+	migrations, err := NewFileMigrationSource(dir).FindMigrations()
+	c.Assert(err, IsNil)
+	c.Assert(migrations, HasLen, 3)
+	c.Assert(migrations[0].Up, DeepEquals, []string{"CREATE TABLE people (id int);\n", "CREATE INDEX people_id ON people (id);\n"})
+	c.Assert(migrations[0].Down, HasLen, 1)
+	c.Assert(migrations[0].Down[0], Matches, "\\s*DROP TABLE people;\n")
+	c.Assert(migrations[2].DisableTransactionUp, Equals, true)
+	c.Assert(migrations[2].Up, HasLen, 1)
+	c.Assert(migrations[2].Up[0], Matches, "(?s)CREATE FUNCTION one.*LANGUAGE plpgsql\n")
+
+	// Converting again leaves the files alone, changed files aren't overwritten.
+	_, err = changelog.WriteMigrations(dir)
+	c.Assert(err, IsNil)
+
+	c.Assert(os.WriteFile(filepath.Join(dir, "0002_2.sql"), []byte("-- +migrate Up\n"), 0o600), IsNil)
+
+	_, err = changelog.WriteMigrations(dir)
+	c.Assert(err, ErrorMatches, ".*0002_2.sql already exists")
+}
+
+func (*LiquibaseSuite) TestUnsupported(c *C) {
+	files := fstest.MapFS{
+		"changelog.xml": {Data: []byte(`<databaseChangeLog>
+    <changeSet id="1" author="alice" context="test">
+        <createTable tableName="people"/>
+    </changeSet>
+</databaseChangeLog>`)},
+		"loop.yaml":     {Data: []byte("databaseChangeLog:\n  - include:\n      file: loop.yaml\n")},
+		"changelog.sql": {Data: []byte("--liquibase formatted sql\n")},
+	}
+
+	_, err := ReadLiquibaseChangelog(files, "changelog.xml")
+	c.Assert(err, ErrorMatches, "liquibase: changeset changelog.xml::1::alice uses createTable, context, which sql-migrate has no equivalent for")
+
+	_, err = ReadLiquibaseChangelog(files, "loop.yaml")
+	c.Assert(err, ErrorMatches, "liquibase: loop.yaml includes itself")
+
+	_, err = ReadLiquibaseChangelog(files, "changelog.sql")
+	c.Assert(err, ErrorMatches, "liquibase: changelog.sql isn't an XML, YAML or JSON changelog")
+}
+
+func (*LiquibaseSuite) TestSplitSQL(c *C) {
+	c.Assert(splitLiquibaseSQL("SELECT 'a;b';\r\nSELECT 2", "", true), DeepEquals, []string{"SELECT 'a;b'", "SELECT 2"})
+	c.Assert(splitLiquibaseSQL("BEGIN\n  x := 1;\nEND;\n/\nSELECT 1 FROM dual\n/", "/", true), DeepEquals,
+		[]string{"BEGIN\n  x := 1;\nEND;", "SELECT 1 FROM dual"})
+	c.Assert(splitLiquibaseSQL("\n  \n", "", false), HasLen, 0)
+}
+
+func (s *SqliteMigrateSuite) TestImportFromLiquibase(c *C) {
+	_, err := s.db.Exec(`CREATE TABLE DATABASECHANGELOG (ID varchar(255) not null, AUTHOR varchar(255) not null,
+		FILENAME varchar(255) not null, DATEEXECUTED timestamp not null, ORDEREXECUTED int not null, EXECTYPE varchar(10) not null)`)
+	c.Assert(err, IsNil)
+
+	_, err = s.db.Exec(`INSERT INTO DATABASECHANGELOG VALUES
+		('1', 'alice', 'classpath:db/changelog/master.xml', '2024-01-02 00:00:00', 1, 'EXECUTED'),
+		('2', 'alice', '/opt/app/db/changelog/changes/002-pets.yaml', '2024-01-03 00:00:00', 2, 'MARK_RAN'),
+		('add-function', 'bob', 'db/changelog/master.xml', '2024-01-04 00:00:00', 3, 'FAILED'),
+		('9', 'carol', 'db/changelog/old.xml', '2024-01-05 00:00:00', 4, 'EXECUTED')`)
+	c.Assert(err, IsNil)
+
+	changelog, err := ReadLiquibaseChangelog(liquibaseFiles, "db/changelog/master.xml")
+	c.Assert(err, IsNil)
+
+	dir := c.MkDir()
+	_, err = changelog.WriteMigrations(dir)
+	c.Assert(err, IsNil)
+
+	source := NewFileMigrationSource(dir)
+
+	report, err := s.ex.ImportFromLiquibase(context.Background(), s.db, s.dialect, source, changelog)
+	c.Assert(err, ErrorMatches, `liquibase: no migration of the source has versions \[db/changelog/old.xml::9::carol\]`)
+	c.Assert(report, DeepEquals, ImportReport{Unmatched: []string{"db/changelog/old.xml::9::carol"}})
+
+	report, err = s.ex.ImportFromLiquibase(context.Background(), s.db, s.dialect, source, changelog, WithImportDryRun())
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ImportReport{Imported: []string{"0001_1.sql", "0002_2.sql"}, Unmatched: []string{"db/changelog/old.xml::9::carol"}})
+
+	_, err = s.db.Exec("DELETE FROM DATABASECHANGELOG WHERE ID = '9'")
+	c.Assert(err, IsNil)
+
+	report, err = s.ex.ImportFromLiquibase(context.Background(), s.db, s.dialect, source, changelog)
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, ImportReport{Imported: []string{"0001_1.sql", "0002_2.sql"}})
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[1].AppliedAt.Equal(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)), Equals, true)
+}
@@ -0,0 +1,292 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// ErrLockTimeout is returned when the migration lock could not be acquired
+// within MigrationExecutor.LockTimeout.
+var ErrLockTimeout = errors.New("timed out waiting for the migration lock")
+
+const lockPollInterval = 500 * time.Millisecond
+
+// DefaultLockLease is the lease of the lock rows unless
+// MigrationExecutor.LockLease is set.
+const DefaultLockLease = 2 * time.Minute
+
+// lockReleaseTimeout bounds the queries releasing or renewing the lock, which
+// run after the context of the run may be done.
+const lockReleaseTimeout = 30 * time.Second
+
+// lockKey returns the key of the lock which serializes migrators of the same table.
+// The key includes the schema, so that the same source can be applied to many
+// schemas of one database in parallel while each schema stays serialized.
+func (ex *MigrationExecutor) lockKey() string {
+	if ex.LockKey != "" {
+		return ex.LockKey
+	}
+
+	if strings.TrimSpace(ex.SchemaName) != "" {
+		return "sql-migrate:" + ex.SchemaName + "." + ex.TableName
+	}
+
+	return "sql-migrate:" + ex.TableName
+}
+
+// lock acquires the migration lock on db when locking is enabled and returns
+// a function releasing it. The lease of a lock row is renewed on pool.
+func (ex *MigrationExecutor) lock(ctx context.Context, pool *sql.DB, db SqlDB, d dialect.Dialect) (func(), error) {
+	if !ex.Lock {
+		return func() {}, nil
+	}
+
+	return ex.acquireLock(ctx, pool, db, d)
+}
+
+// acquireLock blocks until the migration lock is acquired, ctx is done or
+// LockTimeout elapses. Dialects with advisory locks hold them on a connection
+// of their own, or on db when it is a *sql.Conn, the others fall back to a row
+// in the lock table. The lease of the row is renewed on a connection of pool
+// while the lock is held, so a single connection busy with the migrations
+// doesn't let it expire.
+func (ex *MigrationExecutor) acquireLock(ctx context.Context, pool *sql.DB, db SqlDB, d dialect.Dialect) (func(), error) {
+	waitCtx := ctx
+	if ex.LockTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, ex.LockTimeout)
+		defer cancel()
+	}
+
+	key := ex.lockKey()
+
+	switch locker := d.(type) {
+	case dialect.AdvisoryLocker:
+		conn, release, err := session(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+
+		rep := ex.newRepository(conn, d)
+
+		err = waitLock(ctx, waitCtx, func(ctx context.Context) (bool, error) {
+			return rep.TryAdvisoryLock(ctx, locker, key)
+		})
+		if err != nil {
+			release()
+
+			return nil, err
+		}
+
+		logWith(ctx, ex.Logger, LevelInfo, fmt.Sprintf("Acquired migration lock %s", key), Field{"lock", key})
+
+		return func() {
+			unlockCtx, cancel := releaseContext(ctx)
+			defer cancel()
+
+			err := rep.AdvisoryUnlock(unlockCtx, locker, key)
+			if err != nil {
+				logWith(ctx, ex.Logger, LevelError, fmt.Sprintf("Failed to release migration lock %s: %v", key, err),
+					Field{"lock", key}, Field{"error", err})
+			}
+
+			release()
+		}, nil
+
+	case dialect.TableLocker:
+		rep := ex.newRepository(db, d)
+
+		if ex.CreateSchema && ex.SchemaName != "" {
+			err := rep.CreateSchema(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := rep.CreateLockTable(ctx, locker)
+		if err != nil {
+			return nil, err
+		}
+
+		owner, err := lockOwner()
+		if err != nil {
+			return nil, err
+		}
+
+		lease := ex.lockLease()
+
+		err = waitLock(ctx, waitCtx, func(ctx context.Context) (bool, error) {
+			return rep.TryLockRow(ctx, locker, key, owner, lease)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		logWith(ctx, ex.Logger, LevelInfo, fmt.Sprintf("Acquired migration lock %s", key), Field{"lock", key})
+
+		stop := ex.renewLock(ctx, ex.newRepository(pool, d), locker, key, owner, lease)
+
+		return func() {
+			stop()
+
+			unlockCtx, cancel := releaseContext(ctx)
+			defer cancel()
+
+			err := rep.UnlockRow(unlockCtx, locker, key, owner)
+			if err != nil {
+				logWith(ctx, ex.Logger, LevelError, fmt.Sprintf("Failed to release migration lock %s: %v", key, err),
+					Field{"lock", key}, Field{"error", err})
+			}
+		}, nil
+	}
+
+	return nil, fmt.Errorf("dialect %T supports neither advisory locks nor lock tables", d)
+}
+
+func (ex *MigrationExecutor) lockLease() time.Duration {
+	if ex.LockLease > 0 {
+		return ex.LockLease
+	}
+
+	return DefaultLockLease
+}
+
+// renewLock renews the lease of the lock row every third of it, until the
+// returned function is called, which cancels a pending renewal, e.g. waiting
+// for a connection of a pool capped to the one running the migrations.
+func (ex *MigrationExecutor) renewLock(
+	ctx context.Context,
+	rep *MigrationRepository,
+	locker dialect.TableLocker,
+	key, owner string,
+	lease time.Duration,
+) func() {
+	done := make(chan struct{})
+	renewCtx, cancelRenew := context.WithCancel(context.WithoutCancel(ctx))
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			timeoutCtx, cancel := context.WithTimeout(renewCtx, lockReleaseTimeout)
+			err := rep.RenewLockRow(timeoutCtx, locker, key, owner)
+			cancel()
+
+			if err != nil && renewCtx.Err() == nil {
+				logWith(ctx, ex.Logger, LevelError, fmt.Sprintf("Failed to renew migration lock %s: %v", key, err),
+					Field{"lock", key}, Field{"error", err})
+			}
+		}
+	}()
+
+	return func() {
+		cancelRenew()
+		close(done)
+		wg.Wait()
+	}
+}
+
+// releaseContext returns the context of the queries releasing or renewing
+// the lock: ctx without its cancellation, bounded by lockReleaseTimeout.
+func releaseContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), lockReleaseTimeout)
+}
+
+// ForceUnlock removes the lock row of the executor, e.g. left by a migrator
+// killed before its lease expired, and returns its owner, empty when the lock
+// was free. It must only be used when no migrator runs. Advisory locks are
+// released with the session holding them, so dialects implementing
+// dialect.AdvisoryLocker return an error.
+func (ex *MigrationExecutor) ForceUnlock(ctx context.Context, db *sql.DB, d dialect.Dialect) (string, error) {
+	key := ex.lockKey()
+
+	switch locker := d.(type) {
+	case dialect.AdvisoryLocker:
+		return "", fmt.Errorf("the advisory lock %s is released when the session holding it ends", key)
+
+	case dialect.TableLocker:
+		rep := ex.newRepository(db, d)
+
+		owner, _, err := rep.LockRow(ctx, locker, key)
+		if err != nil || owner == "" {
+			return "", err
+		}
+
+		err = rep.UnlockRow(ctx, locker, key, owner)
+		if err != nil {
+			return "", err
+		}
+
+		logWith(ctx, ex.Logger, LevelWarn, fmt.Sprintf("Removed migration lock %s of %s", key, owner),
+			Field{"lock", key}, Field{"owner", owner})
+
+		return owner, nil
+	}
+
+	return "", fmt.Errorf("dialect %T supports neither advisory locks nor lock tables", d)
+}
+
+// waitLock polls try until it succeeds. It returns ErrLockTimeout when waitCtx
+// expires before ctx does.
+func waitLock(ctx, waitCtx context.Context, try func(ctx context.Context) (bool, error)) error {
+	for {
+		acquired, err := try(waitCtx)
+		if err != nil && waitCtx.Err() == nil {
+			return err
+		}
+
+		if acquired {
+			return nil
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return ErrLockTimeout
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// lockOwner returns a unique identity of the current migrator.
+func lockOwner() (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	b := make([]byte, 8)
+
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b)), nil
+}
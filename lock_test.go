@@ -0,0 +1,209 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+type nullLogger struct{}
+
+func (nullLogger) Tracef(string, ...any) {}
+func (nullLogger) Infof(string, ...any)  {}
+func (nullLogger) Errorf(string, ...any) {}
+
+type LockSuite struct {
+	db *sql.DB
+}
+
+var _ = Suite(&LockSuite{})
+
+func (s *LockSuite) SetUpTest(c *C) {
+	var err error
+	s.db, err = sql.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+}
+
+func (s *LockSuite) TearDownTest(c *C) {
+	c.Assert(s.db.Close(), IsNil)
+}
+
+func (s *LockSuite) newExecutor() *MigrationExecutor {
+	ex := NewMigrationExecutor()
+	ex.Lock = true
+	ex.CreateTable = true
+	ex.Logger = nullLogger{}
+
+	return ex
+}
+
+func (s *LockSuite) TestLockTable(c *C) {
+	ctx := context.Background()
+
+	first := s.newExecutor()
+	unlock, err := first.acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+
+	second := s.newExecutor()
+	second.LockTimeout = 10 * time.Millisecond
+
+	_, err = second.acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(errors.Is(err, ErrLockTimeout), Equals, true)
+
+	unlock()
+
+	unlock, err = second.acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+	unlock()
+}
+
+func (s *LockSuite) TestExecWithLock(c *C) {
+	source := NewMemoryMigrationSource([]*Migration{
+		{Id: "1_initial", Up: []string{"CREATE TABLE people (id int)"}, Down: []string{"DROP TABLE people"}},
+	})
+
+	n, err := s.newExecutor().Exec(s.db, dialect.NewSqliteDialect(), source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	var locks int
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM "migrations_lock"`).Scan(&locks)
+	c.Assert(err, IsNil)
+	c.Assert(locks, Equals, 0)
+}
+
+func (s *LockSuite) TestLockPerSchema(c *C) {
+	ctx := context.Background()
+
+	tenantA := s.newExecutor()
+	tenantA.SchemaName = "tenant_a"
+	unlockA, err := tenantA.acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+
+	defer unlockA()
+
+	tenantB := s.newExecutor()
+	tenantB.SchemaName = "tenant_b"
+	tenantB.LockTimeout = 10 * time.Millisecond
+	unlockB, err := tenantB.acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+
+	defer unlockB()
+
+	again := s.newExecutor()
+	again.SchemaName = "tenant_a"
+	again.LockTimeout = 10 * time.Millisecond
+	_, err = again.acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(errors.Is(err, ErrLockTimeout), Equals, true)
+}
+
+func (s *LockSuite) TestSingleConnection(c *C) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// A second connection would block until the timeout.
+	s.db.SetMaxOpenConns(1)
+
+	source := NewMemoryMigrationSource([]*Migration{
+		{Id: "1_initial", Up: []string{"CREATE TABLE people (id int)"}, Down: []string{"DROP TABLE people"}},
+		{Id: "2_pets", Up: []string{"CREATE TABLE pets (id int)"}, Down: []string{"DROP TABLE pets"}},
+	})
+
+	ex := s.newExecutor()
+	ex.SingleConnection = true
+
+	n, err := ex.SkipMax(ctx, s.db, dialect.NewSqliteDialect(), source, Up, 1)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	n, err = ex.ExecContext(ctx, s.db, dialect.NewSqliteDialect(), source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+	c.Assert(s.db.Stats().OpenConnections, Equals, 1)
+}
+
+func (s *LockSuite) TestLockLeaseExpired(c *C) {
+	ctx := context.Background()
+
+	ex := s.newExecutor()
+	ex.LockLease = time.Minute
+	ex.LockTimeout = 10 * time.Millisecond
+
+	rep := ex.newRepository(s.db, dialect.NewSqliteDialect())
+	c.Assert(rep.CreateLockTable(ctx, dialect.NewSqliteDialect()), IsNil)
+
+	_, err := s.db.Exec(`INSERT INTO "migrations_lock"(lock_key, owner, acquired_at) VALUES (?, ?, ?)`,
+		ex.lockKey(), "crashed", time.Now().UTC().Add(-30*time.Second))
+	c.Assert(err, IsNil)
+
+	_, err = ex.acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(errors.Is(err, ErrLockTimeout), Equals, true)
+
+	_, err = s.db.Exec(`UPDATE "migrations_lock" SET acquired_at = ?`, time.Now().UTC().Add(-2*time.Minute))
+	c.Assert(err, IsNil)
+
+	unlock, err := ex.acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+
+	owner, _, err := rep.LockRow(ctx, dialect.NewSqliteDialect(), ex.lockKey())
+	c.Assert(err, IsNil)
+	c.Assert(owner, Not(Equals), "crashed")
+
+	unlock()
+}
+
+func (s *LockSuite) TestForceUnlock(c *C) {
+	ctx := context.Background()
+
+	first := s.newExecutor()
+	unlockFirst, err := first.acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+
+	defer unlockFirst()
+
+	owner, err := s.newExecutor().ForceUnlock(ctx, s.db, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+	c.Assert(owner, Not(Equals), "")
+
+	owner, err = s.newExecutor().ForceUnlock(ctx, s.db, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+	c.Assert(owner, Equals, "")
+
+	unlock, err := s.newExecutor().acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+	unlock()
+}
+
+func (s *LockSuite) TestLockLeaseRenewed(c *C) {
+	ctx := context.Background()
+
+	conn, err := s.db.Conn(ctx)
+	c.Assert(err, IsNil)
+
+	defer conn.Close()
+
+	// The lease is renewed on the pool while the connection holds the lock.
+	first := s.newExecutor()
+	first.LockLease = 60 * time.Millisecond
+	unlock, err := first.acquireLock(ctx, s.db, conn, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+
+	defer unlock()
+
+	time.Sleep(200 * time.Millisecond)
+
+	second := s.newExecutor()
+	second.LockLease = 60 * time.Millisecond
+	second.LockTimeout = 10 * time.Millisecond
+
+	_, err = second.acquireLock(ctx, s.db, s.db, dialect.NewSqliteDialect())
+	c.Assert(errors.Is(err, ErrLockTimeout), Equals, true)
+}
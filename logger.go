@@ -1,6 +1,7 @@
 package migrate
 
 import (
+	`context`
 	`fmt`
 )
 
@@ -12,6 +13,106 @@ type Logger interface {
 	Errorf(format string, v ...any)
 }
 
+// LogLevel is the level of a message of a FieldLogger.
+type LogLevel int
+
+const (
+	LevelTrace LogLevel = iota + 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelTrace:
+		return "trace"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("LogLevel(%d)", int(l))
+	}
+}
+
+// Field is a structured attribute of a log message, e.g. migration_id.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// FieldLogger is a Logger which also takes the structured fields of the
+// messages, such as migration_id, direction, duration and statements. The
+// executor passes the fields when its Logger implements FieldLogger.
+type FieldLogger interface {
+	Logger
+	Log(ctx context.Context, level LogLevel, msg string, fields ...Field)
+}
+
+// LevelEnabler is implemented by loggers which discard the messages of some
+// levels. The repository asks it before formatting a traced query and its
+// arguments, which would be thrown away otherwise.
+type LevelEnabler interface {
+	Enabled(ctx context.Context, level LogLevel) bool
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a context carrying the logger. The executor and the
+// repository log the calls given the context to it instead of their own
+// Logger, e.g. to log with the correlation id of a request or a tenant.
+func WithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the logger of WithLogger, or nil.
+func LoggerFromContext(ctx context.Context) Logger {
+	logger, _ := ctx.Value(loggerKey{}).(Logger)
+
+	return logger
+}
+
+// logWith logs the message with its fields, or the message alone when the
+// logger doesn't take fields. Warnings go to Errorf of such loggers, which
+// have no warning level. The logger of the context takes precedence.
+func logWith(ctx context.Context, logger Logger, level LogLevel, msg string, fields ...Field) {
+	if l := LoggerFromContext(ctx); l != nil {
+		logger = l
+	}
+
+	if fl, ok := logger.(FieldLogger); ok {
+		fl.Log(ctx, level, msg, fields...)
+
+		return
+	}
+
+	switch level {
+	case LevelTrace:
+		logger.Tracef("%s", msg)
+	case LevelWarn, LevelError:
+		logger.Errorf("%s", msg)
+	default:
+		logger.Infof("%s", msg)
+	}
+}
+
+// logEnabled reports whether the logger, or the logger of the context, logs
+// the messages of the level. Loggers which aren't a LevelEnabler log all levels.
+func logEnabled(ctx context.Context, logger Logger, level LogLevel) bool {
+	if l := LoggerFromContext(ctx); l != nil {
+		logger = l
+	}
+
+	if e, ok := logger.(LevelEnabler); ok {
+		return e.Enabled(ctx, level)
+	}
+
+	return true
+}
+
 var _ Logger = (*defaultLogger)(nil)
 
 type defaultLogger struct {
@@ -0,0 +1,105 @@
+// Package logrusadapter logs the migrations of sql-migrate with logrus.
+//
+//	ex := migrate.NewMigrationExecutor()
+//	ex.Logger = logrusadapter.New(logrus.StandardLogger())
+//
+// Traced queries are logged at the debug level, as logrus.FieldLogger has no
+// trace level. The structured fields of the executor become logrus fields.
+package logrusadapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+var (
+	_ migrate.FieldLogger  = (*Logger)(nil)
+	_ migrate.LevelEnabler = (*Logger)(nil)
+)
+
+// Logger implements migrate.FieldLogger over a logrus.FieldLogger.
+type Logger struct {
+	logger logrus.FieldLogger
+}
+
+// New returns a Logger writing to logger, e.g. a *logrus.Logger or *logrus.Entry.
+func New(logger logrus.FieldLogger) *Logger {
+	return &Logger{logger: logger}
+}
+
+func (l *Logger) Tracef(format string, v ...any) {
+	l.logger.Debugf(format, v...)
+}
+
+func (l *Logger) Infof(format string, v ...any) {
+	l.logger.Infof(format, v...)
+}
+
+func (l *Logger) Errorf(format string, v ...any) {
+	l.logger.Errorf(format, v...)
+}
+
+// Log logs the message with the fields.
+func (l *Logger) Log(_ context.Context, level migrate.LogLevel, msg string, fields ...migrate.Field) {
+	data := make(logrus.Fields, len(fields))
+	for _, f := range fields {
+		data[f.Key] = value(f.Value)
+	}
+
+	entry := l.logger.WithFields(data)
+
+	switch level {
+	case migrate.LevelTrace:
+		entry.Debug(msg)
+	case migrate.LevelWarn:
+		entry.Warn(msg)
+	case migrate.LevelError:
+		entry.Error(msg)
+	default:
+		entry.Info(msg)
+	}
+}
+
+// Enabled reports whether the logrus logger logs the entries of the level. A
+// logrus.FieldLogger other than a *logrus.Logger or *logrus.Entry logs all.
+func (l *Logger) Enabled(_ context.Context, level migrate.LogLevel) bool {
+	var logger *logrus.Logger
+
+	switch v := l.logger.(type) {
+	case *logrus.Logger:
+		logger = v
+	case *logrus.Entry:
+		logger = v.Logger
+	default:
+		return true
+	}
+
+	switch level {
+	case migrate.LevelTrace:
+		return logger.IsLevelEnabled(logrus.DebugLevel)
+	case migrate.LevelWarn:
+		return logger.IsLevelEnabled(logrus.WarnLevel)
+	case migrate.LevelError:
+		return logger.IsLevelEnabled(logrus.ErrorLevel)
+	default:
+		return logger.IsLevelEnabled(logrus.InfoLevel)
+	}
+}
+
+func value(v any) any {
+	switch v := v.(type) {
+	case time.Duration:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
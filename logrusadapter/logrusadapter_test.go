@@ -0,0 +1,49 @@
+package logrusadapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type LogrusSuite struct{}
+
+var _ = Suite(&LogrusSuite{})
+
+func (*LogrusSuite) TestLog(c *C) {
+	base, hook := test.NewNullLogger()
+	base.SetLevel(logrus.DebugLevel)
+
+	logger := New(base)
+
+	logger.Tracef("SELECT %d", 1)
+	logger.Log(context.Background(), migrate.LevelError, "Failed to apply migration 1_init.sql",
+		migrate.Field{Key: "migration_id", Value: "1_init.sql"},
+		migrate.Field{Key: "direction", Value: migrate.Up},
+		migrate.Field{Key: "duration", Value: 2 * time.Second},
+		migrate.Field{Key: "error", Value: errors.New("syntax error")},
+	)
+
+	entries := hook.AllEntries()
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[0].Level, Equals, logrus.DebugLevel)
+	c.Assert(entries[0].Message, Equals, "SELECT 1")
+	c.Assert(entries[1].Level, Equals, logrus.ErrorLevel)
+	c.Assert(entries[1].Data, DeepEquals, logrus.Fields{
+		"migration_id": "1_init.sql",
+		"direction":    "up",
+		"duration":     2 * time.Second,
+		"error":        "syntax error",
+	})
+}
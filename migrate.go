@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/http"
+	"time"
 
 	`github.com/kva3umoda/sql-migrate/dialect`
 )
@@ -43,6 +45,21 @@ func SetIgnoreUnknown(v bool) {
 	migrateExecutor.IgnoreUnknown = v
 }
 
+// SetLock sets the flag that serializes concurrent migrators with a database lock.
+func SetLock(enable bool) {
+	migrateExecutor.Lock = enable
+}
+
+// SetLockTimeout sets how long to wait for the migration lock. Zero waits indefinitely.
+func SetLockTimeout(timeout time.Duration) {
+	migrateExecutor.LockTimeout = timeout
+}
+
+// SetSingleFlight sets the flag that coalesces concurrent Exec calls of this process.
+func SetSingleFlight(enable bool) {
+	migrateExecutor.SingleFlight = enable
+}
+
 func SetLogger(logger Logger) {
 	migrateExecutor.Logger = logger
 }
@@ -58,6 +75,8 @@ const (
 	GoDrOr     DialectName = "godror"
 	Snowflake  DialectName = "snowflake"
 	ClickHouse DialectName = "clickhouse"
+	Cockroach  DialectName = "cockroach"
+	TiDB       DialectName = "tidb"
 )
 
 func GetDialect(name DialectName) (dialect.Dialect, error) {
@@ -69,7 +88,7 @@ func GetDialect(name DialectName) (dialect.Dialect, error) {
 	case MySQL:
 		return dialect.NewMySQLDialect("InnoDB", "UTF8"), nil
 	case MSSQL:
-		return dialect.NewSqliteDialect(), nil
+		return dialect.NewSqlServerDialect(), nil
 	case OCI8:
 		return dialect.NewOracleDialect(), nil
 	case GoDrOr:
@@ -77,7 +96,11 @@ func GetDialect(name DialectName) (dialect.Dialect, error) {
 	case Snowflake:
 		return dialect.NewSnowflakeDialect(), nil
 	case ClickHouse:
-		return dialect.NewClickhouseDialect("", dialect.TinyLogEngine), nil
+		return dialect.NewClickhouseDialect("", dialect.MergeTreeEngine), nil
+	case Cockroach:
+		return dialect.NewCockroachDialect(), nil
+	case TiDB:
+		return dialect.NewTiDBDialect(), nil
 	}
 
 	return nil, fmt.Errorf("unknown dialect: %s", name)
@@ -126,6 +149,48 @@ func ExecVersionContext(ctx context.Context, db *sql.DB, dialect dialect.Dialect
 	return migrateExecutor.ExecVersionContext(ctx, db, dialect, m, dir, version)
 }
 
+// ExecAll Execute a set of migrations against many databases concurrently.
+// Returns one result per target, see MigrationExecutor.ExecAll.
+func ExecAll(ctx context.Context, targets []Target, m MigrationSource, dir MigrationDirection, opts ...ExecAllOption) ([]TargetResult, error) {
+	return migrateExecutor.ExecAll(ctx, targets, m, dir, opts...)
+}
+
+// ExecTenants Execute a set of migrations in every tenant schema of the database.
+// Returns one result per tenant, see MigrationExecutor.ExecTenants.
+func ExecTenants(ctx context.Context, db *sql.DB, dialect dialect.Dialect, tenants TenantSource, m MigrationSource, dir MigrationDirection, opts ...ExecAllOption) ([]TenantResult, error) {
+	return migrateExecutor.ExecTenants(ctx, db, dialect, tenants, m, dir, opts...)
+}
+
+// Sandbox applies the migrations in a temporary schema, calls fn and drops
+// the schema, see MigrationExecutor.Sandbox.
+func Sandbox(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource, fn func(ctx context.Context, conn *sql.Conn, schema string) error) error {
+	return migrateExecutor.Sandbox(ctx, db, dialect, m, fn)
+}
+
+// ImportFromGolangMigrate records the migrations applied by golang-migrate,
+// see MigrationExecutor.ImportFromGolangMigrate.
+func ImportFromGolangMigrate(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource, opts ...ImportOption) (ImportReport, error) {
+	return migrateExecutor.ImportFromGolangMigrate(ctx, db, dialect, m, opts...)
+}
+
+// ImportFromGoose records the migrations applied by goose, see
+// MigrationExecutor.ImportFromGoose.
+func ImportFromGoose(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource, opts ...ImportOption) (ImportReport, error) {
+	return migrateExecutor.ImportFromGoose(ctx, db, dialect, m, opts...)
+}
+
+// ImportFromFlyway records the migrations applied by Flyway, see
+// MigrationExecutor.ImportFromFlyway.
+func ImportFromFlyway(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource, opts ...ImportOption) (ImportReport, error) {
+	return migrateExecutor.ImportFromFlyway(ctx, db, dialect, m, opts...)
+}
+
+// ExportToGolangMigrate writes the migrations in the layout of golang-migrate,
+// see MigrationExecutor.ExportToGolangMigrate.
+func ExportToGolangMigrate(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource, dir string) (ExportReport, error) {
+	return migrateExecutor.ExportToGolangMigrate(ctx, db, dialect, m, dir)
+}
+
 // PlanMigration Plan a migration.
 func PlanMigration(db *sql.DB, dialect dialect.Dialect, m MigrationSource, dir MigrationDirection, max int) ([]*PlannedMigration, *MigrationRepository, error) {
 	return migrateExecutor.PlanMigration(context.Background(), db, dialect, m, dir, max)
@@ -136,6 +201,18 @@ func PlanMigrationToVersion(db *sql.DB, dialect dialect.Dialect, m MigrationSour
 	return migrateExecutor.PlanMigrationToVersion(context.Background(), db, dialect, m, dir, version)
 }
 
+// Plan plans a set of migrations for ExecPlan.
+// Will plan at most `max` migrations. Pass 0 for no limit.
+func Plan(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource, dir MigrationDirection, max int) (*MigrationPlan, error) {
+	return migrateExecutor.Plan(ctx, db, dialect, m, dir, max)
+}
+
+// ExecPlan Execute a set of planned migrations, see MigrationExecutor.ExecPlan.
+// Returns the number of applied migrations.
+func ExecPlan(ctx context.Context, db *sql.DB, plan *MigrationPlan) (int, error) {
+	return migrateExecutor.ExecPlan(ctx, db, plan)
+}
+
 // SkipMax Skip a set of migrations
 // Will skip at most `max` migrations. Pass 0 for no limit.
 // Returns the number of skipped migrations.
@@ -143,6 +220,35 @@ func SkipMax(db *sql.DB, dialect dialect.Dialect, m MigrationSource, dir Migrati
 	return migrateExecutor.SkipMax(context.Background(), db, dialect, m, dir, max)
 }
 
+// Init creates the migration table without applying any migration.
+func Init(db *sql.DB, dialect dialect.Dialect) error {
+	return migrateExecutor.Init(context.Background(), db, dialect)
+}
+
 func GetMigrationRecords(db *sql.DB, dialect dialect.Dialect) ([]MigrationRecord, error) {
 	return migrateExecutor.GetMigrationRecords(context.Background(), db, dialect)
 }
+
+// HasPending reports whether migrations of the source aren't applied yet,
+// see MigrationExecutor.HasPending.
+func HasPending(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource) (bool, error) {
+	return migrateExecutor.HasPending(ctx, db, dialect, m)
+}
+
+// CurrentVersion returns the version of the latest applied migration, see
+// MigrationExecutor.CurrentVersion.
+func CurrentVersion(ctx context.Context, db *sql.DB, dialect dialect.Dialect) (int64, error) {
+	return migrateExecutor.CurrentVersion(ctx, db, dialect)
+}
+
+// Readiness compares the migration table with the migrations of the source,
+// see MigrationExecutor.Readiness.
+func Readiness(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource) (Report, error) {
+	return migrateExecutor.Readiness(ctx, db, dialect, m)
+}
+
+// ReadinessHandler serves the readiness of the schema for probes, see
+// MigrationExecutor.ReadinessHandler.
+func ReadinessHandler(db *sql.DB, dialect dialect.Dialect, m MigrationSource) http.Handler {
+	return migrateExecutor.ReadinessHandler(db, dialect, m)
+}
@@ -0,0 +1,647 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+var sqliteMigrations = []*Migration{
+	{
+		Id:   "123",
+		Up:   []string{"CREATE TABLE people (id int)"},
+		Down: []string{"DROP TABLE people"},
+	},
+	{
+		Id:   "124",
+		Up:   []string{"ALTER TABLE people ADD COLUMN first_name text"},
+		Down: []string{"SELECT 0"}, // Not really supported
+	},
+}
+
+type SqliteMigrateSuite struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+	ex      *MigrationExecutor
+}
+
+var _ = Suite(&SqliteMigrateSuite{})
+
+func (s *SqliteMigrateSuite) SetUpTest(c *C) {
+	var err error
+	s.db, err = sql.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+
+	s.dialect = dialect.NewSqliteDialect()
+
+	s.ex = NewMigrationExecutor()
+	s.ex.CreateTable = true
+	s.ex.Logger = nullLogger{}
+}
+
+func (s *SqliteMigrateSuite) TearDownTest(c *C) {
+	c.Assert(s.db.Close(), IsNil)
+}
+
+func (s *SqliteMigrateSuite) TestRunMigration(c *C) {
+	source := NewMemoryMigrationSource(sqliteMigrations[:1])
+
+	n, err := s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	_, err = s.db.Exec("SELECT * FROM people")
+	c.Assert(err, IsNil)
+
+	n, err = s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 0)
+}
+
+func (s *SqliteMigrateSuite) TestConcurrentlyAppliedMigration(c *C) {
+	ctx := context.Background()
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	migrations, rep, err := s.ex.PlanMigration(ctx, s.db, s.dialect, source, Up, 0)
+	c.Assert(err, IsNil)
+	c.Assert(migrations, HasLen, 2)
+
+	// Another migrator applies the first migration after this one planned it.
+	n, err := s.ex.ExecMax(s.db, s.dialect, source, Up, 1)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	n, err = s.ex.applyMigrations(ctx, Up, rep, migrations)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	records, err := s.ex.GetMigrationRecords(ctx, s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+}
+
+func (s *SqliteMigrateSuite) TestConcurrentlySkippedMigration(c *C) {
+	ctx := context.Background()
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	migrations, rep, err := s.ex.PlanMigration(ctx, s.db, s.dialect, source, Up, 1)
+	c.Assert(err, IsNil)
+
+	err = rep.SaveMigration(ctx, MigrationRecord{Id: migrations[0].Id, AppliedAt: time.Now()})
+	c.Assert(err, IsNil)
+
+	err = s.ex.saveMigration(rep, migrations[0])
+	c.Assert(err, NotNil)
+	c.Assert(s.ex.appliedConcurrently(ctx, rep, Up, migrations[0]), Equals, true)
+}
+
+// blockingSource blocks FindMigrations until released and counts the calls.
+type blockingSource struct {
+	release chan struct{}
+	calls   int32
+	source  MigrationSource
+}
+
+func (b *blockingSource) FindMigrations() ([]*Migration, error) {
+	atomic.AddInt32(&b.calls, 1)
+	<-b.release
+
+	return b.source.FindMigrations()
+}
+
+func (s *SqliteMigrateSuite) TestSingleFlight(c *C) {
+	s.ex.SingleFlight = true
+
+	source := &blockingSource{release: make(chan struct{}), source: NewMemoryMigrationSource(sqliteMigrations)}
+
+	var wg sync.WaitGroup
+
+	results := make([]int, 3)
+	for i := range results {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			n, err := s.ex.Exec(s.db, s.dialect, source, Up)
+			c.Check(err, IsNil)
+
+			results[i] = n
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(source.release)
+	wg.Wait()
+
+	c.Assert(atomic.LoadInt32(&source.calls), Equals, int32(1))
+	c.Assert(results, DeepEquals, []int{2, 2, 2})
+}
+
+func (s *SqliteMigrateSuite) TestRecordsChecksum(c *C) {
+	source := NewMemoryMigrationSource(sqliteMigrations[:1])
+
+	_, err := s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].Checksum, Equals, sqliteMigrations[0].Checksum())
+}
+
+func (s *SqliteMigrateSuite) TestRecordsDurationAndAppliedBy(c *C) {
+	s.ex.AppliedBy = "deploy@ci"
+
+	_, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations[:1]), Up)
+	c.Assert(err, IsNil)
+
+	// Skipped migrations are recorded without a duration.
+	_, err = s.ex.SkipMax(context.Background(), s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations), Up, 0)
+	c.Assert(err, IsNil)
+
+	var durations []sql.NullInt64
+
+	rows, err := s.db.Query("SELECT duration_ms FROM migrations ORDER BY id")
+	c.Assert(err, IsNil)
+	defer rows.Close()
+
+	for rows.Next() {
+		var duration sql.NullInt64
+		c.Assert(rows.Scan(&duration), IsNil)
+		durations = append(durations, duration)
+	}
+
+	c.Assert(durations, HasLen, 2)
+	c.Assert(durations[0].Valid, Equals, true)
+	c.Assert(durations[1].Valid, Equals, false)
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[0].AppliedBy, Equals, "deploy@ci")
+	c.Assert(records[0].Duration < time.Minute, Equals, true)
+	c.Assert(records[1].Duration, Equals, time.Duration(0))
+}
+
+func (s *SqliteMigrateSuite) TestAddsChecksumColumn(c *C) {
+	// A table written before the checksum column existed, with 123 applied.
+	_, err := s.db.Exec("CREATE TABLE migrations (id text primary key, applied_at datetime not null)")
+	c.Assert(err, IsNil)
+
+	_, err = s.db.Exec("INSERT INTO migrations VALUES ('123', CURRENT_TIMESTAMP); CREATE TABLE people (id int)")
+	c.Assert(err, IsNil)
+
+	// Reading the table leaves it alone, the columns are only added by the
+	// runs holding the migration lock.
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].Checksum, Equals, "")
+
+	_, err = s.db.Exec("SELECT checksum FROM migrations")
+	c.Assert(err, NotNil)
+
+	s.ex.AppliedBy = "deploy@ci"
+
+	n, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations), Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	records, err = s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[0].Checksum, Equals, "")
+	c.Assert(records[1].Checksum, Equals, sqliteMigrations[1].Checksum())
+	c.Assert(records[0].AppliedBy, Equals, "")
+	c.Assert(records[1].AppliedBy, Equals, "deploy@ci")
+}
+
+type fieldLogger struct {
+	nullLogger
+	fields map[string][]Field
+}
+
+func (l *fieldLogger) Log(_ context.Context, _ LogLevel, msg string, fields ...Field) {
+	l.fields[msg] = fields
+}
+
+func (s *SqliteMigrateSuite) TestLogsFields(c *C) {
+	logger := &fieldLogger{fields: make(map[string][]Field)}
+	s.ex.Logger = logger
+
+	_, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations[:1]), Up)
+	c.Assert(err, IsNil)
+
+	fields := logger.fields["Applied migration 123"]
+	c.Assert(fields, HasLen, 4)
+	c.Assert(fields[0], Equals, Field{"migration_id", "123"})
+	c.Assert(fields[1], Equals, Field{"direction", Up})
+	c.Assert(fields[2].Key, Equals, "duration")
+	c.Assert(fields[3], Equals, Field{"statements", 1})
+}
+
+// levelLogger logs no trace messages and counts those it was given anyway.
+type levelLogger struct {
+	fieldLogger
+	traced int
+}
+
+func (l *levelLogger) Enabled(_ context.Context, level LogLevel) bool {
+	return level != LevelTrace
+}
+
+func (l *levelLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...Field) {
+	if level == LevelTrace {
+		l.traced++
+	}
+
+	l.fieldLogger.Log(ctx, level, msg, fields...)
+}
+
+func (s *SqliteMigrateSuite) TestSkipsDisabledTrace(c *C) {
+	logger := &levelLogger{fieldLogger: fieldLogger{fields: make(map[string][]Field)}}
+	s.ex.Logger = logger
+
+	_, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations), Up)
+	c.Assert(err, IsNil)
+	c.Assert(logger.traced, Equals, 0)
+	c.Assert(logger.fields["Applied migration 123"], HasLen, 4)
+}
+
+func (s *SqliteMigrateSuite) TestSkipMax(c *C) {
+	migrations := make([]*Migration, 2000)
+	for i := range migrations {
+		migrations[i] = &Migration{Id: fmt.Sprintf("%d_table.sql", i+1), Up: []string{"SELECT 1"}}
+	}
+
+	source := NewMemoryMigrationSource(migrations)
+
+	n, err := s.ex.SkipMax(context.Background(), s.db, s.dialect, source, Up, 1500)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1500)
+
+	n, err = s.ex.SkipMax(context.Background(), s.db, s.dialect, source, Up, 0)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 500)
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2000)
+	c.Assert(records[0].Checksum, Equals, migrations[0].Checksum())
+}
+
+func (s *SqliteMigrateSuite) TestSkipMaxFailed(c *C) {
+	_, err := s.ex.SkipMax(context.Background(), s.db, s.dialect, NewMemoryMigrationSource(nil), Up, 0)
+	c.Assert(err, IsNil)
+
+	_, err = s.db.Exec(`CREATE TRIGGER refuse BEFORE INSERT ON migrations WHEN NEW.id = '124' BEGIN SELECT RAISE(ABORT, 'refused'); END`)
+	c.Assert(err, IsNil)
+
+	// The error is returned, rather than recording the others one by one.
+	n, err := s.ex.SkipMax(context.Background(), s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations), Up, 0)
+	c.Assert(err, ErrorMatches, "refused")
+	c.Assert(n, Equals, 0)
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 0)
+}
+
+func (s *SqliteMigrateSuite) TestListMigrationIds(c *C) {
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	_, err := s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+
+	_, rep, err := s.ex.PlanMigration(context.Background(), s.db, s.dialect, source, Up, 0)
+	c.Assert(err, IsNil)
+
+	ids, err := rep.ListMigrationIds(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, []string{"123", "124"})
+}
+
+func (s *SqliteMigrateSuite) TestHasMigration(c *C) {
+	source := NewMemoryMigrationSource(sqliteMigrations[:1])
+
+	_, err := s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+
+	_, rep, err := s.ex.PlanMigration(context.Background(), s.db, s.dialect, source, Up, 0)
+	c.Assert(err, IsNil)
+
+	exists, err := rep.HasMigration(context.Background(), "123")
+	c.Assert(err, IsNil)
+	c.Assert(exists, Equals, true)
+
+	exists, err = rep.HasMigration(context.Background(), "124")
+	c.Assert(err, IsNil)
+	c.Assert(exists, Equals, false)
+}
+
+func (s *SqliteMigrateSuite) TestContextLogger(c *C) {
+	configured := &fieldLogger{fields: make(map[string][]Field)}
+	s.ex.Logger = configured
+
+	request := &fieldLogger{fields: make(map[string][]Field)}
+	ctx := WithLogger(context.Background(), request)
+	c.Assert(LoggerFromContext(ctx), Equals, request)
+
+	_, err := s.ex.ExecContext(ctx, s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations[:1]), Up)
+	c.Assert(err, IsNil)
+
+	c.Assert(request.fields["Applied migration 123"], HasLen, 4)
+	c.Assert(configured.fields, HasLen, 0)
+
+	// The queries of the repository are traced with the logger of the context too.
+	traced := false
+	for msg := range request.fields {
+		traced = traced || strings.Contains(msg, "CREATE TABLE people")
+	}
+
+	c.Assert(traced, Equals, true)
+}
+
+type warnLogger struct {
+	nullLogger
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (l *warnLogger) Errorf(format string, v ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.warnings = append(l.warnings, fmt.Sprintf(format, v...))
+}
+
+func (s *SqliteMigrateSuite) TestSlowStatement(c *C) {
+	logger := &warnLogger{}
+	s.ex.Logger = logger
+	s.ex.SlowStatement = time.Millisecond
+
+	source := NewMemoryMigrationSource([]*Migration{{
+		Id: "1_slow.sql",
+		Up: []string{
+			"CREATE TABLE people (id int)",
+			"WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 3000000) SELECT count(*) FROM n",
+		},
+		UpLines: []sqlparse.LineRange{{First: 2, Last: 2}, {First: 4, Last: 6}},
+	}})
+
+	_, err := s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	c.Assert(len(logger.warnings) >= 2, Equals, true)
+	c.Assert(logger.warnings[len(logger.warnings)-2], Equals, "Slow statement: lines 4-6 of migration 1_slow.sql is still running after 1ms")
+	c.Assert(logger.warnings[len(logger.warnings)-1], Matches, "Slow statement: lines 4-6 of migration 1_slow.sql took .*")
+}
+
+func (s *SqliteMigrateSuite) TestStatementTimeout(c *C) {
+	source := NewMemoryMigrationSource([]*Migration{{
+		Id: "1_runaway.sql",
+		Up: []string{
+			"CREATE TABLE people (id int)",
+			"WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n) SELECT count(*) FROM n",
+		},
+		StatementTimeoutUp: 50 * time.Millisecond,
+	}})
+
+	n, err := s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, ErrorMatches, "statement timeout of 50ms exceeded: context deadline exceeded handling 1_runaway.sql")
+	c.Assert(n, Equals, 0)
+
+	source.Migrations[0].Up = source.Migrations[0].Up[:1]
+
+	n, err = s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+}
+
+// retryingDialect retries the transactions aborted by errors asking to
+// restart them, as CockroachDB does.
+type retryingDialect struct {
+	*dialect.SqliteDialect
+}
+
+func (retryingDialect) IsRetryableTx(err error) bool {
+	return strings.Contains(err.Error(), "restart transaction")
+}
+
+func (s *SqliteMigrateSuite) TestRetryTx(c *C) {
+	defer func(backoff time.Duration) { txRetryBackoff = backoff }(txRetryBackoff)
+	txRetryBackoff = time.Millisecond
+
+	conflicts := 2
+	source := NewMemoryMigrationSource([]*Migration{{
+		Id: "1_people.sql",
+		Stream: func(_ MigrationDirection, fn func(string, sqlparse.LineRange) error) error {
+			if conflicts > 0 {
+				conflicts--
+
+				return errors.New("restart transaction: TransactionRetryWithProtoRefreshError")
+			}
+
+			return fn("CREATE TABLE people (id int)", sqlparse.LineRange{})
+		},
+	}})
+
+	d := retryingDialect{dialect.NewSqliteDialect()}
+
+	n, err := s.ex.Exec(s.db, d, source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+	c.Assert(conflicts, Equals, 0)
+
+	// Other errors aren't retried.
+	source.Migrations = append(source.Migrations, &Migration{Id: "2_broken.sql", Up: []string{"SELECT * FROM missing"}})
+
+	_, err = s.ex.Exec(s.db, d, source, Up)
+	c.Assert(err, ErrorMatches, "no such table: missing handling 2_broken.sql")
+}
+
+// ddlDialect commits the DDL statements on the spot, as TiDB does.
+type ddlDialect struct {
+	*dialect.SqliteDialect
+}
+
+func (ddlDialect) IsDDL(stmt string) bool {
+	return dialect.NewTiDBDialect().IsDDL(stmt)
+}
+
+func (s *SqliteMigrateSuite) TestDDLWithoutTransaction(c *C) {
+	ctx := context.Background()
+	d := ddlDialect{dialect.NewSqliteDialect()}
+
+	c.Assert(d.IsDDL("/* users */\n  create table people (id int)"), Equals, true)
+	c.Assert(d.IsDDL("-- backfill\nUPDATE people SET id = 1"), Equals, false)
+
+	source := NewMemoryMigrationSource([]*Migration{
+		{Id: "1_people.sql", Up: []string{"CREATE TABLE people (id int)", "SELECT * FROM missing"}},
+		{Id: "2_backfill.sql", Up: []string{"INSERT INTO people VALUES (1)"}},
+	})
+
+	plan, err := s.ex.Plan(ctx, s.db, d, source, Up, 0)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Migrations, HasLen, 2)
+	c.Assert(plan.Migrations[0].DisableTransaction, Equals, true)
+	c.Assert(plan.Migrations[1].DisableTransaction, Equals, false)
+
+	// The table created before the failure isn't rolled back.
+	_, err = s.ex.Exec(s.db, d, source, Up)
+	c.Assert(err, ErrorMatches, "no such table: missing handling 1_people.sql")
+
+	_, err = s.db.Exec("SELECT * FROM people")
+	c.Assert(err, IsNil)
+
+	// Streams aren't read while planning, they run without a transaction.
+	reads := 0
+	source = NewMemoryMigrationSource([]*Migration{{
+		Id: "3_backfill.sql",
+		Stream: func(_ MigrationDirection, fn func(string, sqlparse.LineRange) error) error {
+			reads++
+			return fn("INSERT INTO people VALUES (2)", sqlparse.LineRange{})
+		},
+	}})
+
+	plan, err = s.ex.Plan(ctx, s.db, d, source, Up, 0)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Migrations[0].DisableTransaction, Equals, true)
+	c.Assert(reads, Equals, 0)
+}
+
+func (s *SqliteMigrateSuite) TestInit(c *C) {
+	ctx := context.Background()
+
+	s.ex.CreateTable = false
+	c.Assert(s.ex.Init(ctx, s.db, s.dialect), NotNil)
+
+	s.ex.CreateTable = true
+	c.Assert(s.ex.Init(ctx, s.db, s.dialect), IsNil)
+	c.Assert(s.ex.Init(ctx, s.db, s.dialect), IsNil)
+
+	records, err := s.ex.GetMigrationRecords(ctx, s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 0)
+}
+
+func (s *SqliteMigrateSuite) TestBaseline(c *C) {
+	ctx := context.Background()
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	// The existing schema already has the table of the first migration.
+	_, err := s.db.Exec("CREATE TABLE people (id int)")
+	c.Assert(err, IsNil)
+
+	n, err := s.ex.Baseline(ctx, s.db, s.dialect, source, 123)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	_, err = s.ex.Baseline(ctx, s.db, s.dialect, source, 123)
+	c.Assert(err, ErrorMatches, "baseline: the migration table already holds 1 migrations")
+
+	n, err = s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+}
+
+func (s *SqliteMigrateSuite) TestBaselineVersion(c *C) {
+	ctx := context.Background()
+	source := NewMemoryMigrationSource([]*Migration{
+		{Id: "1_a.sql", Up: []string{"SELECT 1"}},
+		{Id: "2_b.sql", Up: []string{"SELECT 2"}},
+		{Id: "2_c.sql", Up: []string{"SELECT 3"}},
+		{Id: "3_d.sql", Up: []string{"SELECT 4"}},
+	})
+
+	_, err := s.ex.Baseline(ctx, s.db, s.dialect, source, -1)
+	c.Assert(err, ErrorMatches, "baseline: invalid version -1")
+
+	_, err = s.ex.Baseline(ctx, s.db, s.dialect, source, 2)
+	c.Assert(err, ErrorMatches, "baseline: version 2 matches several migrations: 2_b.sql, 2_c.sql")
+
+	_, err = s.ex.Baseline(ctx, s.db, s.dialect, source, 4)
+	c.Assert(err, ErrorMatches, ".*unknown migration with version id 4.*")
+
+	n, err := s.ex.Baseline(ctx, s.db, s.dialect, source, 3)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 4)
+
+	records, err := s.ex.GetMigrationRecords(ctx, s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 4)
+	c.Assert(records[3].Checksum, Equals, source.Migrations[3].Checksum())
+}
+
+func (s *SqliteMigrateSuite) TestLastMigration(c *C) {
+	ctx := context.Background()
+
+	last, err := s.ex.LastMigration(ctx, s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(last, IsNil)
+
+	var index string
+	err = s.db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'migrations' AND sql LIKE '%applied_at%'`).Scan(&index)
+	c.Assert(err, IsNil)
+	c.Assert(index, Equals, "migrations_applied_at_idx")
+
+	_, err = s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations), Up)
+	c.Assert(err, IsNil)
+
+	last, err = s.ex.LastMigration(ctx, s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(last.Id, Equals, "124")
+}
+
+func (*SqliteMigrateSuite) TestGetDialectClickHouse(c *C) {
+	d, err := GetDialect(ClickHouse)
+	c.Assert(err, IsNil)
+
+	// Rollbacks delete their record, which TinyLog can't.
+	c.Assert(d.QueryCreateMigrateTable("", "migrations"), Equals,
+		`CREATE TABLE IF NOT EXISTS "migrations" (id String, applied_at DateTime) ENGINE = MergeTree ORDER BY id;`)
+	c.Assert(d.(*dialect.ClickhouseDialect).MutationsSync, Equals, 1)
+}
+
+func (s *SqliteMigrateSuite) TestDetectColumnsErrors(c *C) {
+	db, err := sql.Open("sqlite3", filepath.Join(c.MkDir(), "closed.db"))
+	c.Assert(err, IsNil)
+	c.Assert(db.Close(), IsNil)
+
+	// Only a missing column is told apart, a failed probe isn't.
+	rep := s.ex.newRepository(db, s.dialect)
+	err = rep.DetectColumns(context.Background(), false)
+	c.Assert(err, ErrorMatches, "detect column checksum of the migration table: sql: database is closed")
+}
+
+func (s *SqliteMigrateSuite) TestDetectColumnsCached(c *C) {
+	_, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations[:1]), Up)
+	c.Assert(err, IsNil)
+
+	columns, ok := s.ex.columns.load(columnKey{db: s.db, tableName: s.ex.TableName})
+	c.Assert(ok, Equals, true)
+	c.Assert(columns, DeepEquals, []string{"checksum", "duration_ms", "applied_by"})
+
+	// The cached columns are used without probing them again.
+	_, err = s.db.Exec("ALTER TABLE migrations RENAME COLUMN applied_by TO deployed_by")
+	c.Assert(err, IsNil)
+
+	_, err = s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations), Up)
+	c.Assert(err, ErrorMatches, ".*applied_by.*")
+}
@@ -0,0 +1,312 @@
+// Package migrateadmin serves the migrations of a service over gRPC, and over
+// REST through grpc-gateway, so a platform manages the migrations of all its
+// services with the same API:
+//
+//	srv := migrateadmin.NewServer(migrateadmin.Migrator{
+//		DB:      db,
+//		Dialect: dialect.NewPostgresDialect(),
+//		Source:  source,
+//	}, migrateadmin.WithAuthorizer(migrateadmin.BearerToken(token)))
+//
+//	adminpb.RegisterAdminServer(grpcServer, srv)
+//
+//	mux := runtime.NewServeMux()
+//	err := adminpb.RegisterAdminHandlerServer(ctx, mux, srv)
+//
+// The REST endpoints are GET /v1/migrations/status, GET /v1/migrations/plan,
+// POST /v1/migrations/up and POST /v1/migrations/down, see adminpb/admin.proto.
+package migrateadmin
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/migrateadmin/adminpb"
+)
+
+var _ adminpb.AdminServer = (*Server)(nil)
+
+// Migrator is the database of a service and its migrations.
+type Migrator struct {
+	// Executor runs the migrations, nil for the defaults.
+	Executor *migrate.MigrationExecutor
+	DB       *sql.DB
+	Dialect  dialect.Dialect
+	Source   migrate.MigrationSource
+}
+
+// Authorizer allows or denies a call, with the full gRPC name of its method,
+// e.g. adminpb.Admin_Up_FullMethodName, and its request. Calls through the
+// gateway carry the headers of the HTTP request as incoming metadata. An
+// error without a gRPC status denies the call with PermissionDenied.
+type Authorizer func(ctx context.Context, fullMethod string, req proto.Message) error
+
+// BearerToken returns an Authorizer allowing the calls whose authorization
+// metadata, or Authorization header through the gateway, is "Bearer <token>".
+func BearerToken(token string) Authorizer {
+	return func(ctx context.Context, _ string, _ proto.Message) error {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		for _, value := range md.Get("authorization") {
+			got, ok := strings.CutPrefix(value, "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				return nil
+			}
+		}
+
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+}
+
+// Option configures the Server.
+type Option func(*Server)
+
+// WithAuthorizer checks every call with authorize before serving it. Without
+// it, every call is allowed.
+func WithAuthorizer(authorize Authorizer) Option {
+	return func(s *Server) {
+		s.authorize = authorize
+	}
+}
+
+// Server implements adminpb.AdminServer with a Migrator.
+type Server struct {
+	adminpb.UnimplementedAdminServer
+
+	migrator  Migrator
+	authorize Authorizer
+
+	// mu runs one migration of this process at a time, the lock of the
+	// executor guards against other processes.
+	mu sync.Mutex
+}
+
+// NewServer returns a server managing the migrations of the migrator.
+func NewServer(m Migrator, opts ...Option) *Server {
+	if m.Executor == nil {
+		m.Executor = migrate.NewMigrationExecutor()
+	}
+
+	s := &Server{migrator: m}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Status returns the state of every migration, ordered like the source.
+func (s *Server) Status(ctx context.Context, req *adminpb.StatusRequest) (*adminpb.StatusResponse, error) {
+	if err := s.check(ctx, adminpb.Admin_Status_FullMethodName, req); err != nil {
+		return nil, err
+	}
+
+	m := s.migrator
+
+	migrations, err := m.Source.FindMigrations()
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	records, err := m.Executor.GetMigrationRecords(ctx, m.DB, m.Dialect)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	byId := make(map[string]*adminpb.Migration, len(migrations)+len(records))
+	ids := make([]*migrate.Migration, 0, len(migrations)+len(records))
+
+	for _, migration := range migrations {
+		byId[migration.Id] = &adminpb.Migration{
+			Id:       migration.Id,
+			State:    adminpb.State_STATE_PENDING,
+			Checksum: migration.Checksum(),
+		}
+		ids = append(ids, migration)
+	}
+
+	for _, record := range records {
+		row, ok := byId[record.Id]
+		if ok {
+			row.State = adminpb.State_STATE_APPLIED
+		} else {
+			// Applied in the database, but missing from the source.
+			row = &adminpb.Migration{Id: record.Id, State: adminpb.State_STATE_UNKNOWN}
+			byId[record.Id] = row
+			ids = append(ids, &migrate.Migration{Id: record.Id})
+		}
+
+		row.AppliedAt = timestamppb.New(record.AppliedAt)
+	}
+
+	sort.SliceStable(ids, func(i, j int) bool { return ids[i].Less(ids[j]) })
+
+	response := &adminpb.StatusResponse{Migrations: make([]*adminpb.Migration, 0, len(ids))}
+	for _, id := range ids {
+		response.Migrations = append(response.Migrations, byId[id.Id])
+	}
+
+	return response, nil
+}
+
+// Plan returns the migrations which would run, with their queries.
+func (s *Server) Plan(ctx context.Context, req *adminpb.PlanRequest) (*adminpb.PlanResponse, error) {
+	if err := s.check(ctx, adminpb.Admin_Plan_FullMethodName, req); err != nil {
+		return nil, err
+	}
+
+	dir := migrate.Up
+	if req.GetDirection() == adminpb.Direction_DIRECTION_DOWN {
+		dir = migrate.Down
+	}
+
+	limit, version, err := target(dir, req.GetLimit(), req.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	m := s.migrator
+
+	var planned []*migrate.PlannedMigration
+	if version >= 0 {
+		planned, _, err = m.Executor.PlanMigrationToVersion(ctx, m.DB, m.Dialect, m.Source, dir, version)
+	} else {
+		planned, _, err = m.Executor.PlanMigration(ctx, m.DB, m.Dialect, m.Source, dir, limit)
+	}
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &adminpb.PlanResponse{
+		Direction:  req.GetDirection(),
+		Migrations: make([]*adminpb.PlannedMigration, 0, len(planned)),
+	}
+
+	for _, migration := range planned {
+		response.Migrations = append(response.Migrations, &adminpb.PlannedMigration{
+			Id:            migration.Id,
+			NoTransaction: migration.DisableTransaction,
+			Queries:       migration.Queries,
+		})
+	}
+
+	return response, nil
+}
+
+// Up applies migrations.
+func (s *Server) Up(ctx context.Context, req *adminpb.ExecRequest) (*adminpb.ExecResponse, error) {
+	if err := s.check(ctx, adminpb.Admin_Up_FullMethodName, req); err != nil {
+		return nil, err
+	}
+
+	return s.exec(ctx, migrate.Up, req)
+}
+
+// Down reverts migrations, a single one unless the request sets the limit or
+// the version.
+func (s *Server) Down(ctx context.Context, req *adminpb.ExecRequest) (*adminpb.ExecResponse, error) {
+	if err := s.check(ctx, adminpb.Admin_Down_FullMethodName, req); err != nil {
+		return nil, err
+	}
+
+	return s.exec(ctx, migrate.Down, req)
+}
+
+func (s *Server) exec(ctx context.Context, dir migrate.MigrationDirection, req *adminpb.ExecRequest) (*adminpb.ExecResponse, error) {
+	limit, version, err := target(dir, req.GetLimit(), req.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.migrator
+
+	var n int
+	if version >= 0 {
+		n, err = m.Executor.ExecVersionContext(ctx, m.DB, m.Dialect, m.Source, dir, version)
+	} else {
+		n, err = m.Executor.ExecMaxContext(ctx, m.DB, m.Dialect, m.Source, dir, limit)
+	}
+
+	if err != nil {
+		// The migrations applied before the error stay applied.
+		return nil, statusError(err)
+	}
+
+	return &adminpb.ExecResponse{Applied: int32(n)}, nil
+}
+
+// check runs the authorizer, if any.
+func (s *Server) check(ctx context.Context, fullMethod string, req proto.Message) error {
+	if s.authorize == nil {
+		return nil
+	}
+
+	err := s.authorize(ctx, fullMethod, req)
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	return status.Error(codes.PermissionDenied, err.Error())
+}
+
+// target returns the limit, and the version or -1, of a request. Down
+// defaults to a single migration, like the down command.
+func target(dir migrate.MigrationDirection, limit *wrapperspb.Int32Value, version *wrapperspb.Int64Value) (int, int64, error) {
+	if limit != nil && version != nil {
+		return 0, 0, status.Error(codes.InvalidArgument, "limit and version are mutually exclusive")
+	}
+
+	if version != nil {
+		if version.GetValue() < 0 {
+			return 0, 0, status.Errorf(codes.InvalidArgument, "invalid version %d", version.GetValue())
+		}
+
+		return 0, version.GetValue(), nil
+	}
+
+	if limit == nil {
+		if dir == migrate.Down {
+			return 1, -1, nil
+		}
+
+		return 0, -1, nil
+	}
+
+	if limit.GetValue() < 0 {
+		return 0, 0, status.Errorf(codes.InvalidArgument, "invalid limit %d", limit.GetValue())
+	}
+
+	return int(limit.GetValue()), -1, nil
+}
+
+// statusError returns err with the gRPC code of the context errors, and
+// Internal otherwise.
+func statusError(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,235 @@
+package migrateadmin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/migrateadmin/adminpb"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type AdminSuite struct {
+	db     *sql.DB
+	server *Server
+
+	grpc *grpc.Server
+	conn *grpc.ClientConn
+}
+
+var _ = Suite(&AdminSuite{})
+
+type nullLogger struct{}
+
+func (nullLogger) Tracef(string, ...any) {}
+func (nullLogger) Infof(string, ...any)  {}
+func (nullLogger) Errorf(string, ...any) {}
+
+var source = migrate.NewMemoryMigrationSource([]*migrate.Migration{
+	{Id: "1_people.sql", Up: []string{"CREATE TABLE people (id int)"}, Down: []string{"DROP TABLE people"}},
+	{Id: "2_pets.sql", Up: []string{"CREATE TABLE pets (id int)"}, Down: []string{"DROP TABLE pets"}},
+})
+
+func (s *AdminSuite) SetUpTest(c *C) {
+	var err error
+
+	s.db, err = sql.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+
+	ex := migrate.NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = nullLogger{}
+
+	s.server = NewServer(Migrator{Executor: ex, DB: s.db, Dialect: dialect.NewSqliteDialect(), Source: source},
+		WithAuthorizer(BearerToken("secret")))
+}
+
+func (s *AdminSuite) TearDownTest(*C) {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.grpc.Stop()
+		s.conn, s.grpc = nil, nil
+	}
+
+	_ = s.db.Close()
+}
+
+// client serves the server on an in-memory listener, until the end of the test.
+func (s *AdminSuite) client(c *C) adminpb.AdminClient {
+	listener := bufconn.Listen(1 << 20)
+
+	s.grpc = grpc.NewServer()
+	adminpb.RegisterAdminServer(s.grpc, s.server)
+
+	go func() { _ = s.grpc.Serve(listener) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	c.Assert(err, IsNil)
+
+	s.conn = conn
+
+	return adminpb.NewAdminClient(conn)
+}
+
+func authorized() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+}
+
+func (s *AdminSuite) TestGRPC(c *C) {
+	client := s.client(c)
+	ctx := authorized()
+
+	plan, err := client.Plan(ctx, &adminpb.PlanRequest{Limit: wrapperspb.Int32(1)})
+	c.Assert(err, IsNil)
+	c.Assert(plan.Migrations, HasLen, 1)
+	c.Assert(plan.Migrations[0].Id, Equals, "1_people.sql")
+	c.Assert(plan.Migrations[0].Queries, DeepEquals, []string{"CREATE TABLE people (id int)"})
+
+	up, err := client.Up(ctx, &adminpb.ExecRequest{})
+	c.Assert(err, IsNil)
+	c.Assert(up.Applied, Equals, int32(2))
+
+	// Down reverts a single migration by default.
+	down, err := client.Down(ctx, &adminpb.ExecRequest{})
+	c.Assert(err, IsNil)
+	c.Assert(down.Applied, Equals, int32(1))
+
+	st, err := client.Status(ctx, &adminpb.StatusRequest{})
+	c.Assert(err, IsNil)
+	c.Assert(st.Migrations, HasLen, 2)
+	c.Assert(st.Migrations[0].State, Equals, adminpb.State_STATE_APPLIED)
+	c.Assert(st.Migrations[0].AppliedAt, NotNil)
+	c.Assert(st.Migrations[0].Checksum, Not(Equals), "")
+	c.Assert(st.Migrations[1].State, Equals, adminpb.State_STATE_PENDING)
+	c.Assert(st.Migrations[1].AppliedAt, IsNil)
+
+	down, err = client.Down(ctx, &adminpb.ExecRequest{Limit: wrapperspb.Int32(0)})
+	c.Assert(err, IsNil)
+	c.Assert(down.Applied, Equals, int32(1))
+}
+
+func (s *AdminSuite) TestGRPCErrors(c *C) {
+	client := s.client(c)
+
+	_, err := client.Status(context.Background(), &adminpb.StatusRequest{})
+	c.Assert(status.Code(err), Equals, codes.Unauthenticated)
+
+	_, err = client.Up(authorized(), &adminpb.ExecRequest{Limit: wrapperspb.Int32(1), Version: wrapperspb.Int64(1)})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	_, err = client.Up(authorized(), &adminpb.ExecRequest{Limit: wrapperspb.Int32(-1)})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *AdminSuite) TestStatusUnknown(c *C) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+
+	_, err := s.server.Up(ctx, &adminpb.ExecRequest{})
+	c.Assert(err, IsNil)
+
+	s.server.migrator.Source = migrate.NewMemoryMigrationSource(nil)
+
+	st, err := s.server.Status(ctx, &adminpb.StatusRequest{})
+	c.Assert(err, IsNil)
+	c.Assert(st.Migrations, HasLen, 2)
+	c.Assert(st.Migrations[0].State, Equals, adminpb.State_STATE_UNKNOWN)
+	c.Assert(st.Migrations[0].Checksum, Equals, "")
+}
+
+func (s *AdminSuite) TestAuthorizer(c *C) {
+	var methods []string
+
+	s.server.authorize = func(_ context.Context, fullMethod string, req proto.Message) error {
+		methods = append(methods, fullMethod)
+
+		if exec, ok := req.(*adminpb.ExecRequest); ok && exec.GetLimit() == nil {
+			return errors.New("a limit is required")
+		}
+
+		return nil
+	}
+
+	_, err := s.server.Up(context.Background(), &adminpb.ExecRequest{})
+	c.Assert(status.Code(err), Equals, codes.PermissionDenied)
+	c.Assert(status.Convert(err).Message(), Equals, "a limit is required")
+
+	_, err = s.server.Up(context.Background(), &adminpb.ExecRequest{Limit: wrapperspb.Int32(1)})
+	c.Assert(err, IsNil)
+
+	c.Assert(methods, DeepEquals, []string{adminpb.Admin_Up_FullMethodName, adminpb.Admin_Up_FullMethodName})
+}
+
+func (s *AdminSuite) TestGateway(c *C) {
+	mux := runtime.NewServeMux()
+	c.Assert(adminpb.RegisterAdminHandlerServer(context.Background(), mux, s.server), IsNil)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	do := func(method, path, body string) (int, map[string]any) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		c.Assert(err, IsNil)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+
+		var decoded map[string]any
+		c.Assert(json.NewDecoder(resp.Body).Decode(&decoded), IsNil)
+
+		return resp.StatusCode, decoded
+	}
+
+	code, body := do(http.MethodGet, "/v1/migrations/plan?direction=DIRECTION_DOWN&limit=0", "")
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(body["migrations"], HasLen, 0)
+
+	code, body = do(http.MethodPost, "/v1/migrations/up", `{"limit": 1}`)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(body["applied"], Equals, float64(1))
+
+	code, body = do(http.MethodGet, "/v1/migrations/status", "")
+	c.Assert(code, Equals, http.StatusOK)
+
+	migrations := body["migrations"].([]any)
+	c.Assert(migrations[0].(map[string]any)["state"], Equals, "STATE_APPLIED")
+	c.Assert(migrations[1].(map[string]any)["state"], Equals, "STATE_PENDING")
+
+	code, _ = do(http.MethodPost, "/v1/migrations/down", `{"limit": 1, "version": 0}`)
+	c.Assert(code, Equals, http.StatusBadRequest)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/migrations/status", http.NoBody)
+	c.Assert(err, IsNil)
+
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusUnauthorized)
+}
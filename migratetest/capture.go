@@ -0,0 +1,144 @@
+package migratetest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+var _ migrate.FieldLogger = (*CaptureLogger)(nil)
+
+// Entry is a message logged to a CaptureLogger.
+type Entry struct {
+	Level  migrate.LogLevel
+	Msg    string
+	Fields []migrate.Field
+}
+
+// Field returns the value of the field of the entry, or nil.
+func (e Entry) Field(key string) any {
+	for _, f := range e.Fields {
+		if f.Key == key {
+			return f.Value
+		}
+	}
+
+	return nil
+}
+
+// CaptureLogger records the messages of the executor with their structured
+// fields, so tests can assert on what was logged:
+//
+//	logger := &migratetest.CaptureLogger{}
+//	ex.Logger = logger
+//	...
+//	entries := logger.Find("Applied migration")
+type CaptureLogger struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func (l *CaptureLogger) Tracef(format string, v ...any) {
+	l.Log(context.Background(), migrate.LevelTrace, fmt.Sprintf(format, v...))
+}
+
+func (l *CaptureLogger) Infof(format string, v ...any) {
+	l.Log(context.Background(), migrate.LevelInfo, fmt.Sprintf(format, v...))
+}
+
+func (l *CaptureLogger) Errorf(format string, v ...any) {
+	l.Log(context.Background(), migrate.LevelError, fmt.Sprintf(format, v...))
+}
+
+// Log records the message.
+func (l *CaptureLogger) Log(_ context.Context, level migrate.LogLevel, msg string, fields ...migrate.Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, Entry{Level: level, Msg: msg, Fields: fields})
+}
+
+// Entries returns the messages logged so far, in order.
+func (l *CaptureLogger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Entry(nil), l.entries...)
+}
+
+// Find returns the messages containing substr, in order.
+func (l *CaptureLogger) Find(substr string) []Entry {
+	var found []Entry
+
+	for _, entry := range l.Entries() {
+		if strings.Contains(entry.Msg, substr) {
+			found = append(found, entry)
+		}
+	}
+
+	return found
+}
+
+// Level returns the messages of the level, in order.
+func (l *CaptureLogger) Level(level migrate.LogLevel) []Entry {
+	var found []Entry
+
+	for _, entry := range l.Entries() {
+		if entry.Level == level {
+			found = append(found, entry)
+		}
+	}
+
+	return found
+}
+
+// Reset drops the messages logged so far.
+func (l *CaptureLogger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = nil
+}
+
+// Clock is a settable clock for the Clock of the executor, so the migrations
+// are recorded with stable applied_at values:
+//
+//	clock := migratetest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	ex.Clock = clock.Now
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Set sets the time of the clock.
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
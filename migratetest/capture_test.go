@@ -0,0 +1,48 @@
+package migratetest
+
+import (
+	"time"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func (*MigrateTestSuite) TestCaptureAndClock(c *C) {
+	logger := &CaptureLogger{}
+	clock := NewClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	fake := NewFakeRepository()
+	defer fake.DB().Close()
+
+	ex := migrate.NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = logger
+	ex.Clock = clock.Now
+
+	source := migrate.NewMemoryMigrationSource(migrations)
+
+	_, err := ex.ExecMax(fake.DB(), NoopDialect{}, source, migrate.Up, 1)
+	c.Assert(err, IsNil)
+
+	clock.Advance(time.Hour)
+
+	_, err = ex.Exec(fake.DB(), NoopDialect{}, source, migrate.Up)
+	c.Assert(err, IsNil)
+
+	records := fake.Records()
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[0].AppliedAt, Equals, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	c.Assert(records[1].AppliedAt, Equals, time.Date(2024, 1, 2, 4, 4, 5, 0, time.UTC))
+
+	applied := logger.Find("Applied migration")
+	c.Assert(applied, HasLen, 2)
+	c.Assert(applied[0].Level, Equals, migrate.LevelInfo)
+	c.Assert(applied[1].Field("migration_id"), Equals, "2_orders.sql")
+	c.Assert(applied[1].Field("direction"), Equals, migrate.Up)
+	c.Assert(logger.Level(migrate.LevelTrace), Not(HasLen), 0)
+
+	logger.Reset()
+	c.Assert(logger.Entries(), HasLen, 0)
+}
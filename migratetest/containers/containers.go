@@ -0,0 +1,237 @@
+//go:build integration
+
+// Package containers runs migrations against Postgres, MySQL and SQL Server
+// started in containers with testcontainers-go. It needs docker and is only
+// built with the integration tag:
+//
+//	go test -tags integration ./...
+//
+// A test runs the migrations of a source up and down on every database:
+//
+//	func TestMigrations(t *testing.T) {
+//		containers.Run(t, migrate.NewFileMigrationSource("migrations"))
+//	}
+package containers
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/microsoft/go-mssqldb"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mssql"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// Database is a database running in a container for the duration of a test.
+type Database struct {
+	// Name is the name of the database system, e.g. postgres.
+	Name    string
+	Dialect dialect.Dialect
+	DB      *sql.DB
+	// DSN is the connection string of DB.
+	DSN string
+}
+
+// Starter starts a database in a container, terminated when the test ends.
+type Starter struct {
+	Name  string
+	Start func(t testing.TB) *Database
+}
+
+// Starters lists the databases Run migrates, in order.
+var Starters = []Starter{
+	{Name: "postgres", Start: Postgres},
+	{Name: "mysql", Start: MySQL},
+	{Name: "sqlserver", Start: SQLServer},
+}
+
+// Postgres starts a postgres container.
+func Postgres(t testing.TB) *Database {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage("postgres:16-alpine"),
+		postgres.WithDatabase("migrate"),
+		testcontainers.WithWaitStrategy(wait.ForLog("database system is ready to accept connections").WithOccurrence(2)),
+	)
+	if err != nil {
+		t.Fatalf("containers: cannot start postgres: %v", err)
+	}
+
+	terminate(t, container)
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("containers: cannot read the postgres dsn: %v", err)
+	}
+
+	return open(t, "postgres", dialect.NewPostgresDialect(), dsn)
+}
+
+// MySQL starts a mysql container.
+func MySQL(t testing.TB) *Database {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mysql.RunContainer(ctx,
+		testcontainers.WithImage("mysql:8.0"),
+		mysql.WithDatabase("migrate"),
+	)
+	if err != nil {
+		t.Fatalf("containers: cannot start mysql: %v", err)
+	}
+
+	terminate(t, container)
+
+	dsn, err := container.ConnectionString(ctx, "parseTime=true")
+	if err != nil {
+		t.Fatalf("containers: cannot read the mysql dsn: %v", err)
+	}
+
+	return open(t, "mysql", dialect.NewMySQLDialect("InnoDB", "UTF8"), dsn)
+}
+
+// SQLServer starts a SQL Server container, accepting its license.
+func SQLServer(t testing.TB) *Database {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mssql.RunContainer(ctx,
+		testcontainers.WithImage("mcr.microsoft.com/mssql/server:2022-latest"),
+		mssql.WithAcceptEULA(),
+	)
+	if err != nil {
+		t.Fatalf("containers: cannot start sqlserver: %v", err)
+	}
+
+	terminate(t, container)
+
+	dsn, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("containers: cannot read the sqlserver dsn: %v", err)
+	}
+
+	return open(t, "sqlserver", dialect.NewSqlServerDialect(), dsn)
+}
+
+// Run runs the migrations of the source on every database of Starters, in
+// a subtest each. See UpDown.
+func Run(t *testing.T, source migrate.MigrationSource) {
+	for _, starter := range Starters {
+		starter := starter
+
+		t.Run(starter.Name, func(t *testing.T) {
+			UpDown(t, starter.Start(t), source)
+		})
+	}
+}
+
+// UpDown applies all the migrations of the source, checks that none is
+// pending, then rolls them all back and checks that none is recorded.
+func UpDown(t testing.TB, db *Database, source migrate.MigrationSource) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	ex := migrate.NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = testLogger{t}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		t.Fatalf("containers: cannot read the migrations: %v", err)
+	}
+
+	n, err := ex.ExecContext(ctx, db.DB, db.Dialect, source, migrate.Up)
+	if err != nil {
+		t.Fatalf("containers: %s: cannot apply the migrations: %v", db.Name, err)
+	}
+
+	if n != len(migrations) {
+		t.Errorf("containers: %s: applied %d migrations, expected %d", db.Name, n, len(migrations))
+	}
+
+	pending, err := ex.HasPending(ctx, db.DB, db.Dialect, source)
+	if err != nil {
+		t.Fatalf("containers: %s: cannot read the pending migrations: %v", db.Name, err)
+	}
+
+	if pending {
+		t.Errorf("containers: %s: migrations are pending after up", db.Name)
+	}
+
+	n, err = ex.ExecContext(ctx, db.DB, db.Dialect, source, migrate.Down)
+	if err != nil {
+		t.Fatalf("containers: %s: cannot roll back the migrations: %v", db.Name, err)
+	}
+
+	if n != len(migrations) {
+		t.Errorf("containers: %s: rolled back %d migrations, expected %d", db.Name, n, len(migrations))
+	}
+
+	records, err := ex.GetMigrationRecords(ctx, db.DB, db.Dialect)
+	if err != nil {
+		t.Fatalf("containers: %s: cannot read the applied migrations: %v", db.Name, err)
+	}
+
+	if len(records) > 0 {
+		t.Errorf("containers: %s: %d migrations are recorded after down", db.Name, len(records))
+	}
+}
+
+func open(t testing.TB, name string, d dialect.Dialect, dsn string) *Database {
+	t.Helper()
+
+	db, err := sql.Open(name, dsn)
+	if err != nil {
+		t.Fatalf("containers: cannot open %s: %v", name, err)
+	}
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	if err := db.Ping(); err != nil {
+		t.Fatalf("containers: cannot connect to %s: %v", name, err)
+	}
+
+	return &Database{Name: name, Dialect: d, DB: db, DSN: dsn}
+}
+
+func terminate(t testing.TB, container testcontainers.Container) {
+	t.Cleanup(func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Logf("containers: cannot terminate the container: %v", err)
+		}
+	})
+}
+
+// testLogger logs the executor through the log of the test.
+type testLogger struct {
+	t testing.TB
+}
+
+func (l testLogger) Tracef(format string, v ...any) {
+	l.t.Logf(format, v...)
+}
+
+func (l testLogger) Infof(format string, v ...any) {
+	l.t.Logf(format, v...)
+}
+
+func (l testLogger) Errorf(format string, v ...any) {
+	l.t.Logf(format, v...)
+}
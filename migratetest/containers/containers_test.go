@@ -0,0 +1,13 @@
+//go:build integration
+
+package containers
+
+import (
+	"testing"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func TestRun(t *testing.T) {
+	Run(t, migrate.NewFileMigrationSource("../../test-migrations"))
+}
@@ -0,0 +1,345 @@
+package migratetest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+var (
+	_ dialect.Dialect    = NoopDialect{}
+	_ dialect.IdSelector = NoopDialect{}
+)
+
+// noopPrefix starts the queries of NoopDialect. Other queries are statements
+// of the migrations.
+const noopPrefix = "NOOP "
+
+// NoopDialect renders the queries of the migration table as commands of the
+// FakeRepository. It only runs on the DB of a FakeRepository.
+type NoopDialect struct{}
+
+func (NoopDialect) QueryCreateMigrateSchema(string) string {
+	return noopPrefix + "CREATE SCHEMA"
+}
+
+func (NoopDialect) QueryCreateMigrateTable(string, string) string {
+	return noopPrefix + "CREATE TABLE"
+}
+
+func (NoopDialect) QueryDeleteMigrate(string, string) string {
+	return noopPrefix + "DELETE"
+}
+
+func (NoopDialect) QuerySelectMigrate(string, string) string {
+	return noopPrefix + "SELECT"
+}
+
+func (NoopDialect) QuerySelectMigrateIds(string, string) string {
+	return noopPrefix + "SELECT IDS"
+}
+
+func (NoopDialect) QueryInsertMigrate(string, string) string {
+	return noopPrefix + "INSERT"
+}
+
+// FakeRepository is an in-memory migration table, for unit tests of code
+// wrapping the executor without a database. The executor runs on its DB with
+// NoopDialect:
+//
+//	fake := migratetest.NewFakeRepository()
+//	n, err := ex.Exec(fake.DB(), migratetest.NoopDialect{}, source, migrate.Up)
+//
+// The statements of the migrations aren't run but recorded, and fail with the
+// errors given to Fail. A rolled back transaction restores the records and the
+// statements; transactions aren't isolated from each other.
+type FakeRepository struct {
+	mu         sync.Mutex
+	records    map[string]time.Time
+	statements []string
+	failures   map[string]error
+	db         *sql.DB
+}
+
+// NewFakeRepository returns an empty FakeRepository.
+func NewFakeRepository() *FakeRepository {
+	f := &FakeRepository{
+		records:  make(map[string]time.Time),
+		failures: make(map[string]error),
+	}
+
+	f.db = sql.OpenDB(fakeConnector{f})
+
+	return f
+}
+
+// DB returns the database of the fake.
+func (f *FakeRepository) DB() *sql.DB {
+	return f.db
+}
+
+// Seed records the migrations as applied.
+func (f *FakeRepository) Seed(records ...migrate.MigrationRecord) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, record := range records {
+		f.records[record.Id] = record.AppliedAt
+	}
+}
+
+// Records returns the applied migrations, ordered by id.
+func (f *FakeRepository) Records() []migrate.MigrationRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records := make([]migrate.MigrationRecord, 0, len(f.records))
+	for id, appliedAt := range f.records {
+		records = append(records, migrate.MigrationRecord{Id: id, AppliedAt: appliedAt})
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Id < records[j].Id
+	})
+
+	return records
+}
+
+// Statements returns the statements of the migrations run so far, in order.
+func (f *FakeRepository) Statements() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.statements...)
+}
+
+// Fail makes the statement fail with err, compared without the surrounding
+// white space.
+func (f *FakeRepository) Fail(statement string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures[strings.TrimSpace(statement)] = err
+}
+
+// exec runs a command of NoopDialect or records a statement.
+func (f *FakeRepository) exec(query string, args []driver.NamedValue) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	command, ok := strings.CutPrefix(query, noopPrefix)
+	if !ok {
+		statement := strings.TrimSpace(query)
+		if err := f.failures[statement]; err != nil {
+			return err
+		}
+
+		f.statements = append(f.statements, statement)
+
+		return nil
+	}
+
+	switch command {
+	case "CREATE SCHEMA", "CREATE TABLE":
+		return nil
+	case "INSERT":
+		if len(args) < 2 {
+			return errors.New("migratetest: insert takes the id and the time")
+		}
+
+		id, _ := args[0].Value.(string)
+		appliedAt, _ := args[1].Value.(time.Time)
+
+		if _, ok := f.records[id]; ok {
+			return errors.New("migratetest: migration " + id + " is already recorded")
+		}
+
+		f.records[id] = appliedAt
+
+		return nil
+	case "DELETE":
+		if len(args) < 1 {
+			return errors.New("migratetest: delete takes the id")
+		}
+
+		id, _ := args[0].Value.(string)
+		delete(f.records, id)
+
+		return nil
+	default:
+		return errors.New("migratetest: unknown command " + command)
+	}
+}
+
+// query runs a select command of NoopDialect.
+func (f *FakeRepository) query(query string) (driver.Rows, error) {
+	command, ok := strings.CutPrefix(query, noopPrefix)
+	if !ok {
+		return nil, errors.New("migratetest: cannot query " + query)
+	}
+
+	records := f.Records()
+
+	switch command {
+	case "SELECT":
+		rows := &fakeRows{columns: []string{"id", "applied_at"}}
+		for _, record := range records {
+			rows.values = append(rows.values, []driver.Value{record.Id, record.AppliedAt})
+		}
+
+		return rows, nil
+	case "SELECT IDS":
+		rows := &fakeRows{columns: []string{"id"}}
+		for _, record := range records {
+			rows.values = append(rows.values, []driver.Value{record.Id})
+		}
+
+		return rows, nil
+	default:
+		return nil, errors.New("migratetest: unknown command " + command)
+	}
+}
+
+// snapshot returns a function restoring the records and the statements.
+func (f *FakeRepository) snapshot() func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records := make(map[string]time.Time, len(f.records))
+	for id, appliedAt := range f.records {
+		records[id] = appliedAt
+	}
+
+	statements := len(f.statements)
+
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.records = records
+		f.statements = f.statements[:statements]
+	}
+}
+
+type fakeConnector struct {
+	fake *FakeRepository
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{fake: c.fake}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("migratetest: open the fake through FakeRepository.DB")
+}
+
+type fakeConn struct {
+	fake *FakeRepository
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{restore: c.fake.snapshot()}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.fake.exec(query, args); err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	return c.fake.query(query)
+}
+
+type fakeTx struct {
+	restore func()
+}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.restore()
+
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+
+	return values
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package migratetest
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+type FakeSuite struct {
+	fake *FakeRepository
+	ex   *migrate.MigrationExecutor
+}
+
+var _ = Suite(&FakeSuite{})
+
+func (s *FakeSuite) SetUpTest(*C) {
+	s.fake = NewFakeRepository()
+	s.ex = migrate.NewMigrationExecutor()
+	s.ex.CreateTable = true
+	s.ex.Logger = discardLogger{}
+}
+
+func (s *FakeSuite) TearDownTest(*C) {
+	_ = s.fake.DB().Close()
+}
+
+func (s *FakeSuite) TestExec(c *C) {
+	source := migrate.NewMemoryMigrationSource(migrations)
+
+	n, err := s.ex.Exec(s.fake.DB(), NoopDialect{}, source, migrate.Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	records := s.fake.Records()
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[0].Id, Equals, "1_users.sql")
+	c.Assert(records[1].Id, Equals, "2_orders.sql")
+	c.Assert(s.fake.Statements(), DeepEquals, []string{"CREATE TABLE users (id int)", "CREATE TABLE orders (id int)"})
+
+	pending, err := s.ex.HasPending(context.Background(), s.fake.DB(), NoopDialect{}, source)
+	c.Assert(err, IsNil)
+	c.Assert(pending, Equals, false)
+
+	n, err = s.ex.ExecMax(s.fake.DB(), NoopDialect{}, source, migrate.Down, 1)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+	c.Assert(s.fake.Records(), HasLen, 1)
+}
+
+func (s *FakeSuite) TestFail(c *C) {
+	failure := errors.New("relation orders already exists")
+	s.fake.Fail("CREATE TABLE orders (id int)", failure)
+
+	n, err := s.ex.Exec(s.fake.DB(), NoopDialect{}, migrate.NewMemoryMigrationSource(migrations), migrate.Up)
+	c.Assert(err, ErrorMatches, ".*relation orders already exists.*")
+	c.Assert(n, Equals, 1)
+
+	records := s.fake.Records()
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].Id, Equals, "1_users.sql")
+	c.Assert(s.fake.Statements(), DeepEquals, []string{"CREATE TABLE users (id int)"})
+}
+
+func (s *FakeSuite) TestSeed(c *C) {
+	appliedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.fake.Seed(migrate.MigrationRecord{Id: "1_users.sql", AppliedAt: appliedAt})
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.fake.DB(), NoopDialect{})
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].AppliedAt.Equal(appliedAt), Equals, true)
+
+	n, err := s.ex.Exec(s.fake.DB(), NoopDialect{}, migrate.NewMemoryMigrationSource(migrations), migrate.Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+	c.Assert(s.fake.Statements(), DeepEquals, []string{"CREATE TABLE orders (id int)"})
+}
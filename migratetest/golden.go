@@ -0,0 +1,155 @@
+package migratetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+// UpdateGoldenEnv names the environment variable which makes AssertGolden
+// and AssertPlan write the golden files instead of comparing them, e.g.
+//
+//	MIGRATETEST_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "MIGRATETEST_UPDATE_GOLDEN"
+
+// diffContext is the number of unchanged lines shown around a difference.
+const diffContext = 3
+
+// RenderPlan renders the planned migrations in order, each as a comment with
+// its id and direction followed by its statements, e.g.
+//
+//	-- 1_users.sql up
+//	CREATE TABLE users (id int);
+//
+// The rendering only depends on the plan, so it can be compared to a golden file.
+func RenderPlan(plan *migrate.MigrationPlan) (string, error) {
+	var b strings.Builder
+
+	for i, migration := range plan.Migrations {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "-- %s %s\n", migration.Id, plan.Direction)
+
+		err := migration.Statements(func(stmt string, _ sqlparse.LineRange) error {
+			b.WriteString(strings.TrimSpace(stmt))
+			b.WriteString("\n")
+
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return b.String(), nil
+}
+
+// AssertPlan plans at most max migrations of the source in the direction, 0
+// for all of them, and compares the rendered plan to the golden file.
+func (db *DB) AssertPlan(golden string, dir migrate.MigrationDirection, max int) {
+	db.t.Helper()
+
+	plan, err := db.Executor.Plan(context.Background(), db.DB, db.Dialect, db.Source, dir, max)
+	if err != nil {
+		db.t.Fatalf("migratetest: cannot plan the migrations %s: %v", dir, err)
+	}
+
+	rendered, err := RenderPlan(plan)
+	if err != nil {
+		db.t.Fatalf("migratetest: cannot render the plan: %v", err)
+	}
+
+	AssertGolden(db.t, golden, rendered)
+}
+
+// AssertGolden compares got to the content of the golden file, and reports
+// the differing lines. With UpdateGoldenEnv set, it writes got to the file.
+func AssertGolden(t testing.TB, golden, got string) {
+	t.Helper()
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatalf("migratetest: cannot write %s: %v", golden, err)
+		}
+
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatalf("migratetest: cannot write %s: %v", golden, err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("migratetest: golden file %s does not exist, set %s=1 to write it", golden, UpdateGoldenEnv)
+	}
+
+	if err != nil {
+		t.Fatalf("migratetest: cannot read %s: %v", golden, err)
+	}
+
+	if string(want) != got {
+		t.Errorf("migratetest: %s differs, set %s=1 to update it:\n%s", golden, UpdateGoldenEnv, diff(string(want), got))
+	}
+}
+
+// diff shows the lines between the common head and tail of want and got,
+// removed lines prefixed by - and added ones by +, with a few lines of context.
+func diff(want, got string) string {
+	wantLines := strings.SplitAfter(want, "\n")
+	gotLines := strings.SplitAfter(got, "\n")
+
+	head := 0
+	for head < len(wantLines) && head < len(gotLines) && wantLines[head] == gotLines[head] {
+		head++
+	}
+
+	tail := 0
+	for tail < len(wantLines)-head && tail < len(gotLines)-head &&
+		wantLines[len(wantLines)-1-tail] == gotLines[len(gotLines)-1-tail] {
+		tail++
+	}
+
+	var b strings.Builder
+
+	from := max(head-diffContext, 0)
+	fmt.Fprintf(&b, "@@ line %d @@\n", from+1)
+
+	for _, line := range wantLines[from:head] {
+		writeLine(&b, "  ", line)
+	}
+
+	for _, line := range wantLines[head : len(wantLines)-tail] {
+		writeLine(&b, "- ", line)
+	}
+
+	for _, line := range gotLines[head : len(gotLines)-tail] {
+		writeLine(&b, "+ ", line)
+	}
+
+	after := wantLines[len(wantLines)-tail:]
+	for _, line := range after[:min(diffContext, len(after))] {
+		writeLine(&b, "  ", line)
+	}
+
+	return b.String()
+}
+
+func writeLine(b *strings.Builder, prefix, line string) {
+	if line == "" {
+		return
+	}
+
+	b.WriteString(prefix)
+	b.WriteString(strings.TrimSuffix(line, "\n"))
+	b.WriteString("\n")
+}
@@ -0,0 +1,52 @@
+package migratetest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func (*MigrateTestSuite) TestAssertPlan(c *C) {
+	t := &recorder{}
+	defer t.close()
+
+	db := Open(t, migrate.NewMemoryMigrationSource(migrations))
+
+	db.AssertPlan("testdata/plan_up.golden", migrate.Up, 0)
+	c.Assert(t.errors, HasLen, 0)
+
+	db.AssertPlan("testdata/plan_up.golden", migrate.Up, 1)
+	c.Assert(t.errors, HasLen, 1)
+	c.Assert(strings.HasSuffix(t.errors[0], `
+@@ line 1 @@
+  -- 1_users.sql up
+  CREATE TABLE users (id int)
+- 
+- -- 2_orders.sql up
+- CREATE TABLE orders (id int)
+`), Equals, true, Commentf("%s", t.errors[0]))
+}
+
+func (*MigrateTestSuite) TestUpdateGolden(c *C) {
+	t := &recorder{}
+	golden := filepath.Join(c.MkDir(), "plan", "down.golden")
+
+	c.Assert(os.Setenv(UpdateGoldenEnv, "1"), IsNil)
+	AssertGolden(t, golden, "-- 1_users.sql down\n")
+	c.Assert(os.Unsetenv(UpdateGoldenEnv), IsNil)
+
+	AssertGolden(t, golden, "-- 1_users.sql down\n")
+	c.Assert(t.errors, HasLen, 0)
+}
+
+func (*MigrateTestSuite) TestDiff(c *C) {
+	want := "a\nb\nc\nd\ne\nf\ng\n"
+	got := "a\nb\nc\nd\nE\nf\ng\n"
+
+	c.Assert(diff(want, got), Equals, "@@ line 2 @@\n  b\n  c\n  d\n- e\n+ E\n  f\n  g\n")
+}
@@ -0,0 +1,181 @@
+// Package migratetest tests the migrations of an application against an
+// in-memory SQLite database.
+//
+//	func TestMigrations(t *testing.T) {
+//		db := migratetest.New(t, migrate.NewEmbedFileSystemMigrationSource(migrations, "migrations"))
+//
+//		db.AssertPending()
+//		db.AssertTableExists("users")
+//
+//		db.Exec(migrate.Down, 1)
+//		db.AssertPending("3_add_email.sql")
+//	}
+//
+// The helpers fail the test on errors, so the tests need no error handling.
+package migratetest
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// DB is an in-memory SQLite database with the migrations of a source.
+type DB struct {
+	*sql.DB
+	// Executor applies the migrations. It creates the migration table and
+	// logs through the test log.
+	Executor *migrate.MigrationExecutor
+	Dialect  dialect.Dialect
+	Source   migrate.MigrationSource
+
+	t testing.TB
+}
+
+// Open returns an empty in-memory database for the migrations of the source.
+// The database is closed when the test ends.
+func Open(t testing.TB, source migrate.MigrationSource) *DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("migratetest: cannot open sqlite: %v", err)
+	}
+
+	// Every connection to :memory: opens a database of its own.
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	ex := migrate.NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = testLogger{t}
+
+	return &DB{
+		DB:       db,
+		Executor: ex,
+		Dialect:  dialect.NewSqliteDialect(),
+		Source:   source,
+		t:        t,
+	}
+}
+
+// New returns an in-memory database with all the migrations of the source
+// applied.
+func New(t testing.TB, source migrate.MigrationSource) *DB {
+	t.Helper()
+
+	db := Open(t, source)
+	db.Exec(migrate.Up, 0)
+
+	return db
+}
+
+// Exec applies at most max migrations of the source in the direction, all of
+// them when max is 0, and returns their number.
+func (db *DB) Exec(dir migrate.MigrationDirection, max int) int {
+	db.t.Helper()
+
+	n, err := db.Executor.ExecMax(db.DB, db.Dialect, db.Source, dir, max)
+	if err != nil {
+		db.t.Fatalf("migratetest: cannot apply the migrations %s: %v", dir, err)
+	}
+
+	return n
+}
+
+// AssertApplied checks that the migrations are applied, or all the
+// migrations of the source when no id is given.
+func (db *DB) AssertApplied(ids ...string) {
+	db.t.Helper()
+
+	if len(ids) == 0 {
+		if pending := db.report().Pending; len(pending) > 0 {
+			db.t.Errorf("migratetest: migrations %v are pending", pending)
+		}
+
+		return
+	}
+
+	for _, id := range ids {
+		if !db.recorded(id) {
+			db.t.Errorf("migratetest: migration %s is not applied", id)
+		}
+	}
+}
+
+// AssertPending checks that exactly the migrations are pending, in order, or
+// none when no id is given.
+func (db *DB) AssertPending(ids ...string) {
+	db.t.Helper()
+
+	pending := db.report().Pending
+	if !slices.Equal(pending, ids) && (len(pending) > 0 || len(ids) > 0) {
+		db.t.Errorf("migratetest: pending migrations are %v, expected %v", pending, ids)
+	}
+}
+
+// AssertTableExists checks that the migrations created the table or view.
+func (db *DB) AssertTableExists(name string) {
+	db.t.Helper()
+
+	var count int
+
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type IN ('table', 'view') AND name = ?`, name).Scan(&count)
+	if err != nil {
+		db.t.Fatalf("migratetest: cannot read the tables: %v", err)
+	}
+
+	if count == 0 {
+		db.t.Errorf("migratetest: table %s does not exist", name)
+	}
+}
+
+func (db *DB) report() migrate.Report {
+	db.t.Helper()
+
+	report, err := db.Executor.Readiness(context.Background(), db.DB, db.Dialect, db.Source)
+	if err != nil {
+		db.t.Fatalf("migratetest: cannot read the migrations: %v", err)
+	}
+
+	return report
+}
+
+func (db *DB) recorded(id string) bool {
+	db.t.Helper()
+
+	records, err := db.Executor.GetMigrationRecords(context.Background(), db.DB, db.Dialect)
+	if err != nil {
+		db.t.Fatalf("migratetest: cannot read the applied migrations: %v", err)
+	}
+
+	return slices.ContainsFunc(records, func(record migrate.MigrationRecord) bool {
+		return record.Id == id
+	})
+}
+
+// testLogger logs the executor through the log of the test.
+type testLogger struct {
+	t testing.TB
+}
+
+func (l testLogger) Tracef(format string, v ...any) {
+	l.t.Logf(format, v...)
+}
+
+func (l testLogger) Infof(format string, v ...any) {
+	l.t.Logf(format, v...)
+}
+
+func (l testLogger) Errorf(format string, v ...any) {
+	l.t.Logf(format, v...)
+}
@@ -0,0 +1,107 @@
+package migratetest
+
+import (
+	"fmt"
+	"testing"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type MigrateTestSuite struct{}
+
+var _ = Suite(&MigrateTestSuite{})
+
+var migrations = []*migrate.Migration{
+	{
+		Id:   "1_users.sql",
+		Up:   []string{"CREATE TABLE users (id int)"},
+		Down: []string{"DROP TABLE users"},
+	},
+	{
+		Id:   "2_orders.sql",
+		Up:   []string{"CREATE TABLE orders (id int)"},
+		Down: []string{"DROP TABLE orders"},
+	},
+}
+
+// recorder is a testing.TB recording the failures of the assertions.
+type recorder struct {
+	testing.TB
+	errors  []string
+	cleanup []func()
+}
+
+func (*recorder) Helper() {}
+
+func (*recorder) Logf(string, ...any) {}
+
+func (r *recorder) Errorf(format string, v ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, v...))
+}
+
+func (r *recorder) Fatalf(format string, v ...any) {
+	panic(fmt.Sprintf(format, v...))
+}
+
+func (r *recorder) Cleanup(fn func()) {
+	r.cleanup = append(r.cleanup, fn)
+}
+
+func (r *recorder) close() {
+	for _, fn := range r.cleanup {
+		fn()
+	}
+}
+
+func (*MigrateTestSuite) TestNew(c *C) {
+	t := &recorder{}
+	defer t.close()
+
+	db := New(t, migrate.NewMemoryMigrationSource(migrations))
+
+	db.AssertApplied()
+	db.AssertApplied("1_users.sql", "2_orders.sql")
+	db.AssertPending()
+	db.AssertTableExists("users")
+	db.AssertTableExists("orders")
+	c.Assert(t.errors, HasLen, 0)
+
+	c.Assert(db.Exec(migrate.Down, 1), Equals, 1)
+
+	db.AssertPending("2_orders.sql")
+	db.AssertApplied("1_users.sql")
+	c.Assert(t.errors, HasLen, 0)
+
+	db.AssertApplied()
+	db.AssertApplied("2_orders.sql")
+	db.AssertPending()
+	db.AssertTableExists("orders")
+	c.Assert(t.errors, DeepEquals, []string{
+		"migratetest: migrations [2_orders.sql] are pending",
+		"migratetest: migration 2_orders.sql is not applied",
+		"migratetest: pending migrations are [2_orders.sql], expected []",
+		"migratetest: table orders does not exist",
+	})
+}
+
+func (*MigrateTestSuite) TestOpen(c *C) {
+	t := &recorder{}
+	defer t.close()
+
+	db := Open(t, migrate.NewMemoryMigrationSource(migrations))
+
+	db.AssertPending("1_users.sql", "2_orders.sql")
+	c.Assert(t.errors, HasLen, 0)
+
+	c.Assert(db.Exec(migrate.Up, 1), Equals, 1)
+
+	db.AssertApplied("1_users.sql")
+	db.AssertPending("2_orders.sql")
+	db.AssertTableExists("users")
+	c.Assert(t.errors, HasLen, 0)
+}
@@ -0,0 +1,173 @@
+package migratetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// ErrSnapshotUnsupported is returned by VerifyReversible for dialects whose
+// schema it can't read.
+var ErrSnapshotUnsupported = errors.New("migratetest: cannot snapshot the schema of the dialect")
+
+// VerifyReversible applies the migrations of the source one at a time on a
+// scratch database, and rolls each back right after applying it to check that
+// the schema returns to its state before the migration, catching broken Down
+// scripts. The migration is then applied again before the next one.
+//
+// The schema is read from sqlite_master for SQLite and from
+// information_schema for the other databases, without the migration table.
+func VerifyReversible(source migrate.MigrationSource, db *sql.DB, d dialect.Dialect) error {
+	ctx := context.Background()
+
+	ex := migrate.NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = discardLogger{}
+
+	snapshot, err := snapshotter(d, ex.TableName)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		before, err := snapshot(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		for _, dir := range []migrate.MigrationDirection{migrate.Up, migrate.Down} {
+			if _, err := ex.ExecMaxContext(ctx, db, d, source, dir, 1); err != nil {
+				return fmt.Errorf("migratetest: cannot apply migration %s %s: %w", migration.Id, dir, err)
+			}
+		}
+
+		after, err := snapshot(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		if before != after {
+			return fmt.Errorf("migratetest: migration %s is not reversible, the schema differs after down:\n%s",
+				migration.Id, diff(before, after))
+		}
+
+		if _, err := ex.ExecMaxContext(ctx, db, d, source, migrate.Up, 1); err != nil {
+			return fmt.Errorf("migratetest: cannot apply migration %s up again: %w", migration.Id, err)
+		}
+	}
+
+	return nil
+}
+
+// AssertReversible checks the migrations of the source with VerifyReversible,
+// on a database where none is applied yet.
+func (db *DB) AssertReversible() {
+	db.t.Helper()
+
+	if err := VerifyReversible(db.Source, db.DB, db.Dialect); err != nil {
+		db.t.Errorf("%v", err)
+	}
+}
+
+// snapshotFunc renders the schema of the database as lines of text.
+type snapshotFunc func(ctx context.Context, db *sql.DB) (string, error)
+
+// snapshotter returns how to snapshot the schema of the dialect, leaving out
+// the migration table, its index and its lock table.
+func snapshotter(d dialect.Dialect, table string) (snapshotFunc, error) {
+	switch d.(type) {
+	case *dialect.SqliteDialect:
+		return func(ctx context.Context, db *sql.DB) (string, error) {
+			return snapshotQuery(ctx, db,
+				`SELECT type, name, tbl_name, COALESCE(sql, '') FROM sqlite_master
+				WHERE name NOT LIKE 'sqlite_%' AND tbl_name NOT IN (?, ?) ORDER BY type, name`,
+				table, table+"_lock")
+		}, nil
+	case *dialect.PostgresDialect, *dialect.MySQLDialect, *dialect.SqlServerDialect, *dialect.SnowflakeDialect:
+		return func(ctx context.Context, db *sql.DB) (string, error) {
+			tables, err := snapshotQuery(ctx, db, fmt.Sprintf(
+				`SELECT table_schema, table_name, table_type FROM information_schema.tables
+				WHERE %s ORDER BY table_schema, table_name`, userTables(table)))
+			if err != nil {
+				return "", err
+			}
+
+			columns, err := snapshotQuery(ctx, db, fmt.Sprintf(
+				`SELECT table_schema, table_name, column_name, data_type, is_nullable, column_default
+				FROM information_schema.columns
+				WHERE %s ORDER BY table_schema, table_name, ordinal_position`, userTables(table)))
+			if err != nil {
+				return "", err
+			}
+
+			return tables + columns, nil
+		}, nil
+	default:
+		return nil, ErrSnapshotUnsupported
+	}
+}
+
+// userTables is the condition on information_schema leaving out the system
+// schemas and the tables of the executor.
+func userTables(table string) string {
+	return fmt.Sprintf(`table_schema NOT IN ('information_schema', 'pg_catalog', 'mysql', 'performance_schema', 'sys')
+		AND table_name NOT IN ('%s', '%s_lock')`, table, table)
+}
+
+// snapshotQuery renders the rows of the query as lines of values separated by |.
+func snapshotQuery(ctx context.Context, db *sql.DB, query string, args ...any) (string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", fmt.Errorf("migratetest: cannot snapshot the schema: %w", err)
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var b strings.Builder
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+
+		for i, value := range values {
+			if i > 0 {
+				b.WriteString("|")
+			}
+
+			b.WriteString(value.String)
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String(), rows.Err()
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Tracef(string, ...any) {}
+func (discardLogger) Infof(string, ...any)  {}
+func (discardLogger) Errorf(string, ...any) {}
@@ -0,0 +1,45 @@
+package migratetest
+
+import (
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func (*MigrateTestSuite) TestVerifyReversible(c *C) {
+	t := &recorder{}
+	defer t.close()
+
+	db := Open(t, migrate.NewMemoryMigrationSource(migrations))
+	c.Assert(VerifyReversible(db.Source, db.DB, db.Dialect), IsNil)
+
+	db.AssertApplied()
+	db.AssertTableExists("users")
+	db.AssertTableExists("orders")
+	c.Assert(t.errors, HasLen, 0)
+}
+
+func (*MigrateTestSuite) TestAssertReversible(c *C) {
+	t := &recorder{}
+	defer t.close()
+
+	broken := []*migrate.Migration{
+		migrations[0],
+		{
+			Id:   "2_orders.sql",
+			Up:   []string{"CREATE TABLE orders (id int)", "CREATE INDEX orders_id ON orders (id)"},
+			Down: []string{"DROP INDEX orders_id"},
+		},
+	}
+
+	db := Open(t, migrate.NewMemoryMigrationSource(broken))
+	db.AssertReversible()
+
+	c.Assert(t.errors, HasLen, 1)
+	c.Assert(t.errors[0], Equals, `migratetest: migration 2_orders.sql is not reversible, the schema differs after down:
+@@ line 1 @@
++ table|orders|orders|CREATE TABLE orders (id int)
+  table|users|users|CREATE TABLE users (id int)
+`)
+}
@@ -1,12 +1,15 @@
 package migrate
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"regexp"
 	"strconv"
-)
+	"strings"
+	"time"
 
-var numberPrefixRegex = regexp.MustCompile(`^(\d+).*$`)
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
 
 type Migration struct {
 	Id                     string
@@ -14,15 +17,82 @@ type Migration struct {
 	Down                   []string
 	DisableTransactionUp   bool
 	DisableTransactionDown bool
+
+	// StatementTimeoutUp and StatementTimeoutDown bound the time of each
+	// statement of the direction, see sqlparse.ParsedMigration. Zero doesn't
+	// bound it.
+	StatementTimeoutUp   time.Duration
+	StatementTimeoutDown time.Duration
+
+	// DependsOn holds the ids of the migrations this one needs applied
+	// first, see MigrationExecutor.ParallelMigrations. Migrations without
+	// it need all the migrations before them.
+	DependsOn []string
+
+	// UpLines and DownLines are the lines of the statements in the migration
+	// file, when the migration was parsed from one.
+	UpLines   []sqlparse.LineRange
+	DownLines []sqlparse.LineRange
+
+	// Stream, when set, provides the statements instead of Up and Down, read
+	// while the migration runs, e.g. for a generated migration too large to
+	// hold in memory.
+	Stream StatementStream
+
+	// checksum caches the checksum of the migration, read while parsing a
+	// streamed migration or computed from the statements checksumUp and
+	// checksumDown, so that a copy with other statements isn't taken for it.
+	checksum     string
+	checksumUp   []string
+	checksumDown []string
+
+	// destructiveUp tells whether an Up statement of a streamed migration
+	// drops or truncates something, found while parsing it when scanned is
+	// set, so that the stream isn't read again before the migration runs.
+	destructiveUp bool
+	scanned       bool
+}
+
+// StatementStream calls fn with each statement of the migration in the
+// direction, in order, with its lines in the migration file if known. It
+// stops at the first error of fn and returns it.
+type StatementStream func(dir MigrationDirection, fn func(stmt string, lines sqlparse.LineRange) error) error
+
+// statements calls fn with each statement of the direction, from Stream or
+// from Up and Down.
+func (m *Migration) statements(dir MigrationDirection, fn func(stmt string, lines sqlparse.LineRange) error) error {
+	if m.Stream != nil {
+		return m.Stream(dir, fn)
+	}
+
+	stmts, lines := m.Up, m.UpLines
+	if dir == Down {
+		stmts, lines = m.Down, m.DownLines
+	}
+
+	for i, stmt := range stmts {
+		var r sqlparse.LineRange
+		if i < len(lines) {
+			r = lines[i]
+		}
+
+		if err := fn(stmt, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (m *Migration) Less(other *Migration) bool {
+	numeric, otherNumeric := m.isNumeric(), other.isNumeric()
+
 	switch {
-	case m.isNumeric() && other.isNumeric() && m.VersionInt() != other.VersionInt():
+	case numeric && otherNumeric && m.VersionInt() != other.VersionInt():
 		return m.VersionInt() < other.VersionInt()
-	case m.isNumeric() && !other.isNumeric():
+	case numeric && !otherNumeric:
 		return true
-	case !m.isNumeric() && other.isNumeric():
+	case !numeric && otherNumeric:
 		return false
 	default:
 		return m.Id < other.Id
@@ -30,15 +100,40 @@ func (m *Migration) Less(other *Migration) bool {
 }
 
 func (m *Migration) isNumeric() bool {
-	return len(m.NumberPrefixMatches()) > 0
+	_, ok := m.versionPrefix()
+	return ok
+}
+
+// versionPrefix returns the leading digits of the id. It matches ^(\d+).*$
+// without a regular expression, as sorting thousands of migrations calls it
+// a lot.
+func (m *Migration) versionPrefix() (string, bool) {
+	i := 0
+	for i < len(m.Id) && m.Id[i] >= '0' && m.Id[i] <= '9' {
+		i++
+	}
+
+	// The dot of the expression doesn't match line breaks.
+	if i == 0 || strings.IndexByte(m.Id[i:], '\n') >= 0 {
+		return "", false
+	}
+
+	return m.Id[:i], true
 }
 
+// NumberPrefixMatches returns the id and its leading digits, or nil when the
+// id doesn't start with a digit.
 func (m *Migration) NumberPrefixMatches() []string {
-	return numberPrefixRegex.FindStringSubmatch(m.Id)
+	version, ok := m.versionPrefix()
+	if !ok {
+		return nil
+	}
+
+	return []string{m.Id, version}
 }
 
 func (m *Migration) VersionInt() int64 {
-	v := m.NumberPrefixMatches()[1]
+	v, _ := m.versionPrefix()
 
 	value, err := strconv.ParseInt(v, 10, 64)
 	if err != nil {
@@ -48,8 +143,107 @@ func (m *Migration) VersionInt() int64 {
 	return value
 }
 
+// Checksum returns the hex encoded SHA-256 of the Up and Down statements of
+// the migration. It's empty when the statements of a Stream can't be read.
+// It's computed once for the Up and Down slices, so their statements must not
+// be changed in place afterwards.
+func (m *Migration) Checksum() string {
+	if m.checksum != "" && (m.Stream != nil || sameSlice(m.checksumUp, m.Up) && sameSlice(m.checksumDown, m.Down)) {
+		return m.checksum
+	}
+
+	sum, err := m.computeChecksum()
+	if err != nil {
+		return ""
+	}
+
+	if m.Stream == nil {
+		m.checksum, m.checksumUp, m.checksumDown = sum, m.Up, m.Down
+	}
+
+	return sum
+}
+
+// sameSlice tells whether a and b are the same slice of statements.
+func sameSlice(a, b []string) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+func (m *Migration) computeChecksum() (string, error) {
+	h := sha256.New()
+
+	write := func(stmt string, _ sqlparse.LineRange) error {
+		h.Write([]byte(stmt))
+		h.Write([]byte{0})
+
+		return nil
+	}
+
+	if err := m.statements(Up, write); err != nil {
+		return "", err
+	}
+
+	h.Write([]byte{1})
+
+	if err := m.statements(Down, write); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 type PlannedMigration struct {
 	*Migration
 	DisableTransaction bool
-	Queries            []string
+	// StatementTimeout bounds the time of each query, whose context is
+	// canceled once it passed. Zero doesn't bound it.
+	StatementTimeout time.Duration
+	Queries          []string
+	// Lines holds the lines of the queries in the migration file, if known.
+	Lines []sqlparse.LineRange
+
+	// direction selects the statements of a streamed migration.
+	direction MigrationDirection
+}
+
+// Statements calls fn with each query of the migration and its lines in the
+// migration file, if known. The queries of a migration with a Stream are
+// read while iterating, Queries is empty then.
+func (m *PlannedMigration) Statements(fn func(stmt string, lines sqlparse.LineRange) error) error {
+	if m.Migration != nil && m.Stream != nil && m.direction != 0 {
+		return m.Stream(m.direction, fn)
+	}
+
+	for i, stmt := range m.Queries {
+		var lines sqlparse.LineRange
+		if i < len(m.Lines) {
+			lines = m.Lines[i]
+		}
+
+		if err := fn(stmt, lines); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// statementCount returns the number of queries of the migration, -1 for a
+// streamed migration whose queries aren't read yet.
+func (m *PlannedMigration) statementCount() int {
+	if m.Migration != nil && m.Stream != nil && m.direction != 0 {
+		return -1
+	}
+
+	return len(m.Queries)
+}
+
+// statementName describes the i-th query for the logs, by its lines in the
+// migration file when they are known.
+func statementName(i int, lines sqlparse.LineRange) string {
+	if lines.First > 0 {
+		return lines.String()
+	}
+
+	return fmt.Sprintf("statement %d", i+1)
 }
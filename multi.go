@@ -0,0 +1,185 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// Target is one of the databases ExecAll applies the migration source to.
+type Target struct {
+	// Name identifies the target in results and errors, e.g. a shard name.
+	Name    string
+	DB      *sql.DB
+	Dialect dialect.Dialect
+}
+
+// TargetResult is the outcome of applying the migration source to a single Target.
+type TargetResult struct {
+	Target  Target
+	Applied int
+	Err     error
+	// Skipped is set when the target was never migrated because another target
+	// failed and the run stopped on the first error.
+	Skipped bool
+}
+
+type execAllOptions struct {
+	concurrency     int
+	continueOnError bool
+	max             int
+}
+
+// ExecAllOption configures ExecAll and ExecTenants.
+type ExecAllOption func(*execAllOptions)
+
+func newExecAllOptions(opts []ExecAllOption) execAllOptions {
+	options := execAllOptions{concurrency: 1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// WithConcurrency sets the number of targets (or tenants) migrated at the same time. Defaults to 1.
+func WithConcurrency(n int) ExecAllOption {
+	return func(o *execAllOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithContinueOnError keeps migrating the remaining targets after a failure.
+// By default ExecAll and ExecTenants stop starting new targets after the first error.
+func WithContinueOnError() ExecAllOption {
+	return func(o *execAllOptions) {
+		o.continueOnError = true
+	}
+}
+
+// WithMax applies at most max migrations to each target or tenant. Pass 0 for no limit.
+func WithMax(max int) ExecAllOption {
+	return func(o *execAllOptions) {
+		o.max = max
+	}
+}
+
+// ExecAll applies the same migration source to many databases concurrently.
+// The result holds one entry per target, in the order of targets. The returned
+// error joins the errors of all failed targets.
+func (ex *MigrationExecutor) ExecAll(
+	ctx context.Context,
+	targets []Target,
+	source MigrationSource,
+	dir MigrationDirection,
+	opts ...ExecAllOption,
+) ([]TargetResult, error) {
+	options := newExecAllOptions(opts)
+
+	// Read the source once instead of once per target.
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	source = NewMemoryMigrationSource(migrations)
+
+	results := make([]TargetResult, len(targets))
+
+	started := runParallel(ctx, len(targets), options, func(ctx context.Context, i int) error {
+		applied, err := ex.ExecMaxContext(ctx, targets[i].DB, targets[i].Dialect, source, dir, options.max)
+		results[i] = TargetResult{Target: targets[i], Applied: applied, Err: err}
+
+		return err
+	})
+
+	for i := range results {
+		if !started[i] {
+			results[i] = TargetResult{Target: targets[i], Skipped: true}
+		}
+	}
+
+	var errs []error
+
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("target %s: %w", result.Target.Name, result.Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// runParallel calls fn for every index in [0, n), at most options.concurrency
+// at a time. Unless options.continueOnError is set, no new calls are started
+// after the first error, and the running ones are finished. It reports which
+// indexes fn was called for.
+func runParallel(ctx context.Context, n int, options execAllOptions, fn func(ctx context.Context, i int) error) []bool {
+	started := make([]bool, n)
+	jobs := make(chan int)
+
+	// failed stops the dispatch without canceling the running calls.
+	failed := make(chan struct{})
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+	)
+
+	for w := 0; w < options.concurrency; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				// The dispatch may have handed out i as the first error
+				// stopped it.
+				select {
+				case <-failed:
+					continue
+				default:
+				}
+
+				started[i] = true
+
+				err := fn(ctx, i)
+				if err != nil && !options.continueOnError {
+					once.Do(func() { close(failed) })
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i := 0; i < n; i++ {
+		select {
+		case <-failed:
+			break dispatch
+		default:
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case jobs <- i:
+		case <-failed:
+			break dispatch
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return started
+}
@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+type ExecAllSuite struct {
+	dbs []*sql.DB
+}
+
+var _ = Suite(&ExecAllSuite{})
+
+func (s *ExecAllSuite) SetUpTest(c *C) {
+	dir := c.MkDir()
+	s.dbs = nil
+
+	for _, name := range []string{"a.db", "b.db", "c.db"} {
+		db, err := sql.Open("sqlite3", filepath.Join(dir, name))
+		c.Assert(err, IsNil)
+
+		s.dbs = append(s.dbs, db)
+	}
+}
+
+func (s *ExecAllSuite) TearDownTest(c *C) {
+	for _, db := range s.dbs {
+		c.Assert(db.Close(), IsNil)
+	}
+}
+
+func (s *ExecAllSuite) targets() []Target {
+	targets := make([]Target, 0, len(s.dbs))
+	for i, db := range s.dbs {
+		targets = append(targets, Target{Name: string(rune('a' + i)), DB: db, Dialect: dialect.NewSqliteDialect()})
+	}
+
+	return targets
+}
+
+func (*ExecAllSuite) newExecutor() *MigrationExecutor {
+	ex := NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = nullLogger{}
+
+	return ex
+}
+
+var execAllSource = NewMemoryMigrationSource([]*Migration{
+	{Id: "1_initial", Up: []string{"CREATE TABLE people (id int)"}, Down: []string{"DROP TABLE people"}},
+	{Id: "2_record", Up: []string{"INSERT INTO people (id) VALUES (1)"}, Down: []string{"DELETE FROM people"}},
+})
+
+func (s *ExecAllSuite) TestExecAll(c *C) {
+	results, err := s.newExecutor().ExecAll(context.Background(), s.targets(), execAllSource, Up, WithConcurrency(2))
+	c.Assert(err, IsNil)
+	c.Assert(results, HasLen, 3)
+
+	for _, result := range results {
+		c.Assert(result.Err, IsNil)
+		c.Assert(result.Skipped, Equals, false)
+		c.Assert(result.Applied, Equals, 2)
+	}
+}
+
+func (s *ExecAllSuite) TestExecAllFailFast(c *C) {
+	_, err := s.dbs[0].Exec("CREATE TABLE people (id int)")
+	c.Assert(err, IsNil)
+
+	results, err := s.newExecutor().ExecAll(context.Background(), s.targets(), execAllSource, Up)
+	c.Assert(err, NotNil)
+	c.Assert(results[0].Err, NotNil)
+	c.Assert(results[1].Skipped, Equals, true)
+	c.Assert(results[2].Skipped, Equals, true)
+}
+
+func (s *ExecAllSuite) TestExecAllContinueOnError(c *C) {
+	_, err := s.dbs[0].Exec("CREATE TABLE people (id int)")
+	c.Assert(err, IsNil)
+
+	results, err := s.newExecutor().ExecAll(context.Background(), s.targets(), execAllSource, Up, WithContinueOnError())
+	c.Assert(err, ErrorMatches, "target a: .*")
+	c.Assert(results[0].Err, NotNil)
+	c.Assert(results[1].Applied, Equals, 2)
+	c.Assert(results[2].Applied, Equals, 2)
+}
+
+func (*ExecAllSuite) TestRunParallelFinishesRunning(c *C) {
+	running := make(chan struct{})
+	failed := make(chan struct{})
+
+	var canceled error
+
+	started := runParallel(context.Background(), 3, execAllOptions{concurrency: 2}, func(ctx context.Context, i int) error {
+		switch i {
+		case 0:
+			<-running
+			close(failed)
+
+			return errors.New("failed")
+		case 1:
+			close(running)
+			<-failed
+			canceled = ctx.Err()
+		}
+
+		return nil
+	})
+
+	c.Assert(canceled, IsNil)
+	c.Assert(started[:2], DeepEquals, []bool{true, true})
+}
@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	`context`
+	`fmt`
+	`strings`
+	`sync`
+
+	`github.com/kva3umoda/sql-migrate/dialect`
+)
+
+// Notice is a message the database raised while running a statement of a
+// migration, e.g. by RAISE NOTICE in postgres or as a warning in mysql.
+type Notice struct {
+	// Severity is the level given by the database, e.g. NOTICE or Warning.
+	Severity string
+	Message  string
+}
+
+// level maps the severity to the level of the log message.
+func (n Notice) level() LogLevel {
+	switch strings.ToUpper(n.Severity) {
+	case "WARNING", "ERROR":
+		return LevelWarn
+	default:
+		return LevelInfo
+	}
+}
+
+// NoticeBuffer collects the notices a driver pushes, e.g. through the notice
+// handler of lib/pq, until the executor takes them after each statement.
+//
+//	notices := &migrate.NoticeBuffer{}
+//	connector = pq.ConnectorWithNoticeHandler(connector, func(err *pq.Error) {
+//		notices.Add(migrate.Notice{Severity: err.Severity, Message: err.Message})
+//	})
+//	ex.Notices = notices
+type NoticeBuffer struct {
+	mu      sync.Mutex
+	notices []Notice
+}
+
+// Add appends a notice. It's safe to call from any goroutine.
+func (b *NoticeBuffer) Add(notice Notice) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.notices = append(b.notices, notice)
+}
+
+func (b *NoticeBuffer) take() []Notice {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	notices := b.notices
+	b.notices = nil
+
+	return notices
+}
+
+// statementNotices takes the notices raised by the named statement of the
+// migration, from Notices and, after a statement which succeeded in a
+// transaction, from the session of dialects keeping its warnings. The
+// notices are logged too.
+func (ex *MigrationExecutor) statementNotices(
+	ctx context.Context,
+	rep *MigrationRepository,
+	migration *PlannedMigration,
+	name string,
+	err error,
+) []Notice {
+	notices := ex.Notices.take()
+
+	if reader, ok := rep.dialect.(dialect.WarningReader); ok && err == nil && !migration.DisableTransaction {
+		warnings, err := rep.ReadWarnings(ctx, reader)
+		if err != nil {
+			logWith(ctx, ex.Logger, LevelWarn, fmt.Sprintf("Cannot read the warnings of migration %s: %v", migration.Id, err),
+				Field{"migration_id", migration.Id}, Field{"error", err})
+		}
+
+		notices = append(notices, warnings...)
+	}
+
+	for _, notice := range notices {
+		logWith(ctx, ex.Logger, notice.level(),
+			fmt.Sprintf("%s of migration %s at %s: %s", notice.Severity, migration.Id, name, notice.Message),
+			Field{"migration_id", migration.Id}, Field{"severity", notice.Severity})
+	}
+
+	return notices
+}
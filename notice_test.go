@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+
+	"github.com/mattn/go-sqlite3"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// notices receives the notices raised by raise_notice of the sqlite3_notice
+// driver, as lib/pq pushes the notices of postgres.
+var notices = &NoticeBuffer{}
+
+func init() {
+	sql.Register("sqlite3_notice", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("raise_notice", func(msg string) int {
+				notices.Add(Notice{Severity: "NOTICE", Message: msg})
+
+				return 0
+			}, false)
+		},
+	})
+}
+
+type NoticeSuite struct{}
+
+var _ = Suite(&NoticeSuite{})
+
+func (*NoticeSuite) TestNotices(c *C) {
+	db, err := sql.Open("sqlite3_notice", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	logger := &fieldLogger{fields: make(map[string][]Field)}
+
+	ex := NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = logger
+	ex.Notices = notices
+
+	var finished MigrationFinished
+	ex.EventSink = func(event MigrationEvent) {
+		if e, ok := event.(MigrationFinished); ok {
+			finished = e
+		}
+	}
+
+	notices.Add(Notice{Severity: "NOTICE", Message: "before the migration"})
+
+	_, err = ex.ExecContext(context.Background(), db, dialect.NewSqliteDialect(), NewMemoryMigrationSource([]*Migration{{
+		Id: "1_people.sql",
+		Up: []string{
+			"CREATE TABLE people (id int)",
+			"SELECT raise_notice('created people')",
+		},
+	}}), Up)
+	c.Assert(err, IsNil)
+
+	c.Assert(finished.Notices, DeepEquals, []Notice{{Severity: "NOTICE", Message: "created people"}})
+	c.Assert(logger.fields["NOTICE of migration 1_people.sql at statement 2: created people"], DeepEquals,
+		[]Field{{"migration_id", "1_people.sql"}, {"severity", "NOTICE"}})
+}
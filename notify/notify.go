@@ -0,0 +1,236 @@
+// Package notify posts the outcome of sql-migrate runs to a webhook, e.g. a
+// Slack incoming webhook, so schema changes show up in on-call channels.
+//
+//	ex := migrate.NewMigrationExecutor()
+//	ex.Observer = notify.NewWebhook(url, notify.WithEnvironment("production"))
+//
+// A notification is sent at the end of every run which applied a migration
+// or failed. Runs with nothing to do are not notified.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+// Format is the payload format of the webhook.
+type Format int
+
+const (
+	// FormatJSON posts a Payload.
+	FormatJSON Format = iota
+	// FormatSlack posts a message for a Slack incoming webhook.
+	FormatSlack
+)
+
+// Payload is the JSON body posted at the end of a run.
+type Payload struct {
+	Environment string             `json:"environment,omitempty"`
+	Dialect     string             `json:"dialect"`
+	Schema      string             `json:"schema,omitempty"`
+	Table       string             `json:"table"`
+	Direction   string             `json:"direction"`
+	Applied     int                `json:"applied"`
+	Migrations  []MigrationPayload `json:"migrations"`
+	Duration    float64            `json:"duration_seconds"`
+	Error       string             `json:"error,omitempty"`
+}
+
+// MigrationPayload is a migration of the run, in the order applied.
+type MigrationPayload struct {
+	Id       string  `json:"id"`
+	Duration float64 `json:"duration_seconds"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Option configures the Webhook.
+type Option func(*Webhook)
+
+// WithEnvironment names the environment in the notifications.
+func WithEnvironment(name string) Option {
+	return func(w *Webhook) {
+		w.environment = name
+	}
+}
+
+// WithFormat selects the payload format, FormatJSON by default.
+func WithFormat(format Format) Option {
+	return func(w *Webhook) {
+		w.format = format
+	}
+}
+
+// WithClient posts with client instead of a client with a 10 seconds timeout.
+func WithClient(client *http.Client) Option {
+	return func(w *Webhook) {
+		w.client = client
+	}
+}
+
+// WithErrorHandler is called when a notification can't be delivered, as the
+// outcome of the run doesn't depend on it.
+func WithErrorHandler(handler func(error)) Option {
+	return func(w *Webhook) {
+		w.onError = handler
+	}
+}
+
+var _ migrate.Observer = (*Webhook)(nil)
+
+// Webhook implements migrate.Observer by posting a notification per run.
+type Webhook struct {
+	url         string
+	environment string
+	format      Format
+	client      *http.Client
+	onError     func(error)
+}
+
+// NewWebhook returns a Webhook posting to url.
+func NewWebhook(url string, opts ...Option) *Webhook {
+	w := &Webhook{
+		url:     url,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		onError: func(error) {},
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+type runKey struct{}
+
+// run collects the migrations of a run, which may apply them from several
+// goroutines of the caller.
+type run struct {
+	mu         sync.Mutex
+	migrations []MigrationPayload
+}
+
+func (w *Webhook) StartRun(ctx context.Context, info migrate.RunInfo) (context.Context, func(migrate.RunResult)) {
+	started := time.Now()
+	r := &run{}
+
+	return context.WithValue(ctx, runKey{}, r), func(result migrate.RunResult) {
+		if result.Applied == 0 && result.Err == nil {
+			return
+		}
+
+		payload := Payload{
+			Environment: w.environment,
+			Dialect:     info.Dialect,
+			Schema:      info.SchemaName,
+			Table:       info.TableName,
+			Direction:   info.Direction.String(),
+			Applied:     result.Applied,
+			Migrations:  r.migrations,
+			Duration:    time.Since(started).Seconds(),
+		}
+
+		if result.Err != nil {
+			payload.Error = result.Err.Error()
+		}
+
+		if err := w.post(ctx, payload); err != nil {
+			w.onError(fmt.Errorf("notify %s: %w", w.url, err))
+		}
+	}
+}
+
+func (*Webhook) StartMigration(ctx context.Context, m migrate.MigrationInfo) (context.Context, func(error)) {
+	started := time.Now()
+
+	return ctx, func(err error) {
+		r, ok := ctx.Value(runKey{}).(*run)
+		if !ok {
+			return
+		}
+
+		migration := MigrationPayload{Id: m.Id, Duration: time.Since(started).Seconds()}
+		if err != nil {
+			migration.Error = err.Error()
+		}
+
+		r.mu.Lock()
+		r.migrations = append(r.migrations, migration)
+		r.mu.Unlock()
+	}
+}
+
+func (*Webhook) StartStatement(ctx context.Context, _ string) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+
+func (w *Webhook) post(ctx context.Context, payload Payload) error {
+	var body any = payload
+	if w.format == FormatSlack {
+		body = map[string]string{"text": SlackText(payload)}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	// The run may have failed because its context was canceled, still notify.
+	req, err := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// SlackText summarizes the payload as the text of a Slack message.
+func SlackText(p Payload) string {
+	var b strings.Builder
+
+	if p.Error != "" {
+		b.WriteString(":x: ")
+	} else {
+		b.WriteString(":white_check_mark: ")
+	}
+
+	if p.Environment != "" {
+		b.WriteString("*" + p.Environment + "*: ")
+	}
+
+	fmt.Fprintf(&b, "applied %d migrations %s in %.1fs", p.Applied, p.Direction, p.Duration)
+
+	for _, m := range p.Migrations {
+		fmt.Fprintf(&b, "\n• `%s` %.1fs", m.Id, m.Duration)
+
+		if m.Error != "" {
+			b.WriteString(" failed")
+		}
+	}
+
+	if p.Error != "" {
+		b.WriteString("\n```" + p.Error + "```")
+	}
+
+	return b.String()
+}
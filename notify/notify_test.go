@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type NotifySuite struct{}
+
+var _ = Suite(&NotifySuite{})
+
+type nullLogger struct{}
+
+func (nullLogger) Tracef(string, ...any) {}
+func (nullLogger) Infof(string, ...any)  {}
+func (nullLogger) Errorf(string, ...any) {}
+
+func (*NotifySuite) TestWebhook(c *C) {
+	var payloads []Payload
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		c.Check(json.NewDecoder(r.Body).Decode(&p), IsNil)
+		payloads = append(payloads, p)
+	}))
+	defer srv.Close()
+
+	db, err := sql.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	ex := migrate.NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = nullLogger{}
+	ex.Observer = NewWebhook(srv.URL, WithEnvironment("staging"))
+
+	source := migrate.NewMemoryMigrationSource([]*migrate.Migration{
+		{Id: "1_people.sql", Up: []string{"CREATE TABLE people (id int)"}},
+		{Id: "2_broken.sql", Up: []string{"CREATE TABLE people (id int)"}},
+	})
+
+	_, err = ex.ExecContext(context.Background(), db, dialect.NewSqliteDialect(), source, migrate.Up)
+	c.Assert(err, NotNil)
+
+	// Nothing left to do, no notification.
+	_, err = ex.ExecContext(context.Background(), db, dialect.NewSqliteDialect(), migrate.NewMemoryMigrationSource([]*migrate.Migration{
+		{Id: "1_people.sql", Up: []string{"CREATE TABLE people (id int)"}},
+	}), migrate.Up)
+	c.Assert(err, IsNil)
+
+	c.Assert(payloads, HasLen, 1)
+
+	p := payloads[0]
+	c.Assert(p.Environment, Equals, "staging")
+	c.Assert(p.Dialect, Equals, "sqlite")
+	c.Assert(p.Table, Equals, "migrations")
+	c.Assert(p.Direction, Equals, "up")
+	c.Assert(p.Applied, Equals, 1)
+	c.Assert(p.Error, Not(Equals), "")
+	c.Assert(p.Migrations, HasLen, 2)
+	c.Assert(p.Migrations[0].Id, Equals, "1_people.sql")
+	c.Assert(p.Migrations[0].Error, Equals, "")
+	c.Assert(p.Migrations[1].Id, Equals, "2_broken.sql")
+	c.Assert(p.Migrations[1].Error, Not(Equals), "")
+}
+
+func (*NotifySuite) TestSlackText(c *C) {
+	text := SlackText(Payload{
+		Environment: "production",
+		Direction:   "up",
+		Applied:     1,
+		Migrations:  []MigrationPayload{{Id: "1_people.sql", Duration: 0.25}},
+		Duration:    0.5,
+	})
+
+	c.Assert(text, Equals, ":white_check_mark: *production*: applied 1 migrations up in 0.5s\n• `1_people.sql` 0.2s")
+}
@@ -0,0 +1,168 @@
+package migrate
+
+import (
+	`context`
+	`fmt`
+	`strings`
+
+	`github.com/kva3umoda/sql-migrate/dialect`
+)
+
+// Observer is notified as the executor runs migrations, e.g. to trace or to
+// measure them. Each Start method returns the context of the nested events,
+// and a function the executor calls once the event is done.
+type Observer interface {
+	// StartRun is called when an Exec run starts, before taking the lock.
+	StartRun(ctx context.Context, run RunInfo) (context.Context, func(RunResult))
+	// StartMigration is called before a migration is applied.
+	StartMigration(ctx context.Context, migration MigrationInfo) (context.Context, func(err error))
+	// StartStatement is called before a statement of a migration is executed.
+	StartStatement(ctx context.Context, query string) (context.Context, func(err error))
+}
+
+// RunInfo describes an Exec run.
+type RunInfo struct {
+	// Dialect is the short name of the dialect, e.g. postgres.
+	Dialect    string
+	SchemaName string
+	TableName  string
+	Direction  MigrationDirection
+	// Max is the limit of the run, 0 for no limit.
+	Max int
+	// Version is the version the run migrates to, or -1.
+	Version int64
+}
+
+// RunResult is the outcome of an Exec run.
+type RunResult struct {
+	// Planned is the number of migrations the run planned to apply.
+	Planned int
+	Applied int
+	Err     error
+}
+
+// MigrationInfo describes a migration about to be applied.
+type MigrationInfo struct {
+	Id        string
+	Direction MigrationDirection
+	// Statements is the number of statements, -1 when it's unknown, see
+	// MigrationStarted.
+	Statements int
+	// Checksum is the checksum of the migration stored in the migration table.
+	Checksum string
+}
+
+func (ex *MigrationExecutor) startRun(
+	ctx context.Context,
+	d dialect.Dialect,
+	dir MigrationDirection,
+	max int,
+	version int64,
+) (context.Context, func(RunResult)) {
+	if ex.Observer == nil {
+		return ctx, func(RunResult) {}
+	}
+
+	return ex.Observer.StartRun(ctx, RunInfo{
+		Dialect:    DialectShortName(d),
+		SchemaName: ex.SchemaName,
+		TableName:  ex.TableName,
+		Direction:  dir,
+		Max:        max,
+		Version:    version,
+	})
+}
+
+func (ex *MigrationExecutor) startMigration(ctx context.Context, dir MigrationDirection, migration *PlannedMigration) (context.Context, func(error)) {
+	if ex.Observer == nil {
+		return ctx, func(error) {}
+	}
+
+	return ex.Observer.StartMigration(ctx, MigrationInfo{
+		Id:         migration.Id,
+		Direction:  dir,
+		Statements: migration.statementCount(),
+		Checksum:   migration.Checksum(),
+	})
+}
+
+func (ex *MigrationExecutor) startStatement(ctx context.Context, query string) (context.Context, func(error)) {
+	if ex.Observer == nil {
+		return ctx, func(error) {}
+	}
+
+	return ex.Observer.StartStatement(ctx, query)
+}
+
+// DialectShortName returns the name of a dialect of the dialect package
+// without its type decoration, e.g. postgres for *dialect.PostgresDialect.
+func DialectShortName(d dialect.Dialect) string {
+	name := fmt.Sprintf("%T", d)
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	return strings.ToLower(strings.TrimSuffix(name, "Dialect"))
+}
+
+// Observers combines observers, e.g. tracing and metrics. Nil observers are
+// skipped. The observers start in order and are done in reverse order.
+func Observers(observers ...Observer) Observer {
+	var combined multiObserver
+
+	for _, o := range observers {
+		if o != nil {
+			combined = append(combined, o)
+		}
+	}
+
+	switch len(combined) {
+	case 0:
+		return nil
+	case 1:
+		return combined[0]
+	default:
+		return combined
+	}
+}
+
+type multiObserver []Observer
+
+func (m multiObserver) StartRun(ctx context.Context, run RunInfo) (context.Context, func(RunResult)) {
+	dones := make([]func(RunResult), len(m))
+	for i, o := range m {
+		ctx, dones[i] = o.StartRun(ctx, run)
+	}
+
+	return ctx, func(result RunResult) {
+		for i := len(dones) - 1; i >= 0; i-- {
+			dones[i](result)
+		}
+	}
+}
+
+func (m multiObserver) StartMigration(ctx context.Context, migration MigrationInfo) (context.Context, func(error)) {
+	return m.start(ctx, func(ctx context.Context, o Observer) (context.Context, func(error)) {
+		return o.StartMigration(ctx, migration)
+	})
+}
+
+func (m multiObserver) StartStatement(ctx context.Context, query string) (context.Context, func(error)) {
+	return m.start(ctx, func(ctx context.Context, o Observer) (context.Context, func(error)) {
+		return o.StartStatement(ctx, query)
+	})
+}
+
+func (m multiObserver) start(
+	ctx context.Context,
+	start func(context.Context, Observer) (context.Context, func(error)),
+) (context.Context, func(error)) {
+	dones := make([]func(error), len(m))
+	for i, o := range m {
+		ctx, dones[i] = start(ctx, o)
+	}
+
+	return ctx, func(err error) {
+		for i := len(dones) - 1; i >= 0; i-- {
+			dones[i](err)
+		}
+	}
+}
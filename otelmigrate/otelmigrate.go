@@ -0,0 +1,124 @@
+// Package otelmigrate traces the migrations of sql-migrate with OpenTelemetry.
+//
+//	ex := migrate.NewMigrationExecutor()
+//	ex.Observer = otelmigrate.NewObserver()
+//
+// Every Exec run gets a span, with a child span per migration and a
+// grandchild span per statement. Failed spans record the error and have the
+// error status.
+package otelmigrate
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+const instrumentationName = "github.com/kva3umoda/sql-migrate/otelmigrate"
+
+// Attributes of the spans.
+const (
+	DialectKey     = attribute.Key("migrate.dialect")
+	SchemaKey      = attribute.Key("migrate.schema")
+	TableKey       = attribute.Key("migrate.table")
+	DirectionKey   = attribute.Key("migrate.direction")
+	MigrationKey   = attribute.Key("migrate.migration_id")
+	StatementsKey  = attribute.Key("migrate.statements")
+	PlannedKey     = attribute.Key("migrate.planned")
+	AppliedKey     = attribute.Key("migrate.applied")
+	DBStatementKey = attribute.Key("db.statement")
+)
+
+// Option configures the Observer.
+type Option func(*Observer)
+
+// WithTracerProvider uses provider instead of the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *Observer) {
+		o.provider = provider
+	}
+}
+
+// WithStatements sets whether the statements are recorded in the db.statement
+// attribute. They are by default.
+func WithStatements(record bool) Option {
+	return func(o *Observer) {
+		o.statements = record
+	}
+}
+
+var _ migrate.Observer = (*Observer)(nil)
+
+// Observer implements migrate.Observer with OpenTelemetry spans.
+type Observer struct {
+	provider   trace.TracerProvider
+	statements bool
+	tracer     trace.Tracer
+}
+
+// NewObserver returns an Observer tracing with the global tracer provider, unless configured otherwise.
+func NewObserver(opts ...Option) *Observer {
+	o := &Observer{provider: otel.GetTracerProvider(), statements: true}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	o.tracer = o.provider.Tracer(instrumentationName)
+
+	return o
+}
+
+func (o *Observer) StartRun(ctx context.Context, run migrate.RunInfo) (context.Context, func(migrate.RunResult)) {
+	ctx, span := o.tracer.Start(ctx, "migrate "+run.Direction.String(), trace.WithAttributes(
+		DialectKey.String(run.Dialect),
+		SchemaKey.String(run.SchemaName),
+		TableKey.String(run.TableName),
+		DirectionKey.String(run.Direction.String()),
+	))
+
+	return ctx, func(result migrate.RunResult) {
+		span.SetAttributes(PlannedKey.Int(result.Planned), AppliedKey.Int(result.Applied))
+		end(span, result.Err)
+	}
+}
+
+func (o *Observer) StartMigration(ctx context.Context, m migrate.MigrationInfo) (context.Context, func(error)) {
+	attributes := []attribute.KeyValue{
+		MigrationKey.String(m.Id),
+		DirectionKey.String(m.Direction.String()),
+	}
+
+	// The statements of streamed migrations aren't counted beforehand.
+	if m.Statements >= 0 {
+		attributes = append(attributes, StatementsKey.Int(m.Statements))
+	}
+
+	ctx, span := o.tracer.Start(ctx, "migration "+m.Id, trace.WithAttributes(attributes...))
+
+	return ctx, func(err error) { end(span, err) }
+}
+
+func (o *Observer) StartStatement(ctx context.Context, query string) (context.Context, func(error)) {
+	var attrs []attribute.KeyValue
+	if o.statements {
+		attrs = append(attrs, DBStatementKey.String(query))
+	}
+
+	ctx, span := o.tracer.Start(ctx, "statement", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+
+	return ctx, func(err error) { end(span, err) }
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
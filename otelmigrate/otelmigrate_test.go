@@ -0,0 +1,84 @@
+package otelmigrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type OtelSuite struct{}
+
+var _ = Suite(&OtelSuite{})
+
+type nullLogger struct{}
+
+func (nullLogger) Tracef(string, ...any) {}
+func (nullLogger) Infof(string, ...any)  {}
+func (nullLogger) Errorf(string, ...any) {}
+
+func (*OtelSuite) TestSpans(c *C) {
+	db, err := sql.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+
+	ex := migrate.NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = nullLogger{}
+	ex.Observer = NewObserver(WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+
+	source := migrate.NewMemoryMigrationSource([]*migrate.Migration{
+		{Id: "1_people.sql", Up: []string{"CREATE TABLE people (id int)"}},
+		{Id: "2_broken.sql", Up: []string{"CREATE TABLE people (id int)"}},
+	})
+
+	_, err = ex.ExecContext(context.Background(), db, dialect.NewSqliteDialect(), source, migrate.Up)
+	c.Assert(err, NotNil)
+
+	spans := recorder.Ended()
+
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+
+	c.Assert(names, DeepEquals, []string{
+		"statement", "migration 1_people.sql",
+		"statement", "migration 2_broken.sql",
+		"migrate up",
+	})
+
+	run := spans[4]
+	c.Assert(run.Status().Code, Equals, codes.Error)
+	c.Assert(run.Attributes(), DeepEquals, []attribute.KeyValue{
+		DialectKey.String("sqlite"),
+		SchemaKey.String(""),
+		TableKey.String("migrations"),
+		DirectionKey.String("up"),
+		PlannedKey.Int(2),
+		AppliedKey.Int(1),
+	})
+
+	c.Assert(spans[1].Parent().SpanID(), Equals, run.SpanContext().SpanID())
+	c.Assert(spans[0].Parent().SpanID(), Equals, spans[1].SpanContext().SpanID())
+	c.Assert(spans[0].Attributes(), DeepEquals, []attribute.KeyValue{DBStatementKey.String("CREATE TABLE people (id int)")})
+	c.Assert(spans[1].Status().Code, Equals, codes.Unset)
+	c.Assert(spans[3].Status().Code, Equals, codes.Error)
+	c.Assert(spans[3].Attributes()[0], Equals, MigrationKey.String("2_broken.sql"))
+}
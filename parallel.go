@@ -0,0 +1,153 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// errParallelConnection is returned when the migrations can't be applied on
+// connections of their own.
+var errParallelConnection = errors.New("ParallelMigrations needs a *sql.DB to apply each migration on its own connection, it can't be combined with SingleConnection or a Runner")
+
+// applyParallel applies the planned migrations following their dependencies,
+// at most ParallelMigrations at a time, and returns the number of applied
+// migrations. No migration is started after one failed, the running ones are
+// finished. The error is the one of the first failed migration in order.
+func (ex *MigrationExecutor) applyParallel(
+	ctx context.Context,
+	dir MigrationDirection,
+	rep *MigrationRepository,
+	migrations []*PlannedMigration,
+) (int, error) {
+	if _, ok := rep.db.(*sql.DB); !ok || ex.Runner != nil {
+		return 0, errParallelConnection
+	}
+
+	waits, err := ex.dependencies(ctx, rep, migrations)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		failed  atomic.Bool
+		applied atomic.Int64
+	)
+
+	done := make([]chan struct{}, len(migrations))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	errs := make([]error, len(migrations))
+	slots := make(chan struct{}, ex.ParallelMigrations)
+
+	for i, migration := range migrations {
+		i, migration := i, migration
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, j := range waits[i] {
+				<-done[j]
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			defer func() { <-slots }()
+
+			if failed.Load() || ctx.Err() != nil {
+				return
+			}
+
+			ok, err := ex.applyPlanned(ctx, dir, rep, migration)
+			if err != nil {
+				errs[i] = err
+				failed.Store(true)
+
+				return
+			}
+
+			if ok {
+				applied.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return int(applied.Load()), err
+		}
+	}
+
+	return int(applied.Load()), ctx.Err()
+}
+
+// dependencies returns the indexes of the migrations each migration of the
+// plan waits for: those of its DependsOn, or all the migrations before it
+// without DependsOn. A migration may only depend on a migration before it, or
+// on an applied one.
+func (ex *MigrationExecutor) dependencies(ctx context.Context, rep *MigrationRepository, migrations []*PlannedMigration) ([][]int, error) {
+	index := make(map[string]int, len(migrations))
+	for i, migration := range migrations {
+		index[migration.Id] = i
+	}
+
+	var applied map[string]bool
+
+	waits := make([][]int, len(migrations))
+
+	for i, migration := range migrations {
+		if len(migration.DependsOn) == 0 {
+			for j := 0; j < i; j++ {
+				waits[i] = append(waits[i], j)
+			}
+
+			continue
+		}
+
+		for _, id := range migration.DependsOn {
+			j, planned := index[id]
+
+			switch {
+			case planned && j < i:
+				waits[i] = append(waits[i], j)
+
+				continue
+			case planned:
+				return nil, fmt.Errorf("Migration %s depends on %s, which comes after it", migration.Id, id)
+			}
+
+			if applied == nil {
+				ids, err := rep.ListMigrationIds(ctx)
+				if err != nil {
+					return nil, err
+				}
+
+				applied = make(map[string]bool, len(ids))
+				for _, id := range ids {
+					applied[id] = true
+				}
+			}
+
+			if !applied[id] {
+				return nil, fmt.Errorf("Migration %s depends on %s, which is neither applied nor pending", migration.Id, id)
+			}
+		}
+	}
+
+	return waits, nil
+}
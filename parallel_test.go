@@ -0,0 +1,98 @@
+package migrate
+
+import (
+	"context"
+	"sync"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+var parallelMigrations = []*Migration{
+	{Id: "1_people.sql", Up: []string{"CREATE TABLE people (id int)"}},
+	{Id: "2_orders.sql", Up: []string{"CREATE TABLE orders (id int)"}, DependsOn: []string{"1_people.sql"}},
+	{Id: "3_items.sql", Up: []string{"CREATE TABLE items (id int)"}, DependsOn: []string{"1_people.sql"}},
+	{Id: "4_invoices.sql", Up: []string{"CREATE TABLE invoices (id int)"}, DependsOn: []string{"2_orders.sql", "3_items.sql"}},
+	{Id: "5_audit.sql", Up: []string{"CREATE TABLE audit (id int)"}},
+}
+
+func (s *SqliteMigrateSuite) TestParallelMigrations(c *C) {
+	var (
+		mu       sync.Mutex
+		started  = map[string]int{}
+		finished = map[string]int{}
+		seq      int
+	)
+
+	s.ex.ParallelMigrations = 4
+	s.ex.EventSink = func(event MigrationEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		seq++
+
+		switch e := event.(type) {
+		case MigrationStarted:
+			started[e.Id] = seq
+		case MigrationFinished:
+			finished[e.Id] = seq
+		}
+	}
+
+	n, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(parallelMigrations), Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 5)
+
+	waits := map[string][]string{
+		"2_orders.sql":   {"1_people.sql"},
+		"3_items.sql":    {"1_people.sql"},
+		"4_invoices.sql": {"2_orders.sql", "3_items.sql"},
+		"5_audit.sql":    {"1_people.sql", "2_orders.sql", "3_items.sql", "4_invoices.sql"},
+	}
+
+	for id, deps := range waits {
+		for _, dep := range deps {
+			c.Assert(started[id] > finished[dep], Equals, true, Commentf("%s started before %s finished", id, dep))
+		}
+	}
+
+	records, err := s.ex.GetMigrationRecords(context.Background(), s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 5)
+}
+
+func (s *SqliteMigrateSuite) TestParallelMigrationsDependencies(c *C) {
+	s.ex.ParallelMigrations = 2
+
+	// A dependency on an applied migration is met.
+	_, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(parallelMigrations[:1]), Up)
+	c.Assert(err, IsNil)
+
+	n, err := s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(parallelMigrations[:2]), Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	later := []*Migration{
+		parallelMigrations[0],
+		parallelMigrations[1],
+		{Id: "3_items.sql", Up: []string{"CREATE TABLE items (id int)"}, DependsOn: []string{"4_invoices.sql"}},
+		{Id: "4_invoices.sql", Up: []string{"CREATE TABLE invoices (id int)"}},
+	}
+
+	_, err = s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(later), Up)
+	c.Assert(err, ErrorMatches, "Migration 3_items.sql depends on 4_invoices.sql, which comes after it")
+
+	missing := []*Migration{
+		parallelMigrations[0],
+		parallelMigrations[1],
+		{Id: "3_items.sql", Up: []string{"CREATE TABLE items (id int)"}, DependsOn: []string{"0_missing.sql"}},
+	}
+
+	_, err = s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(missing), Up)
+	c.Assert(err, ErrorMatches, "Migration 3_items.sql depends on 0_missing.sql, which is neither applied nor pending")
+
+	s.ex.SingleConnection = true
+
+	_, err = s.ex.Exec(s.db, s.dialect, NewMemoryMigrationSource(parallelMigrations), Up)
+	c.Assert(err, Equals, errParallelConnection)
+}
@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// HasPending reports whether migrations of the source aren't applied yet,
+// including ones older than the latest applied migration. It only reads the
+// ids of the applied migrations, and of the source when it implements
+// MigrationIdLister, and stops at the first pending migration.
+func (ex *MigrationExecutor) HasPending(ctx context.Context, db *sql.DB, dialect dialect.Dialect, source MigrationSource) (bool, error) {
+	rep, err := ex.getMigrationRepository(ctx, db, dialect)
+	if err != nil {
+		return false, err
+	}
+
+	appliedIds, err := rep.ListMigrationIds(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	applied := make(map[string]struct{}, len(appliedIds))
+	for _, id := range appliedIds {
+		applied[id] = struct{}{}
+	}
+
+	ids, err := findMigrationIds(source)
+	if err != nil {
+		return false, err
+	}
+
+	for _, id := range ids {
+		if _, ok := applied[id]; !ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CurrentVersion returns the version of the latest applied migration, in the
+// order of the migrations, or 0 when no migration with a version is applied.
+// It only reads the ids of the applied migrations.
+func (ex *MigrationExecutor) CurrentVersion(ctx context.Context, db *sql.DB, dialect dialect.Dialect) (int64, error) {
+	rep, err := ex.getMigrationRepository(ctx, db, dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	ids, err := rep.ListMigrationIds(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var current *Migration
+
+	for _, id := range ids {
+		migration := &Migration{Id: id}
+		if migration.isNumeric() && (current == nil || current.Less(migration)) {
+			current = migration
+		}
+	}
+
+	if current == nil {
+		return 0, nil
+	}
+
+	return current.VersionInt(), nil
+}
+
+// findMigrationIds returns the sorted ids of the migrations of the source,
+// without reading them when the source implements MigrationIdLister.
+func findMigrationIds(source MigrationSource) ([]string, error) {
+	if lister, ok := source.(MigrationIdLister); ok {
+		return lister.FindMigrationIds()
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(migrations))
+	for i, migration := range migrations {
+		ids[i] = migration.Id
+	}
+
+	return ids, nil
+}
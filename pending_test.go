@@ -0,0 +1,42 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func (s *SqliteMigrateSuite) TestHasPending(c *C) {
+	ctx := context.Background()
+	dir := writeMigrations(c, 2)
+	source := NewFileMigrationSource(dir)
+
+	pending, err := s.ex.HasPending(ctx, s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(pending, Equals, true)
+
+	version, err := s.ex.CurrentVersion(ctx, s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(version, Equals, int64(0))
+
+	_, err = s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+
+	pending, err = s.ex.HasPending(ctx, s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(pending, Equals, false)
+
+	version, err = s.ex.CurrentVersion(ctx, s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(version, Equals, int64(2))
+
+	// The files aren't parsed, only their names are listed.
+	c.Assert(os.WriteFile(filepath.Join(dir, "10_broken.sql"), []byte("CREATE TABLE"), 0o600), IsNil)
+
+	pending, err = s.ex.HasPending(ctx, s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(pending, Equals, true)
+}
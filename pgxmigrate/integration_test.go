@@ -0,0 +1,93 @@
+//go:build integration
+
+package pgxmigrate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/migratetest/containers"
+	"github.com/kva3umoda/sql-migrate/pgxmigrate"
+)
+
+func TestPostgres(t *testing.T) {
+	ctx := context.Background()
+	database := containers.Postgres(t)
+
+	config, err := pgxpool.ParseConfig(database.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notices := &migrate.NoticeBuffer{}
+	config.ConnConfig.OnNotice = pgxmigrate.OnNotice(notices)
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer pool.Close()
+
+	ex, db := pgxmigrate.New(pool)
+	ex.CreateTable = true
+	ex.Notices = notices
+
+	defer db.Close()
+
+	var raised []migrate.Notice
+	ex.EventSink = func(event migrate.MigrationEvent) {
+		if e, ok := event.(migrate.MigrationFinished); ok {
+			raised = append(raised, e.Notices...)
+		}
+	}
+
+	source := migrate.NewMemoryMigrationSource([]*migrate.Migration{
+		{
+			Id: "1_users.sql",
+			Up: []string{
+				"CREATE TABLE users (id int PRIMARY KEY, name text);",
+				"COPY users (id, name) FROM STDIN;\n1\talice\n2\tbob\n\\.\n",
+				"DO $$ BEGIN RAISE NOTICE 'copied'; END $$;",
+			},
+			Down: []string{"DROP TABLE users;"},
+		},
+		{
+			Id:                   "2_index.sql",
+			Up:                   []string{"CREATE INDEX CONCURRENTLY users_name ON users (name);", "SELECT 1;"},
+			Down:                 []string{"DROP INDEX users_name;"},
+			DisableTransactionUp: true,
+		},
+	})
+
+	n, err := ex.ExecContext(ctx, db, database.Dialect, source, migrate.Up)
+	if err != nil || n != 2 {
+		t.Fatalf("applied %d migrations: %v", n, err)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM users").Scan(&count); err != nil || count != 2 {
+		t.Fatalf("copied %d users: %v", count, err)
+	}
+
+	if len(raised) != 1 || raised[0].Message != "copied" {
+		t.Fatalf("notices: %v", raised)
+	}
+
+	failing := migrate.NewMemoryMigrationSource(append(source.Migrations, &migrate.Migration{
+		Id: "3_fail.sql",
+		Up: []string{"CREATE TABLE pets (id int);", "INSERT INTO missing VALUES (1);"},
+	}))
+
+	if _, err := ex.ExecContext(ctx, db, database.Dialect, failing, migrate.Up); err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass('pets') IS NOT NULL").Scan(&exists); err != nil || exists {
+		t.Fatalf("pets exists after the rollback: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Package pgxmigrate runs the migrations on a pgx pool with pgx itself
+// rather than through database/sql: the data of COPY ... FROM STDIN
+// statements is sent with the COPY protocol, the statements of a migration
+// are sent at once instead of one round trip each, and the notices of the
+// server are collected.
+//
+//	config, err := pgxpool.ParseConfig(dsn)
+//	if err != nil {
+//		return err
+//	}
+//
+//	notices := &migrate.NoticeBuffer{}
+//	config.ConnConfig.OnNotice = pgxmigrate.OnNotice(notices)
+//
+//	pool, err := pgxpool.NewWithConfig(ctx, config)
+//	if err != nil {
+//		return err
+//	}
+//
+//	ex, db := pgxmigrate.New(pool)
+//	ex.Notices = notices
+//	n, err := ex.ExecContext(ctx, db, &dialect.PostgresDialect{}, source, migrate.Up)
+//
+// The rows of a COPY are written after the statement, the whole enclosed in
+// StatementBegin and StatementEnd so they stay one statement:
+//
+//	-- +migrate StatementBegin
+//	COPY users (id, name) FROM STDIN;
+//	1	alice
+//	2	bob
+//	-- +migrate StatementEnd
+package pgxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+var _ migrate.StatementRunner = Runner{}
+
+// New returns an executor running the statements with Runner, and the
+// database to give it, whose connections are taken from the pool. Closing
+// the database doesn't close the pool.
+func New(pool *pgxpool.Pool) (*migrate.MigrationExecutor, *sql.DB) {
+	ex := migrate.NewMigrationExecutor()
+	ex.Runner = Runner{}
+
+	return ex, stdlib.OpenDBFromPool(pool)
+}
+
+// OnNotice returns a notice handler for pgx.ConnConfig.OnNotice adding the
+// notices to the buffer of the executor.
+func OnNotice(notices *migrate.NoticeBuffer) pgconn.NoticeHandler {
+	return func(_ *pgconn.PgConn, notice *pgconn.Notice) {
+		notices.Add(migrate.Notice{Severity: notice.Severity, Message: notice.Message})
+	}
+}
+
+// Runner is a migrate.StatementRunner for the connections of pgx, opened
+// by New or with stdlib.OpenDBFromPool.
+type Runner struct {
+	// MaxBatch bounds the number of statements sent at once. Zero sends all
+	// the statements between COPY statements at once, 1 sends each by itself.
+	// The statements of a migration without transaction are always sent one
+	// by one, as statements sent at once run in an implicit transaction,
+	// which e.g. CREATE INDEX CONCURRENTLY refuses.
+	MaxBatch int
+}
+
+// RunStatements runs the statements on the pgx connection raw.
+func (r Runner) RunStatements(ctx context.Context, raw any, migration *migrate.PlannedMigration, stmts []string) (int, error) {
+	conn, ok := raw.(*stdlib.Conn)
+	if !ok {
+		return 0, fmt.Errorf("pgxmigrate: the connection is a %T, open the database with pgxmigrate.New", raw)
+	}
+
+	pgConn := conn.Conn().PgConn()
+
+	maxBatch := r.MaxBatch
+	if migration.DisableTransaction {
+		maxBatch = 1
+	}
+
+	for i := 0; i < len(stmts); {
+		if query, data, ok := copyFromStdin(stmts[i]); ok {
+			if _, err := pgConn.CopyFrom(ctx, strings.NewReader(data), query); err != nil {
+				return i, err
+			}
+
+			i++
+
+			continue
+		}
+
+		n := batchLen(stmts[i:], maxBatch)
+
+		done, err := execBatch(ctx, pgConn, stmts[i:i+n])
+		if err != nil {
+			return i + done, err
+		}
+
+		i += n
+	}
+
+	return len(stmts), nil
+}
+
+// copyFromStdin splits a COPY ... FROM STDIN statement followed by its rows
+// into the statement and the rows, in the text format of COPY.
+func copyFromStdin(stmt string) (string, string, bool) {
+	stmt = strings.TrimLeft(stmt, " \t\r\n")
+
+	query, data, _ := strings.Cut(stmt, "\n")
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+
+	upper := strings.ToUpper(query)
+	if !strings.HasPrefix(upper, "COPY ") || !strings.HasSuffix(strings.Join(strings.Fields(upper), " "), "FROM STDIN") {
+		return "", "", false
+	}
+
+	// The end-of-data marker of psql is optional.
+	data = strings.TrimRight(data, " \t\r\n")
+	data = strings.TrimSuffix(data, `\.`)
+
+	if data != "" && !strings.HasSuffix(data, "\n") {
+		data += "\n"
+	}
+
+	return query, data, true
+}
+
+// batchLen returns the number of statements to send at once, at most
+// maxBatch unless it's zero, stopping before a COPY statement.
+func batchLen(stmts []string, maxBatch int) int {
+	n := 1
+	for n < len(stmts) && (maxBatch == 0 || n < maxBatch) {
+		if _, _, ok := copyFromStdin(stmts[n]); ok {
+			break
+		}
+
+		n++
+	}
+
+	return n
+}
+
+// execBatch sends the statements at once with the simple protocol, and
+// returns the number of statements which succeeded before the error.
+func execBatch(ctx context.Context, pgConn *pgconn.PgConn, stmts []string) (int, error) {
+	// The statements may end with a line comment, the separator goes on its own line.
+	results := pgConn.Exec(ctx, strings.Join(stmts, "\n;\n"))
+
+	done := 0
+
+	for results.NextResult() {
+		if _, err := results.ResultReader().Close(); err != nil {
+			break
+		}
+
+		done++
+	}
+
+	err := results.Close()
+	if err == nil {
+		return len(stmts), nil
+	}
+
+	// A statement made of several statements has several results.
+	return min(done, len(stmts)-1), err
+}
@@ -0,0 +1,47 @@
+package pgxmigrate
+
+import (
+	"testing"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type PgxSuite struct{}
+
+var _ = Suite(&PgxSuite{})
+
+func (*PgxSuite) TestCopyFromStdin(c *C) {
+	query, data, ok := copyFromStdin("COPY users (id, name) FROM STDIN;\n1\talice\n2\tbob\n\\.\n")
+	c.Assert(ok, Equals, true)
+	c.Assert(query, Equals, "COPY users (id, name) FROM STDIN")
+	c.Assert(data, Equals, "1\talice\n2\tbob\n")
+
+	query, data, ok = copyFromStdin("\ncopy users from  stdin\n1\talice")
+	c.Assert(ok, Equals, true)
+	c.Assert(query, Equals, "copy users from  stdin")
+	c.Assert(data, Equals, "1\talice\n")
+
+	_, data, ok = copyFromStdin("COPY users FROM STDIN")
+	c.Assert(ok, Equals, true)
+	c.Assert(data, Equals, "")
+
+	for _, stmt := range []string{
+		"COPY users TO STDOUT",
+		"COPY users FROM '/tmp/users.csv'",
+		"CREATE TABLE copy (id int)",
+	} {
+		_, _, ok = copyFromStdin(stmt)
+		c.Assert(ok, Equals, false, Commentf("%s", stmt))
+	}
+}
+
+func (*PgxSuite) TestBatchLen(c *C) {
+	stmts := []string{"SELECT 1", "SELECT 2", "COPY t FROM STDIN\n1", "SELECT 3"}
+
+	c.Assert(batchLen(stmts, 0), Equals, 2)
+	c.Assert(batchLen(stmts, 1), Equals, 1)
+	c.Assert(batchLen(stmts[3:], 0), Equals, 1)
+}
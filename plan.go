@@ -0,0 +1,89 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// ErrStalePlan is returned by ExecPlan when the migration table changed since
+// the migrations were planned, e.g. as another migrator applied some.
+var ErrStalePlan = errors.New("the migration table changed since the migrations were planned")
+
+// MigrationPlan holds the migrations planned by MigrationExecutor.Plan, which
+// ExecPlan applies without finding and listing the migrations again.
+type MigrationPlan struct {
+	Direction  MigrationDirection
+	Migrations []*PlannedMigration
+
+	rep     *MigrationRepository
+	max     int
+	version int64
+	// applied holds the ids of the migrations applied when planning.
+	applied map[string]struct{}
+}
+
+// Plan plans at most max migrations, 0 for no limit, e.g. to show them
+// before applying them with ExecPlan.
+func (ex *MigrationExecutor) Plan(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	dir MigrationDirection,
+	max int,
+) (*MigrationPlan, error) {
+	return ex.plan(ctx, db, dialect, source, dir, max, -1)
+}
+
+// PlanToVersion plans the migrations up or down to version, for ExecPlan.
+func (ex *MigrationExecutor) PlanToVersion(
+	ctx context.Context,
+	db *sql.DB,
+	dialect dialect.Dialect,
+	source MigrationSource,
+	dir MigrationDirection,
+	version int64,
+) (*MigrationPlan, error) {
+	return ex.plan(ctx, db, dialect, source, dir, 0, version)
+}
+
+// ExecPlan applies the planned migrations and returns the number of applied
+// migrations. Once it holds the migration lock, it only checks that the
+// migration table still holds the migrations applied when planning, and
+// fails with ErrStalePlan otherwise, after which the migrations should be
+// planned again. A plan can be applied once.
+func (ex *MigrationExecutor) ExecPlan(ctx context.Context, db *sql.DB, plan *MigrationPlan) (int, error) {
+	return ex.run(ctx, db, db, plan.rep.dialect, plan.Direction, plan.max, plan.version,
+		func(ctx context.Context, conn SqlDB) (*MigrationPlan, error) {
+			return plan.check(ctx, conn)
+		})
+}
+
+// check returns the plan running on conn, or ErrStalePlan when the applied
+// migrations changed.
+func (p *MigrationPlan) check(ctx context.Context, conn SqlDB) (*MigrationPlan, error) {
+	rep := p.rep.withDB(conn)
+
+	ids, err := rep.ListMigrationIds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) != len(p.applied) {
+		return nil, ErrStalePlan
+	}
+
+	for _, id := range ids {
+		if _, ok := p.applied[id]; !ok {
+			return nil, ErrStalePlan
+		}
+	}
+
+	checked := *p
+	checked.rep = rep
+
+	return &checked, nil
+}
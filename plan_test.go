@@ -0,0 +1,51 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func (s *SqliteMigrateSuite) TestExecPlan(c *C) {
+	ctx := context.Background()
+
+	source := &blockingSource{release: make(chan struct{}), source: NewMemoryMigrationSource(sqliteMigrations)}
+	close(source.release)
+
+	plan, err := s.ex.Plan(ctx, s.db, s.dialect, source, Up, 1)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Migrations, HasLen, 1)
+	c.Assert(plan.Migrations[0].Id, Equals, "123")
+
+	n, err := s.ex.ExecPlan(ctx, s.db, plan)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+	c.Assert(source.calls, Equals, int32(1))
+
+	// The plan was applied already.
+	_, err = s.ex.ExecPlan(ctx, s.db, plan)
+	c.Assert(errors.Is(err, ErrStalePlan), Equals, true)
+}
+
+func (s *SqliteMigrateSuite) TestExecStalePlan(c *C) {
+	ctx := context.Background()
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	plan, err := s.ex.Plan(ctx, s.db, s.dialect, source, Up, 0)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Migrations, HasLen, 2)
+
+	// Another migrator applies a migration in between.
+	_, err = s.ex.ExecMax(s.db, s.dialect, source, Up, 1)
+	c.Assert(err, IsNil)
+
+	n, err := s.ex.ExecPlan(ctx, s.db, plan)
+	c.Assert(errors.Is(err, ErrStalePlan), Equals, true)
+	c.Assert(n, Equals, 0)
+
+	records, err := s.ex.GetMigrationRecords(ctx, s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+}
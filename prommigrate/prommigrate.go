@@ -0,0 +1,113 @@
+// Package prommigrate records the migrations of sql-migrate as Prometheus metrics.
+//
+//	recorder := prommigrate.NewRecorder()
+//	prometheus.MustRegister(recorder)
+//
+//	ex := migrate.NewMigrationExecutor()
+//	ex.Observer = recorder
+//
+// The recorder counts the applied and failed migrations, measures the
+// duration of each migration and keeps the number of pending migrations
+// after each up run.
+package prommigrate
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+const namespace = "sql_migrate"
+
+var (
+	_ migrate.Observer     = (*Recorder)(nil)
+	_ prometheus.Collector = (*Recorder)(nil)
+)
+
+// Recorder implements migrate.Observer, and exposes the metrics as a prometheus.Collector.
+type Recorder struct {
+	applied  *prometheus.CounterVec
+	failed   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	pending  *prometheus.GaugeVec
+}
+
+// NewRecorder returns a Recorder, to register with a prometheus.Registerer.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		applied: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "migrations_applied_total",
+			Help:      "Number of migrations applied, by direction.",
+		}, []string{"direction"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "migrations_failed_total",
+			Help:      "Number of migrations which failed to apply, by direction.",
+		}, []string{"direction"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "migration_duration_seconds",
+			Help:      "Duration of the migrations, by direction.",
+			Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300, 900},
+		}, []string{"direction"}),
+		pending: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "pending_migrations",
+			Help:      "Number of migrations not applied yet, by migration table.",
+		}, []string{"schema", "table"}),
+	}
+}
+
+func (r *Recorder) Describe(ch chan<- *prometheus.Desc) {
+	r.applied.Describe(ch)
+	r.failed.Describe(ch)
+	r.duration.Describe(ch)
+	r.pending.Describe(ch)
+}
+
+func (r *Recorder) Collect(ch chan<- prometheus.Metric) {
+	r.applied.Collect(ch)
+	r.failed.Collect(ch)
+	r.duration.Collect(ch)
+	r.pending.Collect(ch)
+}
+
+// SetPending sets the number of pending migrations of a migration table, e.g.
+// from a plan, between runs.
+func (r *Recorder) SetPending(schema, table string, n int) {
+	r.pending.WithLabelValues(schema, table).Set(float64(n))
+}
+
+// StartRun updates the pending migrations after unlimited up runs, which
+// leave the migrations they didn't apply pending.
+func (r *Recorder) StartRun(ctx context.Context, run migrate.RunInfo) (context.Context, func(migrate.RunResult)) {
+	return ctx, func(result migrate.RunResult) {
+		if run.Direction == migrate.Up && run.Max == 0 && run.Version < 0 && result.Planned >= result.Applied {
+			r.SetPending(run.SchemaName, run.TableName, result.Planned-result.Applied)
+		}
+	}
+}
+
+func (r *Recorder) StartMigration(ctx context.Context, m migrate.MigrationInfo) (context.Context, func(error)) {
+	started := time.Now()
+	direction := m.Direction.String()
+
+	return ctx, func(err error) {
+		if err != nil {
+			r.failed.WithLabelValues(direction).Inc()
+
+			return
+		}
+
+		r.applied.WithLabelValues(direction).Inc()
+		r.duration.WithLabelValues(direction).Observe(time.Since(started).Seconds())
+	}
+}
+
+func (*Recorder) StartStatement(ctx context.Context, _ string) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
@@ -0,0 +1,56 @@
+package prommigrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type PromSuite struct{}
+
+var _ = Suite(&PromSuite{})
+
+type nullLogger struct{}
+
+func (nullLogger) Tracef(string, ...any) {}
+func (nullLogger) Infof(string, ...any)  {}
+func (nullLogger) Errorf(string, ...any) {}
+
+func (*PromSuite) TestRecorder(c *C) {
+	db, err := sql.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	recorder := NewRecorder()
+
+	ex := migrate.NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = nullLogger{}
+	ex.Observer = recorder
+
+	source := migrate.NewMemoryMigrationSource([]*migrate.Migration{
+		{Id: "1_people.sql", Up: []string{"CREATE TABLE people (id int)"}},
+		{Id: "2_broken.sql", Up: []string{"CREATE TABLE people (id int)"}},
+		{Id: "3_pets.sql", Up: []string{"CREATE TABLE pets (id int)"}},
+	})
+
+	_, err = ex.ExecContext(context.Background(), db, dialect.NewSqliteDialect(), source, migrate.Up)
+	c.Assert(err, NotNil)
+
+	c.Assert(testutil.ToFloat64(recorder.applied.WithLabelValues("up")), Equals, 1.0)
+	c.Assert(testutil.ToFloat64(recorder.failed.WithLabelValues("up")), Equals, 1.0)
+	c.Assert(testutil.ToFloat64(recorder.pending.WithLabelValues("", "migrations")), Equals, 2.0)
+	c.Assert(testutil.CollectAndCount(recorder, "sql_migrate_migration_duration_seconds"), Equals, 1)
+}
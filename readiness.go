@@ -0,0 +1,109 @@
+package migrate
+
+import (
+	`context`
+	`database/sql`
+	`encoding/json`
+	`net/http`
+
+	`github.com/kva3umoda/sql-migrate/dialect`
+)
+
+// Report describes how far the database is from the migrations of a source.
+type Report struct {
+	// Applied is the number of migrations recorded in the migration table.
+	Applied int `json:"applied"`
+	// Pending lists the migrations of the source which aren't applied yet.
+	Pending []string `json:"pending"`
+	// Unknown lists the applied migrations missing from the source, e.g.
+	// applied by a newer release during a rolling deployment.
+	Unknown []string `json:"unknown"`
+	// Edited lists the applied migrations whose checksum no longer matches
+	// the source. Migrations applied without a checksum, or imported with the
+	// checksum of another tool, aren't verified.
+	Edited []string `json:"edited"`
+}
+
+// Dirty reports whether applied migrations were edited after they were applied.
+func (r Report) Dirty() bool {
+	return len(r.Edited) > 0
+}
+
+// Ready reports whether the schema is current: no migration is pending and
+// none is dirty. Unknown migrations don't make the schema stale.
+func (r Report) Ready() bool {
+	return len(r.Pending) == 0 && !r.Dirty()
+}
+
+// Readiness compares the migration table with the migrations of the source,
+// without applying any, e.g. for the readiness probe of a service which
+// shouldn't take traffic until its schema is current.
+func (ex *MigrationExecutor) Readiness(ctx context.Context, db *sql.DB, dialect dialect.Dialect, m MigrationSource) (Report, error) {
+	migrations, err := m.FindMigrations()
+	if err != nil {
+		return Report{}, err
+	}
+
+	records, err := ex.GetMigrationRecords(ctx, db, dialect)
+	if err != nil {
+		return Report{}, err
+	}
+
+	source := make(map[string]*Migration, len(migrations))
+	for _, migration := range migrations {
+		source[migration.Id] = migration
+	}
+
+	report := Report{Applied: len(records)}
+	applied := make(map[string]bool, len(records))
+
+	for _, record := range records {
+		applied[record.Id] = true
+
+		migration, ok := source[record.Id]
+
+		switch {
+		case !ok:
+			report.Unknown = append(report.Unknown, record.Id)
+		case record.Verifiable() && record.Checksum != migration.Checksum():
+			report.Edited = append(report.Edited, record.Id)
+		}
+	}
+
+	for _, migration := range migrations {
+		if !applied[migration.Id] {
+			report.Pending = append(report.Pending, migration.Id)
+		}
+	}
+
+	return report, nil
+}
+
+// ReadinessHandler serves the Report of Readiness as JSON, with the 200
+// status when the schema is ready and 503 when it isn't or can't be read.
+func (ex *MigrationExecutor) ReadinessHandler(db *sql.DB, dialect dialect.Dialect, m MigrationSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response struct {
+			Ready bool `json:"ready"`
+			Report
+			Error string `json:"error,omitempty"`
+		}
+
+		report, err := ex.Readiness(r.Context(), db, dialect, m)
+		if err != nil {
+			response.Error = err.Error()
+		}
+
+		response.Report = report
+		response.Ready = err == nil && report.Ready()
+
+		status := http.StatusOK
+		if !response.Ready {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
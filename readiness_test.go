@@ -0,0 +1,79 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func (s *SqliteMigrateSuite) TestReadiness(c *C) {
+	ctx := context.Background()
+	source := NewMemoryMigrationSource(sqliteMigrations)
+
+	report, err := s.ex.Readiness(ctx, s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(report.Pending, DeepEquals, []string{"123", "124"})
+	c.Assert(report.Ready(), Equals, false)
+
+	_, err = s.ex.ExecMax(s.db, s.dialect, source, Up, 1)
+	c.Assert(err, IsNil)
+
+	report, err = s.ex.Readiness(ctx, s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(report.Applied, Equals, 1)
+	c.Assert(report.Pending, DeepEquals, []string{"124"})
+
+	_, err = s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+
+	report, err = s.ex.Readiness(ctx, s.db, s.dialect, source)
+	c.Assert(err, IsNil)
+	c.Assert(report.Ready(), Equals, true)
+
+	// A rolled back release doesn't know the last migration.
+	report, err = s.ex.Readiness(ctx, s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations[:1]))
+	c.Assert(err, IsNil)
+	c.Assert(report.Unknown, DeepEquals, []string{"124"})
+	c.Assert(report.Ready(), Equals, true)
+
+	edited := *sqliteMigrations[0]
+	edited.Up = []string{"CREATE TABLE people (id bigint)"}
+
+	report, err = s.ex.Readiness(ctx, s.db, s.dialect, NewMemoryMigrationSource([]*Migration{&edited, sqliteMigrations[1]}))
+	c.Assert(err, IsNil)
+	c.Assert(report.Edited, DeepEquals, []string{"123"})
+	c.Assert(report.Dirty(), Equals, true)
+	c.Assert(report.Ready(), Equals, false)
+}
+
+func (s *SqliteMigrateSuite) TestReadinessHandler(c *C) {
+	source := NewMemoryMigrationSource(sqliteMigrations)
+	handler := s.ex.ReadinessHandler(s.db, s.dialect, source)
+
+	probe := func() (int, map[string]any) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+		var body map[string]any
+		c.Assert(json.Unmarshal(w.Body.Bytes(), &body), IsNil)
+
+		return w.Code, body
+	}
+
+	code, body := probe()
+	c.Assert(code, Equals, http.StatusServiceUnavailable)
+	c.Assert(body["ready"], Equals, false)
+	c.Assert(body["pending"], HasLen, 2)
+
+	_, err := s.ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+
+	code, body = probe()
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(body["ready"], Equals, true)
+	c.Assert(body["applied"], Equals, float64(2))
+}
@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	`crypto/sha256`
+	`encoding/hex`
+	`fmt`
+	`unicode/utf8`
+)
+
+// ArgRedaction controls how the bind arguments of the traced queries are
+// logged, as arguments of Go migrations or seed statements may hold personal data.
+type ArgRedaction int
+
+const (
+	// RedactNone logs the arguments verbatim.
+	RedactNone ArgRedaction = iota
+	// RedactTruncate logs the first characters of string and byte arguments
+	// and their length. Other arguments, e.g. numbers, are logged verbatim.
+	RedactTruncate
+	// RedactHash logs a hash of every argument, so equal values can still be
+	// matched across the log.
+	RedactHash
+	// RedactSuppress logs only the number of arguments.
+	RedactSuppress
+)
+
+// truncatedLength is the number of characters RedactTruncate keeps.
+const truncatedLength = 4
+
+func (r ArgRedaction) redact(v any) any {
+	if v == nil {
+		return nil
+	}
+
+	switch r {
+	case RedactTruncate:
+		switch v := v.(type) {
+		case string:
+			return truncate(v)
+		case []byte:
+			return truncate(string(v))
+		}
+	case RedactHash:
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%T:%v", v, v)))
+
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+
+	return v
+}
+
+func truncate(s string) string {
+	n := utf8.RuneCountInString(s)
+	if n <= truncatedLength {
+		return s
+	}
+
+	return fmt.Sprintf("%s…(%d)", string([]rune(s)[:truncatedLength]), n)
+}
@@ -0,0 +1,25 @@
+package migrate
+
+import (
+	"database/sql"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type RedactSuite struct{}
+
+var _ = Suite(&RedactSuite{})
+
+func (*RedactSuite) TestArgsString(c *C) {
+	args := []any{"alice@example.com", 42, []byte("secret"), sql.NullString{}}
+
+	c.Assert(argsString(RedactNone, args...), Equals, `1:"alice@example.com" 2:42 3:[115 101 99 114 101 116] 4:<nil>`)
+	c.Assert(argsString(RedactTruncate, args...), Equals, `1:"alic…(17)" 2:42 3:"secr…(6)" 4:<nil>`)
+	c.Assert(argsString(RedactSuppress, args...), Equals, "4 args redacted")
+	c.Assert(argsString(RedactSuppress), Equals, "")
+
+	hashed := argsString(RedactHash, args...)
+	c.Assert(hashed, Matches, `1:"sha256:[0-9a-f]{12}" 2:"sha256:[0-9a-f]{12}" 3:"sha256:[0-9a-f]{12}" 4:<nil>`)
+	c.Assert(argsString(RedactHash, "alice@example.com"), Equals, hashed[:len(`1:"sha256:`)+12+1])
+}
@@ -6,6 +6,9 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	`github.com/kva3umoda/sql-migrate/dialect`
@@ -13,9 +16,41 @@ import (
 
 type transactionKey struct{}
 
+// MigrationRecord is a row of the migration table. The db tags name its
+// columns, e.g. for sqlx.
 type MigrationRecord struct {
-	Id        string
-	AppliedAt time.Time
+	Id        string    `db:"id"`
+	AppliedAt time.Time `db:"applied_at"`
+	// Checksum is the Migration.Checksum of the applied migration, empty when
+	// it was applied before the migration table had a checksum column.
+	Checksum string `db:"checksum"`
+	// Duration is the time the migration took to apply, zero when unknown,
+	// e.g. when it was skipped or applied before the migration table had a
+	// duration_ms column. It's stored in milliseconds.
+	Duration time.Duration `db:"-"`
+	// AppliedBy is the MigrationExecutor.AppliedBy of the executor which
+	// applied the migration, empty when unknown.
+	AppliedBy string `db:"applied_by"`
+}
+
+// Verifiable reports whether the checksum of the record can be compared with
+// Migration.Checksum. It can't when the migration was applied before the
+// migration table had a checksum column, or imported with the checksum of
+// another tool, e.g. flyway:1234.
+func (r MigrationRecord) Verifiable() bool {
+	return r.Checksum != "" && !strings.Contains(r.Checksum, ":")
+}
+
+// migrateColumn is an extra column of the migration table, see dialect.ColumnRecorder.
+type migrateColumn struct {
+	name       string
+	columnType dialect.ColumnType
+}
+
+var migrateColumns = []migrateColumn{
+	{name: "checksum", columnType: dialect.ColumnText},
+	{name: "duration_ms", columnType: dialect.ColumnInteger},
+	{name: "applied_by", columnType: dialect.ColumnText},
 }
 
 type SqlExecutor interface {
@@ -23,24 +58,86 @@ type SqlExecutor interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
+// SqlDB is the subset of *sql.DB and *sql.Conn used by MigrationRepository.
+type SqlDB interface {
+	SqlExecutor
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
 type MigrationRepository struct {
 	dialect    dialect.Dialect
-	db         *sql.DB
+	db         SqlDB
 	schemaName string
 	tableName  string
+	// columns holds the extra columns present in the migration table.
+	columns []string
+	// queries holds the queries rendered by the dialect for the table.
+	queries repositoryQueries
 
 	logger    Logger
 	logPrefix string
+	// argRedaction hides the bind arguments in the traced queries.
+	argRedaction ArgRedaction
 }
 
-func NewMigrationRepository(db *sql.DB, dialect dialect.Dialect, schemaName, tableName string, logger Logger) *MigrationRepository {
-	return &MigrationRepository{
+// repositoryQueries are the queries run for each migration, rendered once
+// per repository rather than for every call.
+type repositoryQueries struct {
+	insert    string
+	delete    string
+	selectAll string
+	selectIds string
+	selectId  string
+}
+
+func NewMigrationRepository(db SqlDB, dialect dialect.Dialect, schemaName, tableName string, logger Logger) *MigrationRepository {
+	r := &MigrationRepository{
 		db:         db,
 		dialect:    dialect,
 		schemaName: schemaName,
 		tableName:  tableName,
 		logger:     logger,
 	}
+
+	r.renderQueries()
+
+	return r
+}
+
+// renderQueries renders the queries of the migration table, with the extra
+// columns detected.
+func (r *MigrationRepository) renderQueries() {
+	r.queries = repositoryQueries{
+		insert:    r.dialect.QueryInsertMigrate(r.schemaName, r.tableName),
+		delete:    r.dialect.QueryDeleteMigrate(r.schemaName, r.tableName),
+		selectAll: r.dialect.QuerySelectMigrate(r.schemaName, r.tableName),
+	}
+
+	if recorder, ok := r.dialect.(dialect.ColumnRecorder); ok && len(r.columns) > 0 {
+		r.queries.insert = recorder.QueryInsertMigrateColumns(r.schemaName, r.tableName, r.columns)
+		r.queries.selectAll = recorder.QuerySelectMigrateColumns(r.schemaName, r.tableName, r.columns)
+	}
+
+	if selector, ok := r.dialect.(dialect.IdSelector); ok {
+		r.queries.selectIds = selector.QuerySelectMigrateIds(r.schemaName, r.tableName)
+	}
+
+	if finder, ok := r.dialect.(dialect.IdFinder); ok {
+		r.queries.selectId = finder.QuerySelectMigrateId(r.schemaName, r.tableName)
+	}
+}
+
+// SetArgRedaction sets how the bind arguments of the traced queries are logged.
+func (r *MigrationRepository) SetArgRedaction(redaction ArgRedaction) {
+	r.argRedaction = redaction
+}
+
+// withDB returns a copy of the repository running on db, keeping the detected columns.
+func (r *MigrationRepository) withDB(db SqlDB) *MigrationRepository {
+	rep := *r
+	rep.db = db
+
+	return &rep
 }
 
 func (r *MigrationRepository) BeginTx(ctx context.Context) (*sql.Tx, context.Context, error) {
@@ -63,6 +160,8 @@ func (r *MigrationRepository) CreateSchema(ctx context.Context) error {
 	return nil
 }
 
+// CreateTable creates the migration table, and its index on applied_at for
+// dialects implementing dialect.MigrateIndexer, unless they exist.
 func (r *MigrationRepository) CreateTable(ctx context.Context) error {
 	query := r.dialect.QueryCreateMigrateTable(r.schemaName, r.tableName)
 
@@ -71,53 +170,497 @@ func (r *MigrationRepository) CreateTable(ctx context.Context) error {
 		return err
 	}
 
+	if indexer, ok := r.dialect.(dialect.MigrateIndexer); ok {
+		_, err = r.ExecContext(ctx, indexer.QueryCreateMigrateIndex(r.schemaName, r.tableName))
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// DetectColumns checks which extra columns of the migration table exist, so
+// that they are read and written from then on. With add set, missing columns
+// are added to the table in place. Dialects which don't implement
+// dialect.ColumnRecorder only store id and applied_at.
+func (r *MigrationRepository) DetectColumns(ctx context.Context, add bool) error {
+	recorder, ok := r.dialect.(dialect.ColumnRecorder)
+	if !ok {
+		return nil
+	}
+
+	r.columns = r.columns[:0]
+
+	for _, column := range migrateColumns {
+		exists, err := r.hasColumn(ctx, recorder, column.name)
+		if err != nil {
+			return fmt.Errorf("detect column %s of the migration table: %w", column.name, err)
+		}
+
+		if !exists && add {
+			query := recorder.QueryAddMigrateColumn(r.schemaName, r.tableName, column.name, column.columnType)
+
+			_, err := r.ExecContext(ctx, query)
+			if err != nil {
+				// Another migrator may have added the column in the meantime.
+				added, probeErr := r.hasColumn(ctx, recorder, column.name)
+				if probeErr != nil || !added {
+					return fmt.Errorf("add column %s to the migration table: %w", column.name, err)
+				}
+			}
+
+			exists = true
+		}
+
+		if exists {
+			r.columns = append(r.columns, column.name)
+		}
+	}
+
+	r.renderQueries()
+
+	return nil
+}
+
+// hasColumn tells whether the migration table has the column. Only the
+// undefined column error of the dialect tells it's missing, the other errors
+// of the probe are returned.
+func (r *MigrationRepository) hasColumn(ctx context.Context, recorder dialect.ColumnRecorder, column string) (bool, error) {
+	err := r.probeColumn(ctx, recorder, column)
+
+	switch {
+	case err == nil:
+		return true, nil
+	case recorder.IsUndefinedColumn(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// probeColumn selects the column of the migration table without rows.
+func (r *MigrationRepository) probeColumn(ctx context.Context, recorder dialect.ColumnRecorder, column string) error {
+	rows, err := r.QueryContext(ctx, recorder.QueryProbeMigrateColumn(r.schemaName, r.tableName, column))
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// hasExtraColumn tells whether the extra column was detected.
+func (r *MigrationRepository) hasExtraColumn(column string) bool {
+	for _, c := range r.columns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (r *MigrationRepository) SaveMigration(ctx context.Context, record MigrationRecord) error {
-	query := r.dialect.QueryInsertMigrate(r.schemaName, r.tableName)
-	_, err := r.ExecContext(ctx, query, record.Id, record.AppliedAt)
+	_, err := r.ExecContext(ctx, r.insertQuery(), r.insertArgs(record)...)
 
 	return err
 }
 
+// SaveMigrations records the migrations with a single prepared statement, in
+// the transaction of the context if any. It is much faster than SaveMigration
+// for thousands of records, e.g. when skipping the migrations of an existing
+// database.
+func (r *MigrationRepository) SaveMigrations(ctx context.Context, records []MigrationRecord) error {
+	preparer, ok := r.use(ctx).(interface {
+		PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	})
+	if !ok {
+		for _, record := range records {
+			err := r.SaveMigration(ctx, record)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	query := r.insertQuery()
+
+	stmt, err := preparer.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	for _, record := range records {
+		started := time.Now()
+		args := r.insertArgs(record)
+
+		_, err = stmt.ExecContext(ctx, args...)
+		r.trace(ctx, started, query, args...)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertQuery returns the query inserting a record, with the extra columns.
+func (r *MigrationRepository) insertQuery() string {
+	return r.queries.insert
+}
+
+func (r *MigrationRepository) insertArgs(record MigrationRecord) []any {
+	args := []any{record.Id, record.AppliedAt}
+
+	for _, column := range r.columns {
+		args = append(args, columnValue(record, column))
+	}
+
+	return args
+}
+
 func (r *MigrationRepository) DeleteMigration(ctx context.Context, id string) error {
-	query := r.dialect.QueryDeleteMigrate(r.schemaName, r.tableName)
-	_, err := r.ExecContext(ctx, query, id)
+	_, err := r.ExecContext(ctx, r.queries.delete, id)
 
 	return err
 }
 
 func (r *MigrationRepository) ListMigration(ctx context.Context) ([]MigrationRecord, error) {
 	records := make([]MigrationRecord, 0, 10)
-	query := r.dialect.QuerySelectMigrate(r.schemaName, r.tableName)
+	rows, err := r.QueryContext(ctx, r.queries.selectAll)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec MigrationRecord
+
+		values := make([]sql.NullString, len(r.columns))
+		dest := []any{&rec.Id, &rec.AppliedAt}
+
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, column := range r.columns {
+			setColumnValue(&rec, column, values[i].String)
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, rows.Err()
+}
+
+// LastMigration returns the id and the time of the latest applied migration,
+// or nil when none is applied. Dialects implementing dialect.MigrateIndexer
+// read it through the index on applied_at, the others read the whole table.
+func (r *MigrationRepository) LastMigration(ctx context.Context) (*MigrationRecord, error) {
+	indexer, ok := r.dialect.(dialect.MigrateIndexer)
+	if !ok {
+		records, err := r.ListMigration(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var last *MigrationRecord
+
+		for i := range records {
+			record := &records[i]
+			if last == nil || record.AppliedAt.After(last.AppliedAt) ||
+				record.AppliedAt.Equal(last.AppliedAt) && record.Id > last.Id {
+				last = record
+			}
+		}
+
+		return last, nil
+	}
+
+	rows, err := r.QueryContext(ctx, indexer.QuerySelectLastMigrate(r.schemaName, r.tableName))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var record MigrationRecord
 
-	rows, err := r.QueryContext(ctx, query)
+	err = rows.Scan(&record.Id, &record.AppliedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, rows.Err()
+}
+
+// ListMigrationIds returns the ids of the applied migrations ordered by id,
+// selecting the id column alone for dialects implementing dialect.IdSelector.
+func (r *MigrationRepository) ListMigrationIds(ctx context.Context) ([]string, error) {
+	if r.queries.selectIds == "" {
+		records, err := r.ListMigration(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, len(records))
+		for i, record := range records {
+			ids[i] = record.Id
+		}
+
+		return ids, nil
+	}
+
+	rows, err := r.QueryContext(ctx, r.queries.selectIds)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	var rec MigrationRecord
+	ids := make([]string, 0, 10)
 
 	for rows.Next() {
+		var id string
 
-		err = rows.Scan(&rec.Id, &rec.AppliedAt)
+		err = rows.Scan(&id)
 		if err != nil {
 			return nil, err
 		}
 
-		records = append(records, rec)
+		ids = append(ids, id)
 	}
 
-	return records, nil
+	return ids, rows.Err()
+}
+
+// columnValue returns the value of the extra column for the record, nil when unset.
+func columnValue(record MigrationRecord, column string) any {
+	switch column {
+	case "checksum":
+		if record.Checksum != "" {
+			return record.Checksum
+		}
+	case "duration_ms":
+		if record.Duration > 0 {
+			return record.Duration.Milliseconds()
+		}
+	case "applied_by":
+		if record.AppliedBy != "" {
+			return record.AppliedBy
+		}
+	}
+
+	return nil
+}
+
+func setColumnValue(record *MigrationRecord, column, value string) {
+	switch column {
+	case "checksum":
+		record.Checksum = value
+	case "duration_ms":
+		if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+			record.Duration = time.Duration(ms) * time.Millisecond
+		}
+	case "applied_by":
+		record.AppliedBy = value
+	}
+}
+
+// HasMigration reports whether the migration is recorded as applied.
+func (r *MigrationRepository) HasMigration(ctx context.Context, id string) (bool, error) {
+	if r.queries.selectId == "" {
+		ids, err := r.ListMigrationIds(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		return slices.Contains(ids, id), nil
+	}
+
+	rows, err := r.QueryContext(ctx, r.queries.selectId, id)
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+
+	exists := rows.Next()
+
+	return exists, rows.Err()
+}
+
+// ReadWarnings returns the warnings of the last statement of the session, as
+// notices, for dialects keeping them.
+func (r *MigrationRepository) ReadWarnings(ctx context.Context, reader dialect.WarningReader) ([]Notice, error) {
+	rows, err := r.QueryContext(ctx, reader.QuerySelectWarnings())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var notices []Notice
+
+	for rows.Next() {
+		var (
+			level, message string
+			code           sql.NullString
+		)
+
+		err = rows.Scan(&level, &code, &message)
+		if err != nil {
+			return nil, err
+		}
+
+		if code.Valid {
+			message = code.String + ": " + message
+		}
+
+		notices = append(notices, Notice{Severity: level, Message: message})
+	}
+
+	return notices, rows.Err()
+}
+
+func (r *MigrationRepository) TryAdvisoryLock(ctx context.Context, locker dialect.AdvisoryLocker, key string) (bool, error) {
+	rows, err := r.QueryContext(ctx, locker.QueryTryAdvisoryLock(), key)
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+
+	var acquired bool
+
+	if rows.Next() {
+		err = rows.Scan(&acquired)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return acquired, rows.Err()
+}
+
+func (r *MigrationRepository) AdvisoryUnlock(ctx context.Context, locker dialect.AdvisoryLocker, key string) error {
+	rows, err := r.QueryContext(ctx, locker.QueryAdvisoryUnlock(), key)
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+func (r *MigrationRepository) CreateLockTable(ctx context.Context, locker dialect.TableLocker) error {
+	query := locker.QueryCreateLockTable(r.schemaName, r.lockTableName())
+
+	_, err := r.ExecContext(ctx, query)
+
+	return err
+}
+
+// TryLockRow inserts the lock row and reports whether the row is owned by
+// owner afterwards. A row whose lease expired, acquired more than lease ago
+// and not renewed since, was left by a migrator which is gone and is taken
+// over. A zero lease never expires.
+func (r *MigrationRepository) TryLockRow(ctx context.Context, locker dialect.TableLocker, key, owner string, lease time.Duration) (bool, error) {
+	query := locker.QueryInsertLock(r.schemaName, r.lockTableName())
+
+	_, err := r.ExecContext(ctx, query, key, owner, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+
+	current, acquiredAt, err := r.LockRow(ctx, locker, key)
+	if err != nil || current == owner || current == "" || lease <= 0 || time.Since(acquiredAt) <= lease {
+		return current == owner && err == nil, err
+	}
+
+	logWith(ctx, r.logger, LevelWarn, fmt.Sprintf("Taking over the migration lock %s of %s, expired since %s",
+		key, current, acquiredAt.Add(lease).Format(time.RFC3339)), Field{"lock", key}, Field{"owner", current})
+
+	// Racing migrators delete the expired row alike, only one insert wins.
+	err = r.UnlockRow(ctx, locker, key, current)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = r.ExecContext(ctx, query, key, owner, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+
+	current, _, err = r.LockRow(ctx, locker, key)
+
+	return current == owner && err == nil, err
+}
+
+// LockRow returns the owner of the lock row and the start of its lease, or
+// an empty owner when the lock is free.
+func (r *MigrationRepository) LockRow(ctx context.Context, locker dialect.TableLocker, key string) (string, time.Time, error) {
+	rows, err := r.QueryContext(ctx, locker.QuerySelectLock(r.schemaName, r.lockTableName()), key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	defer rows.Close()
+
+	var (
+		current    string
+		acquiredAt time.Time
+	)
+
+	if rows.Next() {
+		err = rows.Scan(&current, &acquiredAt)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+	}
+
+	return current, acquiredAt, rows.Err()
+}
+
+// RenewLockRow starts a new lease of the lock row owned by owner.
+func (r *MigrationRepository) RenewLockRow(ctx context.Context, locker dialect.TableLocker, key, owner string) error {
+	query := locker.QueryRenewLock(r.schemaName, r.lockTableName())
+
+	_, err := r.ExecContext(ctx, query, time.Now().UTC(), key, owner)
+
+	return err
+}
+
+func (r *MigrationRepository) UnlockRow(ctx context.Context, locker dialect.TableLocker, key, owner string) error {
+	query := locker.QueryDeleteLock(r.schemaName, r.lockTableName())
+
+	_, err := r.ExecContext(ctx, query, key, owner)
+
+	return err
+}
+
+func (r *MigrationRepository) lockTableName() string {
+	return r.tableName + "_lock"
 }
 
 // Exec runs an arbitrary SQL statement.  args represent the bind parameters.
 // This is equivalent to running:  Exec() using database/sql
 func (r *MigrationRepository) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	defer r.trace(time.Now(), query, args...)
+	defer r.trace(ctx, time.Now(), query, args...)
 
 	res, err := r.use(ctx).ExecContext(ctx, query, args...)
 	if err != nil {
@@ -128,7 +671,7 @@ func (r *MigrationRepository) ExecContext(ctx context.Context, query string, arg
 }
 
 func (r *MigrationRepository) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	defer r.trace(time.Now(), query, args...)
+	defer r.trace(ctx, time.Now(), query, args...)
 
 	rows, err := r.use(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
@@ -148,16 +691,26 @@ func (r *MigrationRepository) use(ctx context.Context) SqlExecutor {
 	return tx
 }
 
-func (r *MigrationRepository) trace(started time.Time, query string, args ...any) {
-	var margs = argsString(args...)
+func (r *MigrationRepository) trace(ctx context.Context, started time.Time, query string, args ...any) {
+	if !logEnabled(ctx, r.logger, LevelTrace) {
+		return
+	}
+
+	var margs = argsString(r.argRedaction, args...)
+	duration := time.Since(started)
 
-	r.logger.Tracef("%s%s [%s] (%v)", r.logPrefix, query, margs, (time.Now().Sub(started)))
+	logWith(ctx, r.logger, LevelTrace, fmt.Sprintf("%s%s [%s] (%v)", r.logPrefix, query, margs, duration),
+		Field{"query", query}, Field{"args", margs}, Field{"duration", duration})
 }
 
-func argsString(args ...any) string {
+func argsString(redaction ArgRedaction, args ...any) string {
+	if redaction == RedactSuppress && len(args) > 0 {
+		return fmt.Sprintf("%d args redacted", len(args))
+	}
+
 	var margs string
 	for i, a := range args {
-		v := argValue(a)
+		v := redaction.redact(argValue(a))
 		switch v.(type) {
 		case string:
 			v = fmt.Sprintf("%q", v)
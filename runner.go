@@ -0,0 +1,107 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+// StatementRunner runs the statements of a migration on the connection of
+// the driver, as sql.Conn.Raw hands it, instead of through database/sql,
+// e.g. to use the COPY protocol or to send many statements at once. The
+// connection is in the transaction of the migration, unless the migration
+// disables it. The statements have no trailing semicolon.
+//
+// It returns the index of the statement which failed, with its error. The
+// statements are observed as a whole, not one by one, and the StatementTimeout
+// of the migration bounds them as a whole, n statements by n times it.
+type StatementRunner interface {
+	RunStatements(ctx context.Context, raw any, migration *PlannedMigration, stmts []string) (failed int, err error)
+}
+
+// errRunnerConnection is returned when a migration with a Runner doesn't run
+// on a single connection.
+var errRunnerConnection = errors.New("the statement runner needs the migration to run on a single connection")
+
+// runStatements runs the statements of the migration with the Runner, on the
+// connection of the repository.
+func (ex *MigrationExecutor) runStatements(ctx context.Context, rep *MigrationRepository, migration *PlannedMigration) error {
+	conn, ok := rep.db.(*sql.Conn)
+	if !ok {
+		return errRunnerConnection
+	}
+
+	var (
+		stmts []string
+		names []string
+	)
+
+	err := migration.Statements(func(stmt string, lines sqlparse.LineRange) error {
+		stmt = strings.TrimSuffix(stmt, "\n")
+		stmt = strings.TrimSuffix(stmt, " ")
+		stmt = strings.TrimSuffix(stmt, ";")
+
+		names = append(names, statementName(len(stmts), lines))
+		stmts = append(stmts, stmt)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	timeoutCtx, cancel := statementContext(ctx, migration, len(stmts))
+	stmtCtx, done := ex.startStatement(timeoutCtx, strings.Join(stmts, ";\n"))
+	finished := ex.watchStatement(ctx, migration, fmt.Sprintf("%d statements", len(stmts)))
+
+	var failed int
+
+	err = conn.Raw(func(raw any) error {
+		var err error
+
+		failed, err = ex.Runner.RunStatements(stmtCtx, raw, migration, stmts)
+
+		return err
+	})
+
+	err = statementTimeout(ctx, timeoutCtx, migration, err)
+	cancel()
+	finished()
+	done(err)
+
+	// The last statement run carries the error, or the whole run.
+	last := len(stmts) - 1
+	if err != nil && failed >= 0 && failed < len(stmts) {
+		last = failed
+	}
+
+	name := "the statements"
+	if err != nil && last >= 0 {
+		name = names[last]
+	}
+
+	notices := ex.statementNotices(ctx, rep, migration, name, err)
+
+	for i := 0; i < last; i++ {
+		ex.emit(StatementExecuted{MigrationId: migration.Id, Index: i, Statement: names[i]})
+	}
+
+	if last >= 0 {
+		ex.emit(StatementExecuted{
+			MigrationId: migration.Id,
+			Index:       last,
+			Statement:   names[last],
+			Duration:    time.Since(started),
+			Notices:     notices,
+			Err:         err,
+		})
+	}
+
+	return err
+}
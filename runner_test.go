@@ -0,0 +1,84 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+// sqliteRunner runs the statements on the connection of go-sqlite3.
+type sqliteRunner struct {
+	runs [][]string
+}
+
+func (r *sqliteRunner) RunStatements(_ context.Context, raw any, _ *PlannedMigration, stmts []string) (int, error) {
+	conn, ok := raw.(*sqlite3.SQLiteConn)
+	if !ok {
+		return 0, errors.New("not a sqlite connection")
+	}
+
+	r.runs = append(r.runs, stmts)
+
+	for i, stmt := range stmts {
+		if _, err := conn.Exec(stmt, nil); err != nil {
+			return i, err
+		}
+	}
+
+	return len(stmts), nil
+}
+
+func (s *SqliteMigrateSuite) TestRunner(c *C) {
+	runner := &sqliteRunner{}
+	s.ex.Runner = runner
+
+	var events []StatementExecuted
+	s.ex.EventSink = func(event MigrationEvent) {
+		if e, ok := event.(StatementExecuted); ok {
+			events = append(events, e)
+		}
+	}
+
+	n, err := s.ex.ExecContext(context.Background(), s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations), Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+	c.Assert(runner.runs, DeepEquals, [][]string{
+		{"CREATE TABLE people (id int)"},
+		{"ALTER TABLE people ADD COLUMN first_name text"},
+	})
+	c.Assert(events, HasLen, 2)
+
+	_, err = s.db.Exec("SELECT first_name FROM people")
+	c.Assert(err, IsNil)
+}
+
+func (s *SqliteMigrateSuite) TestRunnerFailure(c *C) {
+	s.ex.Runner = &sqliteRunner{}
+
+	var events []StatementExecuted
+	s.ex.EventSink = func(event MigrationEvent) {
+		if e, ok := event.(StatementExecuted); ok {
+			events = append(events, e)
+		}
+	}
+
+	source := NewMemoryMigrationSource([]*Migration{{
+		Id: "1",
+		Up: []string{"CREATE TABLE pets (id int);", "INSERT INTO missing VALUES (1);", "SELECT 1;"},
+	}})
+
+	_, err := s.ex.ExecContext(context.Background(), s.db, s.dialect, source, Up)
+	c.Assert(err, ErrorMatches, "no such table: missing handling 1")
+
+	c.Assert(events, HasLen, 2)
+	c.Assert(events[1].Index, Equals, 1)
+	c.Assert(events[1].Err, NotNil)
+
+	// The transaction of the migration was rolled back.
+	_, err = s.db.Exec("SELECT * FROM pets")
+	c.Assert(err, ErrorMatches, "no such table: pets")
+}
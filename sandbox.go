@@ -0,0 +1,80 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// sandboxPrefix starts the names of the schemas of Sandbox.
+const sandboxPrefix = "sandbox_"
+
+// Sandbox applies the migrations of the source in a temporary schema with a
+// unique name, calls fn with a connection whose default schema is the
+// sandbox, e.g. to assert on the migrated schema, and drops the schema with
+// its objects afterwards, also when a migration or fn failed. It validates
+// the migrations against a shared database without leaving anything in it.
+//
+//	err := ex.Sandbox(ctx, db, dialect, source, func(ctx context.Context, conn *sql.Conn, schema string) error {
+//		_, err := conn.ExecContext(ctx, "INSERT INTO users (name) VALUES ('alice')")
+//		return err
+//	})
+//
+// The dialect must implement dialect.SchemaSelector and dialect.SchemaDropper.
+func (ex *MigrationExecutor) Sandbox(
+	ctx context.Context,
+	db *sql.DB,
+	d dialect.Dialect,
+	source MigrationSource,
+	fn func(ctx context.Context, conn *sql.Conn, schema string) error,
+) error {
+	selector, ok := d.(dialect.SchemaSelector)
+	if !ok {
+		return fmt.Errorf("dialect %T can't select the schema of a sandbox", d)
+	}
+
+	dropper, ok := d.(dialect.SchemaDropper)
+	if !ok {
+		return fmt.Errorf("dialect %T can't drop the schema of a sandbox", d)
+	}
+
+	schema, err := sandboxName()
+	if err != nil {
+		return err
+	}
+
+	sandbox := *ex
+	sandbox.CreateSchema = true
+	sandbox.CreateTable = true
+
+	return sandbox.inSchema(ctx, db, d, selector, schema, func(tenant *MigrationExecutor, conn *sql.Conn) error {
+		_, err := tenant.exec(ctx, db, conn, d, source, Up, 0, -1)
+		if err == nil {
+			err = fn(ctx, conn, schema)
+		}
+
+		_, dropErr := conn.ExecContext(context.Background(), dropper.QueryDropSchema(schema))
+		if dropErr != nil {
+			dropErr = fmt.Errorf("cannot drop sandbox %s: %w", schema, dropErr)
+		}
+
+		return errors.Join(err, dropErr)
+	})
+}
+
+// sandboxName returns a new schema name for Sandbox.
+func sandboxName() (string, error) {
+	b := make([]byte, 6)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return sandboxPrefix + hex.EncodeToString(b), nil
+}
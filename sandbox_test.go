@@ -0,0 +1,103 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// sandboxDialect drops the emulated schema of tenantDialect with its migration table.
+type sandboxDialect struct {
+	tenantDialect
+}
+
+func (sandboxDialect) QueryDropSchema(schemaName string) string {
+	return fmt.Sprintf(`DROP TABLE IF EXISTS "%s_%s"`, schemaName, defaultTableName)
+}
+
+type SandboxSuite struct {
+	db *sql.DB
+	ex *MigrationExecutor
+}
+
+var _ = Suite(&SandboxSuite{})
+
+func (s *SandboxSuite) SetUpTest(c *C) {
+	var err error
+	s.db, err = sql.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+
+	s.ex = NewMigrationExecutor()
+	s.ex.Logger = nullLogger{}
+}
+
+func (s *SandboxSuite) TearDownTest(c *C) {
+	c.Assert(s.db.Close(), IsNil)
+}
+
+func (s *SandboxSuite) tables(c *C) []string {
+	rows, err := s.db.Query("SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	c.Assert(err, IsNil)
+
+	defer rows.Close()
+
+	var tables []string
+
+	for rows.Next() {
+		var table string
+		c.Assert(rows.Scan(&table), IsNil)
+
+		tables = append(tables, table)
+	}
+
+	return tables
+}
+
+func (s *SandboxSuite) TestSandbox(c *C) {
+	d := sandboxDialect{tenantDialect{dialect.NewSqliteDialect()}}
+
+	var sandbox string
+
+	err := s.ex.Sandbox(context.Background(), s.db, d, NewMemoryMigrationSource(sqliteMigrations),
+		func(ctx context.Context, conn *sql.Conn, schema string) error {
+			sandbox = schema
+
+			var count int
+			err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM people").Scan(&count)
+			c.Assert(err, IsNil)
+			c.Assert(count, Equals, 0)
+
+			c.Assert(s.tables(c), DeepEquals, []string{"people", schema + "_migrations"})
+
+			return nil
+		})
+	c.Assert(err, IsNil)
+	c.Assert(strings.HasPrefix(sandbox, "sandbox_"), Equals, true)
+	c.Assert(s.tables(c), DeepEquals, []string{"people"})
+}
+
+func (s *SandboxSuite) TestSandboxDropsOnError(c *C) {
+	d := sandboxDialect{tenantDialect{dialect.NewSqliteDialect()}}
+	failure := errors.New("unexpected column")
+
+	err := s.ex.Sandbox(context.Background(), s.db, d, NewMemoryMigrationSource(sqliteMigrations[:1]),
+		func(context.Context, *sql.Conn, string) error {
+			return failure
+		})
+	c.Assert(errors.Is(err, failure), Equals, true)
+	c.Assert(s.tables(c), DeepEquals, []string{"people"})
+}
+
+func (s *SandboxSuite) TestSandboxUnsupportedDialect(c *C) {
+	err := s.ex.Sandbox(context.Background(), s.db, tenantDialect{dialect.NewSqliteDialect()},
+		NewMemoryMigrationSource(nil), func(context.Context, *sql.Conn, string) error { return nil })
+	c.Assert(err, ErrorMatches, ".*can't drop the schema of a sandbox")
+}
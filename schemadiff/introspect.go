@@ -0,0 +1,253 @@
+package schemadiff
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// ErrUnsupported is returned by Introspect for dialects whose schema it
+// can't read.
+var ErrUnsupported = errors.New("schemadiff: cannot introspect the schema of the dialect")
+
+// Introspect reads the tables, columns and indexes of the schema of the
+// database, from the catalog for PostgreSQL and from sqlite_master for
+// SQLite, where the schema is ignored. Indexes backing a constraint other
+// than the primary key, e.g. UNIQUE, are left out on SQLite.
+func Introspect(ctx context.Context, db *sql.DB, d dialect.Dialect, schema string) (*Schema, error) {
+	var (
+		s   *Schema
+		err error
+	)
+
+	switch d.(type) {
+	case *dialect.PostgresDialect:
+		s, err = introspectPostgres(ctx, db, schema)
+	case *dialect.SqliteDialect:
+		s, err = introspectSqlite(ctx, db)
+	default:
+		return nil, ErrUnsupported
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("schemadiff: cannot introspect the schema: %w", err)
+	}
+
+	s.Sort()
+
+	return s, nil
+}
+
+const postgresColumns = `
+SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull,
+	COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY c.relname, a.attnum`
+
+const postgresIndexes = `
+SELECT t.relname, i.relname, ix.indisprimary, ix.indisunique,
+	array_to_string(ARRAY(
+		SELECT pg_get_indexdef(ix.indexrelid, k + 1, true)
+		FROM generate_subscripts(ix.indkey, 1) AS k ORDER BY k), ',')
+FROM pg_index ix
+JOIN pg_class i ON i.oid = ix.indexrelid
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname = $1
+ORDER BY t.relname, i.relname`
+
+func introspectPostgres(ctx context.Context, db *sql.DB, schema string) (*Schema, error) {
+	if schema == "" {
+		schema = "public"
+	}
+
+	s := &Schema{}
+
+	rows, err := db.QueryContext(ctx, postgresColumns, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			table  string
+			column Column
+		)
+
+		if err := rows.Scan(&table, &column.Name, &column.Type, &column.Nullable, &column.Default); err != nil {
+			return nil, err
+		}
+
+		t := s.Table(table)
+		if t == nil {
+			s.Tables = append(s.Tables, Table{Name: table})
+			t = &s.Tables[len(s.Tables)-1]
+		}
+
+		t.Columns = append(t.Columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	indexes, err := db.QueryContext(ctx, postgresIndexes, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	defer indexes.Close()
+
+	for indexes.Next() {
+		var (
+			table, columns string
+			primary        bool
+			index          Index
+		)
+
+		if err := indexes.Scan(&table, &index.Name, &primary, &index.Unique, &columns); err != nil {
+			return nil, err
+		}
+
+		t := s.Table(table)
+		if t == nil {
+			continue
+		}
+
+		index.Columns = strings.Split(columns, ",")
+
+		if primary {
+			t.PrimaryKey = index.Columns
+		} else {
+			t.Indexes = append(t.Indexes, index)
+		}
+	}
+
+	return s, indexes.Err()
+}
+
+func introspectSqlite(ctx context.Context, db *sql.DB) (*Schema, error) {
+	names, err := queryStrings(ctx, db,
+		"SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{}
+
+	for _, name := range names {
+		table, err := introspectSqliteTable(ctx, db, name)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Tables = append(s.Tables, *table)
+	}
+
+	return s, nil
+}
+
+func introspectSqliteTable(ctx context.Context, db *sql.DB, name string) (*Table, error) {
+	table := &Table{Name: name}
+
+	rows, err := db.QueryContext(ctx, "SELECT name, type, \"notnull\", COALESCE(dflt_value, ''), pk FROM pragma_table_info(?) ORDER BY cid", name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var primaryKey []string
+
+	for rows.Next() {
+		var (
+			column  Column
+			notNull bool
+			pk      int
+		)
+
+		if err := rows.Scan(&column.Name, &column.Type, &notNull, &column.Default, &pk); err != nil {
+			return nil, err
+		}
+
+		column.Nullable = !notNull && pk == 0
+		table.Columns = append(table.Columns, column)
+
+		if pk > 0 {
+			for len(primaryKey) < pk {
+				primaryKey = append(primaryKey, "")
+			}
+
+			primaryKey[pk-1] = column.Name
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	table.PrimaryKey = primaryKey
+
+	// Only the indexes created by CREATE INDEX, not those of constraints.
+	indexes, err := db.QueryContext(ctx, "SELECT name, \"unique\" FROM pragma_index_list(?) WHERE origin = 'c' ORDER BY name", name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer indexes.Close()
+
+	for indexes.Next() {
+		var index Index
+		if err := indexes.Scan(&index.Name, &index.Unique); err != nil {
+			return nil, err
+		}
+
+		table.Indexes = append(table.Indexes, index)
+	}
+
+	if err := indexes.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range table.Indexes {
+		table.Indexes[i].Columns, err = queryStrings(ctx, db,
+			"SELECT COALESCE(name, '') FROM pragma_index_info(?) ORDER BY seqno", table.Indexes[i].Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return table, nil
+}
+
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var values []string
+
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
@@ -0,0 +1,462 @@
+// Package schemadiff compares the schema of a database, its tables, columns
+// and indexes, with a declarative snapshot or with another database, and
+// drafts the migration bringing the database to the snapshot:
+//
+//	current, err := schemadiff.Introspect(ctx, db, dialect, "public")
+//	if err != nil {
+//		return err
+//	}
+//	current.Exclude("migrations")
+//
+//	desired, err := schemadiff.Load(f)
+//	if err != nil {
+//		return err
+//	}
+//
+//	draft := schemadiff.Draft(schemadiff.Diff(current, desired))
+//
+// The draft is written for PostgreSQL and meant to be reviewed: renames show
+// as a drop and an add, and data isn't migrated.
+package schemadiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Schema is the tables of a database, sorted by name.
+type Schema struct {
+	Tables []Table `json:"tables"`
+}
+
+// Table is a table with its columns, in order, and its indexes, sorted by
+// name. The index of the primary key is PrimaryKey, not one of Indexes.
+type Table struct {
+	Name       string   `json:"name"`
+	Columns    []Column `json:"columns"`
+	PrimaryKey []string `json:"primary_key,omitempty"`
+	Indexes    []Index  `json:"indexes,omitempty"`
+}
+
+// Column is a column of a table. Type and Default are SQL, as the database
+// reports them.
+type Column struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable,omitempty"`
+	Default  string `json:"default,omitempty"`
+}
+
+// Index is an index of a table on columns or expressions, in order.
+type Index struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+}
+
+// Load reads a snapshot written by Save.
+func Load(r io.Reader) (*Schema, error) {
+	var s Schema
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("schemadiff: cannot read the snapshot: %w", err)
+	}
+
+	s.Sort()
+
+	return &s, nil
+}
+
+// Save writes the schema as an indented JSON snapshot, to be edited and
+// loaded back by Load.
+func (s *Schema) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(s)
+}
+
+// Table returns the table with the name, or nil.
+func (s *Schema) Table(name string) *Table {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i]
+		}
+	}
+
+	return nil
+}
+
+// Exclude removes the tables with the names, e.g. the migration table.
+func (s *Schema) Exclude(names ...string) {
+	tables := s.Tables[:0]
+
+	for _, table := range s.Tables {
+		excluded := false
+
+		for _, name := range names {
+			if table.Name == name {
+				excluded = true
+			}
+		}
+
+		if !excluded {
+			tables = append(tables, table)
+		}
+	}
+
+	s.Tables = tables
+}
+
+// Sort sorts the tables and their indexes by name, as Diff expects them.
+func (s *Schema) Sort() {
+	sort.Slice(s.Tables, func(i, j int) bool { return s.Tables[i].Name < s.Tables[j].Name })
+
+	for _, table := range s.Tables {
+		sort.Slice(table.Indexes, func(i, j int) bool { return table.Indexes[i].Name < table.Indexes[j].Name })
+	}
+}
+
+func (t *Table) column(name string) *Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+
+	return nil
+}
+
+func (t *Table) index(name string) *Index {
+	for i := range t.Indexes {
+		if t.Indexes[i].Name == name {
+			return &t.Indexes[i]
+		}
+	}
+
+	return nil
+}
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	CreateTable ChangeKind = iota
+	AddColumn
+	AlterColumn
+	DropIndex
+	CreateIndex
+	DropColumn
+	DropTable
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case CreateTable:
+		return "create table"
+	case AddColumn:
+		return "add column"
+	case AlterColumn:
+		return "alter column"
+	case DropIndex:
+		return "drop index"
+	case CreateIndex:
+		return "create index"
+	case DropColumn:
+		return "drop column"
+	case DropTable:
+		return "drop table"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Change is a difference between two schemas. Table is the table before the
+// change, nil when it's created, and the column or index fields hold the
+// definitions before and after the change, nil when there is none.
+type Change struct {
+	Kind  ChangeKind
+	Table string
+
+	FromTable  *Table
+	ToTable    *Table
+	FromColumn *Column
+	ToColumn   *Column
+	FromIndex  *Index
+	ToIndex    *Index
+}
+
+// Diff returns the changes bringing from to to, tables and columns created
+// first and dropped last, so their indexes can be created and dropped in
+// between.
+func Diff(from, to *Schema) []Change {
+	var changes []Change
+
+	for i := range to.Tables {
+		toTable := &to.Tables[i]
+
+		fromTable := from.Table(toTable.Name)
+		if fromTable == nil {
+			changes = append(changes, Change{Kind: CreateTable, Table: toTable.Name, ToTable: toTable})
+
+			for j := range toTable.Indexes {
+				changes = append(changes, Change{Kind: CreateIndex, Table: toTable.Name, ToIndex: &toTable.Indexes[j]})
+			}
+
+			continue
+		}
+
+		changes = append(changes, diffTable(fromTable, toTable)...)
+	}
+
+	for i := range from.Tables {
+		fromTable := &from.Tables[i]
+
+		if to.Table(fromTable.Name) == nil {
+			for j := range fromTable.Indexes {
+				changes = append(changes, Change{Kind: DropIndex, Table: fromTable.Name, FromIndex: &fromTable.Indexes[j]})
+			}
+
+			changes = append(changes, Change{Kind: DropTable, Table: fromTable.Name, FromTable: fromTable})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Kind < changes[j].Kind })
+
+	return changes
+}
+
+func diffTable(from, to *Table) []Change {
+	var changes []Change
+
+	for i := range to.Columns {
+		toColumn := &to.Columns[i]
+
+		fromColumn := from.column(toColumn.Name)
+		switch {
+		case fromColumn == nil:
+			changes = append(changes, Change{Kind: AddColumn, Table: to.Name, ToColumn: toColumn})
+		case *fromColumn != *toColumn:
+			changes = append(changes, Change{Kind: AlterColumn, Table: to.Name, FromColumn: fromColumn, ToColumn: toColumn})
+		}
+	}
+
+	for i := range from.Columns {
+		if to.column(from.Columns[i].Name) == nil {
+			changes = append(changes, Change{Kind: DropColumn, Table: to.Name, FromColumn: &from.Columns[i]})
+		}
+	}
+
+	for i := range to.Indexes {
+		toIndex := &to.Indexes[i]
+
+		fromIndex := from.index(toIndex.Name)
+		if fromIndex != nil && sameIndex(fromIndex, toIndex) {
+			continue
+		}
+
+		if fromIndex != nil {
+			changes = append(changes, Change{Kind: DropIndex, Table: to.Name, FromIndex: fromIndex})
+		}
+
+		changes = append(changes, Change{Kind: CreateIndex, Table: to.Name, ToIndex: toIndex})
+	}
+
+	for i := range from.Indexes {
+		if to.index(from.Indexes[i].Name) == nil {
+			changes = append(changes, Change{Kind: DropIndex, Table: to.Name, FromIndex: &from.Indexes[i]})
+		}
+	}
+
+	return changes
+}
+
+func sameIndex(a, b *Index) bool {
+	return a.Unique == b.Unique && strings.Join(a.Columns, "\x00") == strings.Join(b.Columns, "\x00")
+}
+
+// Up returns the statements making the change.
+func (c Change) Up() []string {
+	switch c.Kind {
+	case CreateTable:
+		return []string{createTable(c.ToTable)}
+	case DropTable:
+		return []string{"DROP TABLE " + quote(c.Table) + ";"}
+	case AddColumn:
+		return []string{"ALTER TABLE " + quote(c.Table) + " ADD COLUMN " + columnDefinition(c.ToColumn) + ";"}
+	case DropColumn:
+		return []string{"ALTER TABLE " + quote(c.Table) + " DROP COLUMN " + quote(c.FromColumn.Name) + ";"}
+	case AlterColumn:
+		return alterColumn(c.Table, c.FromColumn, c.ToColumn)
+	case CreateIndex:
+		return []string{createIndex(c.Table, c.ToIndex)}
+	case DropIndex:
+		return []string{"DROP INDEX " + quote(c.FromIndex.Name) + ";"}
+	default:
+		return nil
+	}
+}
+
+// Down returns the statements undoing the change.
+func (c Change) Down() []string {
+	reverse := Change{
+		Table:      c.Table,
+		FromTable:  c.ToTable,
+		ToTable:    c.FromTable,
+		FromColumn: c.ToColumn,
+		ToColumn:   c.FromColumn,
+		FromIndex:  c.ToIndex,
+		ToIndex:    c.FromIndex,
+	}
+
+	switch c.Kind {
+	case CreateTable:
+		reverse.Kind = DropTable
+	case DropTable:
+		reverse.Kind = CreateTable
+	case AddColumn:
+		reverse.Kind = DropColumn
+	case DropColumn:
+		reverse.Kind = AddColumn
+	case CreateIndex:
+		reverse.Kind = DropIndex
+	case DropIndex:
+		reverse.Kind = CreateIndex
+	default:
+		reverse.Kind = c.Kind
+	}
+
+	return reverse.Up()
+}
+
+// Draft renders the changes as a migration file, the Down section undoing
+// them in the reverse order. The draft is empty when there is no change.
+func Draft(changes []Change) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+
+	b.WriteString("-- Drafted by schemadiff, review it before applying: renames show as a drop\n")
+	b.WriteString("-- and an add, and data isn't migrated.\n")
+	b.WriteString("-- +migrate Up\n")
+
+	for _, change := range changes {
+		for _, stmt := range change.Up() {
+			b.WriteString(stmt + "\n")
+		}
+	}
+
+	// No blank line before Down, the parser would keep it in the statement.
+	b.WriteString("-- +migrate Down\n")
+
+	for i := len(changes) - 1; i >= 0; i-- {
+		for _, stmt := range changes[i].Down() {
+			b.WriteString(stmt + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// SQL renders the schema as the DDL creating it, each table followed by its
+// indexes, e.g. for a dump of the schema. Like the drafts, it's written for
+// PostgreSQL.
+func (s *Schema) SQL() string {
+	var b strings.Builder
+
+	for i := range s.Tables {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		table := &s.Tables[i]
+		b.WriteString(createTable(table) + "\n")
+
+		for j := range table.Indexes {
+			b.WriteString(createIndex(table.Name, &table.Indexes[j]) + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+func createTable(t *Table) string {
+	lines := make([]string, 0, len(t.Columns)+1)
+	for i := range t.Columns {
+		lines = append(lines, "    "+columnDefinition(&t.Columns[i]))
+	}
+
+	if len(t.PrimaryKey) > 0 {
+		lines = append(lines, "    PRIMARY KEY ("+quoteAll(t.PrimaryKey)+")")
+	}
+
+	return "CREATE TABLE " + quote(t.Name) + " (\n" + strings.Join(lines, ",\n") + "\n);"
+}
+
+func columnDefinition(c *Column) string {
+	definition := quote(c.Name) + " " + c.Type
+	if !c.Nullable {
+		definition += " NOT NULL"
+	}
+
+	if c.Default != "" {
+		definition += " DEFAULT " + c.Default
+	}
+
+	return definition
+}
+
+func alterColumn(table string, from, to *Column) []string {
+	prefix := "ALTER TABLE " + quote(table) + " ALTER COLUMN " + quote(to.Name)
+
+	var stmts []string
+
+	if from.Type != to.Type {
+		stmts = append(stmts, prefix+" TYPE "+to.Type+";")
+	}
+
+	if from.Nullable != to.Nullable {
+		if to.Nullable {
+			stmts = append(stmts, prefix+" DROP NOT NULL;")
+		} else {
+			stmts = append(stmts, prefix+" SET NOT NULL;")
+		}
+	}
+
+	if from.Default != to.Default {
+		if to.Default == "" {
+			stmts = append(stmts, prefix+" DROP DEFAULT;")
+		} else {
+			stmts = append(stmts, prefix+" SET DEFAULT "+to.Default+";")
+		}
+	}
+
+	return stmts
+}
+
+func createIndex(table string, index *Index) string {
+	create := "CREATE INDEX "
+	if index.Unique {
+		create = "CREATE UNIQUE INDEX "
+	}
+
+	// The columns may be expressions, which aren't quoted.
+	return create + quote(index.Name) + " ON " + quote(table) + " (" + strings.Join(index.Columns, ", ") + ");"
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteAll(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quote(name)
+	}
+
+	return strings.Join(quoted, ", ")
+}
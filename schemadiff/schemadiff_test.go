@@ -0,0 +1,159 @@
+package schemadiff
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type SchemaDiffSuite struct {
+	db *sql.DB
+}
+
+var _ = Suite(&SchemaDiffSuite{})
+
+func (s *SchemaDiffSuite) SetUpTest(c *C) {
+	var err error
+	s.db, err = sql.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+
+	_, err = s.db.Exec(`
+		CREATE TABLE users (id integer PRIMARY KEY, email text NOT NULL UNIQUE, name text DEFAULT 'anonymous');
+		CREATE INDEX users_name ON users (name);
+		CREATE TABLE migrations (id text PRIMARY KEY);
+		CREATE TABLE old (id integer)`)
+	c.Assert(err, IsNil)
+}
+
+func (s *SchemaDiffSuite) TearDownTest(c *C) {
+	c.Assert(s.db.Close(), IsNil)
+}
+
+func (s *SchemaDiffSuite) TestIntrospect(c *C) {
+	schema, err := Introspect(context.Background(), s.db, &dialect.SqliteDialect{}, "")
+	c.Assert(err, IsNil)
+
+	schema.Exclude("migrations")
+
+	c.Assert(schema, DeepEquals, &Schema{Tables: []Table{
+		{Name: "old", Columns: []Column{{Name: "id", Type: "INTEGER", Nullable: true}}},
+		{
+			Name: "users",
+			Columns: []Column{
+				{Name: "id", Type: "INTEGER"},
+				{Name: "email", Type: "TEXT"},
+				{Name: "name", Type: "TEXT", Nullable: true, Default: "'anonymous'"},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes:    []Index{{Name: "users_name", Columns: []string{"name"}}},
+		},
+	}})
+
+	_, err = Introspect(context.Background(), s.db, &dialect.MySQLDialect{}, "")
+	c.Assert(err, Equals, ErrUnsupported)
+}
+
+func (s *SchemaDiffSuite) TestSaveLoad(c *C) {
+	schema, err := Introspect(context.Background(), s.db, &dialect.SqliteDialect{}, "")
+	c.Assert(err, IsNil)
+
+	var buf bytes.Buffer
+	c.Assert(schema.Save(&buf), IsNil)
+
+	loaded, err := Load(&buf)
+	c.Assert(err, IsNil)
+	c.Assert(loaded, DeepEquals, schema)
+	c.Assert(Diff(schema, loaded), HasLen, 0)
+	c.Assert(Draft(nil), Equals, "")
+}
+
+func (s *SchemaDiffSuite) TestDiff(c *C) {
+	current, err := Introspect(context.Background(), s.db, &dialect.SqliteDialect{}, "")
+	c.Assert(err, IsNil)
+
+	current.Exclude("migrations")
+
+	desired, err := Load(strings.NewReader(`{"tables": [
+		{"name": "users", "columns": [
+			{"name": "id", "type": "INTEGER"},
+			{"name": "email", "type": "varchar(255)"},
+			{"name": "created_at", "type": "timestamp", "default": "now()"}
+		], "primary_key": ["id"], "indexes": [{"name": "users_email", "columns": ["lower(email)"], "unique": true}]},
+		{"name": "orders", "columns": [{"name": "id", "type": "bigint"}], "primary_key": ["id"]}
+	]}`))
+	c.Assert(err, IsNil)
+
+	changes := Diff(current, desired)
+
+	kinds := make([]ChangeKind, len(changes))
+	for i, change := range changes {
+		kinds[i] = change.Kind
+	}
+
+	c.Assert(kinds, DeepEquals, []ChangeKind{CreateTable, AddColumn, AlterColumn, DropIndex, CreateIndex, DropColumn, DropTable})
+
+	draft := Draft(changes)
+	c.Assert(draft, Equals, `-- Drafted by schemadiff, review it before applying: renames show as a drop
+-- and an add, and data isn't migrated.
+-- +migrate Up
+CREATE TABLE "orders" (
+    "id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+ALTER TABLE "users" ADD COLUMN "created_at" timestamp NOT NULL DEFAULT now();
+ALTER TABLE "users" ALTER COLUMN "email" TYPE varchar(255);
+DROP INDEX "users_name";
+CREATE UNIQUE INDEX "users_email" ON "users" (lower(email));
+ALTER TABLE "users" DROP COLUMN "name";
+DROP TABLE "old";
+-- +migrate Down
+CREATE TABLE "old" (
+    "id" INTEGER
+);
+ALTER TABLE "users" ADD COLUMN "name" TEXT DEFAULT 'anonymous';
+DROP INDEX "users_email";
+CREATE INDEX "users_name" ON "users" (name);
+ALTER TABLE "users" ALTER COLUMN "email" TYPE TEXT;
+ALTER TABLE "users" DROP COLUMN "created_at";
+DROP TABLE "orders";
+`)
+
+	// The draft is a migration file.
+	parsed, err := sqlparse.ParseMigration(strings.NewReader(draft))
+	c.Assert(err, IsNil)
+	c.Assert(parsed.UpStatements, HasLen, 7)
+	c.Assert(parsed.DownStatements, HasLen, 7)
+}
+
+func (s *SchemaDiffSuite) TestSQL(c *C) {
+	schema, err := Introspect(context.Background(), s.db, &dialect.SqliteDialect{}, "")
+	c.Assert(err, IsNil)
+
+	schema.Exclude("migrations")
+
+	c.Assert(schema.SQL(), Equals, `CREATE TABLE "old" (
+    "id" INTEGER
+);
+
+CREATE TABLE "users" (
+    "id" INTEGER NOT NULL,
+    "email" TEXT NOT NULL,
+    "name" TEXT DEFAULT 'anonymous',
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "users_name" ON "users" (name);
+`)
+}
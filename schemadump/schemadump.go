@@ -0,0 +1,241 @@
+// Package schemadump writes the schema of a database to a canonical file,
+// e.g. schema.sql, after migrating. Committed to the repository, the file
+// shows reviewers the net change of the schema in the diff of a branch:
+//
+//	n, err := ex.ExecContext(ctx, db, d, source, migrate.Up)
+//	if err != nil {
+//		return err
+//	}
+//
+//	dumper := &schemadump.PgDump{DataSource: dsn, Exclude: []string{"migrations"}}
+//	changed, err := schemadump.WriteFile(ctx, dumper, "schema.sql")
+//
+// PgDump and MySQLDump run the client tools of the databases, Introspect
+// renders the tables and indexes read from the catalog without any tool.
+package schemadump
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/schemadiff"
+)
+
+// Dumper dumps the schema of a database.
+type Dumper interface {
+	Dump(ctx context.Context) ([]byte, error)
+}
+
+var (
+	_ Dumper = (*PgDump)(nil)
+	_ Dumper = (*MySQLDump)(nil)
+	_ Dumper = (*Introspect)(nil)
+)
+
+// WriteFile dumps the schema to the file, only rewriting it when the schema
+// changed, and tells whether it did.
+func WriteFile(ctx context.Context, d Dumper, path string) (bool, error) {
+	dump, err := d.Dump(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	existing, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(existing, dump) {
+		return false, nil
+	}
+
+	// The file is replaced at once, a failed dump leaves the previous one.
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return false, err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(dump); err != nil {
+		_ = tmp.Close()
+		return false, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return false, err
+	}
+
+	return true, os.Rename(tmp.Name(), path)
+}
+
+// PgDump dumps the schema with pg_dump --schema-only, without owners nor
+// privileges, which differ between databases. The lines naming the versions
+// of the server and of pg_dump are removed.
+type PgDump struct {
+	// DataSource is the connection string, a URL or key=value pairs.
+	DataSource string
+	// Schema restricts the dump to a schema, all of them when empty.
+	Schema string
+	// Exclude are the tables left out, e.g. the migration table.
+	Exclude []string
+	// Command is the pg_dump binary, pg_dump from the PATH when empty.
+	Command string
+}
+
+// pgDumpNoise matches the lines of pg_dump which change without the schema:
+// the versions, and the random keys of \restrict and \unrestrict.
+var pgDumpNoise = regexp.MustCompile(`(?m)^(-- Dumped (from database|by pg_dump) version .*|\\(un)?restrict .*)\n`)
+
+func (p *PgDump) Dump(ctx context.Context) ([]byte, error) {
+	args := []string{"--schema-only", "--no-owner", "--no-privileges", "--dbname", p.DataSource}
+	if p.Schema != "" {
+		args = append(args, "--schema", p.Schema)
+	}
+
+	for _, table := range p.Exclude {
+		args = append(args, "--exclude-table", table)
+	}
+
+	out, err := run(ctx, command(p.Command, "pg_dump"), args, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return canonical(pgDumpNoise.ReplaceAll(out, nil)), nil
+}
+
+// MySQLDump dumps the schema with mysqldump --no-data, without comments nor
+// the counters of AUTO_INCREMENT, which differ between databases.
+type MySQLDump struct {
+	// DataSource is the connection string of go-sql-driver/mysql.
+	DataSource string
+	// Exclude are the tables left out, e.g. the migration table.
+	Exclude []string
+	// Command is the mysqldump binary, mysqldump from the PATH when empty.
+	Command string
+}
+
+var mysqlAutoIncrement = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
+func (m *MySQLDump) Dump(ctx context.Context) ([]byte, error) {
+	cfg, err := mysql.ParseDSN(m.DataSource)
+	if err != nil {
+		return nil, fmt.Errorf("schemadump: %w", err)
+	}
+
+	if cfg.DBName == "" {
+		return nil, errors.New("schemadump: the data source names no database")
+	}
+
+	args := []string{"--no-data", "--routines", "--skip-comments", "--skip-add-drop-table", "--single-transaction"}
+
+	switch cfg.Net {
+	case "unix":
+		args = append(args, "--socket", cfg.Addr)
+	default:
+		host, port, found := strings.Cut(cfg.Addr, ":")
+		args = append(args, "--protocol", "tcp", "--host", host)
+
+		if found {
+			args = append(args, "--port", port)
+		}
+	}
+
+	if cfg.User != "" {
+		args = append(args, "--user", cfg.User)
+	}
+
+	for _, table := range m.Exclude {
+		args = append(args, "--ignore-table", cfg.DBName+"."+table)
+	}
+
+	args = append(args, cfg.DBName)
+
+	// The password is kept out of the arguments of the process.
+	var environ []string
+	if cfg.Passwd != "" {
+		environ = append(environ, "MYSQL_PWD="+cfg.Passwd)
+	}
+
+	out, err := run(ctx, command(m.Command, "mysqldump"), args, environ)
+	if err != nil {
+		return nil, err
+	}
+
+	return canonical(mysqlAutoIncrement.ReplaceAll(out, nil)), nil
+}
+
+// Introspect renders the tables, columns and indexes read by
+// schemadiff.Introspect, for PostgreSQL and SQLite, without any client tool.
+// Views, functions and constraints other than the primary key aren't dumped.
+type Introspect struct {
+	DB      *sql.DB
+	Dialect dialect.Dialect
+	// Schema is the schema of the tables, public when empty on PostgreSQL.
+	Schema string
+	// Exclude are the tables left out, e.g. the migration table.
+	Exclude []string
+}
+
+func (i *Introspect) Dump(ctx context.Context) ([]byte, error) {
+	schema, err := schemadiff.Introspect(ctx, i.DB, i.Dialect, i.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	schema.Exclude(i.Exclude...)
+
+	return canonical([]byte(schema.SQL())), nil
+}
+
+func command(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+
+	return name
+}
+
+// run runs the command and returns its output, or an error with its
+// standard error.
+func run(ctx context.Context, name string, args, environ []string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), environ...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("schemadump: %s failed: %w: %s", name, err, msg)
+		}
+
+		return nil, fmt.Errorf("schemadump: %s failed: %w", name, err)
+	}
+
+	return out, nil
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// canonical trims the dump and collapses the runs of blank lines, so the file
+// only changes with the schema.
+func canonical(dump []byte) []byte {
+	dump = bytes.ReplaceAll(dump, []byte("\r\n"), []byte("\n"))
+	dump = blankLines.ReplaceAll(dump, []byte("\n\n"))
+
+	return append(bytes.TrimSpace(dump), '\n')
+}
@@ -0,0 +1,89 @@
+package schemadump
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type DumpSuite struct{}
+
+var _ = Suite(&DumpSuite{})
+
+// fakeTool writes a script printing its arguments, MYSQL_PWD and the output.
+func fakeTool(c *C, output string) string {
+	path := filepath.Join(c.MkDir(), "tool")
+	script := "#!/bin/sh\necho \"-- args: $*\"\necho \"-- password: $MYSQL_PWD\"\ncat <<'EOF'\n" + output + "EOF\n"
+	c.Assert(os.WriteFile(path, []byte(script), 0o755), IsNil)
+
+	return path
+}
+
+func (*DumpSuite) TestIntrospect(c *C) {
+	dir := c.MkDir()
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE people (id integer PRIMARY KEY); CREATE TABLE migrations (id text PRIMARY KEY)")
+	c.Assert(err, IsNil)
+
+	dumper := &Introspect{DB: db, Dialect: dialect.NewSqliteDialect(), Exclude: []string{"migrations"}}
+	path := filepath.Join(dir, "schema.sql")
+
+	changed, err := WriteFile(context.Background(), dumper, path)
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, true)
+
+	content, err := os.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "CREATE TABLE \"people\" (\n    \"id\" INTEGER NOT NULL,\n    PRIMARY KEY (\"id\")\n);\n")
+
+	changed, err = WriteFile(context.Background(), dumper, path)
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, false)
+
+	_, err = db.Exec("CREATE TABLE pets (id integer)")
+	c.Assert(err, IsNil)
+
+	changed, err = WriteFile(context.Background(), dumper, path)
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, true)
+}
+
+func (*DumpSuite) TestPgDump(c *C) {
+	tool := fakeTool(c, "--\n-- Dumped from database version 16.2\n-- Dumped by pg_dump version 16.2\n\\restrict abc\n\n\n\nCREATE TABLE public.people (id integer);\n\\unrestrict abc\n")
+
+	dump, err := (&PgDump{DataSource: "dbname=app", Schema: "public", Exclude: []string{"migrations"}, Command: tool}).Dump(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(string(dump), Equals, "-- args: --schema-only --no-owner --no-privileges --dbname dbname=app --schema public --exclude-table migrations\n"+
+		"-- password: \n--\n\nCREATE TABLE public.people (id integer);\n")
+
+	_, err = (&PgDump{DataSource: "dbname=app", Command: filepath.Join(c.MkDir(), "missing")}).Dump(context.Background())
+	c.Assert(err, ErrorMatches, "schemadump: .*missing failed: .*")
+}
+
+func (*DumpSuite) TestMySQLDump(c *C) {
+	tool := fakeTool(c, "CREATE TABLE `people` (`id` int NOT NULL AUTO_INCREMENT) ENGINE=InnoDB AUTO_INCREMENT=42;\n")
+
+	dump, err := (&MySQLDump{DataSource: "app:secret@tcp(db:3306)/shop", Exclude: []string{"migrations"}, Command: tool}).Dump(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(string(dump), Equals, "-- args: --no-data --routines --skip-comments --skip-add-drop-table --single-transaction "+
+		"--protocol tcp --host db --port 3306 --user app --ignore-table shop.migrations shop\n"+
+		"-- password: secret\nCREATE TABLE `people` (`id` int NOT NULL AUTO_INCREMENT) ENGINE=InnoDB;\n")
+
+	_, err = (&MySQLDump{DataSource: "app@tcp(db:3306)/", Command: tool}).Dump(context.Background())
+	c.Assert(err, ErrorMatches, "schemadump: the data source names no database")
+}
@@ -0,0 +1,80 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// defaultSeedTableName is the table tracking the applied seeds.
+const defaultSeedTableName = "seeds"
+
+// SeedExecutor applies seed scripts, e.g. reference data, apart from the
+// schema migrations: the seeds are files like migrations, usually in a seeds
+// directory, tracked in their own table. Only their Up section runs.
+//
+// A seed is applied once, and again whenever it changed, so seeds should be
+// idempotent, e.g. INSERT ... ON CONFLICT DO UPDATE. Changes are told by the
+// checksum of the seeds, recorded with the dialects implementing
+// dialect.ColumnRecorder; with the others a seed is applied once. Seeds
+// removed from the source are ignored.
+type SeedExecutor struct {
+	// Executor applies the seeds, with the seed table as migration table.
+	Executor *MigrationExecutor
+}
+
+// NewSeedExecutor returns a SeedExecutor tracking the seeds in the seeds
+// table, which it creates.
+func NewSeedExecutor() *SeedExecutor {
+	ex := NewMigrationExecutor()
+	ex.TableName = defaultSeedTableName
+	ex.CreateTable = true
+	ex.IgnoreUnknown = true
+
+	return &SeedExecutor{Executor: ex}
+}
+
+// Seed applies the new and the changed seeds of the source, in order, and
+// returns their number.
+func (s *SeedExecutor) Seed(ctx context.Context, db *sql.DB, dialect dialect.Dialect, source MigrationSource) (int, error) {
+	ex := s.Executor
+
+	seeds, err := source.FindMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	rep, err := ex.getMigrationRepository(ctx, db, dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	records, err := rep.ListMigration(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := make(map[string]MigrationRecord, len(records))
+	for _, record := range records {
+		applied[record.Id] = record
+	}
+
+	// A changed seed is forgotten, to be applied again with the new ones.
+	for _, seed := range seeds {
+		record, ok := applied[seed.Id]
+		if !ok || !record.Verifiable() || record.Checksum == seed.Checksum() {
+			continue
+		}
+
+		if err := rep.DeleteMigration(ctx, seed.Id); err != nil {
+			return 0, fmt.Errorf("Cannot forget the changed seed %s: %w", seed.Id, err)
+		}
+
+		logWith(ctx, ex.Logger, LevelInfo, fmt.Sprintf("Seed %s changed, applying it again", seed.Id),
+			Field{"migration_id", seed.Id})
+	}
+
+	return ex.ExecMaxContext(ctx, db, dialect, NewMemoryMigrationSource(seeds), Up, 0)
+}
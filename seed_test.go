@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"context"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func (s *SqliteMigrateSuite) TestSeed(c *C) {
+	ctx := context.Background()
+
+	_, err := s.db.Exec("CREATE TABLE colors (name text primary key, hex text)")
+	c.Assert(err, IsNil)
+
+	seeds := []*Migration{
+		{Id: "1_colors.sql", Up: []string{"INSERT INTO colors VALUES ('red', '#f00') ON CONFLICT (name) DO UPDATE SET hex = excluded.hex"}},
+		{Id: "2_more_colors.sql", Up: []string{"INSERT OR IGNORE INTO colors VALUES ('blue', '#00f')"}},
+	}
+
+	seeder := NewSeedExecutor()
+	seeder.Executor.Logger = s.ex.Logger
+
+	n, err := seeder.Seed(ctx, s.db, s.dialect, NewMemoryMigrationSource(seeds))
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	n, err = seeder.Seed(ctx, s.db, s.dialect, NewMemoryMigrationSource(seeds))
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 0)
+
+	// The changed seed is applied again, before the later seeds too.
+	changed := []*Migration{
+		{Id: "1_colors.sql", Up: []string{"INSERT INTO colors VALUES ('red', '#ff0000') ON CONFLICT (name) DO UPDATE SET hex = excluded.hex"}},
+		seeds[1],
+	}
+
+	n, err = seeder.Seed(ctx, s.db, s.dialect, NewMemoryMigrationSource(changed))
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	// The seeds removed are ignored.
+	n, err = seeder.Seed(ctx, s.db, s.dialect, NewMemoryMigrationSource(changed[:1]))
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 0)
+
+	var hex string
+	c.Assert(s.db.QueryRow("SELECT hex FROM colors WHERE name = 'red'").Scan(&hex), IsNil)
+	c.Assert(hex, Equals, "#ff0000")
+
+	// The seeds don't touch the migration table.
+	records, err := s.ex.GetMigrationRecords(ctx, s.db, s.dialect)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 0)
+}
@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// execFlights coalesces concurrent runs of all executors of the process.
+var execFlights = &flightGroup{}
+
+type flightCall struct {
+	done    chan struct{}
+	applied int
+	err     error
+}
+
+// flightGroup runs a function once per key for all concurrent callers.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do runs fn unless a call with the same key is in flight, in which case it waits
+// for that call and returns its result. Waiting stops early when ctx is done.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (int, error)) (int, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+
+		select {
+		case <-call.done:
+			return call.applied, call.err
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	call.applied, call.err = fn()
+
+	return call.applied, call.err
+}
+
+// flightKey identifies runs which can share their result.
+func (ex *MigrationExecutor) flightKey(conn SqlDB, source MigrationSource, dir MigrationDirection, max int, version int64) string {
+	sourceID := fmt.Sprintf("%T", source)
+	if v := reflect.ValueOf(source); v.Kind() == reflect.Pointer {
+		sourceID += fmt.Sprintf("@%x", v.Pointer())
+	}
+
+	return fmt.Sprintf("%p|%s|%s|%s|%d|%d|%d", conn, sourceID, ex.SchemaName, ex.TableName, dir, max, version)
+}
@@ -0,0 +1,93 @@
+// Package slogadapter logs the migrations of sql-migrate with log/slog.
+//
+//	ex := migrate.NewMigrationExecutor()
+//	ex.Logger = slogadapter.New(slog.Default())
+//
+// The structured fields of the executor, such as migration_id, direction,
+// duration and statements, become attributes of the records.
+package slogadapter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+// LevelTrace is the level of the traced queries, below slog.LevelDebug.
+const LevelTrace = slog.LevelDebug - 4
+
+var (
+	_ migrate.FieldLogger  = (*Logger)(nil)
+	_ migrate.LevelEnabler = (*Logger)(nil)
+)
+
+// Logger implements migrate.FieldLogger over a slog.Logger.
+type Logger struct {
+	logger *slog.Logger
+}
+
+// New returns a Logger writing to logger, or to slog.Default() when logger is nil.
+func New(logger *slog.Logger) *Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Logger{logger: logger}
+}
+
+func (l *Logger) Tracef(format string, v ...any) {
+	l.logger.Log(context.Background(), LevelTrace, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Infof(format string, v ...any) {
+	l.logger.Log(context.Background(), slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Errorf(format string, v ...any) {
+	l.logger.Log(context.Background(), slog.LevelError, fmt.Sprintf(format, v...))
+}
+
+// Log logs the message with the fields as attributes.
+func (l *Logger) Log(ctx context.Context, level migrate.LogLevel, msg string, fields ...migrate.Field) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, attr(f))
+	}
+
+	l.logger.LogAttrs(ctx, Level(level), msg, attrs...)
+}
+
+// Enabled reports whether the slog handler takes records of the level.
+func (l *Logger) Enabled(ctx context.Context, level migrate.LogLevel) bool {
+	return l.logger.Enabled(ctx, Level(level))
+}
+
+// Level maps the levels of the executor onto slog levels.
+func Level(level migrate.LogLevel) slog.Level {
+	switch level {
+	case migrate.LevelTrace:
+		return LevelTrace
+	case migrate.LevelWarn:
+		return slog.LevelWarn
+	case migrate.LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func attr(f migrate.Field) slog.Attr {
+	switch v := f.Value.(type) {
+	case time.Duration:
+		return slog.Duration(f.Key, v)
+	case error:
+		return slog.String(f.Key, v.Error())
+	case fmt.Stringer:
+		return slog.String(f.Key, v.String())
+	default:
+		return slog.Any(f.Key, v)
+	}
+}
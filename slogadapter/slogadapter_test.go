@@ -0,0 +1,58 @@
+package slogadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type SlogSuite struct{}
+
+var _ = Suite(&SlogSuite{})
+
+func (*SlogSuite) TestLog(c *C) {
+	var buf bytes.Buffer
+
+	logger := New(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger.Tracef("SELECT %d", 1)
+	logger.Log(context.Background(), migrate.LevelError, "Failed to apply migration 1_init.sql",
+		migrate.Field{Key: "migration_id", Value: "1_init.sql"},
+		migrate.Field{Key: "direction", Value: migrate.Up},
+		migrate.Field{Key: "duration", Value: 2 * time.Second},
+		migrate.Field{Key: "statements", Value: 3},
+		migrate.Field{Key: "error", Value: errors.New("syntax error")},
+	)
+
+	var record map[string]any
+	c.Assert(json.Unmarshal(buf.Bytes(), &record), IsNil)
+
+	delete(record, "time")
+	c.Assert(record, DeepEquals, map[string]any{
+		"level":        "ERROR",
+		"msg":          "Failed to apply migration 1_init.sql",
+		"migration_id": "1_init.sql",
+		"direction":    "up",
+		"duration":     float64(2 * time.Second),
+		"statements":   float64(3),
+		"error":        "syntax error",
+	})
+}
+
+func (*SlogSuite) TestEnabled(c *C) {
+	logger := New(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	c.Assert(logger.Enabled(context.Background(), migrate.LevelTrace), Equals, false)
+	c.Assert(logger.Enabled(context.Background(), migrate.LevelInfo), Equals, true)
+}
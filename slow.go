@@ -0,0 +1,42 @@
+package migrate
+
+import (
+	`context`
+	`fmt`
+	`time`
+)
+
+// watchStatement warns when the named statement of the migration runs longer
+// than SlowStatement: once when the threshold passes, and again with the
+// total duration when it finishes. The returned function must be called when
+// the statement is done.
+func (ex *MigrationExecutor) watchStatement(ctx context.Context, migration *PlannedMigration, name string) func() {
+	if ex.SlowStatement <= 0 {
+		return func() {}
+	}
+
+	started := time.Now()
+	warned := make(chan struct{})
+
+	timer := time.AfterFunc(ex.SlowStatement, func() {
+		defer close(warned)
+
+		logWith(ctx, ex.Logger, LevelWarn,
+			fmt.Sprintf("Slow statement: %s of migration %s is still running after %s", name, migration.Id, ex.SlowStatement),
+			Field{"migration_id", migration.Id}, Field{"statement", name}, Field{"duration", ex.SlowStatement})
+	})
+
+	return func() {
+		if timer.Stop() {
+			return
+		}
+
+		<-warned
+
+		duration := time.Since(started)
+
+		logWith(ctx, ex.Logger, LevelWarn,
+			fmt.Sprintf("Slow statement: %s of migration %s took %s", name, migration.Id, duration.Round(time.Millisecond)),
+			Field{"migration_id", migration.Id}, Field{"statement", name}, Field{"duration", duration})
+	}
+}
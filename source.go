@@ -2,7 +2,9 @@ package migrate
 
 import (
 	`bytes`
+	"crypto/sha256"
 	`embed`
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +12,7 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"sync"
 
 	`github.com/kva3umoda/sql-migrate/sqlparse`
 )
@@ -26,11 +29,61 @@ type MigrationSource interface {
 	FindMigrations() ([]*Migration, error)
 }
 
+// MigrationIdLister is implemented by sources which list the ids of their
+// migrations without reading them, e.g. from the names of the files, for
+// checks which don't need the statements.
+type MigrationIdLister interface {
+	// FindMigrationIds returns the ids of the migrations, sorted like FindMigrations.
+	FindMigrationIds() ([]string, error)
+}
+
 var _ MigrationSource = (*FileSystemMigrationSource)(nil)
 
+var _ MigrationIdLister = (*FileSystemMigrationSource)(nil)
+
 type FileSystemMigrationSource struct {
 	fs   http.FileSystem
 	root string
+
+	// StreamAbove is the size in bytes above which the statements of a
+	// migration file aren't held in memory but read from the file while the
+	// migration runs, e.g. for generated backfills. Zero reads all the files.
+	StreamAbove int64
+	// MaxDepth is the depth of the subdirectories whose .sql files are
+	// migrations too, e.g. 2 for migrations in year/month folders. Zero only
+	// reads the root, a negative depth reads all the subdirectories.
+	MaxDepth int
+	// Naming names the migrations found in subdirectories, NameByFile when
+	// zero.
+	Naming MigrationNaming
+	// Template renders the migration files before they're parsed, nil
+	// parses them as they are. The rendered files are held in memory,
+	// StreamAbove doesn't apply then.
+	Template *MigrationTemplate
+}
+
+// MigrationNaming is how FileSystemMigrationSource names the migrations of
+// subdirectories.
+type MigrationNaming int
+
+const (
+	// NameByFile uses the name of the file as id, e.g. 20240115_users.sql,
+	// which must be unique across the directories. The folders only organize
+	// the files.
+	NameByFile MigrationNaming = iota
+	// NameByPath uses the path of the file relative to the root as id, e.g.
+	// 2024/01/20240115_users.sql. The ids sort by their leading digits, the
+	// year here, then as strings, so folder names need the same width.
+	NameByPath
+)
+
+// sqlFile is a migration file found by FileSystemMigrationSource.
+type sqlFile struct {
+	// id is the id of the migration, path the path of the file in the file
+	// system.
+	id   string
+	path string
+	info os.FileInfo
 }
 
 // NewHttpFileSystemMigrationSource A set of migrations loaded from an http.FileServer
@@ -61,49 +114,167 @@ func (fs *FileSystemMigrationSource) FindMigrations() ([]*Migration, error) {
 	return fs.findMigrations(fs.fs, fs.root)
 }
 
+func (fs *FileSystemMigrationSource) FindMigrationIds() ([]string, error) {
+	files, err := fs.sqlFiles(fs.fs, fs.root)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.id
+	}
+
+	return sortIds(names), nil
+}
+
 func (fs *FileSystemMigrationSource) findMigrations(dir http.FileSystem, root string) ([]*Migration, error) {
-	migrations := make([]*Migration, 0)
+	sqlFiles, err := fs.sqlFiles(dir, root)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := parseAll(len(sqlFiles), func(i int) (*Migration, error) {
+		return fs.migrationFromFile(dir, sqlFiles[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure migrations are sorted
+	sort.Sort(byId(migrations))
 
+	return migrations, nil
+}
+
+// readSqlDir returns the migration files of the directory.
+func readSqlDir(dir http.FileSystem, root string) ([]os.FileInfo, error) {
 	file, err := dir.Open(root)
 	if err != nil {
 		return nil, err
 	}
 
+	defer func() { _ = file.Close() }()
+
 	files, err := file.Readdir(0)
 	if err != nil {
 		return nil, err
 	}
 
+	sqlFiles := make([]os.FileInfo, 0, len(files))
+
 	for _, info := range files {
 		if strings.HasSuffix(info.Name(), ".sql") {
-			migration, err := fs.migrationFromFile(dir, root, info)
-			if err != nil {
-				return nil, err
+			sqlFiles = append(sqlFiles, info)
+		}
+	}
+
+	return sqlFiles, nil
+}
+
+// sqlFiles returns the migration files of the root and of its
+// subdirectories down to MaxDepth.
+func (fs *FileSystemMigrationSource) sqlFiles(dir http.FileSystem, root string) ([]sqlFile, error) {
+	if fs.MaxDepth == 0 {
+		infos, err := readSqlDir(dir, root)
+		if err != nil {
+			return nil, err
+		}
+
+		files := make([]sqlFile, len(infos))
+		for i, info := range infos {
+			files[i] = sqlFile{id: info.Name(), path: path.Join(root, info.Name()), info: info}
+		}
+
+		return files, nil
+	}
+
+	var files []sqlFile
+
+	if err := fs.walkSqlDir(dir, root, "", 0, &files); err != nil {
+		return nil, err
+	}
+
+	if fs.Naming == NameByFile {
+		found := make(map[string]string, len(files))
+		for _, file := range files {
+			if other, ok := found[file.id]; ok {
+				return nil, fmt.Errorf("Duplicate migration %s in %s and %s", file.id, other, file.path)
 			}
 
-			migrations = append(migrations, migration)
+			found[file.id] = file.path
 		}
 	}
 
-	// Make sure migrations are sorted
-	sort.Sort(byId(migrations))
+	return files, nil
+}
 
-	return migrations, nil
+// walkSqlDir adds the migration files of the directory rel of the root, and
+// of its subdirectories, to files.
+func (fs *FileSystemMigrationSource) walkSqlDir(dir http.FileSystem, root, rel string, depth int, files *[]sqlFile) error {
+	file, err := dir.Open(path.Join(root, rel))
+	if err != nil {
+		return err
+	}
+
+	infos, err := file.Readdir(0)
+	_ = file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		name := path.Join(rel, info.Name())
+
+		switch {
+		case info.IsDir() && (fs.MaxDepth < 0 || depth < fs.MaxDepth):
+			if err := fs.walkSqlDir(dir, root, name, depth+1, files); err != nil {
+				return err
+			}
+		case !info.IsDir() && strings.HasSuffix(info.Name(), ".sql"):
+			id := info.Name()
+			if fs.Naming == NameByPath {
+				id = name
+			}
+
+			*files = append(*files, sqlFile{id: id, path: path.Join(root, name), info: info})
+		}
+	}
+
+	return nil
 }
 
-func (fs *FileSystemMigrationSource) migrationFromFile(dir http.FileSystem, root string, info os.FileInfo) (*Migration, error) {
-	path := path.Join(root, info.Name())
+func (fs *FileSystemMigrationSource) migrationFromFile(dir http.FileSystem, f sqlFile) (*Migration, error) {
+	if fs.StreamAbove > 0 && f.info.Size() > fs.StreamAbove && fs.Template == nil {
+		migration, err := streamedMigration(f.id, func() (io.ReadCloser, error) {
+			return dir.Open(f.path)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error while parsing %s: %w", f.id, err)
+		}
+
+		return migration, nil
+	}
 
-	file, err := dir.Open(path)
+	file, err := dir.Open(f.path)
 	if err != nil {
-		return nil, fmt.Errorf("Error while opening %s: %w", info.Name(), err)
+		return nil, fmt.Errorf("Error while opening %s: %w", f.id, err)
 	}
 
 	defer func() { _ = file.Close() }()
 
-	migration, err := parseMigration(info.Name(), file)
+	var r io.ReadSeeker = file
+	if fs.Template != nil {
+		r, err = fs.Template.render(f.id, file)
+		if err != nil {
+			return nil, fmt.Errorf("Error while rendering %s: %w", f.id, err)
+		}
+	}
+
+	migration, err := parseMigration(f.id, r)
 	if err != nil {
-		return nil, fmt.Errorf("Error while parsing %s: %w", info.Name(), err)
+		return nil, fmt.Errorf("Error while parsing %s: %w", f.id, err)
 	}
 
 	return migration, nil
@@ -136,6 +307,8 @@ func (m *MemoryMigrationSource) FindMigrations() ([]*Migration, error) {
 
 var _ MigrationSource = (*AssetMigrationSource)(nil)
 
+var _ MigrationIdLister = (*AssetMigrationSource)(nil)
+
 type AssetFunc func(path string) ([]byte, error)
 type AssetDirFunc func(path string) ([]string, error)
 
@@ -156,33 +329,111 @@ func NewAssetMigrationSource(asset AssetFunc, assetDir AssetDirFunc, dir string)
 		Dir:      dir,
 	}
 }
+
+func (a *AssetMigrationSource) FindMigrationIds() ([]string, error) {
+	names, err := a.sqlNames()
+	if err != nil {
+		return nil, err
+	}
+
+	return sortIds(names), nil
+}
+
 func (a *AssetMigrationSource) FindMigrations() ([]*Migration, error) {
-	migrations := make([]*Migration, 0)
+	names, err := a.sqlNames()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := parseAll(len(names), func(i int) (*Migration, error) {
+		file, err := a.Asset(path.Join(a.Dir, names[i]))
+		if err != nil {
+			return nil, err
+		}
+
+		return parseMigration(names[i], bytes.NewReader(file))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure migrations are sorted
+	sort.Sort(byId(migrations))
+
+	return migrations, nil
+}
 
+// sqlNames returns the names of the migration files of Dir.
+func (a *AssetMigrationSource) sqlNames() ([]string, error) {
 	files, err := a.AssetDir(a.Dir)
 	if err != nil {
 		return nil, err
 	}
 
+	names := make([]string, 0, len(files))
+
 	for _, name := range files {
 		if strings.HasSuffix(name, ".sql") {
-			file, err := a.Asset(path.Join(a.Dir, name))
-			if err != nil {
-				return nil, err
-			}
+			names = append(names, name)
+		}
+	}
 
-			migration, err := parseMigration(name, bytes.NewReader(file))
-			if err != nil {
-				return nil, err
-			}
+	return names, nil
+}
 
-			migrations = append(migrations, migration)
-		}
+// sortIds sorts the ids in the order of the migrations.
+func sortIds(ids []string) []string {
+	migrations := make([]*Migration, len(ids))
+	for i, id := range ids {
+		migrations[i] = &Migration{Id: id}
 	}
 
-	// Make sure migrations are sorted
 	sort.Sort(byId(migrations))
 
+	for i, migration := range migrations {
+		ids[i] = migration.Id
+	}
+
+	return ids
+}
+
+// parseConcurrency bounds the migration files read and parsed at once, as
+// reading thousands of files one by one from a network file system is slow.
+const parseConcurrency = 16
+
+// parseAll parses the n migration files with parse, concurrently, keeping
+// the migrations in the order of the files. On failures, it returns the
+// error of the first failing file in that order.
+func parseAll(n int, parse func(i int) (*Migration, error)) ([]*Migration, error) {
+	migrations := make([]*Migration, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, parseConcurrency)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			migrations[i], errs[i] = parse(i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return migrations, nil
 }
 
@@ -199,9 +450,88 @@ func parseMigration(id string, r io.ReadSeeker) (*Migration, error) {
 
 	m.Up = parsed.UpStatements
 	m.Down = parsed.DownStatements
+	m.UpLines = parsed.UpLines
+	m.DownLines = parsed.DownLines
 
 	m.DisableTransactionUp = parsed.DisableTransactionUp
 	m.DisableTransactionDown = parsed.DisableTransactionDown
+	m.StatementTimeoutUp = parsed.StatementTimeoutUp
+	m.StatementTimeoutDown = parsed.StatementTimeoutDown
+	m.DependsOn = parsed.DependsOn
+
+	return m, nil
+}
+
+// streamedMigration returns a migration reading its statements from the file
+// opened by open whenever they're needed. The file is read once to check it,
+// finding its destructive statements, and once more for the checksum.
+func streamedMigration(id string, open func() (io.ReadCloser, error)) (*Migration, error) {
+	m := &Migration{
+		Id: id,
+	}
+
+	m.Stream = func(dir MigrationDirection, fn func(stmt string, lines sqlparse.LineRange) error) error {
+		file, err := open()
+		if err != nil {
+			return fmt.Errorf("Error while opening %s: %w", id, err)
+		}
+
+		defer func() { _ = file.Close() }()
+
+		_, err = sqlparse.StreamMigration(file, func(stmt sqlparse.Statement) error {
+			if stmt.Down != (dir == Down) {
+				return nil
+			}
+
+			return fn(stmt.SQL, stmt.Lines)
+		})
+
+		return err
+	}
+
+	file, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = file.Close() }()
+
+	h := sha256.New()
+
+	parsed, err := sqlparse.StreamMigration(file, func(stmt sqlparse.Statement) error {
+		if !stmt.Down {
+			h.Write([]byte(stmt.SQL))
+			h.Write([]byte{0})
+
+			m.destructiveUp = m.destructiveUp || IsDestructive(stmt.SQL)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing migration (%s): %w", id, err)
+	}
+
+	m.scanned = true
+	m.DisableTransactionUp = parsed.DisableTransactionUp
+	m.DisableTransactionDown = parsed.DisableTransactionDown
+	m.StatementTimeoutUp = parsed.StatementTimeoutUp
+	m.StatementTimeoutDown = parsed.StatementTimeoutDown
+	m.DependsOn = parsed.DependsOn
+
+	h.Write([]byte{1})
+
+	err = m.Stream(Down, func(stmt string, _ sqlparse.LineRange) error {
+		h.Write([]byte(stmt))
+		h.Write([]byte{0})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing migration (%s): %w", id, err)
+	}
+
+	m.checksum = hex.EncodeToString(h.Sum(nil))
 
 	return m, nil
 }
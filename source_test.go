@@ -0,0 +1,185 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type SourceSuite struct{}
+
+var _ = Suite(&SourceSuite{})
+
+func writeMigrations(c *C, n int) string {
+	dir := c.MkDir()
+
+	for i := 1; i <= n; i++ {
+		content := fmt.Sprintf("-- +migrate Up\nCREATE TABLE t%d (id int);\n", i)
+		c.Assert(os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d_table.sql", i)), []byte(content), 0o600), IsNil)
+	}
+
+	return dir
+}
+
+func (*SourceSuite) TestFindMigrationsInOrder(c *C) {
+	dir := writeMigrations(c, 3*parseConcurrency)
+
+	migrations, err := NewFileMigrationSource(dir).FindMigrations()
+	c.Assert(err, IsNil)
+	c.Assert(migrations, HasLen, 3*parseConcurrency)
+
+	for i, m := range migrations {
+		c.Assert(m.Id, Equals, fmt.Sprintf("%d_table.sql", i+1))
+		c.Assert(m.Up, DeepEquals, []string{fmt.Sprintf("CREATE TABLE t%d (id int);\n", i+1)})
+	}
+}
+
+func (*SourceSuite) TestFindMigrationsError(c *C) {
+	dir := writeMigrations(c, 3*parseConcurrency)
+	c.Assert(os.WriteFile(filepath.Join(dir, "7_table.sql"), []byte("CREATE TABLE t7 (id int);\n"), 0o600), IsNil)
+
+	_, err := NewFileMigrationSource(dir).FindMigrations()
+	c.Assert(err, ErrorMatches, "(?s)Error while parsing 7_table.sql: .*no Up/Down annotations.*")
+}
+
+func (*SourceSuite) TestTemplate(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "1_events.sql"), []byte(
+		"-- +migrate Up\nCREATE TABLE {{ env \"SQL_MIGRATE_TEST_SCHEMA\" }}.events (id int) TABLESPACE {{ .tablespace }};\n"), 0o600), IsNil)
+
+	c.Assert(os.Setenv("SQL_MIGRATE_TEST_SCHEMA", "app"), IsNil)
+	defer os.Unsetenv("SQL_MIGRATE_TEST_SCHEMA")
+
+	source := NewFileMigrationSource(dir)
+	source.Template = &MigrationTemplate{Data: map[string]any{"tablespace": "fast"}}
+
+	migrations, err := source.FindMigrations()
+	c.Assert(err, IsNil)
+	c.Assert(migrations[0].Up, DeepEquals, []string{"CREATE TABLE app.events (id int) TABLESPACE fast;\n"})
+
+	source.Template.Data = nil
+	_, err = source.FindMigrations()
+	c.Assert(err, ErrorMatches, `Error while rendering 1_events.sql: .*map has no entry for key "tablespace"`)
+
+	c.Assert(os.Unsetenv("SQL_MIGRATE_TEST_SCHEMA"), IsNil)
+	_, err = source.FindMigrations()
+	c.Assert(err, ErrorMatches, `Error while rendering 1_events.sql: .*environment variable SQL_MIGRATE_TEST_SCHEMA is not set`)
+}
+
+func (*SourceSuite) TestFindMigrationsRecursive(c *C) {
+	dir := c.MkDir()
+
+	for _, name := range []string{"1_root.sql", "2024/01/20240115_users.sql", "2023/12/20231201_people.sql", "2024/01/deep/3_deep.sql", "2024/notes.txt"} {
+		c.Assert(os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o700), IsNil)
+		c.Assert(os.WriteFile(filepath.Join(dir, name), []byte("-- +migrate Up\nSELECT 1;\n"), 0o600), IsNil)
+	}
+
+	ids := func(source *FileSystemMigrationSource) []string {
+		migrations, err := source.FindMigrations()
+		c.Assert(err, IsNil)
+
+		listed, err := source.FindMigrationIds()
+		c.Assert(err, IsNil)
+
+		var found []string
+		for _, m := range migrations {
+			found = append(found, m.Id)
+		}
+
+		c.Assert(listed, DeepEquals, found)
+
+		return found
+	}
+
+	source := NewFileMigrationSource(dir)
+	c.Assert(ids(source), DeepEquals, []string{"1_root.sql"})
+
+	source.MaxDepth = 2
+	c.Assert(ids(source), DeepEquals, []string{"1_root.sql", "20231201_people.sql", "20240115_users.sql"})
+
+	source.MaxDepth, source.Naming = -1, NameByPath
+	c.Assert(ids(source), DeepEquals, []string{"1_root.sql", "2023/12/20231201_people.sql", "2024/01/20240115_users.sql", "2024/01/deep/3_deep.sql"})
+
+	c.Assert(os.WriteFile(filepath.Join(dir, "2023", "1_root.sql"), []byte("-- +migrate Up\nSELECT 1;\n"), 0o600), IsNil)
+
+	source.Naming = NameByFile
+	_, err := source.FindMigrations()
+	c.Assert(err, ErrorMatches, "Duplicate migration 1_root.sql in .* and .*")
+}
+
+func (s *SqliteMigrateSuite) TestStreamedMigration(c *C) {
+	dir := c.MkDir()
+
+	var b strings.Builder
+
+	b.WriteString("-- +migrate Up\nCREATE TABLE backfill (id int);\n")
+
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&b, "INSERT INTO backfill (id) VALUES (%d);\n", i)
+	}
+
+	b.WriteString("-- +migrate Down\nDROP TABLE backfill;\n")
+	c.Assert(os.WriteFile(filepath.Join(dir, "1_backfill.sql"), []byte(b.String()), 0o600), IsNil)
+
+	parsed, err := NewFileMigrationSource(dir).FindMigrations()
+	c.Assert(err, IsNil)
+
+	source := NewFileMigrationSource(dir)
+	source.StreamAbove = 1024
+
+	migrations, err := source.FindMigrations()
+	c.Assert(err, IsNil)
+	c.Assert(migrations, HasLen, 1)
+	c.Assert(migrations[0].Up, IsNil)
+	c.Assert(migrations[0].Stream, NotNil)
+	c.Assert(migrations[0].Checksum(), Equals, parsed[0].Checksum())
+
+	var (
+		statements []string
+		counts     []int
+	)
+
+	ex := *s.ex
+	ex.EventSink = func(event MigrationEvent) {
+		switch e := event.(type) {
+		case MigrationStarted:
+			counts = append(counts, e.Statements)
+		case StatementExecuted:
+			statements = append(statements, e.Statement)
+		}
+	}
+
+	n, err := ex.Exec(s.db, s.dialect, source, Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+	c.Assert(statements, HasLen, 201)
+	c.Assert(statements[200], Equals, "line 202")
+	// The statements of streamed migrations aren't counted beforehand.
+	c.Assert(counts, DeepEquals, []int{-1})
+
+	var count int
+	c.Assert(s.db.QueryRow("SELECT COUNT(*) FROM backfill").Scan(&count), IsNil)
+	c.Assert(count, Equals, 200)
+
+	n, err = ex.Exec(s.db, s.dialect, source, Down)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+	c.Assert(statements, HasLen, 202)
+}
+
+func (*SourceSuite) TestChecksumCached(c *C) {
+	m := &Migration{Id: "1_a.sql", Up: []string{"CREATE TABLE a (id int);"}}
+
+	sum := m.Checksum()
+	c.Assert(m.checksum, Equals, sum)
+
+	// A copy with other statements doesn't reuse the checksum.
+	edited := *m
+	edited.Up = []string{"CREATE TABLE a (id bigint);"}
+	c.Assert(edited.Checksum(), Not(Equals), sum)
+	c.Assert(m.Checksum(), Equals, sum)
+}
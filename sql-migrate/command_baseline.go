@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newBaselineCommand() *cobra.Command {
+	var version int64
+
+	cmd := &cobra.Command{
+		Use:   "baseline --version=NUMBER",
+		Short: "Mark the migrations of an existing database as applied",
+		Long: `Onboard an existing database: create the migration table and record all
+migrations up to and including the version as applied, without running
+them. The migration table must not hold any migration yet.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return BaselineMigrations(version)
+		},
+	}
+
+	cmd.Flags().Int64Var(&version, "version", -1, "last migration already present in the database")
+
+	return cmd
+}
+
+// BaselineMigrations records the migrations up to and including version as applied.
+func BaselineMigrations(version int64) error {
+	if version < 0 {
+		return errors.New("The version option is required")
+	}
+
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	n, err := env.Executor().Baseline(context.Background(), db, dialect, env.Source(), version)
+	if err != nil {
+		return fmt.Errorf("Baseline failed: %w", err)
+	}
+
+	if n == 1 {
+		ui.Info("Recorded 1 migration as applied")
+	} else {
+		ui.Info(fmt.Sprintf("Recorded %d migrations as applied", n))
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the check command.
+const (
+	checkUpToDate = 0
+	checkPending  = 1
+	checkDrift    = 2
+	checkError    = 3
+)
+
+func newCheckCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "Check whether the database is up to date",
+		Long: `Check whether the database is up to date, for gating deploys.
+
+Exits with 0 when all migrations are applied, 1 when migrations are
+pending, 2 when the database drifted from the migration directory, with
+applied migrations edited since or missing from it, and 3 when the check
+itself fails or is called with invalid flags. Migrations applied without a
+checksum of sql-migrate aren't compared.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{errorCodeAnnotation: strconv.Itoa(checkError)},
+		RunE: func(*cobra.Command, []string) error {
+			if code := runCheck(); code != checkUpToDate {
+				return exitCode(code)
+			}
+
+			return nil
+		},
+	}
+}
+
+// runCheck reports the state of the database and returns the exit code.
+func runCheck() int {
+	env, err := GetEnvironment()
+	if err != nil {
+		ui.Error(fmt.Sprintf("Could not parse config: %s", err))
+		return checkError
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		ui.Error(err.Error())
+		return checkError
+	}
+	defer db.Close()
+
+	migrations, err := env.Source().FindMigrations()
+	if err != nil {
+		ui.Error(err.Error())
+		return checkError
+	}
+
+	records, err := env.Executor().GetMigrationRecords(context.Background(), db, dialect)
+	if err != nil {
+		ui.Error(err.Error())
+		return checkError
+	}
+
+	applied := make(map[string]bool, len(records))
+	for _, record := range records {
+		applied[record.Id] = true
+	}
+
+	var pending []string
+
+	for _, m := range migrations {
+		if !applied[m.Id] {
+			pending = append(pending, m.Id)
+		}
+	}
+
+	report := findDrift(migrations, records)
+
+	if len(report.Edited) > 0 {
+		ui.Error(fmt.Sprintf("Migrations edited after they were applied: %s", strings.Join(report.Edited, ", ")))
+	}
+
+	if len(report.Missing) > 0 {
+		ui.Error(fmt.Sprintf("Migrations applied but missing from %s: %s", env.Dir, strings.Join(report.Missing, ", ")))
+	}
+
+	if len(report.Edited) > 0 || len(report.Missing) > 0 {
+		return checkDrift
+	}
+
+	if len(pending) > 0 {
+		ui.Info(fmt.Sprintf("Pending migrations: %s", strings.Join(pending, ", ")))
+		return checkPending
+	}
+
+	ui.Info("Database is up to date")
+
+	return checkUpToDate
+}
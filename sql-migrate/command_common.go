@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/schemadump"
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+// ApplyOptions selects the migrations ApplyMigrations runs.
+type ApplyOptions struct {
+	// DryRun prints the SQL of the planned migrations, with the statements
+	// recording them, instead of executing them.
+	DryRun bool
+	// Limit caps the number of migrations, 0 means no limit.
+	Limit int
+	// Version migrates up (or down) to and including this version when non-negative.
+	Version int64
+	// To rolls back every migration newer than this version when non-negative,
+	// leaving the version itself applied. Only used with migrate.Down.
+	To int64
+	// Yes skips the confirmation of migrations reverting the database.
+	Yes bool
+	// WaitForDB retries connecting for up to WaitTimeout before migrating,
+	// and always takes the migration lock.
+	WaitForDB   bool
+	WaitTimeout time.Duration
+	// Parallel is the number of datasources migrated at the same time, for
+	// environments with several datasources.
+	Parallel int
+	// ContinueOnError keeps migrating the other datasources after a failure.
+	ContinueOnError bool
+	// DumpSchema is the file the schema is written to after migrating, the
+	// dump_schema setting of the environment when empty.
+	DumpSchema string
+	// Confirm is the token confirming destructive migrations, for the
+	// environments with require_down_confirmation.
+	Confirm string
+	// Seed applies the new and changed seeds after the migrations.
+	Seed bool
+	// ParallelMigrations is the number of migrations applied at the same
+	// time, the parallel_migrations setting of the environment when zero.
+	ParallelMigrations int
+}
+
+// ApplyMigrations applies the migrations of the environment in the given direction.
+func ApplyMigrations(dir migrate.MigrationDirection, opts ApplyOptions) error {
+	targets := 0
+	for _, set := range []bool{opts.Limit > 0, opts.Version >= 0, opts.To >= 0} {
+		if set {
+			targets++
+		}
+	}
+
+	if targets > 1 {
+		return errors.New("The limit, version and to options are mutually exclusive")
+	}
+
+	if opts.Seed && opts.DryRun {
+		return errors.New("The seed and dry-run options are mutually exclusive")
+	}
+
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	if opts.DumpSchema != "" {
+		env.DumpSchema = opts.DumpSchema
+	}
+
+	if opts.ParallelMigrations > 0 {
+		env.ParallelMigrations = opts.ParallelMigrations
+	}
+
+	if len(env.DataSources) > 0 {
+		if env.DumpSchema != "" {
+			return errors.New("Dumping the schema needs a single datasource, use -datasource")
+		}
+
+		if opts.Seed {
+			return errors.New("Seeding needs a single datasource, use -datasource")
+		}
+
+		return applyTargets(env, dir, opts)
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	ex := env.Executor()
+	ex.AllowDestructive(opts.Confirm)
+	source := env.Source()
+
+	if opts.WaitForDB {
+		if err := WaitForDB(ctx, db, opts.WaitTimeout); err != nil {
+			return err
+		}
+
+		ex.Lock = true
+	}
+
+	limit := opts.Limit
+
+	if opts.To >= 0 {
+		planned, _, err := ex.PlanMigration(ctx, db, dialect, source, migrate.Down, 0)
+		if err != nil {
+			return fmt.Errorf("Cannot plan migration: %w", err)
+		}
+
+		limit, err = rollbackLimit(planned, opts.To)
+		if err != nil {
+			return err
+		}
+
+		if limit == 0 {
+			ui.Info(fmt.Sprintf("Nothing to roll back, version %d is the latest applied", opts.To))
+			return nil
+		}
+	}
+
+	if opts.DryRun {
+		var run *migrate.DryRun
+
+		if opts.Version >= 0 {
+			run, err = ex.ExecDryRunToVersion(ctx, db, dialect, source, dir, opts.Version)
+		} else {
+			run, err = ex.ExecDryRun(ctx, db, dialect, source, dir, limit)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Cannot plan migration: %w", err)
+		}
+
+		_, err = run.WriteTo(ui.Writer)
+
+		return err
+	}
+
+	// The confirmed plan is applied as is, it fails if another migrator
+	// changed the migration table in the meantime.
+	var plan *migrate.MigrationPlan
+
+	if dir == migrate.Down && !opts.Yes {
+		if opts.Version >= 0 {
+			plan, err = ex.PlanToVersion(ctx, db, dialect, source, dir, opts.Version)
+		} else {
+			plan, err = ex.Plan(ctx, db, dialect, source, dir, limit)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Cannot plan migration: %w", err)
+		}
+
+		if err := ConfirmRevert(plan.Migrations); err != nil {
+			return err
+		}
+	}
+
+	var n int
+
+	switch {
+	case plan != nil:
+		n, err = ex.ExecPlan(ctx, db, plan)
+	case opts.Version >= 0:
+		n, err = ex.ExecVersionContext(ctx, db, dialect, source, dir, opts.Version)
+	default:
+		n, err = ex.ExecMaxContext(ctx, db, dialect, source, dir, limit)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Migration failed: %w", err)
+	}
+
+	if n == 1 {
+		ui.Info("Applied 1 migration")
+	} else {
+		ui.Info(fmt.Sprintf("Applied %d migrations", n))
+	}
+
+	if opts.Seed {
+		n, err = env.Seeder().Seed(ctx, db, dialect, env.SeedSource())
+		if err != nil {
+			return fmt.Errorf("Seeding failed: %w", err)
+		}
+
+		ui.Info(fmt.Sprintf("Applied %d seeds", n))
+	}
+
+	if env.DumpSchema != "" {
+		return dumpSchema(ctx, env, db, dialect)
+	}
+
+	return nil
+}
+
+// dumpSchema writes the schema of the database to the dump_schema file of
+// the environment, with pg_dump for PostgreSQL, mysqldump for MySQL, and from
+// the catalog for SQLite or through an SSH tunnel.
+func dumpSchema(ctx context.Context, env *Environment, db *sql.DB, d dialect.Dialect) error {
+	ex := env.Executor()
+
+	// The migration tables would change with every migration.
+	tables := []string{ex.TableName, ex.TableName + "_lock"}
+
+	pgTables := tables
+	if ex.SchemaName != "" {
+		pgTables = []string{ex.SchemaName + "." + tables[0], ex.SchemaName + "." + tables[1]}
+	}
+
+	dataSource, err := resolveDataSource(ctx, env.DataSource)
+	if err != nil {
+		return err
+	}
+
+	var dumper schemadump.Dumper
+
+	switch name := migrate.DialectName(env.Dialect); {
+	case name == migrate.Postgres && env.SSH == "":
+		dumper = &schemadump.PgDump{DataSource: dataSource, Exclude: pgTables}
+	case name == migrate.MySQL && env.SSH == "":
+		dumper = &schemadump.MySQLDump{DataSource: dataSource, Exclude: tables}
+	case name == migrate.Postgres || name == migrate.SQLite3:
+		dumper = &schemadump.Introspect{DB: db, Dialect: d, Schema: ex.SchemaName, Exclude: tables}
+	default:
+		return fmt.Errorf("Cannot dump the schema of %s databases", env.Dialect)
+	}
+
+	changed, err := schemadump.WriteFile(ctx, dumper, env.DumpSchema)
+	if err != nil {
+		return fmt.Errorf("Cannot dump the schema: %w", err)
+	}
+
+	if changed {
+		ui.Info(fmt.Sprintf("Wrote the schema to %s", env.DumpSchema))
+	}
+
+	return nil
+}
+
+// rollbackLimit counts the planned down migrations newer than version. The
+// version must be 0 or one of the applied migrations, so a typo can't roll back
+// more than intended.
+func rollbackLimit(planned []*migrate.PlannedMigration, version int64) (int, error) {
+	for i, m := range planned {
+		if len(m.NumberPrefixMatches()) == 0 {
+			// Migrations without a version sort after all the numbered ones.
+			continue
+		}
+
+		if v := m.VersionInt(); v == version {
+			return i, nil
+		} else if v < version {
+			break
+		}
+	}
+
+	if version == 0 {
+		return len(planned), nil
+	}
+
+	return 0, fmt.Errorf("Version %d is not an applied migration", version)
+}
+
+const (
+	waitMinBackoff = 500 * time.Millisecond
+	waitMaxBackoff = 10 * time.Second
+)
+
+// WaitForDB pings the database until it answers, backing off exponentially
+// between attempts, and gives up after timeout.
+func WaitForDB(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := waitMinBackoff
+
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		ui.Info(fmt.Sprintf("Database not ready, retrying in %s: %s", backoff, err))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Database not ready after %s: %w", timeout, err)
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, waitMaxBackoff)
+	}
+}
+
+// ConfirmRevert lists the migrations about to be reverted and asks to type the
+// name of the environment, so a rollback doesn't hit the wrong database by accident.
+func ConfirmRevert(migrations []*migrate.PlannedMigration) error {
+	ids := make([]string, 0, len(migrations))
+	for _, m := range migrations {
+		ids = append(ids, m.Id)
+	}
+
+	return confirmRevert(ids)
+}
+
+func confirmRevert(ids []string) error {
+	return confirm("will be reverted", ids)
+}
+
+// confirm lists the migrations and what will happen to them, and asks to type
+// the name of the environment to continue.
+func confirm(action string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	name := environmentName()
+
+	ui.Output(fmt.Sprintf("The following migrations %s in %s:", action, name))
+
+	for _, id := range ids {
+		ui.Output("    " + id)
+	}
+
+	answer, err := ui.Ask(fmt.Sprintf("Type %q to continue: ", name))
+	if err != nil || answer != name {
+		return errors.New("Aborted, pass -yes to skip the confirmation")
+	}
+
+	return nil
+}
+
+// environmentName returns the name of the selected environment.
+func environmentName() string {
+	if ConfigEnvironment == "" {
+		return defaultEnvironment
+	}
+
+	return ConfigEnvironment
+}
+
+// PrintMigration prints the queries a planned migration would run.
+func PrintMigration(m *migrate.PlannedMigration, dir migrate.MigrationDirection) {
+	ui.Output(fmt.Sprintf("==> Would apply migration %s (%s)", m.Id, dir.String()))
+
+	if m.DisableTransaction {
+		ui.Output("-- notransaction")
+	}
+
+	err := m.Statements(func(q string, _ sqlparse.LineRange) error {
+		ui.Output(q)
+		return nil
+	})
+	if err != nil {
+		ui.Error(err.Error())
+	}
+}
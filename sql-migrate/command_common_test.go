@@ -0,0 +1,153 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+type CommonSuite struct{}
+
+var _ = Suite(&CommonSuite{})
+
+func (*CommonSuite) TestRollbackLimit(c *C) {
+	var planned []*migrate.PlannedMigration
+	for _, id := range []string{"notes.sql", "30_c.sql", "20_b.sql", "10_a.sql"} {
+		planned = append(planned, &migrate.PlannedMigration{Migration: &migrate.Migration{Id: id}})
+	}
+
+	limit, err := rollbackLimit(planned, 20)
+	c.Assert(err, IsNil)
+	c.Assert(limit, Equals, 2)
+
+	limit, err = rollbackLimit(planned, 0)
+	c.Assert(err, IsNil)
+	c.Assert(limit, Equals, 4)
+
+	_, err = rollbackLimit(planned, 15)
+	c.Assert(err, NotNil)
+}
+
+func (*CommonSuite) TestConfirmRevert(c *C) {
+	defer func(u UI) { *ui = u }(*ui)
+	ui.Writer = io.Discard
+
+	migrations := []*migrate.PlannedMigration{{Migration: &migrate.Migration{Id: "1_a.sql"}}}
+
+	ui.Reader = strings.NewReader("development\n")
+	c.Assert(ConfirmRevert(migrations), IsNil)
+
+	ui.Reader = strings.NewReader("production\n")
+	c.Assert(ConfirmRevert(migrations), NotNil)
+
+	ui.Reader = strings.NewReader("")
+	c.Assert(ConfirmRevert(migrations), NotNil)
+}
+
+func (*CommonSuite) TestDryRun(c *C) {
+	var out strings.Builder
+
+	defer func(u UI) { *ui = u }(*ui)
+	ui.Writer = &out
+
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "1_people.sql"), []byte("-- +migrate Up\nCREATE TABLE people (id int);\n"), 0o644), IsNil)
+
+	defer func(dir, dialect, dataSource string) {
+		ConfigDir, ConfigDialect, ConfigDataSource = dir, dialect, dataSource
+	}(ConfigDir, ConfigDialect, ConfigDataSource)
+	ConfigDir, ConfigDialect, ConfigDataSource = dir, "sqlite3", filepath.Join(dir, "test.db")
+
+	c.Assert(ApplyMigrations(migrate.Up, ApplyOptions{Version: -1, To: -1, DryRun: true}), IsNil)
+	c.Assert(out.String(), Matches, `(?s)-- Migration table\n.*-- Migration 1_people.sql \(up\)\nCREATE TABLE people \(id int\);\nINSERT INTO "migrations".*`)
+}
+
+func (*CommonSuite) TestConfirmDown(c *C) {
+	defer func(u UI) { *ui = u }(*ui)
+	ui.Writer = io.Discard
+
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "1_people.sql"), []byte("-- +migrate Up\nCREATE TABLE people (id int);\n-- +migrate Down\nDROP TABLE people;\n"), 0o644), IsNil)
+
+	file := filepath.Join(dir, "dbconfig.yml")
+	config := fmt.Sprintf("development:\n  dialect: sqlite3\n  datasource: %s\n  dir: %s\n  require_down_confirmation: true\n", filepath.Join(dir, "test.db"), dir)
+	c.Assert(os.WriteFile(file, []byte(config), 0o644), IsNil)
+
+	defer func() { ConfigFile, ConfigEnvironment = "", "" }()
+	ConfigFile, ConfigEnvironment = file, "development"
+
+	c.Assert(ApplyMigrations(migrate.Up, ApplyOptions{Version: -1, To: -1}), IsNil)
+
+	err := ApplyMigrations(migrate.Down, ApplyOptions{Limit: 1, Version: -1, To: -1, Yes: true})
+
+	var refused *migrate.DestructiveError
+	c.Assert(errors.As(err, &refused), Equals, true)
+	c.Assert(refused.Migrations, DeepEquals, []string{"1_people.sql"})
+
+	c.Assert(ApplyMigrations(migrate.Down, ApplyOptions{Limit: 1, Version: -1, To: -1, Yes: true, Confirm: refused.Token}), IsNil)
+}
+
+func (*CommonSuite) TestSeed(c *C) {
+	defer func(u UI) { *ui = u }(*ui)
+	ui.Writer = io.Discard
+
+	dir := c.MkDir()
+	seeds := filepath.Join(dir, "seeds")
+	c.Assert(os.Mkdir(seeds, 0o755), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "1_people.sql"), []byte("-- +migrate Up\nCREATE TABLE people (id int primary key);\n"), 0o644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(seeds, "1_people.sql"), []byte("-- +migrate Up\nINSERT OR IGNORE INTO people (id) VALUES (1);\n"), 0o644), IsNil)
+
+	file := filepath.Join(dir, "dbconfig.yml")
+	config := fmt.Sprintf("development:\n  dialect: sqlite3\n  datasource: %s\n  dir: %s\n  seed_dir: %s\n", filepath.Join(dir, "test.db"), dir, seeds)
+	c.Assert(os.WriteFile(file, []byte(config), 0o644), IsNil)
+
+	defer func() { ConfigFile, ConfigEnvironment = "", "" }()
+	ConfigFile, ConfigEnvironment = file, "development"
+
+	c.Assert(ApplyMigrations(migrate.Up, ApplyOptions{Version: -1, To: -1, Seed: true, DryRun: true}), ErrorMatches, "The seed and dry-run options are mutually exclusive")
+	c.Assert(ApplyMigrations(migrate.Up, ApplyOptions{Version: -1, To: -1, Seed: true}), IsNil)
+	c.Assert(ApplyMigrations(migrate.Up, ApplyOptions{Version: -1, To: -1, Seed: true}), IsNil)
+
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+
+	db, err := sql.Open("sqlite3", env.DataSource)
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	var people, seeded int
+	c.Assert(db.QueryRow("SELECT COUNT(*) FROM people").Scan(&people), IsNil)
+	c.Assert(db.QueryRow("SELECT COUNT(*) FROM seeds").Scan(&seeded), IsNil)
+	c.Assert(people, Equals, 1)
+	c.Assert(seeded, Equals, 1)
+}
+
+func (*CommonSuite) TestDumpSchema(c *C) {
+	defer func(u UI) { *ui = u }(*ui)
+	ui.Writer = io.Discard
+
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "1_people.sql"), []byte("-- +migrate Up\nCREATE TABLE people (id int);\n"), 0o644), IsNil)
+
+	defer func(dir, dialect, dataSource string) {
+		ConfigDir, ConfigDialect, ConfigDataSource = dir, dialect, dataSource
+	}(ConfigDir, ConfigDialect, ConfigDataSource)
+	ConfigDir, ConfigDialect, ConfigDataSource = dir, "sqlite3", filepath.Join(dir, "test.db")
+
+	schema := filepath.Join(dir, "schema.sql")
+
+	c.Assert(ApplyMigrations(migrate.Up, ApplyOptions{Version: -1, To: -1, DumpSchema: schema}), IsNil)
+
+	content, err := os.ReadFile(schema)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "CREATE TABLE \"people\" (\n    \"id\" INT\n);\n")
+}
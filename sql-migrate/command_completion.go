@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// registerCompletions completes the environments of the config file for --env
+// and the versions of the migrations for the version flags, in the scripts of
+// the "completion" command cobra adds.
+func registerCompletions(root *cobra.Command) {
+	_ = root.RegisterFlagCompletionFunc("env", completeWords("environments"))
+
+	for _, cmd := range root.Commands() {
+		for _, name := range []string{"version", "to", "through"} {
+			if cmd.Flags().Lookup(name) != nil {
+				_ = cmd.RegisterFlagCompletionFunc(name, completeWords("versions"))
+			}
+		}
+	}
+}
+
+func completeWords(list string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		words, err := completionWords(list)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		return words, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completionWords returns the candidates of a completion list, sorted.
+func completionWords(list string) ([]string, error) {
+	var words []string
+
+	switch list {
+	case "environments":
+		config, err := ReadConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		for name := range config {
+			words = append(words, name)
+		}
+	case "versions":
+		env, err := GetEnvironment()
+		if err != nil {
+			return nil, err
+		}
+
+		migrations, err := env.Source().FindMigrations()
+		if err != nil {
+			return nil, err
+		}
+
+		// Migrations are sorted by version already.
+		for _, m := range migrations {
+			if m.NumberPrefixMatches() != nil {
+				words = append(words, strconv.FormatInt(m.VersionInt(), 10))
+			}
+		}
+
+		return words, nil
+	default:
+		return nil, fmt.Errorf("Unknown completion list: %s", list)
+	}
+
+	sort.Strings(words)
+
+	return words, nil
+}
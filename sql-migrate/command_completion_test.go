@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type CompletionSuite struct{}
+
+var _ = Suite(&CompletionSuite{})
+
+func (*CompletionSuite) TestCompletionWords(c *C) {
+	dir := c.MkDir()
+	file := filepath.Join(dir, "dbconfig.yml")
+	content := "production:\n  dialect: postgres\ndevelopment:\n  dialect: sqlite3\n  dir: " + dir + "\n"
+	c.Assert(os.WriteFile(file, []byte(content), 0o644), IsNil)
+
+	for _, name := range []string{"2_b.sql", "10_c.sql", "1_a.sql", "init.sql"} {
+		c.Assert(os.WriteFile(filepath.Join(dir, name), []byte("-- +migrate Up\n"), 0o644), IsNil)
+	}
+
+	defer func() { ConfigFile, ConfigEnvironment = "", "" }()
+	ConfigFile = file
+	ConfigEnvironment = "development"
+
+	words, err := completionWords("environments")
+	c.Assert(err, IsNil)
+	c.Assert(words, DeepEquals, []string{"development", "production"})
+
+	words, err = completionWords("versions")
+	c.Assert(err, IsNil)
+	c.Assert(words, DeepEquals, []string{"1", "2", "10"})
+
+	_, err = completionWords("tables")
+	c.Assert(err, ErrorMatches, "Unknown completion list: tables")
+}
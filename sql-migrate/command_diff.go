@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kva3umoda/sql-migrate/schemadiff"
+)
+
+func newDiffCommand() *cobra.Command {
+	var (
+		snapshot string
+		save     string
+		sequence bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff [NAME]",
+		Short: "Draft a migration from the difference with a schema snapshot",
+		Long: `Compare the tables, columns and indexes of the database with a schema
+snapshot, and draft a migration NAME bringing the database to the snapshot,
+to be reviewed before applying. The DDL is written for PostgreSQL.
+
+With --save, write the schema of the database as a snapshot instead, to be
+edited and given back to --snapshot. PostgreSQL and SQLite are supported.`,
+		Args: func(_ *cobra.Command, args []string) error {
+			switch {
+			case save != "" && (len(args) > 0 || snapshot != ""):
+				return errors.New("The save option takes no name nor snapshot")
+			case save == "" && (len(args) != 1 || snapshot == ""):
+				return errors.New("A name for the migration and a snapshot are needed")
+			}
+
+			return nil
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			if save != "" {
+				return SaveSchema(save)
+			}
+
+			return DiffSchema(args[0], snapshot, sequence)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&snapshot, "snapshot", "", "schema snapshot to compare the database with")
+	f.StringVar(&save, "save", "", "write the schema of the database to this snapshot")
+	f.BoolVar(&sequence, "sequence", false, "prefix the file with the next sequence number instead of a timestamp")
+
+	return cmd
+}
+
+// introspect reads the schema of the database of the environment, without
+// the migration table.
+func introspect(env *Environment) (*schemadiff.Schema, error) {
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ex := env.Executor()
+
+	schema, err := schemadiff.Introspect(context.Background(), db, dialect, env.SchemaName)
+	if err != nil {
+		return nil, err
+	}
+
+	schema.Exclude(ex.TableName, ex.TableName+"_lock")
+
+	return schema, nil
+}
+
+// SaveSchema writes the schema of the database to the snapshot file.
+func SaveSchema(path string) error {
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	schema, err := introspect(env)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := schema.Save(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	ui.Info(fmt.Sprintf("Saved %d tables to %s", len(schema.Tables), path))
+
+	return nil
+}
+
+// DiffSchema drafts a migration bringing the database to the snapshot.
+func DiffSchema(name, snapshot string, sequence bool) error {
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	if err := env.checkWritable(); err != nil {
+		return err
+	}
+
+	f, err := os.Open(snapshot)
+	if err != nil {
+		return err
+	}
+
+	desired, err := schemadiff.Load(f)
+	_ = f.Close()
+
+	if err != nil {
+		return err
+	}
+
+	current, err := introspect(env)
+	if err != nil {
+		return err
+	}
+
+	changes := schemadiff.Diff(current, desired)
+	if len(changes) == 0 {
+		ui.Info("The database matches the snapshot")
+		return nil
+	}
+
+	pathName, _, err := migrationPath(env.Dir, name, sequence, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	// Never overwrite an existing migration.
+	out, err := os.OpenFile(pathName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := out.WriteString(schemadiff.Draft(changes)); err != nil {
+		_ = out.Close()
+		_ = os.Remove(pathName)
+
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	ui.Info(fmt.Sprintf("Drafted migration %s with %d changes, review it before applying", pathName, len(changes)))
+
+	return nil
+}
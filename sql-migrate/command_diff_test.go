@@ -0,0 +1,51 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type DiffSuite struct{}
+
+var _ = Suite(&DiffSuite{})
+
+func (*DiffSuite) TestSaveAndDiff(c *C) {
+	dir := c.MkDir()
+	dbPath := filepath.Join(dir, "test.db")
+
+	db, err := sql.Open("sqlite3", dbPath)
+	c.Assert(err, IsNil)
+
+	_, err = db.Exec("CREATE TABLE users (id integer PRIMARY KEY); CREATE TABLE migrations (id text)")
+	c.Assert(err, IsNil)
+	c.Assert(db.Close(), IsNil)
+
+	defer func(dir, dialect, dataSource string) {
+		ConfigDir, ConfigDialect, ConfigDataSource = dir, dialect, dataSource
+	}(ConfigDir, ConfigDialect, ConfigDataSource)
+	ConfigDir, ConfigDialect, ConfigDataSource = dir, "sqlite3", dbPath
+
+	snapshot := filepath.Join(dir, "schema.json")
+	c.Assert(SaveSchema(snapshot), IsNil)
+
+	data, err := os.ReadFile(snapshot)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(data), `"migrations"`), Equals, false)
+
+	// Nothing to draft while the database matches.
+	c.Assert(DiffSchema("same", snapshot, true), IsNil)
+
+	edited := strings.Replace(string(data), `"columns": [`, `"columns": [{"name": "email", "type": "text", "nullable": true},`, 1)
+	c.Assert(os.WriteFile(snapshot, []byte(edited), 0o644), IsNil)
+
+	c.Assert(DiffSchema("add email", snapshot, true), IsNil)
+
+	draft, err := os.ReadFile(filepath.Join(dir, "0001_add_email.sql"))
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(draft), `ALTER TABLE "users" ADD COLUMN "email" text;`), Equals, true)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func newDownCommand() *cobra.Command {
+	opts := ApplyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Undo a database migration",
+		Long: `Undo a database migration.
+
+Before reverting, the migrations are listed and the name of the environment
+must be typed to continue, unless --yes is given.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// The default limit only applies when no other target is given.
+			if (opts.Version >= 0 || opts.To >= 0) && !cmd.Flags().Changed("limit") {
+				opts.Limit = 0
+			}
+
+			return ApplyMigrations(migrate.Down, opts)
+		},
+	}
+
+	f := cmd.Flags()
+	f.IntVar(&opts.Limit, "limit", 1, "limit the number of migrations (0 = unlimited)")
+	f.Int64Var(&opts.Version, "version", -1, "migrate down to a specific version")
+	f.Int64Var(&opts.To, "to", -1, "roll back every migration newer than a specific version")
+	f.BoolVar(&opts.DryRun, "dry-run", false, "don't apply migrations, just print the SQL they would run")
+	f.BoolVar(&opts.Yes, "yes", false, "don't ask to confirm the migrations to revert")
+	f.StringVar(&opts.Confirm, "confirm", "", "token confirming destructive migrations, given by the refused run")
+	f.IntVar(&opts.Parallel, "parallel", 1, "number of datasources migrated at the same time")
+	f.BoolVar(&opts.ContinueOnError, "continue-on-error", false, "keep migrating the other datasources after a failure")
+
+	return cmd
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+// Exit codes of the drift command, the same as those of check.
+const (
+	driftNone  = checkUpToDate
+	driftFound = checkDrift
+	driftError = checkError
+)
+
+func newDriftCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "drift",
+		Short: "Detect applied migrations that were edited or removed",
+		Long: `Verify the checksums stored for the applied migrations against the
+migration directory.
+
+Reports migrations which were edited after they were applied and applied
+migrations missing from the migration directory. Exits with 0 when there
+is no drift, 2 on drift, like check, and 3 when the check itself fails or
+is called with invalid flags. Migrations applied before checksums were
+stored can't be verified and are skipped.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{errorCodeAnnotation: strconv.Itoa(driftError)},
+		RunE: func(*cobra.Command, []string) error {
+			if code := runDrift(); code != driftNone {
+				return exitCode(code)
+			}
+
+			return nil
+		},
+	}
+}
+
+// runDrift reports the drift of the database and returns the exit code.
+func runDrift() int {
+	env, err := GetEnvironment()
+	if err != nil {
+		ui.Error(fmt.Sprintf("Could not parse config: %s", err))
+		return driftError
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		ui.Error(err.Error())
+		return driftError
+	}
+	defer db.Close()
+
+	migrations, err := env.Source().FindMigrations()
+	if err != nil {
+		ui.Error(err.Error())
+		return driftError
+	}
+
+	records, err := env.Executor().GetMigrationRecords(context.Background(), db, dialect)
+	if err != nil {
+		ui.Error(err.Error())
+		return driftError
+	}
+
+	report := findDrift(migrations, records)
+
+	if len(report.Unverified) > 0 {
+		ui.Info(fmt.Sprintf("Skipped %d migrations applied without a checksum of sql-migrate", len(report.Unverified)))
+	}
+
+	if len(report.Edited) == 0 && len(report.Missing) == 0 {
+		ui.Info("No drift detected")
+		return driftNone
+	}
+
+	if len(report.Edited) > 0 {
+		ui.Error(fmt.Sprintf("Migrations edited after they were applied: %s", strings.Join(report.Edited, ", ")))
+	}
+
+	if len(report.Missing) > 0 {
+		ui.Error(fmt.Sprintf("Migrations applied but missing from %s: %s", env.Dir, strings.Join(report.Missing, ", ")))
+	}
+
+	return driftFound
+}
+
+// driftReport lists the ids of the applied migrations which don't match the source.
+type driftReport struct {
+	// Edited migrations have a stored checksum different from the source.
+	Edited []string
+	// Missing migrations are applied, but not in the source.
+	Missing []string
+	// Unverified migrations were applied without storing a checksum, or
+	// imported with the checksum of another tool.
+	Unverified []string
+}
+
+// findDrift compares the records of the applied migrations with the source,
+// in the order of the records.
+func findDrift(migrations []*migrate.Migration, records []migrate.MigrationRecord) driftReport {
+	source := make(map[string]*migrate.Migration, len(migrations))
+	for _, m := range migrations {
+		source[m.Id] = m
+	}
+
+	var report driftReport
+
+	for _, record := range records {
+		m, ok := source[record.Id]
+
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, record.Id)
+		case !record.Verifiable():
+			report.Unverified = append(report.Unverified, record.Id)
+		case record.Checksum != m.Checksum():
+			report.Edited = append(report.Edited, record.Id)
+		}
+	}
+
+	return report
+}
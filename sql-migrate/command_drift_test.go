@@ -0,0 +1,35 @@
+package main
+
+import (
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+type DriftSuite struct{}
+
+var _ = Suite(&DriftSuite{})
+
+func (*DriftSuite) TestFindDrift(c *C) {
+	migrations := []*migrate.Migration{
+		{Id: "1_a.sql", Up: []string{"CREATE TABLE a (id int);"}},
+		{Id: "2_b.sql", Up: []string{"CREATE TABLE b (id int);"}},
+		{Id: "3_c.sql", Up: []string{"CREATE TABLE c (id int);"}},
+		{Id: "4_d.sql", Up: []string{"CREATE TABLE d (id int);"}},
+	}
+	records := []migrate.MigrationRecord{
+		{Id: "1_a.sql", Checksum: migrations[0].Checksum()},
+		{Id: "2_b.sql", Checksum: migrations[0].Checksum()},
+		{Id: "3_c.sql"},
+		{Id: "5_e.sql", Checksum: migrations[0].Checksum()},
+	}
+
+	c.Assert(findDrift(migrations, records), DeepEquals, driftReport{
+		Edited:     []string{"2_b.sql"},
+		Missing:    []string{"5_e.sql"},
+		Unverified: []string{"3_c.sql"},
+	})
+
+	c.Assert(findDrift(migrations, records[:1]), DeepEquals, driftReport{})
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func newExportCommand() *cobra.Command {
+	var (
+		out     string
+		limit   int
+		version int64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the SQL of the pending migrations to a file",
+		Long: `Write the SQL of the pending migrations to a file, to be reviewed and
+executed by hand. Each migration is followed by the insert into the
+migration table recording it, and wrapped in a transaction unless it is
+marked notransaction.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return ExportMigrations(out, limit, version)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&out, "out", "-", "file to write, - for stdout")
+	f.IntVar(&limit, "limit", 0, "limit the number of migrations (0 = unlimited)")
+	f.Int64Var(&version, "version", -1, "export up to a specific version")
+
+	return cmd
+}
+
+// ExportMigrations writes the pending migrations and their bookkeeping to out.
+func ExportMigrations(out string, limit int, version int64) error {
+	if limit > 0 && version >= 0 {
+		return errors.New("The limit and version options are mutually exclusive")
+	}
+
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	ex := env.Executor()
+
+	var planned []*migrate.PlannedMigration
+	if version >= 0 {
+		planned, _, err = ex.PlanMigrationToVersion(ctx, db, dialect, env.Source(), migrate.Up, version)
+	} else {
+		planned, _, err = ex.PlanMigration(ctx, db, dialect, env.Source(), migrate.Up, limit)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Cannot plan migration: %w", err)
+	}
+
+	w := io.Writer(os.Stdout)
+
+	if out != "" && out != "-" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = f.Close() }()
+
+		w = f
+	}
+
+	script := exportScript{
+		dialect:     migrate.DialectName(env.Dialect),
+		createTable: dialect.QueryCreateMigrateTable(ex.SchemaName, ex.TableName),
+		insert:      dialect.QueryInsertMigrate(ex.SchemaName, ex.TableName),
+	}
+
+	if _, err := io.WriteString(w, script.render(planned, time.Now().UTC())); err != nil {
+		return err
+	}
+
+	if w != io.Writer(os.Stdout) {
+		ui.Info(fmt.Sprintf("Exported %d migrations to %s", len(planned), out))
+	}
+
+	return nil
+}
+
+// exportScript renders planned migrations as a standalone SQL script.
+type exportScript struct {
+	dialect     migrate.DialectName
+	createTable string
+	insert      string
+}
+
+func (s exportScript) render(planned []*migrate.PlannedMigration, now time.Time) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "-- Generated by sql-migrate on %s: %d pending migrations.\n\n", now.Format(time.RFC3339), len(planned))
+	b.WriteString(terminate(s.createTable) + "\n")
+
+	begin, commit := s.transaction()
+
+	for _, m := range planned {
+		fmt.Fprintf(&b, "\n-- Migration %s\n", m.Id)
+
+		transactional := !m.DisableTransaction && begin != ""
+		if transactional {
+			b.WriteString(begin + "\n")
+		}
+
+		for _, q := range m.Queries {
+			b.WriteString(strings.TrimRight(q, "\n") + "\n")
+		}
+
+		b.WriteString(terminate(inlineArgs(s.insert, quoteLiteral(m.Id), "CURRENT_TIMESTAMP")) + "\n")
+
+		if transactional {
+			b.WriteString(commit + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// transaction returns the statements starting and committing a transaction,
+// or nothing for dialects without a portable syntax.
+func (s exportScript) transaction() (string, string) {
+	switch s.dialect {
+	case migrate.Postgres, migrate.SQLite3:
+		return "BEGIN;", "COMMIT;"
+	case migrate.MySQL:
+		return "START TRANSACTION;", "COMMIT;"
+	case migrate.MSSQL:
+		return "BEGIN TRANSACTION;", "COMMIT TRANSACTION;"
+	}
+
+	return "", ""
+}
+
+func terminate(query string) string {
+	query = strings.TrimSpace(query)
+	if strings.HasSuffix(query, ";") {
+		return query
+	}
+
+	return query + ";"
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// inlineArgs replaces the placeholders of query ($1, :1, @p1 or ?) with the
+// SQL expressions of args, in order. Placeholders inside quotes are kept.
+func inlineArgs(query string, args ...string) string {
+	var (
+		b     strings.Builder
+		quote rune
+		next  int
+	)
+
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if quote != 0 {
+			if r == quote {
+				quote = 0
+			}
+
+			b.WriteRune(r)
+
+			continue
+		}
+
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case next < len(args) && r == '?':
+			b.WriteString(args[next])
+			next++
+
+			continue
+		case next < len(args) && (r == '$' || r == ':' || r == '@'):
+			j := i + 1
+			if r == '@' && j < len(runes) && runes[j] == 'p' {
+				j++
+			}
+
+			k := j
+			for k < len(runes) && runes[k] >= '0' && runes[k] <= '9' {
+				k++
+			}
+
+			if k > j {
+				b.WriteString(args[next])
+				next++
+				i = k - 1
+
+				continue
+			}
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
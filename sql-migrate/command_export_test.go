@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+type ExportSuite struct{}
+
+var _ = Suite(&ExportSuite{})
+
+func (*ExportSuite) TestInlineArgs(c *C) {
+	c.Assert(inlineArgs("VALUES ($1, $2)", "'a'", "NOW()"), Equals, "VALUES ('a', NOW())")
+	c.Assert(inlineArgs("VALUES (?, ?)", "'a'", "NOW()"), Equals, "VALUES ('a', NOW())")
+	c.Assert(inlineArgs("VALUES (:1, :2)", "'a'", "NOW()"), Equals, "VALUES ('a', NOW())")
+	c.Assert(inlineArgs("VALUES (@p1, @p2)", "'a'", "NOW()"), Equals, "VALUES ('a', NOW())")
+	c.Assert(inlineArgs(`INSERT INTO "t?"(id) VALUES (?)`, "'a'"), Equals, `INSERT INTO "t?"(id) VALUES ('a')`)
+	c.Assert(quoteLiteral("it's"), Equals, "'it''s'")
+}
+
+func (*ExportSuite) TestRender(c *C) {
+	script := exportScript{
+		dialect:     migrate.Postgres,
+		createTable: "CREATE TABLE IF NOT EXISTS migrations (id text)",
+		insert:      "INSERT INTO migrations(id, applied_at) VALUES ($1, $2)",
+	}
+
+	planned := []*migrate.PlannedMigration{
+		{Migration: &migrate.Migration{Id: "1_a.sql"}, Queries: []string{"CREATE TABLE a (id int);\n"}},
+		{Migration: &migrate.Migration{Id: "2_b.sql"}, Queries: []string{"CREATE INDEX CONCURRENTLY i ON a (id);\n"}, DisableTransaction: true},
+	}
+
+	got := script.render(planned, time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC))
+	c.Assert(got, Equals, strings.Join([]string{
+		"-- Generated by sql-migrate on 2024-01-15T12:30:00Z: 2 pending migrations.",
+		"",
+		"CREATE TABLE IF NOT EXISTS migrations (id text);",
+		"",
+		"-- Migration 1_a.sql",
+		"BEGIN;",
+		"CREATE TABLE a (id int);",
+		"INSERT INTO migrations(id, applied_at) VALUES ('1_a.sql', CURRENT_TIMESTAMP);",
+		"COMMIT;",
+		"",
+		"-- Migration 2_b.sql",
+		"CREATE INDEX CONCURRENTLY i ON a (id);",
+		"INSERT INTO migrations(id, applied_at) VALUES ('2_b.sql', CURRENT_TIMESTAMP);",
+		"",
+	}, "\n"))
+}
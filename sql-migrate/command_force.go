@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/audit"
+)
+
+func newForceCommand() *cobra.Command {
+	var (
+		version int64
+		state   string
+		yes     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "force --version=NUMBER --state=applied|pending",
+		Short: "Record a migration as applied or pending without running it",
+		Long: `Correct the migration table after manual intervention, by recording a
+migration as applied or removing its record, without running it.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return ForceMigration(version, state, yes)
+		},
+	}
+
+	f := cmd.Flags()
+	f.Int64Var(&version, "version", -1, "version of the migration to change")
+	f.StringVar(&state, "state", "", "state to record, applied or pending")
+	f.BoolVar(&yes, "yes", false, "don't ask for confirmation")
+
+	return cmd
+}
+
+// ForceMigration records the migration with the version as applied, or
+// deletes its record for the pending state.
+func ForceMigration(version int64, state string, yes bool) error {
+	if version < 0 {
+		return errors.New("The version option is required")
+	}
+
+	if state != "applied" && state != "pending" {
+		return errors.New("The state option must be applied or pending")
+	}
+
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrations, err := env.Source().FindMigrations()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	// The record to change may belong to a migration missing from the source.
+	ex := env.Executor()
+	ex.IgnoreUnknown = true
+
+	_, rep, err := ex.PlanMigration(ctx, db, dialect, migrate.NewMemoryMigrationSource(migrations), migrate.Up, 0)
+	if err != nil {
+		return fmt.Errorf("Cannot read the migration table: %w", err)
+	}
+
+	records, err := rep.ListMigration(ctx)
+	if err != nil {
+		return err
+	}
+
+	m, applied, err := forcedMigration(migrations, records, version)
+	if err != nil {
+		return err
+	}
+
+	if applied == (state == "applied") {
+		return fmt.Errorf("Migration %s is already %s", m.Id, state)
+	}
+
+	if !yes {
+		if err := confirm("will be recorded as "+state, []string{m.Id}); err != nil {
+			return err
+		}
+	}
+
+	if state == "applied" {
+		err = rep.SaveMigration(ctx, migrate.MigrationRecord{Id: m.Id, AppliedAt: time.Now().UTC(), Checksum: m.Checksum(), AppliedBy: ex.AppliedBy})
+	} else {
+		err = rep.DeleteMigration(ctx, m.Id)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Cannot record migration %s as %s: %w", m.Id, state, err)
+	}
+
+	ex.Logger.Infof("AUDIT: %s forced migration %s to %s in %s at %s",
+		audit.CurrentUser(), m.Id, state, environmentName(), time.Now().UTC().Format(time.RFC3339))
+
+	return nil
+}
+
+// forcedMigration finds the migration with the version in the source, or among
+// the records for migrations missing from the source, and reports whether it
+// is applied.
+func forcedMigration(migrations []*migrate.Migration, records []migrate.MigrationRecord, version int64) (*migrate.Migration, bool, error) {
+	applied := make(map[string]bool, len(records))
+
+	candidates := append([]*migrate.Migration{}, migrations...)
+	for _, r := range records {
+		applied[r.Id] = true
+		candidates = append(candidates, &migrate.Migration{Id: r.Id})
+	}
+
+	for _, m := range candidates {
+		if m.NumberPrefixMatches() != nil && m.VersionInt() == version {
+			return m, applied[m.Id], nil
+		}
+	}
+
+	return nil, false, fmt.Errorf("No migration with version %d", version)
+}
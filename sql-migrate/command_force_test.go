@@ -0,0 +1,35 @@
+package main
+
+import (
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+type ForceSuite struct{}
+
+var _ = Suite(&ForceSuite{})
+
+func (*ForceSuite) TestForcedMigration(c *C) {
+	migrations := []*migrate.Migration{{Id: "1_a.sql"}, {Id: "2_b.sql"}, {Id: "init.sql"}}
+	records := []migrate.MigrationRecord{{Id: "1_a.sql"}, {Id: "3_removed.sql"}}
+
+	m, applied, err := forcedMigration(migrations, records, 1)
+	c.Assert(err, IsNil)
+	c.Assert(m.Id, Equals, "1_a.sql")
+	c.Assert(applied, Equals, true)
+
+	m, applied, err = forcedMigration(migrations, records, 2)
+	c.Assert(err, IsNil)
+	c.Assert(m.Id, Equals, "2_b.sql")
+	c.Assert(applied, Equals, false)
+
+	m, applied, err = forcedMigration(migrations, records, 3)
+	c.Assert(err, IsNil)
+	c.Assert(m.Id, Equals, "3_removed.sql")
+	c.Assert(applied, Equals, true)
+
+	_, _, err = forcedMigration(migrations, records, 4)
+	c.Assert(err, ErrorMatches, "No migration with version 4")
+}
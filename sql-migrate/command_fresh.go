@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+// forceFlag overrides the protection of an environment.
+const forceFlag = "i-know-what-i-am-doing"
+
+func newFreshCommand() *cobra.Command {
+	var (
+		force bool
+		yes   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fresh",
+		Short: "Revert all migrations and apply them again",
+		Long: `Rebuild the database by reverting all applied migrations and applying
+all migrations again, e.g. for development and staging databases.
+
+Environments marked with "protected: true" in the config file are refused,
+unless --` + forceFlag + ` is given. Before reverting, the
+migrations are listed and the name of the environment must be typed to
+continue, unless --yes is given.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return FreshMigrations(force, yes)
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&force, forceFlag, false, "run against a protected environment")
+	f.BoolVar(&yes, "yes", false, "don't ask to confirm the migrations to revert")
+
+	return cmd
+}
+
+// FreshMigrations reverts all applied migrations and applies all migrations.
+func FreshMigrations(force, yes bool) error {
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	if err := checkProtected(env, force); err != nil {
+		return err
+	}
+
+	if len(env.DataSources) > 0 {
+		return errors.New("The fresh command doesn't support environments with several datasources")
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	ex := env.Executor()
+	source := env.Source()
+
+	if !yes {
+		migrations, _, err := ex.PlanMigration(ctx, db, dialect, source, migrate.Down, 0)
+		if err != nil {
+			return fmt.Errorf("Cannot plan migration: %w", err)
+		}
+
+		if err := ConfirmRevert(migrations); err != nil {
+			return err
+		}
+	}
+
+	n, err := ex.ExecMaxContext(ctx, db, dialect, source, migrate.Down, 0)
+	if err != nil {
+		return fmt.Errorf("Migration (down) failed: %w", err)
+	}
+
+	ui.Info(fmt.Sprintf("Reverted %d migrations", n))
+
+	n, err = ex.ExecMaxContext(ctx, db, dialect, source, migrate.Up, 0)
+	if err != nil {
+		return fmt.Errorf("Migration (up) failed: %w", err)
+	}
+
+	ui.Info(fmt.Sprintf("Applied %d migrations", n))
+
+	return nil
+}
+
+// checkProtected refuses protected environments, unless forced.
+func checkProtected(env *Environment, force bool) error {
+	if env.Protected && !force {
+		return fmt.Errorf("The environment %s is protected, pass --%s to run anyway", environmentName(), forceFlag)
+	}
+
+	return nil
+}
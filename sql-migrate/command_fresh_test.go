@@ -0,0 +1,16 @@
+package main
+
+import (
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type FreshSuite struct{}
+
+var _ = Suite(&FreshSuite{})
+
+func (*FreshSuite) TestCheckProtected(c *C) {
+	c.Assert(checkProtected(&Environment{}, false), IsNil)
+	c.Assert(checkProtected(&Environment{Protected: true}, true), IsNil)
+	c.Assert(checkProtected(&Environment{Protected: true}, false), ErrorMatches, "The environment .* is protected, pass --i-know-what-i-am-doing to run anyway")
+}
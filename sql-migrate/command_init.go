@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newInitCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "init",
+		Short: "Create the migration table without applying migrations",
+		Long: `Create the schema of the migration table, when one is configured, and the
+migration table itself, then exit without applying any migration.
+
+Useful to prepare databases from provisioning tooling before the
+application runs for the first time.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return InitMigrationTable()
+		},
+	}
+}
+
+// InitMigrationTable creates the schema and the migration table of the environment.
+func InitMigrationTable() error {
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ex := env.Executor()
+	ex.CreateSchema = env.SchemaName != ""
+
+	if err := ex.Init(context.Background(), db, dialect); err != nil {
+		return fmt.Errorf("Cannot create the migration table: %w", err)
+	}
+
+	ui.Info(fmt.Sprintf("Migration table %s is ready", ex.TableName))
+
+	return nil
+}
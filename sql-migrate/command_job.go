@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kva3umoda/sql-migrate/k8smigrate"
+)
+
+func newJobCommand() *cobra.Command {
+	opts := k8smigrate.DefaultOptions()
+
+	cmd := &cobra.Command{
+		Use:   "job",
+		Short: "Apply the migrations as a Kubernetes Job",
+		Long: `Apply the migrations as a Kubernetes Job, or an init container.
+
+Waits for the database, holds the migration lock while applying, prints a
+JSON line per progress event and writes the outcome of the run as JSON to
+the termination message of the container. Exits with 0, or with 2 when
+migrations were applied with --detailed-exit-code, and 1 on failure.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			if code := runJob(opts); code != k8smigrate.ExitNoop {
+				return exitCode(code)
+			}
+
+			return nil
+		},
+	}
+
+	opts.RegisterFlags(cmd.Flags())
+
+	return cmd
+}
+
+// runJob applies the migrations of the environment and returns the exit code.
+func runJob(opts k8smigrate.Options) int {
+	opts.Progress = ui.Writer
+
+	summary, err := applyJob(opts)
+	if err != nil {
+		// The failure happened before applying, it's reported the same way.
+		summary = k8smigrate.Summary{Outcome: k8smigrate.Failed, Error: err.Error()}
+
+		ui.Error(err.Error())
+
+		if opts.TerminationLog != "" {
+			if err := k8smigrate.WriteTerminationLog(opts.TerminationLog, summary); err != nil {
+				ui.Error(fmt.Sprintf("Cannot write the termination log: %s", err))
+			}
+		}
+	}
+
+	return opts.ExitCode(summary)
+}
+
+func applyJob(opts k8smigrate.Options) (k8smigrate.Summary, error) {
+	env, err := GetEnvironment()
+	if err != nil {
+		return k8smigrate.Summary{}, fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	if len(env.DataSources) > 0 {
+		return k8smigrate.Summary{}, errors.New("The job command migrates a single datasource, run a job per datasource")
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return k8smigrate.Summary{}, err
+	}
+	defer db.Close()
+
+	// Kubernetes stops the container with SIGTERM, e.g. when the Job is deleted.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return k8smigrate.Apply(ctx, env.Executor(), db, dialect, env.Source(), opts), nil
+}
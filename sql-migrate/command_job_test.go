@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/k8smigrate"
+)
+
+type JobSuite struct{}
+
+var _ = Suite(&JobSuite{})
+
+func (*JobSuite) TestRunJob(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "1_people.sql"), []byte("-- +migrate Up\nCREATE TABLE people (id int);\n"), 0o644), IsNil)
+
+	defer func(dir, dialect, dataSource string) {
+		ConfigDir, ConfigDialect, ConfigDataSource = dir, dialect, dataSource
+	}(ConfigDir, ConfigDialect, ConfigDataSource)
+	ConfigDir, ConfigDialect, ConfigDataSource = dir, "sqlite3", filepath.Join(dir, "test.db")
+
+	opts := k8smigrate.Options{TerminationLog: filepath.Join(dir, "termination-log"), DetailedExitCode: true}
+
+	summary := func() k8smigrate.Summary {
+		content, err := os.ReadFile(opts.TerminationLog)
+		c.Assert(err, IsNil)
+
+		var s k8smigrate.Summary
+		c.Assert(json.Unmarshal(content, &s), IsNil)
+
+		return s
+	}
+
+	c.Assert(runJob(opts), Equals, k8smigrate.ExitApplied)
+	c.Assert(summary().Migrations, DeepEquals, []string{"1_people.sql"})
+
+	c.Assert(runJob(opts), Equals, k8smigrate.ExitNoop)
+	c.Assert(summary().Outcome, Equals, k8smigrate.Noop)
+
+	ConfigDialect = "nosuchdb"
+
+	c.Assert(runJob(opts), Equals, k8smigrate.ExitFailed)
+	c.Assert(summary().Outcome, Equals, k8smigrate.Failed)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func newLiquibaseCommand() *cobra.Command {
+	var (
+		searchPath  string
+		importState bool
+		dryRun      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "liquibase CHANGELOG",
+		Short: "Convert a Liquibase changelog to migrations",
+		Long: `Convert a Liquibase changelog, an XML, YAML or JSON file, and the changelogs
+it includes to a migration file per changeset in the migration directory.
+Only sql and sqlFile changes convert, render the others with liquibase
+update-sql first. The files already converted are left alone, so the
+command can run again.
+
+With --import, the changesets Liquibase applied, according to its
+DATABASECHANGELOG table, are recorded as applied too.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return ConvertLiquibase(args[0], searchPath, importState, dryRun)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&searchPath, "search-path", ".", "search path of Liquibase, the paths of the changelogs are relative to it")
+	f.BoolVar(&importState, "import", false, "record the changesets applied by Liquibase as applied")
+	f.BoolVar(&dryRun, "dry-run", false, "with --import, list the migrations to record without recording them")
+
+	return cmd
+}
+
+// ConvertLiquibase writes the migrations of the Liquibase changelog, and
+// imports its state when importState is set.
+func ConvertLiquibase(changelog, searchPath string, importState, dryRun bool) error {
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	if err := env.checkWritable(); err != nil {
+		return err
+	}
+
+	converted, err := migrate.ReadLiquibaseChangelog(os.DirFS(searchPath), changelog)
+	if err != nil {
+		return err
+	}
+
+	names, err := converted.WriteMigrations(env.Dir)
+	if err != nil {
+		return err
+	}
+
+	ui.Info(fmt.Sprintf("Converted %d changesets to %s", len(names), env.Dir))
+
+	if !importState {
+		return nil
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var opts []migrate.ImportOption
+	if dryRun {
+		opts = append(opts, migrate.WithImportDryRun())
+	}
+
+	report, err := env.Executor().ImportFromLiquibase(context.Background(), db, dialect, env.Source(), converted, opts...)
+	if err != nil {
+		return fmt.Errorf("Import failed: %w", err)
+	}
+
+	for _, id := range report.Unmatched {
+		ui.Error(fmt.Sprintf("Changeset %s matches no migration", id))
+	}
+
+	verb := "Recorded"
+	if dryRun {
+		verb = "Would record"
+	}
+
+	ui.Info(fmt.Sprintf("%s %d migrations as applied, %d were recorded already", verb, len(report.Imported), len(report.Existing)))
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+type LiquibaseSuite struct{}
+
+var _ = Suite(&LiquibaseSuite{})
+
+func (*LiquibaseSuite) TestConvertLiquibase(c *C) {
+	root := c.MkDir()
+	changelog := "databaseChangeLog:\n" +
+		"  - changeSet:\n      id: people\n      author: alice\n      changes:\n        - sql: CREATE TABLE people (id int)\n" +
+		"  - changeSet:\n      id: pets\n      author: alice\n      changes:\n        - sql: CREATE TABLE pets (id int)\n"
+	c.Assert(os.WriteFile(filepath.Join(root, "changelog.yaml"), []byte(changelog), 0o644), IsNil)
+
+	dbPath := filepath.Join(root, "test.db")
+
+	db, err := sql.Open("sqlite3", dbPath)
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE DATABASECHANGELOG (ID text, AUTHOR text, FILENAME text, DATEEXECUTED timestamp,
+		ORDEREXECUTED int, EXECTYPE text)`)
+	c.Assert(err, IsNil)
+
+	_, err = db.Exec("INSERT INTO DATABASECHANGELOG VALUES ('people', 'alice', 'changelog.yaml', '2024-01-02 00:00:00', 1, 'EXECUTED')")
+	c.Assert(err, IsNil)
+
+	defer func(dir, dialect, dataSource string) {
+		ConfigDir, ConfigDialect, ConfigDataSource = dir, dialect, dataSource
+	}(ConfigDir, ConfigDialect, ConfigDataSource)
+	ConfigDir, ConfigDialect, ConfigDataSource = filepath.Join(root, "migrations"), "sqlite3", dbPath
+
+	c.Assert(ConvertLiquibase("changelog.yaml", root, true, false), IsNil)
+
+	names, err := filepath.Glob(filepath.Join(ConfigDir, "*.sql"))
+	c.Assert(err, IsNil)
+	c.Assert(names, HasLen, 2)
+
+	records, err := migrate.NewMigrationExecutor().GetMigrationRecords(context.Background(), db, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].Id, Equals, "0001_people.sql")
+
+	// Converting again leaves the files and the records alone.
+	c.Assert(ConvertLiquibase("changelog.yaml", root, true, false), IsNil)
+}
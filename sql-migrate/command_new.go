@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const templateContent = `-- +migrate Up
+
+-- +migrate Down
+`
+
+var tpl = template.Must(template.New("new_migration").Parse(templateContent))
+
+// TemplateData is the data available to migration templates.
+type TemplateData struct {
+	// Name is the sanitized name of the migration.
+	Name      string
+	Timestamp time.Time
+	Author    string
+	Dialect   string
+}
+
+var (
+	nameSanitizer  = regexp.MustCompile(`[^a-z0-9]+`)
+	sequencePrefix = regexp.MustCompile(`^(\d+)_`)
+)
+
+func newNewCommand() *cobra.Command {
+	var (
+		sequence     bool
+		templateFile string
+		author       string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "new NAME",
+		Short: "Create a new migration",
+		Args: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("A name for the migration is needed")
+			}
+
+			return nil
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return CreateMigration(args[0], sequence, templateFile, author)
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&sequence, "sequence", false, "prefix the file with the next sequence number instead of a timestamp")
+	f.StringVar(&templateFile, "template", "", "Go text/template used for the file contents, with {{.Name}}, {{.Timestamp}}, {{.Author}} and {{.Dialect}}")
+	f.StringVar(&author, "author", "", "author passed to the template (default $SQL_MIGRATE_AUTHOR or the current user)")
+
+	return cmd
+}
+
+func CreateMigration(name string, sequence bool, templateFile, author string) error {
+	env, err := GetEnvironment()
+	if err != nil {
+		return err
+	}
+
+	if err := env.checkWritable(); err != nil {
+		return err
+	}
+
+	if templateFile == "" {
+		templateFile = env.Template
+	}
+
+	t := tpl
+	if templateFile != "" {
+		t, err = template.ParseFiles(templateFile)
+		if err != nil {
+			return fmt.Errorf("Cannot parse template: %w", err)
+		}
+	}
+
+	if _, err := os.Stat(env.Dir); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	pathName, name, err := migrationPath(env.Dir, name, sequence, now)
+	if err != nil {
+		return err
+	}
+
+	// Never overwrite an existing migration.
+	f, err := os.OpenFile(pathName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	data := TemplateData{
+		Name:      name,
+		Timestamp: now,
+		Author:    templateAuthor(author),
+		Dialect:   env.Dialect,
+	}
+
+	if err := t.Execute(f, data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(pathName)
+
+		return err
+	}
+
+	ui.Info(fmt.Sprintf("Created migration %s", pathName))
+
+	return nil
+}
+
+// migrationPath returns the path of a new migration in dir and its sanitized
+// name, prefixed with the next sequence number or the timestamp of now.
+func migrationPath(dir, name string, sequence bool, now time.Time) (string, string, error) {
+	name = strings.Trim(nameSanitizer.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", errors.New("The migration name must contain letters or digits")
+	}
+
+	prefix := now.Format("20060102150405")
+	if sequence {
+		var err error
+
+		prefix, err = nextSequence(dir)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return filepath.Join(dir, prefix+"_"+name+".sql"), name, nil
+}
+
+// nextSequence returns the successor of the highest numeric prefix in dir,
+// padded to the width of the existing prefixes (at least 4 digits).
+func nextSequence(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var last int64
+
+	width := 4
+
+	for _, entry := range entries {
+		matches := sequencePrefix.FindStringSubmatch(entry.Name())
+		if matches == nil || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		n, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if n > last {
+			last = n
+		}
+
+		if len(matches[1]) > width {
+			width = len(matches[1])
+		}
+	}
+
+	return fmt.Sprintf("%0*d", width, last+1), nil
+}
+
+func templateAuthor(author string) string {
+	if author != "" {
+		return author
+	}
+
+	if author := os.Getenv("SQL_MIGRATE_AUTHOR"); author != "" {
+		return author
+	}
+
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return ""
+}
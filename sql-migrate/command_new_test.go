@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type NewSuite struct{}
+
+var _ = Suite(&NewSuite{})
+
+func (*NewSuite) TestNextSequence(c *C) {
+	dir := c.MkDir()
+
+	next, err := nextSequence(dir)
+	c.Assert(err, IsNil)
+	c.Assert(next, Equals, "0001")
+
+	for _, name := range []string{"00041_b.sql", "7_a.sql", "99_notes.txt"} {
+		c.Assert(os.WriteFile(filepath.Join(dir, name), nil, 0o644), IsNil)
+	}
+
+	next, err = nextSequence(dir)
+	c.Assert(err, IsNil)
+	c.Assert(next, Equals, "00042")
+}
+
+func (*NewSuite) TestTemplate(c *C) {
+	dir := c.MkDir()
+	templateFile := filepath.Join(dir, "template.sql")
+	content := "-- {{.Name}} by {{.Author}} for {{.Dialect}}\n-- +migrate Up notransaction\n"
+	c.Assert(os.WriteFile(templateFile, []byte(content), 0o644), IsNil)
+
+	defer func(dir, dialect string) { ConfigDir, ConfigDialect = dir, dialect }(ConfigDir, ConfigDialect)
+	ConfigDir, ConfigDialect = dir, "postgres"
+
+	c.Assert(CreateMigration("Add Users", true, templateFile, "alice"), IsNil)
+
+	data, err := os.ReadFile(filepath.Join(dir, "0001_add_users.sql"))
+	c.Assert(err, IsNil)
+	c.Assert(strings.HasPrefix(string(data), "-- add_users by alice for postgres\n"), Equals, true)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func newRedoCommand() *cobra.Command {
+	var (
+		limit   int
+		dryrun  bool
+		yes     bool
+		confirm string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "redo",
+		Short: "Reapply the last migrations",
+		Long: `Reapply the last migrations.
+
+Before reverting, the migrations are listed and the name of the environment
+must be typed to continue, unless --yes is given.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			if limit < 1 {
+				return errors.New("The limit must be at least 1")
+			}
+
+			return RedoMigrations(limit, dryrun, yes, confirm)
+		},
+	}
+
+	f := cmd.Flags()
+	f.IntVar(&limit, "limit", 1, "number of migrations to reapply")
+	f.BoolVar(&dryrun, "dry-run", false, "don't apply migrations, just print them")
+	f.BoolVar(&yes, "yes", false, "don't ask to confirm the migrations to revert")
+	f.StringVar(&confirm, "confirm", "", "token confirming destructive migrations, given by the refused run")
+
+	return cmd
+}
+
+// RedoMigrations rolls back the last limit migrations and applies them again.
+// Unless yes is set, the rollback has to be confirmed. The token confirm
+// confirms the destructive migrations, see require_down_confirmation.
+func RedoMigrations(limit int, dryrun, yes bool, confirm string) error {
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	ex := env.Executor()
+	ex.AllowDestructive(confirm)
+	source := env.Source()
+
+	migrations, _, err := ex.PlanMigration(ctx, db, dialect, source, migrate.Down, limit)
+	if err != nil {
+		return fmt.Errorf("Cannot plan migration: %w", err)
+	}
+
+	if len(migrations) == 0 {
+		ui.Info("Nothing to do!")
+		return nil
+	}
+
+	if dryrun {
+		for _, m := range migrations {
+			PrintMigration(m, migrate.Down)
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			PrintMigration(&migrate.PlannedMigration{
+				Migration:          m.Migration,
+				Queries:            m.Up,
+				Lines:              m.UpLines,
+				DisableTransaction: m.DisableTransactionUp,
+			}, migrate.Up)
+		}
+
+		return nil
+	}
+
+	if !yes {
+		if err := ConfirmRevert(migrations); err != nil {
+			return err
+		}
+	}
+
+	_, err = ex.ExecMaxContext(ctx, db, dialect, source, migrate.Down, len(migrations))
+	if err != nil {
+		return fmt.Errorf("Migration (down) failed: %w", err)
+	}
+
+	n, err := ex.ExecMaxContext(ctx, db, dialect, source, migrate.Up, len(migrations))
+	if err != nil {
+		return fmt.Errorf("Migration (up) failed: %w", err)
+	}
+
+	if n == 1 {
+		ui.Info("Reapplied 1 migration")
+	} else {
+		ui.Info(fmt.Sprintf("Reapplied %d migrations", n))
+	}
+
+	return nil
+}
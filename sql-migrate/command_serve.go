@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/prommigrate"
+)
+
+func newServeCommand() *cobra.Command {
+	var listen, tokenEnv, tokenFile string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an HTTP API to observe and run migrations",
+		Long: `Serve an HTTP API to observe and run migrations.
+
+Every request must carry the token in an "Authorization: Bearer <token>"
+header. The endpoints answer with JSON:
+
+  GET  /status                    State of every migration.
+  GET  /plan?direction=up&limit=  Migrations (and queries) that would run.
+  POST /up?limit=&version=        Apply migrations.
+  POST /down?limit=1&version=     Revert migrations.
+  GET  /metrics                   Prometheus metrics of the migrations.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return serve(listen, tokenEnv, tokenFile)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&listen, "listen", ":8686", "address to listen on")
+	f.StringVar(&tokenEnv, "token-env", "SQL_MIGRATE_TOKEN", "environment variable holding the bearer token")
+	f.StringVar(&tokenFile, "token-file", "", "file holding the bearer token, instead of --token-env")
+
+	return cmd
+}
+
+// serve runs the HTTP API until interrupted.
+func serve(listen, tokenEnv, tokenFile string) error {
+	token := os.Getenv(tokenEnv)
+	if tokenFile != "" {
+		content, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return fmt.Errorf("Cannot read the token: %w", err)
+		}
+
+		token = strings.TrimRight(string(content), "\r\n")
+	}
+
+	if token == "" {
+		return errors.New("No token, set --token-env or --token-file")
+	}
+
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	db, d, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	srv := &http.Server{
+		Addr:              listen,
+		Handler:           newServer(env, db, d, token).handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	ui.Info(fmt.Sprintf("Listening on %s", listen))
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// server serves the HTTP API of a single environment.
+type server struct {
+	env     *Environment
+	db      *sql.DB
+	dialect dialect.Dialect
+	token   string
+
+	recorder *prommigrate.Recorder
+	registry *prometheus.Registry
+
+	// mu runs one migration of this process at a time, the lock of the
+	// executor guards against other processes.
+	mu sync.Mutex
+}
+
+func newServer(env *Environment, db *sql.DB, d dialect.Dialect, token string) *server {
+	s := &server{
+		env:      env,
+		db:       db,
+		dialect:  d,
+		token:    token,
+		recorder: prommigrate.NewRecorder(),
+		registry: prometheus.NewRegistry(),
+	}
+
+	s.registry.MustRegister(s.recorder)
+
+	return s
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.method(http.MethodGet, s.handleStatus))
+	mux.HandleFunc("/plan", s.method(http.MethodGet, s.handlePlan))
+	mux.HandleFunc("/up", s.method(http.MethodPost, s.handleExec(migrate.Up)))
+	mux.HandleFunc("/down", s.method(http.MethodPost, s.handleExec(migrate.Down)))
+	mux.HandleFunc("/metrics", s.method(http.MethodGet, s.handleMetrics))
+
+	return s.authenticate(mux)
+}
+
+func (s *server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (*server) method(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.env.Executor().Status(r.Context(), s.db, s.dialect, s.env.Source())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statusRows(status))
+}
+
+type planResponse struct {
+	Direction  string          `json:"direction"`
+	Migrations []plannedResult `json:"migrations"`
+}
+
+type plannedResult struct {
+	Id            string   `json:"id"`
+	NoTransaction bool     `json:"notransaction,omitempty"`
+	Queries       []string `json:"queries"`
+}
+
+func (s *server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	dir := migrate.Up
+	if r.URL.Query().Get("direction") == "down" {
+		dir = migrate.Down
+	}
+
+	limit, version, err := targetParams(r, dir)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	ex := s.env.Executor()
+
+	var planned []*migrate.PlannedMigration
+	if version >= 0 {
+		planned, _, err = ex.PlanMigrationToVersion(r.Context(), s.db, s.dialect, s.env.Source(), dir, version)
+	} else {
+		planned, _, err = ex.PlanMigration(r.Context(), s.db, s.dialect, s.env.Source(), dir, limit)
+	}
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := planResponse{Direction: dir.String(), Migrations: make([]plannedResult, 0, len(planned))}
+	for _, m := range planned {
+		response.Migrations = append(response.Migrations, plannedResult{
+			Id:            m.Id,
+			NoTransaction: m.DisableTransaction,
+			Queries:       m.Queries,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+type execResponse struct {
+	Direction string `json:"direction"`
+	Applied   int    `json:"applied"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (s *server) handleExec(dir migrate.MigrationDirection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, version, err := targetParams(r, dir)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		ex := s.env.Executor()
+		ex.Observer = migrate.Observers(ex.Observer, s.recorder)
+
+		var n int
+		if version >= 0 {
+			n, err = ex.ExecVersionContext(r.Context(), s.db, s.dialect, s.env.Source(), dir, version)
+		} else {
+			n, err = ex.ExecMaxContext(r.Context(), s.db, s.dialect, s.env.Source(), dir, limit)
+		}
+
+		response := execResponse{Direction: dir.String(), Applied: n}
+		if err != nil {
+			response.Error = err.Error()
+			writeJSON(w, http.StatusInternalServerError, response)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// handleMetrics serves the metrics, with the pending migrations planned afresh.
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	ex := s.env.Executor()
+
+	planned, _, err := ex.PlanMigration(r.Context(), s.db, s.dialect, s.env.Source(), migrate.Up, 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.recorder.SetPending(ex.SchemaName, ex.TableName, len(planned))
+
+	promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// targetParams reads the limit and version query parameters. Down defaults to
+// a single migration, like the down command.
+func targetParams(r *http.Request, dir migrate.MigrationDirection) (int, int64, error) {
+	query := r.URL.Query()
+
+	limit := 0
+	if dir == migrate.Down {
+		limit = 1
+	}
+
+	var version int64 = -1
+
+	if v := query.Get("version"); v != "" {
+		var err error
+
+		version, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || version < 0 {
+			return 0, 0, fmt.Errorf("invalid version %q", v)
+		}
+
+		limit = 0
+	}
+
+	if v := query.Get("limit"); v != "" {
+		if version >= 0 {
+			return 0, 0, errors.New("limit and version are mutually exclusive")
+		}
+
+		var err error
+
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return 0, 0, fmt.Errorf("invalid limit %q", v)
+		}
+	}
+
+	return limit, version, nil
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+type ServeSuite struct {
+	db  *sql.DB
+	srv *httptest.Server
+}
+
+var _ = Suite(&ServeSuite{})
+
+func (s *ServeSuite) SetUpTest(c *C) {
+	dir := c.MkDir()
+	migration := "-- +migrate Up\nCREATE TABLE people (id int);\n-- +migrate Down\nDROP TABLE people;\n"
+	c.Assert(os.WriteFile(filepath.Join(dir, "1_people.sql"), []byte(migration), 0o644), IsNil)
+
+	var err error
+	s.db, err = sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	c.Assert(err, IsNil)
+
+	env := &Environment{Dialect: "sqlite3", Dir: dir}
+	s.srv = httptest.NewServer(newServer(env, s.db, dialect.NewSqliteDialect(), "secret").handler())
+
+	ui.Writer = io.Discard
+}
+
+func (s *ServeSuite) TearDownTest(c *C) {
+	ui.Writer = os.Stdout
+	s.srv.Close()
+	c.Assert(s.db.Close(), IsNil)
+}
+
+func (s *ServeSuite) request(c *C, method, path, token string, v any) int {
+	req, err := http.NewRequest(method, s.srv.URL+path, nil)
+	c.Assert(err, IsNil)
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, IsNil)
+
+	defer resp.Body.Close()
+
+	if v != nil {
+		c.Assert(json.NewDecoder(resp.Body).Decode(v), IsNil)
+	}
+
+	return resp.StatusCode
+}
+
+func (s *ServeSuite) TestAuthentication(c *C) {
+	c.Assert(s.request(c, http.MethodGet, "/status", "", nil), Equals, http.StatusUnauthorized)
+	c.Assert(s.request(c, http.MethodGet, "/status", "wrong", nil), Equals, http.StatusUnauthorized)
+	c.Assert(s.request(c, http.MethodGet, "/up", "secret", nil), Equals, http.StatusMethodNotAllowed)
+}
+
+func (s *ServeSuite) TestPlanAndUp(c *C) {
+	var plan planResponse
+	c.Assert(s.request(c, http.MethodGet, "/plan", "secret", &plan), Equals, http.StatusOK)
+	c.Assert(plan.Migrations, HasLen, 1)
+	c.Assert(plan.Migrations[0].Id, Equals, "1_people.sql")
+
+	var result execResponse
+	c.Assert(s.request(c, http.MethodPost, "/up", "secret", &result), Equals, http.StatusOK)
+	c.Assert(result.Applied, Equals, 1)
+
+	var rows []*statusRow
+	c.Assert(s.request(c, http.MethodGet, "/status", "secret", &rows), Equals, http.StatusOK)
+	c.Assert(rows, HasLen, 1)
+	c.Assert(rows[0].State, Equals, "applied")
+
+	c.Assert(s.request(c, http.MethodPost, "/down?limit=x", "secret", nil), Equals, http.StatusBadRequest)
+	c.Assert(s.request(c, http.MethodPost, "/down", "secret", &result), Equals, http.StatusOK)
+	c.Assert(result.Applied, Equals, 1)
+}
+
+func (s *ServeSuite) TestMetrics(c *C) {
+	metrics := func() string {
+		req, err := http.NewRequest(http.MethodGet, s.srv.URL+"/metrics", nil)
+		c.Assert(err, IsNil)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, IsNil)
+
+		defer resp.Body.Close()
+
+		c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+		body, err := io.ReadAll(resp.Body)
+		c.Assert(err, IsNil)
+
+		return string(body)
+	}
+
+	c.Assert(metrics(), Matches, `(?s).*sql_migrate_pending_migrations\{schema="",table="migrations"\} 1\n.*`)
+
+	c.Assert(s.request(c, http.MethodPost, "/up", "secret", nil), Equals, http.StatusOK)
+
+	body := metrics()
+	c.Assert(body, Matches, `(?s).*sql_migrate_pending_migrations\{schema="",table="migrations"\} 0\n.*`)
+	c.Assert(body, Matches, `(?s).*sql_migrate_migrations_applied_total\{direction="up"\} 1\n.*`)
+}
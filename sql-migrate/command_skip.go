@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func newSkipCommand() *cobra.Command {
+	var (
+		limit   int
+		version int64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "skip",
+		Short: "Sets the database level to the most recent version available, without running the migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return SkipMigrations(limit, version)
+		},
+	}
+
+	f := cmd.Flags()
+	f.IntVar(&limit, "limit", 0, "limit the number of migrations (0 = unlimited)")
+	f.Int64Var(&version, "version", -1, "skip up to and including a specific version")
+
+	return cmd
+}
+
+// SkipMigrations records pending migrations as applied without running them.
+func SkipMigrations(limit int, version int64) error {
+	if limit > 0 && version >= 0 {
+		return errors.New("The limit and version options are mutually exclusive")
+	}
+
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	ex := env.Executor()
+	source := env.Source()
+
+	if version >= 0 {
+		// SkipMax has no version variant, so translate the version into a limit.
+		planned, _, err := ex.PlanMigrationToVersion(ctx, db, dialect, source, migrate.Up, version)
+		if err != nil {
+			return fmt.Errorf("Cannot plan migration: %w", err)
+		}
+
+		if len(planned) == 0 {
+			ui.Info("Skipped 0 migrations")
+			return nil
+		}
+
+		limit = len(planned)
+	}
+
+	n, err := ex.SkipMax(ctx, db, dialect, source, migrate.Up, limit)
+	if err != nil {
+		return fmt.Errorf("Migration failed: %w", err)
+	}
+
+	if n == 1 {
+		ui.Info("Skipped 1 migration")
+	} else {
+		ui.Info(fmt.Sprintf("Skipped %d migrations", n))
+	}
+
+	return nil
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+// squashOptions configures SquashMigrations.
+type squashOptions struct {
+	Through    int64
+	Name       string
+	ArchiveDir string
+	RecordOnly bool
+	DryRun     bool
+}
+
+func newSquashCommand() *cobra.Command {
+	opts := squashOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "squash --through=NUMBER",
+		Short: "Replace the oldest migrations with a single baseline",
+		Long: `Replace the migrations up to and including a version with a single
+baseline migration.
+
+The baseline concatenates the Up statements of the squashed migrations
+(and their Down statements in reverse). The squashed files are moved to
+the archive directory, and the migration table of the environment is
+updated to record the baseline instead of the squashed migrations. All
+squashed migrations must be applied.
+
+Run with --record-only against the other environments once the baseline
+is committed, to update their migration tables the same way.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			if opts.Through < 0 {
+				return errors.New("The through option is required")
+			}
+
+			return SquashMigrations(opts)
+		},
+	}
+
+	f := cmd.Flags()
+	f.Int64Var(&opts.Through, "through", -1, "version of the last migration to squash")
+	f.StringVar(&opts.Name, "name", "baseline", "name of the baseline migration")
+	f.StringVar(&opts.ArchiveDir, "archive", "", "where to move the squashed files (default <dir>/archive)")
+	f.BoolVar(&opts.RecordOnly, "record-only", false, "only update the migration table, e.g. of another environment")
+	f.BoolVar(&opts.DryRun, "dry-run", false, "don't change anything, just print the plan")
+
+	return cmd
+}
+
+// SquashMigrations replaces the migrations up to opts.Through with a baseline.
+func SquashMigrations(opts squashOptions) error {
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	if err := env.checkWritable(); err != nil {
+		return err
+	}
+
+	if len(env.DataSources) > 0 {
+		return errors.New("The squash command doesn't support several datasources, use -datasource")
+	}
+
+	if opts.ArchiveDir == "" {
+		opts.ArchiveDir = filepath.Join(env.Dir, "archive")
+	}
+
+	migrations, err := env.Source().FindMigrations()
+	if err != nil {
+		return err
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	ex := env.Executor()
+
+	records, err := ex.GetMigrationRecords(ctx, db, dialect)
+	if err != nil {
+		return err
+	}
+
+	var (
+		baseline *migrate.Migration
+		squashed []*migrate.Migration
+	)
+
+	if opts.RecordOnly {
+		baseline, squashed, err = recordedSquash(migrations, records, opts.Through)
+	} else {
+		squashed, err = squashedMigrations(migrations, records, opts.Through)
+		if err == nil {
+			baseline = squashMigration(fmt.Sprintf("%d_%s.sql", opts.Through, opts.Name), squashed)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var appliedAt time.Time
+
+	ids := make(map[string]bool, len(squashed))
+	for _, m := range squashed {
+		ids[m.Id] = true
+	}
+
+	for _, r := range records {
+		if ids[r.Id] && r.AppliedAt.After(appliedAt) {
+			appliedAt = r.AppliedAt
+		}
+	}
+
+	if opts.DryRun {
+		ui.Output(fmt.Sprintf("Would squash %d migrations into %s:", len(squashed), baseline.Id))
+
+		for _, m := range squashed {
+			ui.Output("    " + m.Id)
+		}
+
+		return nil
+	}
+
+	// Change the files first, they are easier to restore than the database.
+	restore := func() {}
+
+	if !opts.RecordOnly {
+		restore, err = writeSquash(env.Dir, opts.ArchiveDir, baseline, squashed)
+		if err != nil {
+			return err
+		}
+	}
+
+	rep := migrate.NewMigrationRepository(db, dialect, ex.SchemaName, ex.TableName, ex.Logger)
+
+	if err := rep.DetectColumns(ctx, ex.CreateTable); err != nil {
+		restore()
+		return err
+	}
+
+	if err := recordSquash(ctx, rep, baseline, squashed, appliedAt); err != nil {
+		restore()
+		return fmt.Errorf("Cannot record the baseline: %w", err)
+	}
+
+	ui.Info(fmt.Sprintf("Squashed %d migrations into %s", len(squashed), baseline.Id))
+
+	return nil
+}
+
+// squashedMigrations returns the migrations up to and including version, all
+// of which must be applied.
+func squashedMigrations(migrations []*migrate.Migration, records []migrate.MigrationRecord, version int64) ([]*migrate.Migration, error) {
+	applied := make(map[string]bool, len(records))
+	for _, r := range records {
+		applied[r.Id] = true
+	}
+
+	var (
+		squashed []*migrate.Migration
+		found    bool
+	)
+
+	for _, m := range migrations {
+		if len(m.NumberPrefixMatches()) == 0 || m.VersionInt() > version {
+			continue
+		}
+
+		if !applied[m.Id] {
+			return nil, fmt.Errorf("Migration %s is not applied, apply it before squashing", m.Id)
+		}
+
+		found = found || m.VersionInt() == version
+		squashed = append(squashed, m)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("Version %d is not a migration", version)
+	}
+
+	if len(squashed) < 2 {
+		return nil, errors.New("Nothing to squash, at least two migrations are needed")
+	}
+
+	return squashed, nil
+}
+
+// recordedSquash finds the baseline of version in the source and the applied
+// migrations it replaces, which are no longer part of the source.
+func recordedSquash(migrations []*migrate.Migration, records []migrate.MigrationRecord, version int64) (*migrate.Migration, []*migrate.Migration, error) {
+	var baseline *migrate.Migration
+
+	known := make(map[string]bool, len(migrations))
+
+	for _, m := range migrations {
+		known[m.Id] = true
+
+		if len(m.NumberPrefixMatches()) > 0 && m.VersionInt() == version {
+			baseline = m
+		}
+	}
+
+	if baseline == nil {
+		return nil, nil, fmt.Errorf("Version %d is not a migration", version)
+	}
+
+	var squashed []*migrate.Migration
+
+	for _, r := range records {
+		m := &migrate.Migration{Id: r.Id}
+		if r.Id == baseline.Id {
+			return nil, nil, fmt.Errorf("The baseline %s is already recorded", baseline.Id)
+		}
+
+		if !known[r.Id] && len(m.NumberPrefixMatches()) > 0 && m.VersionInt() <= version {
+			squashed = append(squashed, m)
+		}
+	}
+
+	if len(squashed) == 0 {
+		return nil, nil, errors.New("No squashed migrations are recorded")
+	}
+
+	return baseline, squashed, nil
+}
+
+// squashMigration concatenates the migrations into one.
+func squashMigration(id string, migrations []*migrate.Migration) *migrate.Migration {
+	baseline := &migrate.Migration{Id: id}
+
+	for _, m := range migrations {
+		baseline.Up = append(baseline.Up, m.Up...)
+		baseline.DisableTransactionUp = baseline.DisableTransactionUp || m.DisableTransactionUp
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		baseline.Down = append(baseline.Down, migrations[i].Down...)
+		baseline.DisableTransactionDown = baseline.DisableTransactionDown || migrations[i].DisableTransactionDown
+	}
+
+	return baseline
+}
+
+// renderMigration returns the migration file of m.
+func renderMigration(m *migrate.Migration, header string) string {
+	var b strings.Builder
+
+	b.WriteString(header + "\n")
+
+	// No blank line between the sections, the parser would keep it as the
+	// start of the first Down statement.
+	section := func(name string, noTransaction bool, statements []string) {
+		b.WriteString("-- +migrate " + name)
+
+		if noTransaction {
+			b.WriteString(" notransaction")
+		}
+
+		b.WriteString("\n")
+
+		for _, stmt := range statements {
+			stmt = strings.TrimSpace(stmt)
+
+			// Statements with inner semicolons, e.g. function bodies, need markers.
+			if strings.Count(stmt, ";") > 1 || !strings.HasSuffix(stmt, ";") {
+				b.WriteString("-- +migrate StatementBegin\n" + stmt + "\n-- +migrate StatementEnd\n")
+			} else {
+				b.WriteString(stmt + "\n")
+			}
+		}
+	}
+
+	section("Up", m.DisableTransactionUp, m.Up)
+	section("Down", m.DisableTransactionDown, m.Down)
+
+	return b.String()
+}
+
+// writeSquash writes the baseline and moves the squashed files to the archive.
+// The returned function undoes both.
+func writeSquash(dir, archiveDir string, baseline *migrate.Migration, squashed []*migrate.Migration) (func(), error) {
+	if err := os.MkdirAll(archiveDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(squashed))
+	for _, m := range squashed {
+		ids = append(ids, m.Id)
+	}
+
+	header := fmt.Sprintf("-- Squashed from %d migrations, archived in %s:\n-- %s\n", len(ids), archiveDir, strings.Join(ids, "\n-- "))
+
+	// Write the baseline under a temporary name, it may replace a squashed file.
+	baselinePath := filepath.Join(dir, baseline.Id)
+	tmpPath := baselinePath + ".tmp"
+
+	if err := os.WriteFile(tmpPath, []byte(renderMigration(baseline, header)), 0o644); err != nil {
+		return nil, err
+	}
+
+	var moved []string
+
+	restore := func() {
+		for _, id := range moved {
+			_ = os.Rename(filepath.Join(archiveDir, id), filepath.Join(dir, id))
+		}
+
+		_ = os.Remove(tmpPath)
+		_ = os.Remove(baselinePath)
+	}
+
+	for _, id := range ids {
+		if err := os.Rename(filepath.Join(dir, id), filepath.Join(archiveDir, id)); err != nil {
+			restore()
+			return nil, err
+		}
+
+		moved = append(moved, id)
+	}
+
+	if _, err := os.Stat(baselinePath); err == nil {
+		restore()
+		return nil, fmt.Errorf("%s already exists", baselinePath)
+	}
+
+	if err := os.Rename(tmpPath, baselinePath); err != nil {
+		restore()
+		return nil, err
+	}
+
+	return restore, nil
+}
+
+// recordSquash replaces the records of the squashed migrations with the baseline.
+func recordSquash(ctx context.Context, rep *migrate.MigrationRepository, baseline *migrate.Migration, squashed []*migrate.Migration, appliedAt time.Time) error {
+	tx, ctx, err := rep.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range squashed {
+		if err := rep.DeleteMigration(ctx, m.Id); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if err := rep.SaveMigration(ctx, migrate.MigrationRecord{Id: baseline.Id, AppliedAt: appliedAt, Checksum: baseline.Checksum()}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+type SquashSuite struct{}
+
+var _ = Suite(&SquashSuite{})
+
+func (*SquashSuite) TestSquashMigration(c *C) {
+	migrations := []*migrate.Migration{
+		{Id: "1_a.sql", Up: []string{"CREATE TABLE a (id int);\n"}, Down: []string{"DROP TABLE a;\n"}},
+		{
+			Id:                   "2_b.sql",
+			Up:                   []string{"CREATE FUNCTION f() AS $$ BEGIN; END; $$;\n"},
+			Down:                 []string{"DROP FUNCTION f;\n"},
+			DisableTransactionUp: true,
+		},
+	}
+	records := []migrate.MigrationRecord{{Id: "1_a.sql"}, {Id: "2_b.sql"}}
+
+	squashed, err := squashedMigrations(migrations, records, 2)
+	c.Assert(err, IsNil)
+	c.Assert(squashed, HasLen, 2)
+
+	_, err = squashedMigrations(migrations, records[:1], 2)
+	c.Assert(err, ErrorMatches, "Migration 2_b.sql is not applied.*")
+
+	baseline := squashMigration("2_baseline.sql", squashed)
+
+	parsed, err := sqlparse.ParseMigration(strings.NewReader(renderMigration(baseline, "-- header\n")))
+	c.Assert(err, IsNil)
+	c.Assert(parsed.DisableTransactionUp, Equals, true)
+	c.Assert(parsed.UpStatements, DeepEquals, []string{
+		"CREATE TABLE a (id int);\n",
+		"CREATE FUNCTION f() AS $$ BEGIN; END; $$;\n",
+	})
+	c.Assert(parsed.DownStatements, DeepEquals, []string{"DROP FUNCTION f;\n", "DROP TABLE a;\n"})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func newStatusCommand() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show migration status",
+		Args:  cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return showStatus(format)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "output format: table, json or yaml")
+
+	return cmd
+}
+
+// showStatus prints the state of every migration in the format.
+func showStatus(format string) error {
+	if format != "table" && format != "json" && format != "yaml" {
+		return fmt.Errorf("Unknown output format %q", format)
+	}
+
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	// Several datasources are printed per datasource, or keyed by their name.
+	var output any
+
+	if len(env.DataSources) > 0 {
+		targets := make(map[string][]*statusRow)
+
+		for _, target := range env.Targets() {
+			rows, err := loadStatus(target.Environment)
+			if err != nil {
+				return fmt.Errorf("%s: %w", target.Name, err)
+			}
+
+			if format == "table" {
+				ui.Output(fmt.Sprintf("==> %s", target.Name))
+				printStatusTable(rows)
+			}
+
+			targets[target.Name] = rows
+		}
+
+		output = targets
+	} else {
+		rows, err := loadStatus(env)
+		if err != nil {
+			return err
+		}
+
+		if format == "table" {
+			printStatusTable(rows)
+		}
+
+		output = rows
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(ui.Writer)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(output)
+	case "yaml":
+		enc := yaml.NewEncoder(ui.Writer)
+		err = enc.Encode(output)
+		if err == nil {
+			err = enc.Close()
+		}
+	}
+
+	return err
+}
+
+// loadStatus returns the status rows of the database of env.
+func loadStatus(env *Environment) ([]*statusRow, error) {
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	status, err := env.Executor().Status(context.Background(), db, dialect, env.Source())
+	if err != nil {
+		return nil, err
+	}
+
+	return statusRows(status), nil
+}
+
+type statusRow struct {
+	Id        string     `json:"id" yaml:"id"`
+	State     string     `json:"state" yaml:"state"`
+	AppliedAt *time.Time `json:"applied_at,omitempty" yaml:"applied_at,omitempty"`
+	// Checksum of the migration in the source, empty for unknown migrations.
+	Checksum string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	// ChecksumStatus compares the checksum stored when the migration was
+	// applied with Checksum, see checksumStatus. It's empty for pending
+	// migrations.
+	ChecksumStatus string `json:"checksum_status,omitempty" yaml:"checksum_status,omitempty"`
+	// DurationMs and AppliedBy are recorded with the migrations applied since
+	// the migration table has their columns.
+	DurationMs int64  `json:"duration_ms,omitempty" yaml:"duration_ms,omitempty"`
+	AppliedBy  string `json:"applied_by,omitempty" yaml:"applied_by,omitempty"`
+}
+
+// statusRows returns the rows of the migrations of the status, in order.
+func statusRows(status *migrate.Status) []*statusRow {
+	rows := make([]*statusRow, 0, len(status.Migrations))
+
+	for _, m := range status.Migrations {
+		row := &statusRow{Id: m.Id, State: string(m.State), DurationMs: m.Duration.Milliseconds(), AppliedBy: m.AppliedBy}
+
+		if m.State != migrate.StatePending {
+			appliedAt := m.AppliedAt
+			row.AppliedAt = &appliedAt
+		}
+
+		if m.Migration != nil {
+			row.Checksum = m.Migration.Checksum()
+		}
+
+		if m.Record != nil {
+			row.ChecksumStatus = checksumStatus(*m.Record, row.Checksum)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// Checksum statuses of the applied migrations.
+const (
+	// checksumOK migrations are unchanged since they were applied.
+	checksumOK = "ok"
+	// checksumChanged migrations were edited since they were applied.
+	checksumChanged = "changed"
+	// checksumUnknown migrations can't be compared: they're missing from the
+	// source, or were applied without a checksum of sql-migrate.
+	checksumUnknown = "unknown"
+)
+
+// checksumStatus compares the checksum of the record with the checksum of
+// the migration in the source, empty when it's missing from the source.
+func checksumStatus(record migrate.MigrationRecord, checksum string) string {
+	switch {
+	case checksum == "" || !record.Verifiable():
+		return checksumUnknown
+	case record.Checksum != checksum:
+		return checksumChanged
+	default:
+		return checksumOK
+	}
+}
+
+func printStatusTable(rows []*statusRow) {
+	table := tablewriter.NewWriter(ui.Writer)
+	table.SetHeader([]string{"Migration", "State", "Applied At", "Checksum"})
+	table.SetColWidth(60)
+
+	for _, row := range rows {
+		appliedAt := "no"
+		if row.AppliedAt != nil {
+			appliedAt = row.AppliedAt.String()
+		}
+
+		table.Append([]string{row.Id, row.State, appliedAt, row.ChecksumStatus})
+	}
+
+	table.Render()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+type StatusSuite struct{}
+
+var _ = Suite(&StatusSuite{})
+
+func (*StatusSuite) TestStatusRowsChecksum(c *C) {
+	migrations := []*migrate.Migration{
+		{Id: "1_a.sql", Up: []string{"CREATE TABLE a (id int);"}},
+		{Id: "2_b.sql", Up: []string{"CREATE TABLE b (id int);"}},
+		{Id: "3_c.sql", Up: []string{"CREATE TABLE c (id int);"}},
+		{Id: "4_d.sql", Up: []string{"CREATE TABLE d (id int);"}},
+	}
+	records := []*migrate.MigrationRecord{
+		{Id: "1_a.sql", Checksum: migrations[0].Checksum()},
+		{Id: "2_b.sql", Checksum: migrations[0].Checksum()},
+		{Id: "3_c.sql", Checksum: "flyway:1234"},
+		{Id: "5_e.sql", Checksum: migrations[0].Checksum()},
+	}
+
+	status := &migrate.Status{Migrations: []migrate.MigrationStatus{
+		{Id: "1_a.sql", State: migrate.StateApplied, Migration: migrations[0], Record: records[0]},
+		{Id: "2_b.sql", State: migrate.StateApplied, Migration: migrations[1], Record: records[1]},
+		{Id: "3_c.sql", State: migrate.StateApplied, Migration: migrations[2], Record: records[2]},
+		{Id: "4_d.sql", State: migrate.StatePending, Migration: migrations[3]},
+		{Id: "5_e.sql", State: migrate.StateUnknown, Record: records[3]},
+	}}
+
+	var checksums []string
+	for _, row := range statusRows(status) {
+		checksums = append(checksums, row.ChecksumStatus)
+	}
+
+	c.Assert(checksums, DeepEquals, []string{"ok", "changed", "unknown", "", "unknown"})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+// openTargets connects to every datasource of env. The returned function
+// closes the connections.
+func openTargets(env *Environment) ([]migrate.Target, func(), error) {
+	var targets []migrate.Target
+
+	closeAll := func() {
+		for _, target := range targets {
+			_ = target.DB.Close()
+		}
+	}
+
+	for _, t := range env.Targets() {
+		db, dialect, err := GetConnection(t.Environment)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+
+		targets = append(targets, migrate.Target{Name: t.Name, DB: db, Dialect: dialect})
+	}
+
+	return targets, closeAll, nil
+}
+
+// applyTargets applies the migrations to every datasource of env and prints
+// a summary per datasource.
+func applyTargets(env *Environment, dir migrate.MigrationDirection, opts ApplyOptions) error {
+	if opts.Version >= 0 || opts.To >= 0 {
+		return errors.New("The version and to options are not supported with several datasources")
+	}
+
+	targets, closeAll, err := openTargets(env)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	ctx := context.Background()
+	ex := env.Executor()
+	ex.AllowDestructive(opts.Confirm)
+	source := env.Source()
+
+	if opts.WaitForDB {
+		for _, target := range targets {
+			if err := WaitForDB(ctx, target.DB, opts.WaitTimeout); err != nil {
+				return fmt.Errorf("%s: %w", target.Name, err)
+			}
+		}
+
+		ex.Lock = true
+	}
+
+	if opts.DryRun || (dir == migrate.Down && !opts.Yes) {
+		var ids []string
+
+		for _, target := range targets {
+			migrations, _, err := ex.PlanMigration(ctx, target.DB, target.Dialect, source, dir, opts.Limit)
+			if err != nil {
+				return fmt.Errorf("%s: Cannot plan migration: %w", target.Name, err)
+			}
+
+			if opts.DryRun {
+				ui.Output(fmt.Sprintf("==> %s", target.Name))
+			}
+
+			for _, m := range migrations {
+				if opts.DryRun {
+					PrintMigration(m, dir)
+				}
+
+				ids = append(ids, target.Name+": "+m.Id)
+			}
+		}
+
+		if opts.DryRun {
+			return nil
+		}
+
+		if err := confirmRevert(ids); err != nil {
+			return err
+		}
+	}
+
+	execOpts := []migrate.ExecAllOption{migrate.WithConcurrency(opts.Parallel), migrate.WithMax(opts.Limit)}
+	if opts.ContinueOnError {
+		execOpts = append(execOpts, migrate.WithContinueOnError())
+	}
+
+	results, err := ex.ExecAll(ctx, targets, source, dir, execOpts...)
+
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			ui.Info(fmt.Sprintf("%s: skipped", result.Target.Name))
+		case result.Err != nil:
+			ui.Error(fmt.Sprintf("%s: applied %d, failed: %s", result.Target.Name, result.Applied, result.Err))
+		default:
+			ui.Info(fmt.Sprintf("%s: applied %d", result.Target.Name, result.Applied))
+		}
+	}
+
+	if err != nil {
+		return errors.New("Migration failed")
+	}
+
+	return nil
+}
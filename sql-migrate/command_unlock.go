@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kva3umoda/sql-migrate/audit"
+)
+
+func newUnlockCommand() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "Remove the migration lock left by a migrator which crashed",
+		Long: `Remove the row of the lock table, for dialects without advisory locks,
+left by a migrator killed before its lease expired. No migrator may be running.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return ForceUnlock(yes)
+		},
+	}
+
+	cmd.Flags().BoolVar(&yes, "yes", false, "don't ask for confirmation")
+
+	return cmd
+}
+
+// ForceUnlock removes the migration lock of the environment regardless of its
+// owner.
+func ForceUnlock(yes bool) error {
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	db, dialect, err := GetConnection(env)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if !yes {
+		answer, err := ui.Ask(fmt.Sprintf("The migration lock of %s will be removed, type %q to continue: ",
+			environmentName(), environmentName()))
+		if err != nil || answer != environmentName() {
+			return errors.New("Aborted, pass -yes to skip the confirmation")
+		}
+	}
+
+	ex := env.Executor()
+
+	owner, err := ex.ForceUnlock(context.Background(), db, dialect)
+	if err != nil {
+		return fmt.Errorf("Cannot remove the migration lock: %w", err)
+	}
+
+	if owner == "" {
+		ui.Info("The migration lock is free")
+
+		return nil
+	}
+
+	ex.Logger.Infof("AUDIT: %s removed the migration lock of %s in %s at %s",
+		audit.CurrentUser(), owner, environmentName(), time.Now().UTC().Format(time.RFC3339))
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func newUpCommand() *cobra.Command {
+	opts := ApplyOptions{To: -1}
+
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Migrates the database to the most recent version available",
+		Args:  cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return ApplyMigrations(migrate.Up, opts)
+		},
+	}
+
+	f := cmd.Flags()
+	f.IntVar(&opts.Limit, "limit", 0, "limit the number of migrations (0 = unlimited)")
+	f.Int64Var(&opts.Version, "version", -1, "migrate up to a specific version, e.g. the version of 20240115123000_users.sql is 20240115123000")
+	f.BoolVar(&opts.DryRun, "dry-run", false, "don't apply migrations, just print the SQL they would run")
+	f.IntVar(&opts.Parallel, "parallel", 1, "number of datasources migrated at the same time")
+	f.IntVar(&opts.ParallelMigrations, "parallel-migrations", 0, "number of migrations applied at the same time, following their DependsOn (default the parallel_migrations setting)")
+	f.BoolVar(&opts.ContinueOnError, "continue-on-error", false, "keep migrating the other datasources after a failure")
+	f.BoolVar(&opts.WaitForDB, "wait-for-db", false, "retry connecting until the database is ready and hold the migration lock while applying")
+	f.DurationVar(&opts.WaitTimeout, "wait-timeout", 2*time.Minute, "how long --wait-for-db retries")
+	f.BoolVar(&opts.Seed, "seed", false, "apply the new and changed seeds of the seed_dir setting (default seeds) after migrating")
+	f.StringVar(&opts.DumpSchema, "dump-schema", "", "write the schema to this file after migrating, e.g. schema.sql (default the dump_schema setting)")
+	f.StringVar(&opts.Confirm, "confirm", "", "token confirming destructive migrations, given by the refused run")
+
+	return cmd
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/sqlparse"
+)
+
+func newValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the migration files for mistakes",
+		Long: `Check the migration files for mistakes, without connecting to the database.
+Exits with a non-zero status when anything is found.
+
+Reported are files which fail to parse, migrations sharing a version,
+migrations without a Down section, statements outside of the Up and Down
+sections, and unbalanced StatementBegin/StatementEnd markers.`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return validate()
+		},
+	}
+}
+
+// validate reports the problems of the migration files of the environment.
+func validate() error {
+	env, err := GetEnvironment()
+	if err != nil {
+		return fmt.Errorf("Could not parse config: %w", err)
+	}
+
+	if env.DirFormat == dirFormatAtlas {
+		// Atlas lints its own files, only check them against atlas.sum.
+		if _, err := env.Source().FindMigrations(); err != nil {
+			return err
+		}
+
+		ui.Info(fmt.Sprintf("No problems found in %s", env.Dir))
+
+		return nil
+	}
+
+	findings, err := ValidateMigrations(env.Dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range findings {
+		ui.Error(f.String())
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("Found %d problem(s) in %s", len(findings), env.Dir)
+	}
+
+	ui.Info(fmt.Sprintf("No problems found in %s", env.Dir))
+
+	return nil
+}
+
+// Finding is a problem found in a migration file.
+type Finding struct {
+	File string
+	// Line is the 1-based line of the problem, or 0 when it concerns the whole file.
+	Line    int
+	Message string
+}
+
+func (f Finding) String() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", f.File, f.Message)
+}
+
+// ValidateMigrations checks every migration file in dir and returns the problems found.
+func ValidateMigrations(dir string) ([]Finding, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		findings []Finding
+		versions = make(map[int64][]string)
+	)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		name := entry.Name()
+
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		findings = append(findings, validateMigration(name, content)...)
+
+		m := &migrate.Migration{Id: name}
+		if len(m.NumberPrefixMatches()) > 0 {
+			versions[m.VersionInt()] = append(versions[m.VersionInt()], name)
+		}
+	}
+
+	for _, names := range versions {
+		if len(names) > 1 {
+			sort.Strings(names)
+			findings = append(findings, Finding{
+				File:    names[0],
+				Message: fmt.Sprintf("version is shared with %s", strings.Join(names[1:], ", ")),
+			})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+
+		return findings[i].Line < findings[j].Line
+	})
+
+	return findings, nil
+}
+
+// validateMigration checks a single migration file. Besides the errors of the
+// parser, it reports the mistakes the parser silently accepts.
+func validateMigration(name string, content []byte) []Finding {
+	var findings []Finding
+
+	if _, err := sqlparse.ParseMigration(bytes.NewReader(content)); err != nil {
+		// Keep the first line, the parser errors end with a pointer to the docs.
+		message, _, _ := strings.Cut(strings.TrimPrefix(err.Error(), "ERROR: "), "\n")
+		findings = append(findings, Finding{File: name, Message: message})
+	}
+
+	var (
+		section    string
+		hasDown    bool
+		beginLine  int
+		lineNumber int
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "-- +migrate ") {
+			fields := strings.Fields(strings.TrimPrefix(line, "-- +migrate "))
+			if len(fields) == 0 {
+				continue
+			}
+
+			switch fields[0] {
+			case "Up", "Down":
+				if beginLine > 0 {
+					findings = append(findings, Finding{File: name, Line: lineNumber, Message: fmt.Sprintf("%s section starts inside the StatementBegin of line %d", fields[0], beginLine)})
+					beginLine = 0
+				}
+
+				section = fields[0]
+				hasDown = hasDown || section == "Down"
+			case "StatementBegin":
+				if beginLine > 0 {
+					findings = append(findings, Finding{File: name, Line: lineNumber, Message: fmt.Sprintf("StatementBegin inside the StatementBegin of line %d", beginLine)})
+				}
+
+				beginLine = lineNumber
+			case "StatementEnd":
+				if beginLine == 0 {
+					findings = append(findings, Finding{File: name, Line: lineNumber, Message: "StatementEnd without StatementBegin"})
+				}
+
+				beginLine = 0
+			}
+
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if section == "" && trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			findings = append(findings, Finding{File: name, Line: lineNumber, Message: "statement outside of the Up and Down sections is ignored"})
+		}
+	}
+
+	if !hasDown {
+		findings = append(findings, Finding{File: name, Message: "missing '-- +migrate Down' section"})
+	}
+
+	return findings
+}
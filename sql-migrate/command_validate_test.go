@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type ValidateSuite struct{}
+
+var _ = Suite(&ValidateSuite{})
+
+func (*ValidateSuite) TestValidateMigrations(c *C) {
+	dir := c.MkDir()
+
+	files := map[string]string{
+		"1_ok.sql":        "-- +migrate Up\nCREATE TABLE a (id int);\n-- +migrate Down\nDROP TABLE a;\n",
+		"0001_dup.sql":    "-- +migrate Up\nCREATE TABLE b (id int);\n-- +migrate Down\n",
+		"2_no_down.sql":   "-- +migrate Up\nCREATE TABLE c (id int);\n",
+		"3_outside.sql":   "CREATE TABLE d (id int);\n-- +migrate Up\n-- +migrate Down\n",
+		"4_unbalance.sql": "-- +migrate Up\n-- +migrate StatementBegin\nSELECT 1;\n-- +migrate Down\n",
+	}
+	for name, content := range files {
+		c.Assert(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644), IsNil)
+	}
+
+	findings, err := ValidateMigrations(dir)
+	c.Assert(err, IsNil)
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+
+	c.Assert(got, DeepEquals, []string{
+		"0001_dup.sql: version is shared with 1_ok.sql",
+		"2_no_down.sql: missing '-- +migrate Down' section",
+		"3_outside.sql:1: statement outside of the Up and Down sections is ignored",
+		"4_unbalance.sql: The last statement must be ended by a semicolon or '-- +migrate StatementEnd' marker.",
+		"4_unbalance.sql:4: Down section starts inside the StatementBegin of line 2",
+	})
+}
@@ -0,0 +1,798 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/audit"
+	"github.com/kva3umoda/sql-migrate/dialect"
+	"github.com/kva3umoda/sql-migrate/notify"
+	"github.com/kva3umoda/sql-migrate/vitessmigrate"
+)
+
+const (
+	defaultConfigFile  = "dbconfig.yml"
+	defaultEnvironment = "development"
+	defaultDotenvFile  = ".env"
+	// productionEnvironment doesn't read the default dotenv file.
+	productionEnvironment = "production"
+
+	dirFormatSqlMigrate = "sql-migrate"
+	dirFormatAtlas      = "atlas"
+
+	dirNamingFile = "file"
+	dirNamingPath = "path"
+)
+
+var (
+	ConfigFile        string
+	ConfigEnvironment string
+	ConfigDotenv      string
+
+	ConfigDialect    string
+	ConfigDataSource string
+	ConfigDir        string
+	ConfigDirFormat  string
+	ConfigTable      string
+	ConfigSchema     string
+
+	// ConfigDataSourceEnv and ConfigDataSourceFile keep the connection string
+	// out of the process arguments and the config file.
+	ConfigDataSourceEnv  string
+	ConfigDataSourceFile string
+
+	ConfigSSH           string
+	ConfigSSHKey        string
+	ConfigSSHKnownHosts string
+	ConfigSSLCA         string
+	ConfigSSLCert       string
+	ConfigSSLKey        string
+
+	ConfigLock        optionalBool
+	ConfigLockKey     string
+	ConfigLockTimeout string
+
+	ConfigSlowStatement string
+
+	ConfigMaxOpenConns     int
+	ConfigMaxIdleConns     int
+	ConfigSingleConnection bool
+
+	ConfigWebhook       string
+	ConfigWebhookFormat string
+
+	ConfigAuditLog string
+
+	ConfigNotifyChannel string
+)
+
+// optionalBool is a boolean flag which tells whether it was given at all.
+type optionalBool struct {
+	set   bool
+	value bool
+}
+
+func (b *optionalBool) String() string {
+	if b == nil || !b.set {
+		return ""
+	}
+
+	return strconv.FormatBool(b.value)
+}
+
+func (b *optionalBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	b.set, b.value = true, v
+
+	return nil
+}
+
+func (*optionalBool) Type() string { return "bool" }
+
+// ConfigFlags registers the flags describing the database to migrate. The
+// flags override the values of the selected environment of the config file.
+// The output flags are registered too, as every command accepts them.
+func ConfigFlags(f *pflag.FlagSet) {
+	f.StringVar(&ConfigFile, "config", defaultConfigFile, "configuration file to use")
+	f.StringVar(&ConfigEnvironment, "env", defaultEnvironment, "environment of the configuration file")
+	f.StringVar(&ConfigDotenv, "dotenv", "", "file with environment variables to load first (default .env, skipped for production)")
+	f.StringVar(&ConfigDialect, "dialect", "", "database dialect, e.g. postgres")
+	f.StringVar(&ConfigDataSource, "datasource", "", "database connection string")
+	f.StringVar(&ConfigDataSourceEnv, "dsn-env", "", "read the connection string from an environment variable")
+	f.StringVar(&ConfigDataSourceFile, "dsn-file", "", "read the connection string from a file, e.g. a mounted secret")
+	f.StringVar(&ConfigDir, "dir", "", "directory with migration files (default migrations)")
+	f.StringVar(&ConfigDirFormat, "dir-format", "", "format of the migration directory, sql-migrate or atlas (default sql-migrate)")
+	f.StringVar(&ConfigTable, "table", "", "name of the migration table (default migrations)")
+	f.StringVar(&ConfigSchema, "schema", "", "schema of the migration table")
+	f.StringVar(&ConfigSSH, "ssh", "", "connect through an SSH tunnel to user@host[:port]")
+	f.StringVar(&ConfigSSHKey, "ssh-key", "", "private key of the SSH tunnel (default the SSH agent and ~/.ssh/id_ed25519 or id_rsa)")
+	f.StringVar(&ConfigSSHKnownHosts, "ssh-known-hosts", "", "known hosts verifying the SSH server (default ~/.ssh/known_hosts)")
+	f.StringVar(&ConfigSSLCA, "ssl-ca", "", "CA certificate verifying the database server")
+	f.StringVar(&ConfigSSLCert, "ssl-cert", "", "TLS client certificate")
+	f.StringVar(&ConfigSSLKey, "ssl-key", "", "key of the TLS client certificate")
+	f.Var(&ConfigLock, "lock", "serialize concurrent migrators with a lock (default true for postgres and mysql)")
+	f.Lookup("lock").NoOptDefVal = "true"
+	f.StringVar(&ConfigLockKey, "lock-key", "", "key of the migration lock")
+	f.StringVar(&ConfigLockTimeout, "lock-timeout", "", "how long to wait for the lock, e.g. 30s (default wait forever)")
+	f.StringVar(&ConfigSlowStatement, "slow-statement", "", "warn about statements running longer than this, e.g. 30s")
+	f.IntVar(&ConfigMaxOpenConns, "max-open-conns", 0, "maximum number of open database connections (default unlimited)")
+	f.IntVar(&ConfigMaxIdleConns, "max-idle-conns", 0, "maximum number of idle database connections (default 2)")
+	f.BoolVar(&ConfigSingleConnection, "single-connection", false, "run on a single database connection, e.g. behind PgBouncer in transaction mode")
+	f.StringVar(&ConfigWebhook, "webhook", "", "URL notified when migrations are applied or fail")
+	f.StringVar(&ConfigWebhookFormat, "webhook-format", "", "payload of the webhook, json or slack (default json)")
+	f.StringVar(&ConfigAuditLog, "audit-log", "", "file to append a JSON line to per migration applied or rolled back")
+	f.StringVar(&ConfigNotifyChannel, "notify-channel", "", "postgres channel to NOTIFY with the schema version after applying migrations")
+	OutputFlags(f)
+}
+
+// Environment describes a database and the migrations applied to it.
+type Environment struct {
+	Dialect    string `yaml:"dialect" json:"dialect" toml:"dialect"`
+	DataSource string `yaml:"datasource" json:"datasource" toml:"datasource"`
+	Dir        string `yaml:"dir" json:"dir" toml:"dir"`
+	TableName  string `yaml:"table" json:"table" toml:"table"`
+	SchemaName string `yaml:"schema" json:"schema" toml:"schema"`
+	// Template is the template of the new command.
+	Template string `yaml:"template" json:"template" toml:"template"`
+	// DirFormat is the format of Dir: sql-migrate, the default, or atlas for
+	// a directory written by Atlas and checked against its atlas.sum.
+	DirFormat string `yaml:"dir_format" json:"dir_format" toml:"dir_format"`
+	// DirDepth reads the migrations of the subdirectories of Dir too, down to
+	// this depth, -1 for all of them. DirNaming names these migrations by
+	// their file, the default, or by their path relative to Dir.
+	DirDepth  int    `yaml:"dir_depth" json:"dir_depth" toml:"dir_depth"`
+	DirNaming string `yaml:"dir_naming" json:"dir_naming" toml:"dir_naming"`
+	// SQLTemplates renders the migration files with text/template, with
+	// TemplateData as data and the env function reading the environment.
+	SQLTemplates bool              `yaml:"sql_templates" json:"sql_templates" toml:"sql_templates"`
+	TemplateData map[string]string `yaml:"template_data" json:"template_data" toml:"template_data"`
+
+	// SeedDir holds the seed scripts applied by up --seed, seeds by default,
+	// tracked in SeedTable, seeds by default.
+	SeedDir   string `yaml:"seed_dir" json:"seed_dir" toml:"seed_dir"`
+	SeedTable string `yaml:"seed_table" json:"seed_table" toml:"seed_table"`
+
+	// AppliedBy is recorded with each applied migration, the user@host
+	// running sql-migrate by default, e.g. set it to the deployment.
+	AppliedBy string `yaml:"applied_by" json:"applied_by" toml:"applied_by"`
+
+	// ParallelMigrations applies up to that many migrations at the same time,
+	// following their DependsOn, see migrate.MigrationExecutor.
+	ParallelMigrations int `yaml:"parallel_migrations" json:"parallel_migrations" toml:"parallel_migrations"`
+
+	// Lock serializes concurrent migrators. It defaults to true for dialects
+	// with advisory locks, the others would need an extra lock table.
+	Lock        *bool  `yaml:"lock" json:"lock" toml:"lock"`
+	LockKey     string `yaml:"lock_key" json:"lock_key" toml:"lock_key"`
+	LockTimeout string `yaml:"lock_timeout" json:"lock_timeout" toml:"lock_timeout"`
+	// LockLease is how long the lock row stays valid unless renewed, e.g. 5m,
+	// for dialects without advisory locks.
+	LockLease string `yaml:"lock_lease" json:"lock_lease" toml:"lock_lease"`
+
+	// SlowStatement is the duration above which a statement is reported as slow, e.g. 30s.
+	SlowStatement string `yaml:"slow_statement" json:"slow_statement" toml:"slow_statement"`
+
+	// MaxOpenConns and MaxIdleConns cap the connection pool, zero keeps the
+	// defaults of database/sql.
+	MaxOpenConns int `yaml:"max_open_conns" json:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns int `yaml:"max_idle_conns" json:"max_idle_conns" toml:"max_idle_conns"`
+	// SingleConnection runs the lock, the planning and the migrations on one
+	// connection, which a pooler in transaction mode, e.g. PgBouncer, needs.
+	SingleConnection bool `yaml:"single_connection" json:"single_connection" toml:"single_connection"`
+
+	// DDLStrategy submits the CREATE, ALTER and DROP of tables and views to
+	// Vitess as online schema changes with this ddl_strategy, e.g. vitess,
+	// and waits for them, for mysql.
+	DDLStrategy string `yaml:"ddl_strategy" json:"ddl_strategy" toml:"ddl_strategy"`
+	// PlanetScaleOrganization and PlanetScaleDatabase apply them with deploy
+	// requests into PlanetScaleBranch, main by default, instead. The service
+	// token is read from PLANETSCALE_SERVICE_TOKEN_ID and
+	// PLANETSCALE_SERVICE_TOKEN.
+	PlanetScaleOrganization string `yaml:"planetscale_organization" json:"planetscale_organization" toml:"planetscale_organization"`
+	PlanetScaleDatabase     string `yaml:"planetscale_database" json:"planetscale_database" toml:"planetscale_database"`
+	PlanetScaleBranch       string `yaml:"planetscale_branch" json:"planetscale_branch" toml:"planetscale_branch"`
+
+	// DataSources lists the connection strings of several databases sharing
+	// the same migrations, e.g. shards, keyed by a name used in the output.
+	DataSources map[string]string `yaml:"datasources" json:"datasources" toml:"datasources"`
+
+	// SSH connects through an SSH tunnel to user@host[:port], e.g. a bastion,
+	// authenticating with the SSH agent or the key file.
+	SSH           string `yaml:"ssh" json:"ssh" toml:"ssh"`
+	SSHKey        string `yaml:"ssh_key" json:"ssh_key" toml:"ssh_key"`
+	SSHKnownHosts string `yaml:"ssh_known_hosts" json:"ssh_known_hosts" toml:"ssh_known_hosts"`
+
+	// SSLCA, SSLCert and SSLKey are the files of the certificate authority of
+	// the server and of the client certificate, for postgres and mysql.
+	SSLCA   string `yaml:"ssl_ca" json:"ssl_ca" toml:"ssl_ca"`
+	SSLCert string `yaml:"ssl_cert" json:"ssl_cert" toml:"ssl_cert"`
+	SSLKey  string `yaml:"ssl_key" json:"ssl_key" toml:"ssl_key"`
+
+	// Webhook is notified at the end of the runs which applied migrations or
+	// failed, with a JSON payload or, for the slack format, a Slack message.
+	Webhook       string `yaml:"webhook" json:"webhook" toml:"webhook"`
+	WebhookFormat string `yaml:"webhook_format" json:"webhook_format" toml:"webhook_format"`
+
+	// AuditLog is a file to append a JSON line to per migration applied or
+	// rolled back, with who applied it to which host.
+	AuditLog string `yaml:"audit_log" json:"audit_log" toml:"audit_log"`
+
+	// NotifyChannel is a channel notified on postgres with the version of the
+	// schema after the runs which applied migrations, for the instances of the
+	// application to LISTEN on. OutboxQuery inserts a row instead, with the
+	// OutboxTopic and the JSON of the run as arguments.
+	NotifyChannel string `yaml:"notify_channel" json:"notify_channel" toml:"notify_channel"`
+	OutboxQuery   string `yaml:"outbox_query" json:"outbox_query" toml:"outbox_query"`
+	OutboxTopic   string `yaml:"outbox_topic" json:"outbox_topic" toml:"outbox_topic"`
+
+	// DumpSchema is a file the schema is written to after migrating, e.g.
+	// schema.sql, committed so reviews show the net change of the schema.
+	DumpSchema string `yaml:"dump_schema" json:"dump_schema" toml:"dump_schema"`
+
+	// Protected environments refuse destructive commands, such as fresh.
+	Protected bool `yaml:"protected" json:"protected" toml:"protected"`
+	// RequireDownConfirmation refuses the rollbacks, and the migrations
+	// dropping or truncating, unless the token of the plan is given with
+	// --confirm.
+	RequireDownConfirmation bool `yaml:"require_down_confirmation" json:"require_down_confirmation" toml:"require_down_confirmation"`
+
+	lockTimeout   time.Duration
+	lockLease     time.Duration
+	slowStatement time.Duration
+	webhookFormat notify.Format
+	notices       *migrate.NoticeBuffer
+}
+
+// loadDotenv sets the variables of the dotenv file which aren't set yet. The
+// default .env file is optional and skipped for the production environment, a
+// file given with -dotenv must exist.
+func loadDotenv() error {
+	if ConfigDotenv != "" {
+		return godotenv.Load(ConfigDotenv)
+	}
+
+	if ConfigEnvironment == productionEnvironment {
+		return nil
+	}
+
+	err := godotenv.Load(defaultDotenvFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// ReadConfig reads the environments of the config file, keyed by name. The
+// format follows the extension of the file: .json, .toml or else YAML.
+func ReadConfig() (map[string]*Environment, error) {
+	file, err := os.ReadFile(ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := make(map[string]*Environment)
+
+	switch strings.ToLower(filepath.Ext(ConfigFile)) {
+	case ".json":
+		err = json.Unmarshal(file, &config)
+	case ".toml":
+		err = toml.Unmarshal(file, &config)
+	default:
+		err = yaml.Unmarshal(file, &config)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ConfigFile, err)
+	}
+
+	return config, nil
+}
+
+var envVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${NAME} with the value of the environment variable NAME,
+// so secrets don't have to be written in the config file. Unlike os.ExpandEnv
+// a bare $ is kept, as it is common in passwords.
+func expandEnv(value string) (string, error) {
+	var missing []string
+
+	expanded := envVariable.ReplaceAllStringFunc(value, func(match string) string {
+		name := envVariable.FindStringSubmatch(match)[1]
+
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+
+		return v
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("Environment variable %s is not set", strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
+
+// expand expands the environment variables of all settings.
+func (env *Environment) expand() error {
+	for _, value := range []*string{
+		&env.Dialect, &env.DataSource, &env.Dir, &env.DirFormat, &env.DirNaming, &env.TableName, &env.SchemaName, &env.Template,
+		&env.SeedDir, &env.SeedTable, &env.AppliedBy,
+		&env.LockKey, &env.LockTimeout, &env.LockLease, &env.SlowStatement,
+		&env.DDLStrategy, &env.PlanetScaleOrganization, &env.PlanetScaleDatabase, &env.PlanetScaleBranch,
+		&env.SSH, &env.SSHKey, &env.SSHKnownHosts, &env.SSLCA, &env.SSLCert, &env.SSLKey,
+		&env.Webhook, &env.WebhookFormat, &env.AuditLog,
+		&env.NotifyChannel, &env.OutboxQuery, &env.OutboxTopic, &env.DumpSchema,
+	} {
+		expanded, err := expandEnv(*value)
+		if err != nil {
+			return err
+		}
+
+		*value = expanded
+	}
+
+	for _, values := range []map[string]string{env.DataSources, env.TemplateData} {
+		for name, value := range values {
+			expanded, err := expandEnv(value)
+			if err != nil {
+				return err
+			}
+
+			values[name] = expanded
+		}
+	}
+
+	return nil
+}
+
+// GetEnvironment returns the selected environment of the config file, overridden
+// by the flags. Without a config file only the flags are used, unless the file
+// was asked for explicitly.
+func GetEnvironment() (*Environment, error) {
+	if err := loadDotenv(); err != nil {
+		return nil, err
+	}
+
+	env := &Environment{}
+
+	config, err := ReadConfig()
+
+	switch {
+	case err == nil:
+		selected, ok := config[ConfigEnvironment]
+		if !ok || selected == nil {
+			return nil, fmt.Errorf("No environment: %s", ConfigEnvironment)
+		}
+
+		env = selected
+
+		if err := env.expand(); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, os.ErrNotExist) && (ConfigFile == "" || ConfigFile == defaultConfigFile):
+	default:
+		return nil, err
+	}
+
+	override := func(value *string, flag string) {
+		if flag != "" {
+			*value = flag
+		}
+	}
+
+	override(&env.Dialect, ConfigDialect)
+	override(&env.Dir, ConfigDir)
+	override(&env.DirFormat, ConfigDirFormat)
+	override(&env.TableName, ConfigTable)
+	override(&env.SchemaName, ConfigSchema)
+
+	dataSource, err := flagDataSource()
+	if err != nil {
+		return nil, err
+	}
+
+	if dataSource != "" {
+		// A datasource given on the command line replaces all of the config.
+		env.DataSources = nil
+	} else if env.DataSource != "" && len(env.DataSources) > 0 {
+		return nil, errors.New("The datasource and datasources settings are mutually exclusive")
+	}
+
+	override(&env.DataSource, dataSource)
+	override(&env.SSH, ConfigSSH)
+	override(&env.SSHKey, ConfigSSHKey)
+	override(&env.SSHKnownHosts, ConfigSSHKnownHosts)
+	override(&env.SSLCA, ConfigSSLCA)
+	override(&env.SSLCert, ConfigSSLCert)
+	override(&env.SSLKey, ConfigSSLKey)
+	override(&env.LockKey, ConfigLockKey)
+	override(&env.LockTimeout, ConfigLockTimeout)
+	override(&env.SlowStatement, ConfigSlowStatement)
+	override(&env.Webhook, ConfigWebhook)
+	override(&env.WebhookFormat, ConfigWebhookFormat)
+	override(&env.AuditLog, ConfigAuditLog)
+	override(&env.NotifyChannel, ConfigNotifyChannel)
+
+	if ConfigMaxOpenConns != 0 {
+		env.MaxOpenConns = ConfigMaxOpenConns
+	}
+
+	if ConfigMaxIdleConns != 0 {
+		env.MaxIdleConns = ConfigMaxIdleConns
+	}
+
+	if ConfigSingleConnection {
+		env.SingleConnection = true
+	}
+
+	if ConfigLock.set {
+		lock := ConfigLock.value
+		env.Lock = &lock
+	}
+
+	if env.LockTimeout != "" {
+		env.lockTimeout, err = time.ParseDuration(env.LockTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid lock timeout: %w", err)
+		}
+	}
+
+	if env.LockLease != "" {
+		env.lockLease, err = time.ParseDuration(env.LockLease)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid lock lease: %w", err)
+		}
+	}
+
+	if env.SlowStatement != "" {
+		env.slowStatement, err = time.ParseDuration(env.SlowStatement)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid slow statement threshold: %w", err)
+		}
+	}
+
+	switch env.WebhookFormat {
+	case "", "json":
+	case "slack":
+		env.webhookFormat = notify.FormatSlack
+	default:
+		return nil, fmt.Errorf("Invalid webhook format %q, must be json or slack", env.WebhookFormat)
+	}
+
+	if env.NotifyChannel != "" && env.OutboxQuery != "" {
+		return nil, errors.New("The notify_channel and outbox_query settings are mutually exclusive")
+	} else if env.NotifyChannel != "" && migrate.DialectName(env.Dialect) != migrate.Postgres {
+		return nil, fmt.Errorf("The notify_channel setting needs postgres, not %s", env.Dialect)
+	}
+
+	deploys := env.DDLStrategy != "" || env.PlanetScaleOrganization != "" || env.PlanetScaleDatabase != ""
+	if env.DDLStrategy != "" && (env.PlanetScaleOrganization != "" || env.PlanetScaleDatabase != "") {
+		return nil, errors.New("The ddl_strategy and planetscale settings are mutually exclusive")
+	} else if (env.PlanetScaleOrganization == "") != (env.PlanetScaleDatabase == "") {
+		return nil, errors.New("The planetscale_organization and planetscale_database settings go together")
+	} else if deploys && migrate.DialectName(env.Dialect) != migrate.MySQL {
+		return nil, fmt.Errorf("The ddl_strategy and planetscale settings need mysql, not %s", env.Dialect)
+	}
+
+	if env.ParallelMigrations > 1 && (env.SingleConnection || deploys) {
+		return nil, errors.New("The parallel_migrations setting can't be combined with single_connection, ddl_strategy or planetscale")
+	}
+
+	switch env.DirFormat {
+	case "", dirFormatSqlMigrate, dirFormatAtlas:
+	default:
+		return nil, fmt.Errorf("Invalid dir format %q, must be sql-migrate or atlas", env.DirFormat)
+	}
+
+	switch env.DirNaming {
+	case "", dirNamingFile, dirNamingPath:
+	default:
+		return nil, fmt.Errorf("Invalid dir naming %q, must be file or path", env.DirNaming)
+	}
+
+	if env.Dir == "" {
+		env.Dir = "migrations"
+	}
+
+	return env, nil
+}
+
+// flagDataSource returns the connection string given with -datasource, -dsn-env
+// or -dsn-file, at most one of which may be used.
+func flagDataSource() (string, error) {
+	given := 0
+	for _, v := range []string{ConfigDataSource, ConfigDataSourceEnv, ConfigDataSourceFile} {
+		if v != "" {
+			given++
+		}
+	}
+
+	if given > 1 {
+		return "", errors.New("The datasource, dsn-env and dsn-file options are mutually exclusive")
+	}
+
+	switch {
+	case ConfigDataSourceEnv != "":
+		dataSource, ok := os.LookupEnv(ConfigDataSourceEnv)
+		if !ok || dataSource == "" {
+			return "", fmt.Errorf("Environment variable %s is not set", ConfigDataSourceEnv)
+		}
+
+		return dataSource, nil
+	case ConfigDataSourceFile != "":
+		content, err := os.ReadFile(ConfigDataSourceFile)
+		if err != nil {
+			return "", fmt.Errorf("Cannot read the datasource: %w", err)
+		}
+
+		// Secret files usually end with a newline.
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	return ConfigDataSource, nil
+}
+
+// Target is one of the databases of an environment with several datasources.
+type Target struct {
+	Name string
+	*Environment
+}
+
+// Targets returns an environment per datasource of env, ordered by name.
+func (env *Environment) Targets() []Target {
+	names := make([]string, 0, len(env.DataSources))
+	for name := range env.DataSources {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	targets := make([]Target, 0, len(names))
+
+	for _, name := range names {
+		target := *env
+		target.DataSource = env.DataSources[name]
+		target.DataSources = nil
+
+		targets = append(targets, Target{Name: name, Environment: &target})
+	}
+
+	return targets
+}
+
+// Executor returns a migration executor configured for the environment.
+func (env *Environment) Executor() *migrate.MigrationExecutor {
+	ex := migrate.NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = uiLogger{}
+
+	if env.TableName != "" {
+		ex.TableName = env.TableName
+	}
+
+	ex.SchemaName = env.SchemaName
+
+	if env.Lock != nil {
+		ex.Lock = *env.Lock
+	} else if driver, err := lookupDriver(env.Dialect); err == nil {
+		_, ex.Lock = driver.Dialect().(dialect.AdvisoryLocker)
+	}
+
+	ex.LockKey = env.LockKey
+	ex.LockTimeout = env.lockTimeout
+	ex.LockLease = env.lockLease
+	ex.SlowStatement = env.slowStatement
+	ex.Notices = env.notices
+	ex.SingleConnection = env.SingleConnection
+	ex.RequireDownConfirmation = env.RequireDownConfirmation
+
+	ex.ParallelMigrations = env.ParallelMigrations
+	ex.AppliedBy = env.AppliedBy
+	if ex.AppliedBy == "" {
+		ex.AppliedBy = audit.CurrentUser()
+	}
+
+	if env.DDLStrategy != "" {
+		ex.Runner = vitessmigrate.Runner{Deployer: vitessmigrate.OnlineDDL{Strategy: env.DDLStrategy}}
+	} else if env.PlanetScaleDatabase != "" {
+		ex.Runner = vitessmigrate.Runner{Deployer: &vitessmigrate.PlanetScale{
+			Organization: env.PlanetScaleOrganization,
+			Database:     env.PlanetScaleDatabase,
+			Branch:       env.PlanetScaleBranch,
+			TokenId:      os.Getenv("PLANETSCALE_SERVICE_TOKEN_ID"),
+			Token:        os.Getenv("PLANETSCALE_SERVICE_TOKEN"),
+		}}
+	}
+
+	if env.Webhook != "" {
+		ex.Observer = notify.NewWebhook(env.Webhook,
+			notify.WithEnvironment(environmentName()),
+			notify.WithFormat(env.webhookFormat),
+			notify.WithErrorHandler(func(err error) { ui.Error(err.Error()) }))
+	}
+
+	if env.NotifyChannel != "" {
+		ex.Announcer = migrate.PostgresNotify{Channel: env.NotifyChannel}
+	} else if env.OutboxQuery != "" {
+		ex.Announcer = migrate.Outbox{Query: env.OutboxQuery, Topic: env.OutboxTopic}
+	}
+
+	if env.AuditLog != "" {
+		ex.Observer = migrate.Observers(ex.Observer, audit.New(auditFile(env.AuditLog),
+			audit.WithTarget(audit.DSNHost(env.DataSource)),
+			audit.WithErrorHandler(func(err error) { ui.Error(fmt.Sprintf("Cannot write the audit log: %s", err)) })))
+	}
+
+	return ex
+}
+
+// auditFile appends each record to the file, which is only opened while
+// writing, as the executor has no end to close it at.
+type auditFile string
+
+func (f auditFile) Write(p []byte) (int, error) {
+	file, err := os.OpenFile(string(f), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := file.Write(p)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return n, err
+}
+
+// Source returns the migrations of the environment.
+func (env *Environment) Source() migrate.MigrationSource {
+	if env.DirFormat == dirFormatAtlas {
+		return migrate.NewAtlasMigrationSource(env.Dir)
+	}
+
+	source := migrate.NewFileMigrationSource(env.Dir)
+	source.MaxDepth = env.DirDepth
+
+	if env.DirNaming == dirNamingPath {
+		source.Naming = migrate.NameByPath
+	}
+
+	if env.SQLTemplates || len(env.TemplateData) > 0 {
+		data := make(map[string]any, len(env.TemplateData))
+		for key, value := range env.TemplateData {
+			data[key] = value
+		}
+
+		source.Template = &migrate.MigrationTemplate{Data: data}
+	}
+
+	return source
+}
+
+// Seeder returns a seed executor configured for the environment. The seeds
+// aren't announced like migrations.
+func (env *Environment) Seeder() *migrate.SeedExecutor {
+	ex := env.Executor()
+	ex.TableName = "seeds"
+	ex.IgnoreUnknown = true
+	ex.Announcer = nil
+
+	if env.SeedTable != "" {
+		ex.TableName = env.SeedTable
+	}
+
+	return &migrate.SeedExecutor{Executor: ex}
+}
+
+// SeedSource returns the source of the seeds of the environment.
+func (env *Environment) SeedSource() migrate.MigrationSource {
+	dir := env.SeedDir
+	if dir == "" {
+		dir = "seeds"
+	}
+
+	return migrate.NewFileMigrationSource(dir)
+}
+
+// checkWritable refuses to write migrations to a directory of another tool,
+// which wouldn't read them.
+func (env *Environment) checkWritable() error {
+	if env.DirFormat == dirFormatAtlas {
+		return fmt.Errorf("%s is an Atlas directory, write its migrations with atlas migrate diff or new", env.Dir)
+	}
+
+	return nil
+}
+
+func GetConnection(env *Environment) (*sql.DB, dialect.Dialect, error) {
+	if env.Dialect == "" {
+		return nil, nil, errors.New("No dialect specified")
+	}
+
+	if env.DataSource == "" {
+		return nil, nil, errors.New("No data source specified")
+	}
+
+	driver, err := lookupDriver(env.Dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Secrets are resolved only now, so they are never part of the config.
+	dataSource, err := resolveDataSource(context.Background(), env.DataSource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if driver.CheckDataSource != nil {
+		if err := driver.CheckDataSource(dataSource); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The notices of the connections are logged by the executor of the environment.
+	env.notices = &migrate.NoticeBuffer{}
+
+	if driver.Connect == nil && env.SSH == "" && env.SSLCA == "" && env.SSLCert == "" && env.SSLKey == "" {
+		db, err := sql.Open(driver.Name, dataSource)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Cannot connect to database: %w", err)
+		}
+
+		env.configurePool(db)
+
+		return db, driver.Dialect(), nil
+	}
+
+	connector, err := connect(driver, env, dataSource)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Cannot connect to database: %w", err)
+	}
+
+	db := sql.OpenDB(connector)
+	env.configurePool(db)
+
+	return db, driver.Dialect(), nil
+}
+
+// configurePool applies the limits of the connection pool. A single
+// connection caps the pool at one, so nothing opens a second one.
+func (env *Environment) configurePool(db *sql.DB) {
+	if env.SingleConnection {
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+
+		return
+	}
+
+	if env.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(env.MaxOpenConns)
+	}
+
+	if env.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(env.MaxIdleConns)
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/vitessmigrate"
+)
+
+type ConfigSuite struct{}
+
+var _ = Suite(&ConfigSuite{})
+
+func (*ConfigSuite) TestGetEnvironment(c *C) {
+	file := filepath.Join(c.MkDir(), "dbconfig.yml")
+	content := "development:\n  dialect: sqlite3\n  datasource: dev.db\nproduction:\n  dialect: postgres\n  datasource: dbname=prod\n  dir: db/migrations\n  table: schema_migrations\n  schema: app\n"
+	c.Assert(os.WriteFile(file, []byte(content), 0o644), IsNil)
+
+	defer func() { ConfigFile, ConfigEnvironment, ConfigDataSource = "", "", "" }()
+	ConfigFile = file
+
+	ConfigEnvironment = "development"
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+	c.Assert(*env, DeepEquals, Environment{Dialect: "sqlite3", DataSource: "dev.db", Dir: "migrations"})
+
+	ConfigEnvironment = "production"
+	ConfigDataSource = "dbname=override"
+	env, err = GetEnvironment()
+	c.Assert(err, IsNil)
+	c.Assert(*env, DeepEquals, Environment{
+		Dialect:    "postgres",
+		DataSource: "dbname=override",
+		Dir:        "db/migrations",
+		TableName:  "schema_migrations",
+		SchemaName: "app",
+	})
+
+	ConfigEnvironment = "staging"
+	_, err = GetEnvironment()
+	c.Assert(err, ErrorMatches, "No environment: staging")
+}
+
+func (*ConfigSuite) TestConfigFormats(c *C) {
+	dir := c.MkDir()
+	files := map[string]string{
+		"dbconfig.json": `{"development": {"dialect": "postgres", "datasource": "password=${SQL_MIGRATE_TEST_PASSWORD}$x"}}`,
+		"dbconfig.toml": "[development]\ndialect = \"postgres\"\ndatasource = \"password=${SQL_MIGRATE_TEST_PASSWORD}$x\"\n",
+	}
+
+	c.Assert(os.Setenv("SQL_MIGRATE_TEST_PASSWORD", "secret"), IsNil)
+	defer os.Unsetenv("SQL_MIGRATE_TEST_PASSWORD")
+
+	defer func() { ConfigFile, ConfigEnvironment = "", "" }()
+	ConfigEnvironment = "development"
+
+	for name, content := range files {
+		ConfigFile = filepath.Join(dir, name)
+		c.Assert(os.WriteFile(ConfigFile, []byte(content), 0o644), IsNil)
+
+		env, err := GetEnvironment()
+		c.Assert(err, IsNil, Commentf(name))
+		c.Assert(env.Dialect, Equals, "postgres", Commentf(name))
+		c.Assert(env.DataSource, Equals, "password=secret$x", Commentf(name))
+	}
+
+	c.Assert(os.Unsetenv("SQL_MIGRATE_TEST_PASSWORD"), IsNil)
+
+	_, err := GetEnvironment()
+	c.Assert(err, ErrorMatches, "Environment variable SQL_MIGRATE_TEST_PASSWORD is not set")
+}
+
+func (*ConfigSuite) TestExpandSettings(c *C) {
+	c.Assert(os.Setenv("SQL_MIGRATE_TEST_NAME", "orders"), IsNil)
+	defer os.Unsetenv("SQL_MIGRATE_TEST_NAME")
+
+	ConfigFile = filepath.Join(c.MkDir(), "dbconfig.yml")
+	c.Assert(os.WriteFile(ConfigFile, []byte(`development:
+  dialect: mysql
+  webhook: https://hooks.example.com/${SQL_MIGRATE_TEST_NAME}
+  ssh_key: /keys/${SQL_MIGRATE_TEST_NAME}
+  audit_log: /var/log/${SQL_MIGRATE_TEST_NAME}.jsonl
+  applied_by: deploy-${SQL_MIGRATE_TEST_NAME}
+  planetscale_organization: acme
+  planetscale_database: ${SQL_MIGRATE_TEST_NAME}
+  outbox_query: INSERT INTO ${SQL_MIGRATE_TEST_NAME}_outbox VALUES ($1, $2)
+  template_data:
+    schema: ${SQL_MIGRATE_TEST_NAME}
+`), 0o644), IsNil)
+
+	defer func() { ConfigFile, ConfigEnvironment = "", "" }()
+	ConfigEnvironment = "development"
+
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+	c.Assert(env.Webhook, Equals, "https://hooks.example.com/orders")
+	c.Assert(env.SSHKey, Equals, "/keys/orders")
+	c.Assert(env.AuditLog, Equals, "/var/log/orders.jsonl")
+	c.Assert(env.AppliedBy, Equals, "deploy-orders")
+	c.Assert(env.PlanetScaleDatabase, Equals, "orders")
+	c.Assert(env.OutboxQuery, Equals, "INSERT INTO orders_outbox VALUES ($1, $2)")
+	c.Assert(env.TemplateData["schema"], Equals, "orders")
+}
+
+func (*ConfigSuite) TestDotenv(c *C) {
+	dir := c.MkDir()
+
+	dotenv := filepath.Join(dir, "test.env")
+	c.Assert(os.WriteFile(dotenv, []byte("SQL_MIGRATE_TEST_DSN=from-dotenv\n"), 0o644), IsNil)
+	defer os.Unsetenv("SQL_MIGRATE_TEST_DSN")
+
+	ConfigFile = filepath.Join(dir, "dbconfig.yml")
+	c.Assert(os.WriteFile(ConfigFile, []byte("development:\n  datasource: ${SQL_MIGRATE_TEST_DSN}\n"), 0o644), IsNil)
+
+	defer func() { ConfigFile, ConfigEnvironment, ConfigDotenv = "", "", "" }()
+	ConfigEnvironment = "development"
+	ConfigDotenv = dotenv
+
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+	c.Assert(env.DataSource, Equals, "from-dotenv")
+
+	ConfigDotenv = filepath.Join(dir, "missing.env")
+	_, err = GetEnvironment()
+	c.Assert(err, NotNil)
+}
+
+func (*ConfigSuite) TestDataSourceFromSecret(c *C) {
+	secret := filepath.Join(c.MkDir(), "db")
+	c.Assert(os.WriteFile(secret, []byte("dbname=secret\n"), 0o600), IsNil)
+
+	defer func() { ConfigDataSource, ConfigDataSourceEnv, ConfigDataSourceFile = "", "", "" }()
+
+	ConfigDataSourceFile = secret
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+	c.Assert(env.DataSource, Equals, "dbname=secret")
+
+	ConfigDataSource = "dbname=flag"
+	_, err = GetEnvironment()
+	c.Assert(err, ErrorMatches, ".*mutually exclusive")
+
+	c.Assert(os.Setenv("SQL_MIGRATE_TEST_DSN", "dbname=env"), IsNil)
+	defer os.Unsetenv("SQL_MIGRATE_TEST_DSN")
+
+	ConfigDataSource, ConfigDataSourceFile, ConfigDataSourceEnv = "", "", "SQL_MIGRATE_TEST_DSN"
+	env, err = GetEnvironment()
+	c.Assert(err, IsNil)
+	c.Assert(env.DataSource, Equals, "dbname=env")
+}
+
+func (*ConfigSuite) TestLockDefaults(c *C) {
+	c.Assert((&Environment{Dialect: "postgres"}).Executor().Lock, Equals, true)
+	c.Assert((&Environment{Dialect: "sqlite3"}).Executor().Lock, Equals, false)
+
+	defer func() { ConfigLock, ConfigLockTimeout, ConfigSlowStatement = optionalBool{}, "", "" }()
+	c.Assert(ConfigLock.Set("false"), IsNil)
+	ConfigLockTimeout = "30s"
+	ConfigSlowStatement = "10s"
+	ConfigDialect = "postgres"
+	defer func() { ConfigDialect = "" }()
+
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+
+	ex := env.Executor()
+	c.Assert(ex.Lock, Equals, false)
+	c.Assert(ex.LockTimeout, Equals, 30*time.Second)
+	c.Assert(ex.SlowStatement, Equals, 10*time.Second)
+}
+
+func (*ConfigSuite) TestConnectionPool(c *C) {
+	defer func() { ConfigMaxOpenConns, ConfigSingleConnection = 0, false }()
+
+	ConfigMaxOpenConns = 4
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	env.configurePool(db)
+	c.Assert(db.Stats().MaxOpenConnections, Equals, 4)
+	c.Assert(env.Executor().SingleConnection, Equals, false)
+
+	ConfigSingleConnection = true
+	env, err = GetEnvironment()
+	c.Assert(err, IsNil)
+
+	env.configurePool(db)
+	c.Assert(db.Stats().MaxOpenConnections, Equals, 1)
+	c.Assert(env.Executor().SingleConnection, Equals, true)
+}
+
+func (*ConfigSuite) TestWebhookFormat(c *C) {
+	defer func() { ConfigWebhook, ConfigWebhookFormat = "", "" }()
+
+	ConfigWebhook, ConfigWebhookFormat = "http://localhost/hook", "teams"
+	_, err := GetEnvironment()
+	c.Assert(err, ErrorMatches, "Invalid webhook format.*")
+
+	ConfigWebhookFormat = "slack"
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+	c.Assert(env.Webhook, Equals, "http://localhost/hook")
+	c.Assert(env.Executor().Observer, NotNil)
+}
+
+func (*ConfigSuite) TestAnnouncer(c *C) {
+	defer func(dialect string) { ConfigDialect, ConfigNotifyChannel = dialect, "" }(ConfigDialect)
+
+	ConfigDialect, ConfigNotifyChannel = "sqlite3", "schema_migrated"
+	_, err := GetEnvironment()
+	c.Assert(err, ErrorMatches, "The notify_channel setting needs postgres, not sqlite3")
+
+	ConfigDialect = "postgres"
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+	c.Assert(env.Executor().Announcer, Equals, migrate.PostgresNotify{Channel: "schema_migrated"})
+}
+
+func (*ConfigSuite) TestDDLStrategy(c *C) {
+	env := &Environment{Dialect: "mysql", DDLStrategy: "vitess"}
+	c.Assert(env.Executor().Runner, Equals, vitessmigrate.Runner{Deployer: vitessmigrate.OnlineDDL{Strategy: "vitess"}})
+
+	env = &Environment{Dialect: "mysql", PlanetScaleOrganization: "acme", PlanetScaleDatabase: "shop"}
+	c.Assert(env.Executor().Runner, FitsTypeOf, vitessmigrate.Runner{})
+}
+
+func (*ConfigSuite) TestDirFormat(c *C) {
+	defer func() { ConfigDirFormat = "" }()
+
+	ConfigDirFormat = "flyway"
+	_, err := GetEnvironment()
+	c.Assert(err, ErrorMatches, "Invalid dir format.*")
+
+	ConfigDirFormat = "atlas"
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+	c.Assert(env.Source(), FitsTypeOf, &migrate.AtlasMigrationSource{})
+	c.Assert(env.checkWritable(), ErrorMatches, "migrations is an Atlas directory.*")
+}
+
+func (*ConfigSuite) TestDirDepth(c *C) {
+	file := filepath.Join(c.MkDir(), "dbconfig.yml")
+	c.Assert(os.WriteFile(file, []byte("development:\n  dialect: sqlite3\n  datasource: dev.db\n  dir_depth: 2\n  dir_naming: path\n"), 0o644), IsNil)
+
+	defer func() { ConfigFile, ConfigEnvironment = "", "" }()
+	ConfigFile, ConfigEnvironment = file, "development"
+
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+
+	source, ok := env.Source().(*migrate.FileSystemMigrationSource)
+	c.Assert(ok, Equals, true)
+	c.Assert(source.MaxDepth, Equals, 2)
+	c.Assert(source.Naming, Equals, migrate.NameByPath)
+
+	c.Assert(os.WriteFile(file, []byte("development:\n  dialect: sqlite3\n  dir_naming: folder\n"), 0o644), IsNil)
+	_, err = GetEnvironment()
+	c.Assert(err, ErrorMatches, `Invalid dir naming "folder", must be file or path`)
+}
+
+func (*ConfigSuite) TestTemplateData(c *C) {
+	file := filepath.Join(c.MkDir(), "dbconfig.yml")
+	c.Assert(os.WriteFile(file, []byte("development:\n  dialect: sqlite3\n  datasource: dev.db\n  template_data:\n    tablespace: fast\n"), 0o644), IsNil)
+
+	defer func() { ConfigFile, ConfigEnvironment = "", "" }()
+	ConfigFile, ConfigEnvironment = file, "development"
+
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+
+	source, ok := env.Source().(*migrate.FileSystemMigrationSource)
+	c.Assert(ok, Equals, true)
+	c.Assert(source.Template, DeepEquals, &migrate.MigrationTemplate{Data: map[string]any{"tablespace": "fast"}})
+
+	env.TemplateData = nil
+	c.Assert(env.Source().(*migrate.FileSystemMigrationSource).Template, IsNil)
+
+	env.SQLTemplates = true
+	c.Assert(env.Source().(*migrate.FileSystemMigrationSource).Template, NotNil)
+}
+
+func (*ConfigSuite) TestParallelMigrations(c *C) {
+	file := filepath.Join(c.MkDir(), "dbconfig.yml")
+	c.Assert(os.WriteFile(file, []byte("development:\n  dialect: sqlite3\n  datasource: dev.db\n  parallel_migrations: 4\n"), 0o644), IsNil)
+
+	defer func() { ConfigFile, ConfigEnvironment, ConfigSingleConnection = "", "", false }()
+	ConfigFile, ConfigEnvironment = file, "development"
+
+	env, err := GetEnvironment()
+	c.Assert(err, IsNil)
+	c.Assert(env.Executor().ParallelMigrations, Equals, 4)
+
+	ConfigSingleConnection = true
+	_, err = GetEnvironment()
+	c.Assert(err, ErrorMatches, "The parallel_migrations setting can't be combined with single_connection, ddl_strategy or planetscale")
+}
+
+func (*ConfigSuite) TestAuditFile(c *C) {
+	path := filepath.Join(c.MkDir(), "audit.jsonl")
+
+	for _, line := range []string{"a\n", "b\n"} {
+		_, err := auditFile(path).Write([]byte(line))
+		c.Assert(err, IsNil)
+	}
+
+	data, err := os.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "a\nb\n")
+}
+
+func (*ConfigSuite) TestTargets(c *C) {
+	env := &Environment{Dialect: "postgres", DataSources: map[string]string{"b": "dbname=b", "a": "dbname=a"}}
+
+	targets := env.Targets()
+	c.Assert(targets, HasLen, 2)
+	c.Assert(targets[0].Name, Equals, "a")
+	c.Assert(targets[0].DataSource, Equals, "dbname=a")
+	c.Assert(targets[0].Dialect, Equals, "postgres")
+	c.Assert(targets[1].Name, Equals, "b")
+	c.Assert(targets[1].DataSources, IsNil)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// tlsConfig loads the certificate authority and the client certificate of the options.
+func tlsConfig(opts ConnectOptions) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.SSLCA != "" {
+		pem, err := os.ReadFile(opts.SSLCA)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read the CA certificate: %w", err)
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificate found in %s", opts.SSLCA)
+		}
+	}
+
+	if opts.SSLCert != "" || opts.SSLKey != "" {
+		cert, err := tls.LoadX509KeyPair(opts.SSLCert, opts.SSLKey)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot load the client certificate: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// sshTarget splits user@host[:port] into the user and the address of the SSH server.
+func sshTarget(target string) (string, string, error) {
+	user, host, ok := strings.Cut(target, "@")
+	if !ok || user == "" || host == "" {
+		return "", "", fmt.Errorf("Invalid ssh option %q, expected user@host[:port]", target)
+	}
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+
+	return user, host, nil
+}
+
+// dialSSH connects to the SSH server of the environment, authenticating with
+// the SSH agent and the key file, and verifying the server with the known hosts.
+func dialSSH(env *Environment) (*ssh.Client, error) {
+	user, addr, err := sshTarget(env.SSH)
+	if err != nil {
+		return nil, err
+	}
+
+	home, _ := os.UserHomeDir()
+
+	knownHostsFile := env.SSHKnownHosts
+	if knownHostsFile == "" {
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the known hosts: %w", err)
+	}
+
+	var auth []ssh.AuthMethod
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			defer conn.Close()
+
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	keyFiles := []string{env.SSHKey}
+	if env.SSHKey == "" {
+		keyFiles = []string{filepath.Join(home, ".ssh", "id_ed25519"), filepath.Join(home, ".ssh", "id_rsa")}
+	}
+
+	for _, keyFile := range keyFiles {
+		key, err := os.ReadFile(keyFile)
+		if errors.Is(err, os.ErrNotExist) && env.SSHKey == "" {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("Cannot read the SSH key: %w", err)
+		}
+
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot parse the SSH key %s: %w", keyFile, err)
+		}
+
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Cannot connect to %s: %w", env.SSH, err)
+	}
+
+	return client, nil
+}
+
+// tunnelConnector closes the SSH tunnel together with the database.
+type tunnelConnector struct {
+	driver.Connector
+	tunnel *ssh.Client
+}
+
+func (c tunnelConnector) Close() error {
+	if closer, ok := c.Connector.(interface{ Close() error }); ok {
+		_ = closer.Close()
+	}
+
+	return c.tunnel.Close()
+}
+
+// connect opens the database through the driver's connector, for the ssh and
+// ssl options of the environment and its notices.
+func connect(d Driver, env *Environment, dataSource string) (driver.Connector, error) {
+	if d.Connect == nil {
+		return nil, fmt.Errorf("The %s dialect doesn't support the ssh and ssl options", d.Name)
+	}
+
+	opts := ConnectOptions{SSLCA: env.SSLCA, SSLCert: env.SSLCert, SSLKey: env.SSLKey}
+	if env.notices != nil {
+		opts.Notice = env.notices.Add
+	}
+
+	if env.SSH == "" {
+		return d.Connect(dataSource, opts)
+	}
+
+	tunnel, err := dialSSH(env)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return tunnel.DialContext(ctx, network, addr)
+	}
+
+	connector, err := d.Connect(dataSource, opts)
+	if err != nil {
+		_ = tunnel.Close()
+		return nil, err
+	}
+
+	return tunnelConnector{Connector: connector, tunnel: tunnel}, nil
+}
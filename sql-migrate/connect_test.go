@@ -0,0 +1,46 @@
+package main
+
+import (
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type ConnectSuite struct{}
+
+var _ = Suite(&ConnectSuite{})
+
+func (*ConnectSuite) TestSSHTarget(c *C) {
+	user, addr, err := sshTarget("deploy@bastion.example.com")
+	c.Assert(err, IsNil)
+	c.Assert(user, Equals, "deploy")
+	c.Assert(addr, Equals, "bastion.example.com:22")
+
+	_, addr, err = sshTarget("deploy@bastion.example.com:2222")
+	c.Assert(err, IsNil)
+	c.Assert(addr, Equals, "bastion.example.com:2222")
+
+	_, _, err = sshTarget("bastion.example.com")
+	c.Assert(err, ErrorMatches, `Invalid ssh option "bastion.example.com", expected user@host\[:port\]`)
+}
+
+func (*ConnectSuite) TestPostgresTLS(c *C) {
+	dataSource, err := postgresTLS("dbname=app", ConnectOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(dataSource, Equals, "dbname=app")
+
+	dataSource, err = postgresTLS("dbname=app", ConnectOptions{SSLCA: "/certs/ca.pem", SSLCert: "/certs/it's.pem"})
+	c.Assert(err, IsNil)
+	c.Assert(dataSource, Equals, `dbname=app sslrootcert='/certs/ca.pem' sslcert='/certs/it\'s.pem'`)
+
+	dataSource, err = postgresTLS("postgres://db.example.com/app", ConnectOptions{SSLCA: "ca.pem"})
+	c.Assert(err, IsNil)
+	c.Assert(dataSource, Equals, "dbname='app' host='db.example.com' sslrootcert='ca.pem'")
+}
+
+func (*ConnectSuite) TestConnectUnsupported(c *C) {
+	driver, err := lookupDriver("sqlite3")
+	c.Assert(err, IsNil)
+
+	_, err = connect(driver, &Environment{SSLCA: "ca.pem"}, "test.db")
+	c.Assert(err, ErrorMatches, "The sqlite3 dialect doesn't support the ssh and ssl options")
+}
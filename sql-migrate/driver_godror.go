@@ -0,0 +1,17 @@
+//go:build godror
+
+package main
+
+import (
+	_ "github.com/godror/godror"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func init() {
+	RegisterDriver(Driver{
+		Name:    string(migrate.GoDrOr),
+		Dialect: func() dialect.Dialect { return dialect.NewOracleDialect() },
+	})
+}
@@ -0,0 +1,17 @@
+//go:build mssql
+
+package main
+
+import (
+	_ "github.com/microsoft/go-mssqldb"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func init() {
+	RegisterDriver(Driver{
+		Name:    string(migrate.MSSQL),
+		Dialect: func() dialect.Dialect { return dialect.NewSqlServerDialect() },
+	})
+}
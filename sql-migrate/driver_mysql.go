@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-sql-driver/mysql"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func init() {
+	RegisterDriver(Driver{
+		Name:            string(migrate.MySQL),
+		Dialect:         func() dialect.Dialect { return dialect.NewMySQLDialect("InnoDB", "UTF8") },
+		CheckDataSource: checkMySQLDataSource,
+		Connect:         connectMySQL,
+	})
+
+	// TiDB speaks the protocol of mysql.
+	RegisterDriver(Driver{
+		Name:            string(migrate.TiDB),
+		Dialect:         func() dialect.Dialect { return dialect.NewTiDBDialect() },
+		CheckDataSource: checkMySQLDataSource,
+		Connect:         connectMySQL,
+	})
+}
+
+// checkMySQLDataSource requires parseTime, as the mysql driver only maps time
+// columns to time.Time with it.
+// See https://github.com/go-sql-driver/mysql#parsetime
+func checkMySQLDataSource(dataSource string) error {
+	if !strings.Contains(dataSource, "parseTime=true") {
+		return errors.New(`Cannot parse dates.
+
+Make sure that the parseTime option is supplied to your database connection.
+Check https://github.com/go-sql-driver/mysql#parsetime for more info.`)
+	}
+
+	return nil
+}
+
+// mysqlDials numbers the networks registered for tunnels, as the mysql driver
+// only looks dial functions up by the network name.
+var mysqlDials atomic.Int64
+
+func connectMySQL(dataSource string, opts ConnectOptions) (driver.Connector, error) {
+	cfg, err := mysql.ParseDSN(dataSource)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.SSLCA != "" || opts.SSLCert != "" {
+		cfg.TLS, err = tlsConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Dial != nil {
+		network := fmt.Sprintf("sql-migrate-%d", mysqlDials.Add(1))
+		mysql.RegisterDialContext(network, func(ctx context.Context, addr string) (net.Conn, error) {
+			return opts.Dial(ctx, "tcp", addr)
+		})
+
+		cfg.Net = network
+	}
+
+	return mysql.NewConnector(cfg)
+}
@@ -0,0 +1,17 @@
+//go:build oracle
+
+package main
+
+import (
+	_ "github.com/mattn/go-oci8"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func init() {
+	RegisterDriver(Driver{
+		Name:    string(migrate.OCI8),
+		Dialect: func() dialect.Dialect { return dialect.NewOracleDialect() },
+	})
+}
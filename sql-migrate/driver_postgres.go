@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func init() {
+	RegisterDriver(Driver{
+		Name:    string(migrate.Postgres),
+		Dialect: func() dialect.Dialect { return dialect.NewPostgresDialect() },
+		Connect: connectPostgres,
+	})
+
+	// CockroachDB speaks the protocol of postgres.
+	RegisterDriver(Driver{
+		Name:    string(migrate.Cockroach),
+		Dialect: func() dialect.Dialect { return dialect.NewCockroachDialect() },
+		Connect: connectPostgres,
+	})
+}
+
+func connectPostgres(dataSource string, opts ConnectOptions) (driver.Connector, error) {
+	dataSource, err := postgresTLS(dataSource, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := pq.NewConnector(dataSource)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Dial != nil {
+		connector.Dialer(pqDialer(opts.Dial))
+	}
+
+	if opts.Notice != nil {
+		return pq.ConnectorWithNoticeHandler(connector, func(err *pq.Error) {
+			opts.Notice(migrate.Notice{Severity: err.Severity, Message: err.Message})
+		}), nil
+	}
+
+	return connector, nil
+}
+
+// postgresTLS adds the certificate files to the datasource, as pq reads them
+// itself. URLs are converted to the key=value form first.
+func postgresTLS(dataSource string, opts ConnectOptions) (string, error) {
+	params := []struct{ key, value string }{
+		{"sslrootcert", opts.SSLCA},
+		{"sslcert", opts.SSLCert},
+		{"sslkey", opts.SSLKey},
+	}
+
+	var b strings.Builder
+
+	for _, p := range params {
+		if p.value != "" {
+			b.WriteString(" " + p.key + "='" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(p.value) + "'")
+		}
+	}
+
+	if b.Len() == 0 {
+		return dataSource, nil
+	}
+
+	if strings.HasPrefix(dataSource, "postgres://") || strings.HasPrefix(dataSource, "postgresql://") {
+		var err error
+
+		dataSource, err = pq.ParseURL(dataSource)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return dataSource + b.String(), nil
+}
+
+// pqDialer adapts a dial function to the dialer of pq.
+type pqDialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (d pqDialer) Dial(network, addr string) (net.Conn, error) {
+	return d(context.Background(), network, addr)
+}
+
+func (d pqDialer) DialTimeout(network, addr string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return d(ctx, network, addr)
+}
+
+func (d pqDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d(ctx, network, addr)
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func init() {
+	RegisterDriver(Driver{
+		Name:    string(migrate.SQLite3),
+		Dialect: func() dialect.Dialect { return dialect.NewSqliteDialect() },
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// Driver is a database the CLI can connect to. Drivers register themselves
+// from an init function, usually in a file behind a build tag, so supporting
+// another database doesn't touch the commands.
+type Driver struct {
+	// Name is the dialect of the config file and the name of the database/sql driver.
+	Name string
+	// Dialect returns the dialect of the migration table.
+	Dialect func() dialect.Dialect
+	// CheckDataSource rejects data sources the migrations can't work with, optional.
+	CheckDataSource func(dataSource string) error
+	// Connect opens connections through an SSH tunnel or with TLS client
+	// certificates, and with the notice handler, optional. Without it the ssh
+	// and ssl options are refused.
+	Connect func(dataSource string, opts ConnectOptions) (driver.Connector, error)
+}
+
+// ConnectOptions are the connection settings given besides the datasource.
+type ConnectOptions struct {
+	// Dial opens the network connections to the database, e.g. through an
+	// SSH tunnel. Nil dials directly.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	// SSLCA, SSLCert and SSLKey are the files of the certificate authority
+	// verifying the server and of the client certificate and key, optional.
+	SSLCA   string
+	SSLCert string
+	SSLKey  string
+	// Notice receives the notices the database pushes while a statement
+	// runs, e.g. RAISE NOTICE of postgres, optional.
+	Notice func(migrate.Notice)
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+// RegisterDriver makes a driver available by its name. It panics when a
+// driver with the same name is registered twice.
+func RegisterDriver(driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if _, ok := drivers[driver.Name]; ok {
+		panic(fmt.Sprintf("sql-migrate: driver %s registered twice", driver.Name))
+	}
+
+	drivers[driver.Name] = driver
+}
+
+// lookupDriver returns the registered driver for the dialect of the config file.
+func lookupDriver(name string) (Driver, error) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	driver, ok := drivers[name]
+	if !ok {
+		return Driver{}, fmt.Errorf("Unsupported dialect %s, the available dialects are %s (others need a build tag)",
+			name, strings.Join(driverNames(), ", "))
+	}
+
+	return driver, nil
+}
+
+// driverNames returns the names of the registered drivers, sorted.
+func driverNames() []string {
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"testing"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
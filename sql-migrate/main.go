@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// exitCode is returned by commands which report their outcome through the
+// exit code, such as check. The command prints its messages itself.
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(c))
+}
+
+// errorCodeAnnotation annotates the commands reporting their outcome through
+// the exit code with the code of their failures. It's also the exit code of
+// the errors returned before they run, such as unknown flags, so that these
+// aren't taken for an outcome. Other commands exit with 1 on errors.
+const errorCodeAnnotation = "sql-migrate:error-code"
+
+// newRootCommand builds the command tree. The flags describing the database
+// and the output flags are persistent, so every command accepts them.
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "sql-migrate",
+		Short:         "SQL schema migration tool",
+		Version:       GetVersion(),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	root.SetOut(ui.Writer)
+	root.SetErr(ui.ErrorWriter)
+	root.SetVersionTemplate("{{.Version}}\n")
+
+	ConfigFlags(root.PersistentFlags())
+
+	root.AddCommand(
+		newBaselineCommand(),
+		newCheckCommand(),
+		newDiffCommand(),
+		newDownCommand(),
+		newDriftCommand(),
+		newExportCommand(),
+		newForceCommand(),
+		newFreshCommand(),
+		newInitCommand(),
+		newJobCommand(),
+		newLiquibaseCommand(),
+		newNewCommand(),
+		newRedoCommand(),
+		newServeCommand(),
+		newSkipCommand(),
+		newSquashCommand(),
+		newStatusCommand(),
+		newUnlockCommand(),
+		newUpCommand(),
+		newValidateCommand(),
+		newVersionCommand(),
+	)
+
+	registerCompletions(root)
+
+	return root
+}
+
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:    "version",
+		Short:  "Print the version",
+		Args:   cobra.NoArgs,
+		Hidden: true,
+		Run: func(*cobra.Command, []string) {
+			ui.Output(GetVersion())
+		},
+	}
+}
+
+func main() {
+	os.Exit(realMain(os.Args[1:]))
+}
+
+func realMain(args []string) int {
+	root := newRootCommand()
+	root.SetArgs(normalizeArgs(args))
+
+	cmd, err := root.ExecuteC()
+
+	var code exitCode
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &code):
+		return int(code)
+	default:
+		ui.Error(err.Error())
+		return errorCode(cmd)
+	}
+}
+
+// errorCode returns the exit code of the errors of cmd.
+func errorCode(cmd *cobra.Command) int {
+	if cmd != nil {
+		if code, err := strconv.Atoi(cmd.Annotations[errorCodeAnnotation]); err == nil {
+			return code
+		}
+	}
+
+	return 1
+}
+
+// normalizeArgs rewrites the single dash long flags of earlier versions, e.g.
+// -env=production, into the double dash form, so existing scripts keep working.
+func normalizeArgs(args []string) []string {
+	normalized := make([]string, 0, len(args))
+
+	for i, arg := range args {
+		if arg == "--" {
+			return append(normalized, args[i:]...)
+		}
+
+		if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' && !strings.ContainsAny(arg[1:2], "0123456789") {
+			arg = "-" + arg
+		}
+
+		normalized = append(normalized, arg)
+	}
+
+	return normalized
+}
+
+// GetVersion returns the module version the binary was built from.
+func GetVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "dev"
+	}
+
+	return info.Main.Version
+}
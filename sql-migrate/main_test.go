@@ -0,0 +1,32 @@
+package main
+
+import (
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type MainSuite struct{}
+
+var _ = Suite(&MainSuite{})
+
+func (*MainSuite) TestNormalizeArgs(c *C) {
+	args := normalizeArgs([]string{"up", "-env=production", "--limit=1", "-h", "-1", "--", "-name"})
+	c.Assert(args, DeepEquals, []string{"up", "--env=production", "--limit=1", "-h", "-1", "--", "-name"})
+}
+
+func (*MainSuite) TestUsageErrorExitCode(c *C) {
+	// check exits with 1 when migrations are pending, so its usage errors
+	// exit with its error code instead.
+	c.Assert(realMain([]string{"check", "--unknown"}), Equals, checkError)
+	c.Assert(realMain([]string{"drift", "--unknown"}), Equals, driftError)
+	c.Assert(realMain([]string{"up", "--unknown"}), Equals, 1)
+}
+
+func (*MainSuite) TestLookupDriver(c *C) {
+	driver, err := lookupDriver("sqlite3")
+	c.Assert(err, IsNil)
+	c.Assert(driver.Name, Equals, "sqlite3")
+
+	_, err = lookupDriver("db2")
+	c.Assert(err, ErrorMatches, "Unsupported dialect db2, the available dialects are .*sqlite3.*")
+}
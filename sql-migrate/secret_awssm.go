@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// The aws-sm: datasources read a secret of AWS Secrets Manager, e.g.
+// aws-sm:prod/db, or a field of a JSON secret, e.g. aws-sm:prod/db#dsn. The
+// client is configured by the usual AWS environment variables and files.
+func init() {
+	RegisterSecretResolver("aws-sm", resolveAWSSecret)
+}
+
+func resolveAWSSecret(ctx context.Context, ref string) (string, error) {
+	id, field, _ := strings.Cut(ref, "#")
+	if id == "" {
+		return "", errors.New("expected aws-sm:SECRET_ID[#FIELD]")
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if out.SecretString == nil {
+		return "", errors.New("secret has no string value")
+	}
+
+	return secretField(*out.SecretString, field)
+}
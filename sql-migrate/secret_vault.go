@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// The vault: datasources read a field of a Vault secret, e.g.
+// vault:secret/data/db#dsn. The client is configured by the usual
+// environment variables, such as VAULT_ADDR and VAULT_TOKEN.
+func init() {
+	RegisterSecretResolver("vault", resolveVault)
+}
+
+func resolveVault(ctx context.Context, ref string) (string, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	if path == "" || field == "" {
+		return "", errors.New("expected vault:PATH#FIELD")
+	}
+
+	client, err := vault.NewClient(vault.DefaultConfig())
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := client.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	if secret == nil {
+		return "", fmt.Errorf("no secret at %s", path)
+	}
+
+	return vaultField(secret.Data, field)
+}
+
+// vaultField returns a field of the data of a secret. Secrets of the KV
+// version 2 engine nest the fields in a data object.
+func vaultField(data map[string]any, field string) (string, error) {
+	if nested, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	return stringField(data, field)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SecretResolver fetches the connection string a reference points to in a
+// secret manager. The reference is the datasource without the scheme, e.g.
+// secret/data/db#dsn for vault:secret/data/db#dsn.
+type SecretResolver func(ctx context.Context, ref string) (string, error)
+
+var (
+	secretResolversMu sync.RWMutex
+	secretResolvers   = make(map[string]SecretResolver)
+)
+
+// RegisterSecretResolver resolves the datasources starting with scheme and a
+// colon. It panics when a resolver for the same scheme is registered twice.
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+
+	if _, ok := secretResolvers[scheme]; ok {
+		panic(fmt.Sprintf("sql-migrate: secret resolver %s registered twice", scheme))
+	}
+
+	secretResolvers[scheme] = resolver
+}
+
+// resolveDataSource returns the connection string of a datasource referring to
+// a secret manager, and any other datasource as is.
+func resolveDataSource(ctx context.Context, dataSource string) (string, error) {
+	scheme, ref, ok := strings.Cut(dataSource, ":")
+	if !ok {
+		return dataSource, nil
+	}
+
+	secretResolversMu.RLock()
+	resolver, ok := secretResolvers[scheme]
+	secretResolversMu.RUnlock()
+
+	if !ok {
+		return dataSource, nil
+	}
+
+	resolved, err := resolver(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("Cannot resolve the datasource %s: %w", dataSource, err)
+	}
+
+	if resolved == "" {
+		return "", fmt.Errorf("The datasource %s resolved to an empty value", dataSource)
+	}
+
+	return resolved, nil
+}
+
+// secretField returns the field of a JSON object secret, or the whole secret
+// when no field is asked for.
+func secretField(secret, field string) (string, error) {
+	if field == "" {
+		return secret, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+
+	return stringField(fields, field)
+}
+
+// stringField returns the string value of a field of a secret.
+func stringField(fields map[string]any, field string) (string, error) {
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %s", field)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field %s of the secret is not a string", field)
+	}
+
+	return s, nil
+}
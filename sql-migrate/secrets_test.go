@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type SecretsSuite struct{}
+
+var _ = Suite(&SecretsSuite{})
+
+func init() {
+	RegisterSecretResolver("test-secret", func(_ context.Context, ref string) (string, error) {
+		if ref == "missing" {
+			return "", errors.New("not found")
+		}
+
+		return "resolved " + ref, nil
+	})
+}
+
+func (*SecretsSuite) TestResolveDataSource(c *C) {
+	ctx := context.Background()
+
+	for _, dataSource := range []string{"test.db", "postgres://localhost/app", "user:password@/app"} {
+		resolved, err := resolveDataSource(ctx, dataSource)
+		c.Assert(err, IsNil)
+		c.Assert(resolved, Equals, dataSource)
+	}
+
+	resolved, err := resolveDataSource(ctx, "test-secret:prod/db")
+	c.Assert(err, IsNil)
+	c.Assert(resolved, Equals, "resolved prod/db")
+
+	_, err = resolveDataSource(ctx, "test-secret:missing")
+	c.Assert(err, ErrorMatches, "Cannot resolve the datasource test-secret:missing: not found")
+}
+
+func (*SecretsSuite) TestSecretField(c *C) {
+	value, err := secretField("host=db user=app", "")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "host=db user=app")
+
+	value, err = secretField(`{"dsn": "host=db user=app", "port": 5432}`, "dsn")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "host=db user=app")
+
+	_, err = secretField(`{"port": 5432}`, "port")
+	c.Assert(err, ErrorMatches, "field port of the secret is not a string")
+
+	_, err = secretField("host=db", "dsn")
+	c.Assert(err, ErrorMatches, "secret is not a JSON object: .*")
+}
+
+func (*SecretsSuite) TestVaultField(c *C) {
+	value, err := vaultField(map[string]any{"dsn": "v1"}, "dsn")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "v1")
+
+	value, err = vaultField(map[string]any{
+		"data":     map[string]any{"dsn": "v2"},
+		"metadata": map[string]any{"version": 3},
+	}, "dsn")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "v2")
+
+	_, err = vaultField(map[string]any{}, "dsn")
+	c.Assert(err, ErrorMatches, "secret has no field dsn")
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+// Log formats of the UI.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// UI writes command output to stdout and errors to stderr, and reads answers from stdin.
+//
+// Output is the result of a command, such as a status table, and is always
+// written as is. Info, Trace and Error are log messages, which follow the
+// verbosity and the log format.
+type UI struct {
+	Reader      io.Reader
+	Writer      io.Writer
+	ErrorWriter io.Writer
+
+	// Quiet drops the info messages.
+	Quiet bool
+	// Verbose shows the trace messages, e.g. the executed SQL.
+	Verbose bool
+	// NoColor disables the ANSI colors of the text log format.
+	NoColor bool
+	// LogFormat is text or json, which writes each message as a JSON object on its own line.
+	LogFormat string
+}
+
+var ui = &UI{Reader: os.Stdin, Writer: os.Stdout, ErrorWriter: os.Stderr}
+
+// OutputFlags registers the flags controlling the verbosity and format of the messages.
+func OutputFlags(f *pflag.FlagSet) {
+	f.BoolVar(&ui.Quiet, "quiet", false, "only print errors and results")
+	f.BoolVar(&ui.Verbose, "verbose", false, "also print the executed SQL")
+	f.BoolVar(&ui.NoColor, "no-color", false, "disable colors (also with $NO_COLOR)")
+	f.Var((*logFormat)(&ui.LogFormat), "log-format", "format of the messages, text or json")
+}
+
+type logFormat string
+
+func (f *logFormat) String() string {
+	if f == nil || *f == "" {
+		return logFormatText
+	}
+
+	return string(*f)
+}
+
+func (*logFormat) Type() string { return "format" }
+
+func (f *logFormat) Set(s string) error {
+	if s != logFormatText && s != logFormatJSON {
+		return errors.New("must be text or json")
+	}
+
+	*f = logFormat(s)
+
+	return nil
+}
+
+func (u *UI) Output(msg string) {
+	fmt.Fprintln(u.Writer, msg)
+}
+
+// Info logs the progress of a command, unless the UI is quiet.
+func (u *UI) Info(msg string) {
+	if u.Quiet {
+		return
+	}
+
+	u.log(u.Writer, "info", msg)
+}
+
+// Trace logs details, only when the UI is verbose.
+func (u *UI) Trace(msg string) {
+	if !u.Verbose {
+		return
+	}
+
+	u.log(u.Writer, "trace", msg)
+}
+
+func (u *UI) Error(msg string) {
+	if u.LogFormat != logFormatJSON && u.color(u.ErrorWriter) {
+		msg = "\x1b[31m" + msg + "\x1b[0m"
+	}
+
+	u.log(u.ErrorWriter, "error", msg)
+}
+
+func (u *UI) log(w io.Writer, level, msg string) {
+	if u.LogFormat != logFormatJSON {
+		fmt.Fprintln(w, msg)
+		return
+	}
+
+	line, _ := json.Marshal(struct {
+		Time  time.Time `json:"time"`
+		Level string    `json:"level"`
+		Msg   string    `json:"msg"`
+	}{time.Now().UTC(), level, strings.TrimSpace(msg)})
+
+	fmt.Fprintln(w, string(line))
+}
+
+// color reports whether ANSI colors are written to w: only to a terminal,
+// and never with -no-color or the NO_COLOR environment variable.
+func (u *UI) color(w io.Writer) bool {
+	if u.NoColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	stat, err := f.Stat()
+
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// Ask prints the query and returns the line answered, without surrounding spaces.
+func (u *UI) Ask(query string) (string, error) {
+	fmt.Fprint(u.Writer, query)
+
+	line, err := bufio.NewReader(u.Reader).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+var (
+	_ migrate.Logger       = (*uiLogger)(nil)
+	_ migrate.LevelEnabler = (*uiLogger)(nil)
+)
+
+// uiLogger reports the progress of the executor through the UI.
+type uiLogger struct{}
+
+func (uiLogger) Tracef(format string, v ...any) {
+	ui.Trace(fmt.Sprintf(format, v...))
+}
+
+func (uiLogger) Infof(format string, v ...any) {
+	ui.Info(fmt.Sprintf(format, v...))
+}
+
+func (uiLogger) Errorf(format string, v ...any) {
+	ui.Error(fmt.Sprintf(format, v...))
+}
+
+// Enabled skips the traced queries unless the UI is verbose.
+func (uiLogger) Enabled(_ context.Context, level migrate.LogLevel) bool {
+	return level != migrate.LevelTrace || ui.Verbose
+}
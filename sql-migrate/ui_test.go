@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+type UISuite struct{}
+
+var _ = Suite(&UISuite{})
+
+func (*UISuite) TestLevels(c *C) {
+	var out, errs bytes.Buffer
+
+	u := &UI{Writer: &out, ErrorWriter: &errs, Quiet: true}
+	u.Info("applied")
+	u.Trace("SELECT 1")
+	u.Output("result")
+	u.Error("failed")
+	c.Assert(out.String(), Equals, "result\n")
+	c.Assert(errs.String(), Equals, "failed\n")
+
+	out.Reset()
+
+	u = &UI{Writer: &out, ErrorWriter: &errs, Verbose: true}
+	u.Info("applied")
+	u.Trace("SELECT 1")
+	c.Assert(out.String(), Equals, "applied\nSELECT 1\n")
+}
+
+func (*UISuite) TestJSONFormat(c *C) {
+	var out, errs bytes.Buffer
+
+	u := &UI{Writer: &out, ErrorWriter: &errs, LogFormat: logFormatJSON}
+	u.Info("Applied 2 migrations")
+	u.Output("result")
+	u.Error("failed")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	c.Assert(lines, HasLen, 2)
+	c.Assert(lines[1], Equals, "result")
+
+	var msg struct {
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+	}
+
+	c.Assert(json.Unmarshal([]byte(lines[0]), &msg), IsNil)
+	c.Assert(msg.Level, Equals, "info")
+	c.Assert(msg.Msg, Equals, "Applied 2 migrations")
+
+	c.Assert(json.Unmarshal(errs.Bytes(), &msg), IsNil)
+	c.Assert(msg.Level, Equals, "error")
+}
@@ -0,0 +1,37 @@
+package sqlparse
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Fuzz is the entry point of go-fuzz and OSS-Fuzz. It parses data as a
+// migration file and panics when the parsed migration breaks an invariant of
+// the parser, e.g. a statement without its lines. It returns 1 for files
+// which parse, so the fuzzer favours them, and 0 otherwise.
+//
+// The seed corpus of tricky migrations is in testdata/corpus.
+func Fuzz(data []byte) int {
+	p, err := ParseMigration(bytes.NewReader(data))
+	if err != nil {
+		return 0
+	}
+
+	checkStatements(p.UpStatements, p.UpLines)
+	checkStatements(p.DownStatements, p.DownLines)
+
+	return 1
+}
+
+// checkStatements panics unless each statement has its line range, within the file.
+func checkStatements(statements []string, lines []LineRange) {
+	if len(statements) != len(lines) {
+		panic(fmt.Sprintf("sqlparse: %d statements with %d line ranges", len(statements), len(lines)))
+	}
+
+	for i, r := range lines {
+		if r.First < 1 || r.Last < r.First {
+			panic(fmt.Sprintf("sqlparse: statement %d has the invalid %s", i+1, r))
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package sqlparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func FuzzParseMigration(f *testing.F) {
+	seeds, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.sql"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, seed := range seeds {
+		data, err := os.ReadFile(seed)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(data)
+	}
+
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		Fuzz(data)
+	})
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
 const (
@@ -17,8 +20,44 @@ type ParsedMigration struct {
 	UpStatements   []string
 	DownStatements []string
 
+	// UpLines and DownLines hold the lines of each statement in the migration file.
+	UpLines   []LineRange
+	DownLines []LineRange
+
 	DisableTransactionUp   bool
 	DisableTransactionDown bool
+
+	// StatementTimeoutUp and StatementTimeoutDown bound the time of each
+	// statement of the section, as set by "-- +migrate StatementTimeout 5m"
+	// in it, or before the sections for both. Zero doesn't bound it.
+	StatementTimeoutUp   time.Duration
+	StatementTimeoutDown time.Duration
+
+	// DependsOn holds the migrations the migration needs applied first, as
+	// listed by "-- +migrate DependsOn: 0003_foo.sql, 0004_bar.sql" lines.
+	DependsOn []string
+}
+
+// Statement is a statement of a migration file, as streamed by StreamMigration.
+type Statement struct {
+	// Down is set for the statements of the Down section.
+	Down  bool
+	SQL   string
+	Lines LineRange
+}
+
+// LineRange is the first and last line of a statement, counted from 1.
+type LineRange struct {
+	First int
+	Last  int
+}
+
+func (r LineRange) String() string {
+	if r.First == r.Last {
+		return fmt.Sprintf("line %d", r.First)
+	}
+
+	return fmt.Sprintf("lines %d-%d", r.First, r.Last)
 }
 
 // LineSeparator can be used to split migrations by an exact line match. This line
@@ -42,20 +81,39 @@ func errNoTerminator() error {
 // or if the line contains a double-dash comment.
 func endsWithSemicolon(line string) bool {
 	prev := ""
-	scanner := bufio.NewScanner(strings.NewReader(line))
-	scanner.Split(bufio.ScanWords)
 
-	for scanner.Scan() {
-		word := scanner.Text()
+	for {
+		line = strings.TrimLeftFunc(line, unicode.IsSpace)
+		if line == "" {
+			break
+		}
+
+		end := strings.IndexFunc(line, unicode.IsSpace)
+		if end < 0 {
+			end = len(line)
+		}
+
+		word := line[:end]
 		if strings.HasPrefix(word, "--") {
 			break
 		}
+
 		prev = word
+		line = line[end:]
 	}
 
 	return strings.HasSuffix(prev, ";")
 }
 
+// scanBuffers holds the line buffers of the scanners, which are reused as
+// sources parse thousands of migrations.
+var scanBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 64*1024)
+		return &buf
+	},
+}
+
 type migrationDirection int
 
 const (
@@ -108,23 +166,65 @@ func parseCommand(line string) (*migrateCommand, error) {
 // 'StatementBegin' and 'StatementEnd' to allow the script to
 // tell us to ignore semicolons.
 func ParseMigration(r io.ReadSeeker) (*ParsedMigration, error) {
-	p := &ParsedMigration{}
-
 	_, err := r.Seek(0, 0)
 	if err != nil {
 		return nil, err
 	}
 
+	var up, down []string
+
+	var upLines, downLines []LineRange
+
+	p, err := StreamMigration(r, func(stmt Statement) error {
+		if stmt.Down {
+			down = append(down, stmt.SQL)
+			downLines = append(downLines, stmt.Lines)
+		} else {
+			up = append(up, stmt.SQL)
+			upLines = append(upLines, stmt.Lines)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.UpStatements, p.DownStatements = up, down
+	p.UpLines, p.DownLines = upLines, downLines
+
+	return p, nil
+}
+
+// StreamMigration splits the migration like ParseMigration, but hands each
+// statement to fn as soon as it's read instead of collecting them, so huge
+// generated migrations needn't fit in memory. The statements read before a
+// syntax error of the file are handed already. The returned migration only
+// holds the options of the sections. An error of fn stops the stream and is
+// returned.
+func StreamMigration(r io.Reader, fn func(Statement) error) (*ParsedMigration, error) {
+	p := &ParsedMigration{}
+
 	var buf bytes.Buffer
+
+	scanBuf := scanBuffers.Get().(*[]byte)
+	defer scanBuffers.Put(scanBuf)
+
 	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	scanner.Buffer(*scanBuf, 1024*1024)
 
 	statementEnded := false
 	ignoreSemicolons := false
 	currentDirection := directionNone
 
+	// The first and last line with contents of the current statement.
+	lineNumber := 0
+	firstLine, lastLine := 0, 0
+
 	for scanner.Scan() {
 		line := scanner.Text()
+		lineNumber++
+
 		// ignore comment except beginning with '-- +'
 		if strings.HasPrefix(line, "-- ") && !strings.HasPrefix(line, "-- +") {
 			continue
@@ -156,6 +256,40 @@ func ParseMigration(r io.ReadSeeker) (*ParsedMigration, error) {
 					p.DisableTransactionDown = true
 				}
 
+			case "StatementTimeout":
+				if len(cmd.Options) != 1 {
+					return nil, fmt.Errorf("ERROR: '-- +migrate StatementTimeout' needs a duration, e.g. 5m")
+				}
+
+				timeout, err := time.ParseDuration(cmd.Options[0])
+				if err != nil || timeout <= 0 {
+					return nil, fmt.Errorf("ERROR: invalid statement timeout %q, must be a positive duration, e.g. 5m", cmd.Options[0])
+				}
+
+				if currentDirection != directionDown {
+					p.StatementTimeoutUp = timeout
+				}
+				if currentDirection != directionUp {
+					p.StatementTimeoutDown = timeout
+				}
+
+			case "DependsOn", "DependsOn:":
+				var ids []string
+
+				for _, option := range cmd.Options {
+					for _, id := range strings.Split(option, ",") {
+						if id = strings.TrimSpace(id); id != "" {
+							ids = append(ids, id)
+						}
+					}
+				}
+
+				if len(ids) == 0 {
+					return nil, fmt.Errorf("ERROR: '-- +migrate DependsOn' needs a migration, e.g. 0003_foo.sql")
+				}
+
+				p.DependsOn = append(p.DependsOn, ids...)
+
 			case "StatementBegin":
 				if currentDirection != directionNone {
 					ignoreSemicolons = true
@@ -176,9 +310,16 @@ func ParseMigration(r io.ReadSeeker) (*ParsedMigration, error) {
 		isLineSeparator := !ignoreSemicolons && len(LineSeparator) > 0 && line == LineSeparator
 
 		if !isLineSeparator && !strings.HasPrefix(line, "-- +") {
-			if _, err := buf.WriteString(line + "\n"); err != nil {
-				return nil, err
+			if strings.TrimSpace(line) != "" {
+				if firstLine == 0 {
+					firstLine = lineNumber
+				}
+
+				lastLine = lineNumber
 			}
+
+			buf.WriteString(line)
+			buf.WriteByte('\n')
 		}
 
 		// Wrap up the two supported cases: 1) basic with semicolon; 2) psql statement
@@ -186,18 +327,22 @@ func ParseMigration(r io.ReadSeeker) (*ParsedMigration, error) {
 		// do not conclude statement.
 		if (!ignoreSemicolons && (endsWithSemicolon(line) || isLineSeparator)) || statementEnded {
 			statementEnded = false
-			switch currentDirection {
-			case directionUp:
-				p.UpStatements = append(p.UpStatements, buf.String())
-
-			case directionDown:
-				p.DownStatements = append(p.DownStatements, buf.String())
+			lines := LineRange{First: firstLine, Last: lastLine}
+			if firstLine == 0 {
+				lines = LineRange{First: lineNumber, Last: lineNumber}
+			}
 
-			default:
+			if currentDirection != directionUp && currentDirection != directionDown {
 				panic("impossible state")
 			}
 
+			err := fn(Statement{Down: currentDirection == directionDown, SQL: buf.String(), Lines: lines})
+			if err != nil {
+				return nil, err
+			}
+
 			buf.Reset()
+			firstLine, lastLine = 0, 0
 		}
 	}
 
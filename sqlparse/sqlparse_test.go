@@ -1,8 +1,10 @@
 package sqlparse
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	//revive:disable-next-line:dot-imports
 	. "gopkg.in/check.v1"
@@ -81,6 +83,76 @@ func (*SqlParseSuite) TestSplitStatements(c *C) {
 	}
 }
 
+func (*SqlParseSuite) TestStatementLines(c *C) {
+	migration, err := ParseMigration(strings.NewReader(functxt))
+	c.Assert(err, IsNil)
+	c.Assert(migration.UpLines, DeepEquals, []LineRange{{2, 6}, {9, 28}})
+	c.Assert(migration.DownLines, DeepEquals, []LineRange{{32, 32}, {33, 33}})
+	c.Assert(migration.UpLines[0].String(), Equals, "lines 2-6")
+	c.Assert(migration.DownLines[0].String(), Equals, "line 32")
+}
+
+func (*SqlParseSuite) TestStreamMigration(c *C) {
+	var down []Statement
+
+	migration, err := StreamMigration(strings.NewReader(functxt), func(stmt Statement) error {
+		if stmt.Down {
+			down = append(down, stmt)
+		}
+
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(migration.UpStatements, HasLen, 0)
+	c.Assert(down, HasLen, 2)
+	c.Assert(down[0].Lines, Equals, LineRange{32, 32})
+
+	stop := fmt.Errorf("stop")
+	calls := 0
+
+	_, err = StreamMigration(strings.NewReader(functxt), func(Statement) error {
+		calls++
+		return stop
+	})
+	c.Assert(err, Equals, stop)
+	c.Assert(calls, Equals, 1)
+}
+
+func (*SqlParseSuite) TestStatementTimeout(c *C) {
+	migration, err := ParseMigration(strings.NewReader(`-- +migrate StatementTimeout 1m
+-- +migrate Up
+-- +migrate StatementTimeout 5m
+ALTER TABLE people ADD COLUMN name text;
+
+-- +migrate Down
+ALTER TABLE people DROP COLUMN name;
+`))
+	c.Assert(err, IsNil)
+	c.Assert(migration.StatementTimeoutUp, Equals, 5*time.Minute)
+	c.Assert(migration.StatementTimeoutDown, Equals, time.Minute)
+	c.Assert(migration.UpStatements, DeepEquals, []string{"ALTER TABLE people ADD COLUMN name text;\n"})
+
+	_, err = ParseMigration(strings.NewReader("-- +migrate Up\n-- +migrate StatementTimeout forever\nSELECT 1;\n"))
+	c.Assert(err, ErrorMatches, `ERROR: invalid statement timeout "forever", must be a positive duration, e.g. 5m`)
+
+	_, err = ParseMigration(strings.NewReader("-- +migrate Up\n-- +migrate StatementTimeout\nSELECT 1;\n"))
+	c.Assert(err, ErrorMatches, "ERROR: '-- \\+migrate StatementTimeout' needs a duration, e.g. 5m")
+}
+
+func (*SqlParseSuite) TestDependsOn(c *C) {
+	migration, err := ParseMigration(strings.NewReader(`-- +migrate DependsOn: 0001_people.sql
+-- +migrate DependsOn 0002_orders.sql, 0003_items.sql
+-- +migrate Up
+CREATE TABLE invoices (id int);
+`))
+	c.Assert(err, IsNil)
+	c.Assert(migration.DependsOn, DeepEquals, []string{"0001_people.sql", "0002_orders.sql", "0003_items.sql"})
+	c.Assert(migration.UpStatements, DeepEquals, []string{"CREATE TABLE invoices (id int);\n"})
+
+	_, err = ParseMigration(strings.NewReader("-- +migrate DependsOn:\n-- +migrate Up\nSELECT 1;\n"))
+	c.Assert(err, ErrorMatches, "ERROR: '-- \\+migrate DependsOn' needs a migration, e.g. 0003_foo.sql")
+}
+
 func (*SqlParseSuite) TestIntentionallyBadStatements(c *C) {
 	for _, test := range intentionallyBad {
 		_, err := ParseMigration(strings.NewReader(test))
@@ -378,3 +450,27 @@ CREATE TABLE post (
 -- no migration here
 `,
 }
+
+func BenchmarkParseMigration(b *testing.B) {
+	var migration strings.Builder
+
+	migration.WriteString("-- +migrate Up\n")
+
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&migration, "INSERT INTO people (id, name) VALUES (%d, 'person %d'); -- seed\n", i, i)
+	}
+
+	migration.WriteString("\n-- +migrate Down\nDELETE FROM people;\n")
+
+	r := strings.NewReader(migration.String())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := ParseMigration(r)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
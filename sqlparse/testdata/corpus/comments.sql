@@ -0,0 +1,8 @@
+-- a header comment
+-- +migrate Up
+/* a block comment; with a semicolon */
+CREATE TABLE t (
+  id int -- trailing comment;
+);
+-- +migrate Down
+-- nothing to undo
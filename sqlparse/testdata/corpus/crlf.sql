@@ -0,0 +1,5 @@
+-- +migrate Up notransaction
+CREATE INDEX CONCURRENTLY i ON t (id);
+
+-- +migrate Down notransaction
+DROP INDEX CONCURRENTLY i;
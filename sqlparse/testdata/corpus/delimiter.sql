@@ -0,0 +1,10 @@
+-- +migrate Up
+DELIMITER //
+CREATE PROCEDURE p()
+BEGIN
+  SELECT 1;
+END //
+DELIMITER ;
+
+-- +migrate Down
+DROP PROCEDURE p;
@@ -0,0 +1,13 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE FUNCTION touch() RETURNS trigger AS $body$
+BEGIN
+  NEW.updated_at = now(); -- not the end;
+  RETURN NEW;
+END;
+$body$ LANGUAGE plpgsql;
+-- +migrate StatementEnd
+SELECT $$;$$;
+
+-- +migrate Down
+DROP FUNCTION touch();
@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE t (id int)
+GO
+CREATE PROCEDURE p AS
+BEGIN
+  SELECT 1;
+END
+GO
+
+-- +migrate Down
+DROP PROCEDURE p
+GO
+DROP TABLE t
+GO
@@ -0,0 +1,6 @@
+-- +migrate Up
+INSERT INTO greetings (text) VALUES ('héllo wörld'), ('こんにちは'), ('👋;');
+COMMENT ON TABLE greetings IS 'ÿ -- not a comment;';
+
+-- +migrate Down
+DELETE FROM greetings;
@@ -0,0 +1,3 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;
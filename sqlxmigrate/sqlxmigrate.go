@@ -0,0 +1,104 @@
+// Package sqlxmigrate runs the migrations of sqlx based applications on
+// their *sqlx.DB, with the dialect of its driver, and reads the migration
+// table through a *sqlx.DB or a *sqlx.Tx:
+//
+//	ex := migrate.NewMigrationExecutor()
+//	n, err := sqlxmigrate.Exec(ctx, ex, db, source, migrate.Up)
+//
+//	records, err := sqlxmigrate.Status(ctx, ex, tx, dialect)
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// drivers maps the names of the drivers to the dialects, for the drivers
+// whose name isn't that of the dialect.
+var drivers = map[string]migrate.DialectName{
+	"pgx":       migrate.Postgres,
+	"pgx/v5":    migrate.Postgres,
+	"sqlite":    migrate.SQLite3,
+	"sqlserver": migrate.MSSQL,
+	"azuresql":  migrate.MSSQL,
+}
+
+// Dialect returns the dialect of the driver of db, e.g. postgres for pgx.
+func Dialect(db *sqlx.DB) (dialect.Dialect, error) {
+	name, ok := drivers[db.DriverName()]
+	if !ok {
+		name = migrate.DialectName(db.DriverName())
+	}
+
+	return migrate.GetDialect(name)
+}
+
+// Exec applies the migrations of the source in the direction on db, with the
+// dialect of its driver. Each migration runs in its own transaction, so it
+// takes the database, not a transaction.
+func Exec(ctx context.Context, ex *migrate.MigrationExecutor, db *sqlx.DB, source migrate.MigrationSource, dir migrate.MigrationDirection) (int, error) {
+	d, err := Dialect(db)
+	if err != nil {
+		return 0, err
+	}
+
+	return ex.ExecContext(ctx, db.DB, d, source, dir)
+}
+
+// Status reads the migration table of the executor through q, a *sqlx.DB or
+// a *sqlx.Tx, e.g. to check the applied migrations in the transaction of the
+// caller. The columns are matched to the db tags of migrate.MigrationRecord;
+// other columns are ignored.
+func Status(ctx context.Context, ex *migrate.MigrationExecutor, q sqlx.QueryerContext, d dialect.Dialect) ([]migrate.MigrationRecord, error) {
+	rows, err := q.QueryxContext(ctx, d.QuerySelectMigrate(ex.SchemaName, ex.TableName))
+	if err != nil {
+		return nil, fmt.Errorf("sqlxmigrate: cannot read the migration table: %w", err)
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []migrate.MigrationRecord
+
+	for rows.Next() {
+		var (
+			record   migrate.MigrationRecord
+			checksum sql.NullString
+		)
+
+		// The table may have columns the record doesn't, e.g. of later versions.
+		dest := make([]any, len(columns))
+		for i, column := range columns {
+			switch column {
+			case "id":
+				dest[i] = &record.Id
+			case "applied_at":
+				dest[i] = &record.AppliedAt
+			case "checksum":
+				// NULL when the migration was applied before the column existed.
+				dest[i] = &checksum
+			default:
+				dest[i] = new(any)
+			}
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("sqlxmigrate: cannot read the migration table: %w", err)
+		}
+
+		record.Checksum = checksum.String
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
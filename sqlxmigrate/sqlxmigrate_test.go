@@ -0,0 +1,89 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type SqlxSuite struct {
+	db *sqlx.DB
+	ex *migrate.MigrationExecutor
+}
+
+var _ = Suite(&SqlxSuite{})
+
+type nullLogger struct{}
+
+func (nullLogger) Tracef(string, ...any) {}
+func (nullLogger) Infof(string, ...any)  {}
+func (nullLogger) Errorf(string, ...any) {}
+
+var source = migrate.NewMemoryMigrationSource([]*migrate.Migration{
+	{Id: "1_users.sql", Up: []string{"CREATE TABLE users (id int)"}, Down: []string{"DROP TABLE users"}},
+	{Id: "2_orders.sql", Up: []string{"CREATE TABLE orders (id int)"}, Down: []string{"DROP TABLE orders"}},
+})
+
+func (s *SqlxSuite) SetUpTest(c *C) {
+	var err error
+	s.db, err = sqlx.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+
+	s.ex = migrate.NewMigrationExecutor()
+	s.ex.CreateTable = true
+	s.ex.Logger = nullLogger{}
+}
+
+func (s *SqlxSuite) TearDownTest(c *C) {
+	c.Assert(s.db.Close(), IsNil)
+}
+
+func (s *SqlxSuite) TestDialect(c *C) {
+	d, err := Dialect(s.db)
+	c.Assert(err, IsNil)
+	c.Assert(d, FitsTypeOf, &dialect.SqliteDialect{})
+
+	d, err = Dialect(sqlx.NewDb(s.db.DB, "pgx"))
+	c.Assert(err, IsNil)
+	c.Assert(d, FitsTypeOf, &dialect.PostgresDialect{})
+
+	_, err = Dialect(sqlx.NewDb(s.db.DB, "unknown"))
+	c.Assert(err, ErrorMatches, "unknown dialect: unknown")
+}
+
+func (s *SqlxSuite) TestExecAndStatus(c *C) {
+	ctx := context.Background()
+
+	n, err := Exec(ctx, s.ex, s.db, source, migrate.Up)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	c.Assert(err, IsNil)
+
+	defer func() { _ = tx.Rollback() }()
+
+	records, err := Status(ctx, s.ex, tx, dialect.NewSqliteDialect())
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[0].Id, Equals, "1_users.sql")
+	c.Assert(records[0].AppliedAt.IsZero(), Equals, false)
+	c.Assert(records[0].Checksum, Equals, source.Migrations[0].Checksum())
+
+	// The db tags of the record scan the rows of custom queries too.
+	var ids []migrate.MigrationRecord
+	c.Assert(tx.SelectContext(ctx, &ids, "SELECT id, applied_at FROM migrations ORDER BY id"), IsNil)
+	c.Assert(ids, HasLen, 2)
+	c.Assert(ids[1].Id, Equals, "2_orders.sql")
+}
@@ -0,0 +1,129 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// MigrationState is the state of a migration in a Status.
+type MigrationState string
+
+const (
+	// StateApplied migrations are in the source and in the migration table.
+	StateApplied MigrationState = "applied"
+	// StatePending migrations are in the source only.
+	StatePending MigrationState = "pending"
+	// StateUnknown migrations are in the migration table only, e.g. applied
+	// by a newer release.
+	StateUnknown MigrationState = "unknown"
+)
+
+// MigrationStatus is the state of a migration of the source or of the
+// migration table.
+type MigrationStatus struct {
+	Id    string         `json:"id"`
+	State MigrationState `json:"state"`
+	// AppliedAt is the time the migration was applied, zero when it's pending.
+	AppliedAt time.Time `json:"applied_at"`
+	// Duration and AppliedBy are those of the record, see MigrationRecord.
+	Duration  time.Duration `json:"duration,omitempty"`
+	AppliedBy string        `json:"applied_by,omitempty"`
+	// Migration is the migration of the source, nil when it's unknown.
+	Migration *Migration `json:"-"`
+	// Record is the record of the migration table, nil when it's pending.
+	Record *MigrationRecord `json:"-"`
+}
+
+// Status is the state of the migrations of the source and of the migration
+// table, see MigrationExecutor.Status.
+type Status struct {
+	// Migrations are the migrations of the source and the unknown ones, in
+	// the order of the migrations.
+	Migrations []MigrationStatus `json:"migrations"`
+}
+
+// Applied returns the applied migrations, in order.
+func (s *Status) Applied() []MigrationStatus {
+	return s.filter(StateApplied)
+}
+
+// Pending returns the pending migrations, in order.
+func (s *Status) Pending() []MigrationStatus {
+	return s.filter(StatePending)
+}
+
+// Unknown returns the migrations of the migration table missing from the
+// source, in order.
+func (s *Status) Unknown() []MigrationStatus {
+	return s.filter(StateUnknown)
+}
+
+func (s *Status) filter(state MigrationState) []MigrationStatus {
+	var result []MigrationStatus
+
+	for _, migration := range s.Migrations {
+		if migration.State == state {
+			result = append(result, migration)
+		}
+	}
+
+	return result
+}
+
+// Status compares the migrations of the source with the migration table,
+// e.g. to render the state of each migration.
+func (ex *MigrationExecutor) Status(ctx context.Context, db *sql.DB, dialect dialect.Dialect, source MigrationSource) (*Status, error) {
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := ex.GetMigrationRecords(ctx, db, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStatus(migrations, records), nil
+}
+
+// newStatus combines the migrations of the source with the records of the
+// migration table.
+func newStatus(migrations []*Migration, records []MigrationRecord) *Status {
+	byId := make(map[string]*MigrationStatus, len(migrations)+len(records))
+	for _, migration := range migrations {
+		byId[migration.Id] = &MigrationStatus{Id: migration.Id, State: StatePending, Migration: migration}
+	}
+
+	for i, record := range records {
+		status, ok := byId[record.Id]
+		if !ok {
+			status = &MigrationStatus{Id: record.Id, State: StateUnknown}
+			byId[record.Id] = status
+		} else {
+			status.State = StateApplied
+		}
+
+		status.AppliedAt = record.AppliedAt
+		status.Duration = record.Duration
+		status.AppliedBy = record.AppliedBy
+		status.Record = &records[i]
+	}
+
+	ordered := make([]*Migration, 0, len(byId))
+	for id := range byId {
+		ordered = append(ordered, &Migration{Id: id})
+	}
+
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Less(ordered[j]) })
+
+	status := &Status{Migrations: make([]MigrationStatus, 0, len(ordered))}
+	for _, migration := range ordered {
+		status.Migrations = append(status.Migrations, *byId[migration.Id])
+	}
+
+	return status
+}
@@ -0,0 +1,37 @@
+package migrate
+
+import (
+	"context"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+)
+
+func (s *SqliteMigrateSuite) TestStatus(c *C) {
+	ctx := context.Background()
+
+	n, err := s.ex.ExecMax(s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations), Up, 1)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	_, err = s.db.Exec("INSERT INTO migrations (id, applied_at) VALUES ('125_hotfix.sql', '2024-01-02 03:04:05')")
+	c.Assert(err, IsNil)
+
+	status, err := s.ex.Status(ctx, s.db, s.dialect, NewMemoryMigrationSource(sqliteMigrations))
+	c.Assert(err, IsNil)
+	c.Assert(status.Migrations, HasLen, 3)
+
+	c.Assert(status.Applied(), HasLen, 1)
+	c.Assert(status.Applied()[0].Id, Equals, "123")
+	c.Assert(status.Applied()[0].AppliedAt.IsZero(), Equals, false)
+	c.Assert(status.Applied()[0].Migration, Equals, sqliteMigrations[0])
+
+	c.Assert(status.Pending(), HasLen, 1)
+	c.Assert(status.Pending()[0].Id, Equals, "124")
+	c.Assert(status.Pending()[0].AppliedAt.IsZero(), Equals, true)
+
+	c.Assert(status.Unknown(), HasLen, 1)
+	c.Assert(status.Unknown()[0].Id, Equals, "125_hotfix.sql")
+	c.Assert(status.Unknown()[0].Migration, IsNil)
+	c.Assert(status.Migrations[2].Id, Equals, "125_hotfix.sql")
+}
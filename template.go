@@ -0,0 +1,60 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+)
+
+// MigrationTemplate renders the migration files with text/template before
+// they're parsed, so one set of migrations serves several environments, e.g.
+// with the tablespace of each:
+//
+//	CREATE TABLE events (id bigint) TABLESPACE {{ .tablespace }};
+//	CREATE SCHEMA {{ env "TENANT" }};
+//
+// Missing keys of Data and unset environment variables fail the migration.
+// The checksums are those of the rendered migrations.
+type MigrationTemplate struct {
+	// Data is the data of the templates.
+	Data map[string]any
+	// Funcs are functions for the templates, besides env, which returns the
+	// environment variable of the name.
+	Funcs template.FuncMap
+}
+
+// render renders the migration file named id.
+func (t *MigrationTemplate) render(id string, r io.Reader) (*bytes.Reader, error) {
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(id).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"env": templateEnv}).
+		Funcs(t.Funcs).
+		Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, t.Data); err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(b.Bytes()), nil
+}
+
+// templateEnv returns the environment variable of the name, which must be set.
+func templateEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,195 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// TenantSource lists the schemas of the tenants migrated by ExecTenants.
+type TenantSource interface {
+	Tenants(ctx context.Context, db *sql.DB) ([]string, error)
+}
+
+var _ TenantSource = TenantList(nil)
+
+// TenantList A fixed list of tenant schemas.
+type TenantList []string
+
+func (l TenantList) Tenants(_ context.Context, _ *sql.DB) ([]string, error) {
+	return l, nil
+}
+
+var _ TenantSource = TenantQuery("")
+
+// TenantQuery Tenant schemas discovered by a query selecting one schema name per row, e.g.
+//
+//	SELECT schema_name FROM information_schema.schemata WHERE schema_name LIKE 'tenant_%'
+type TenantQuery string
+
+func (q TenantQuery) Tenants(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, string(q))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tenants []string
+
+	for rows.Next() {
+		var tenant string
+
+		err = rows.Scan(&tenant)
+		if err != nil {
+			return nil, err
+		}
+
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, rows.Err()
+}
+
+// TenantResult is the outcome of migrating a single tenant schema.
+type TenantResult struct {
+	Schema  string
+	Applied int
+	Err     error
+	// Skipped is set when the tenant was never migrated because another tenant
+	// failed and the run stopped on the first error.
+	Skipped bool
+}
+
+// ExecTenants applies the migration source to every tenant schema of the database.
+// Each tenant is migrated on its own connection whose default schema (search_path
+// in PostgreSQL) is the tenant schema, with SchemaName set to the tenant schema,
+// so both the migrations and the migration table live in the tenant schema.
+// Tenants are migrated in sequence unless WithConcurrency is passed.
+func (ex *MigrationExecutor) ExecTenants(
+	ctx context.Context,
+	db *sql.DB,
+	d dialect.Dialect,
+	tenants TenantSource,
+	source MigrationSource,
+	dir MigrationDirection,
+	opts ...ExecAllOption,
+) ([]TenantResult, error) {
+	selector, ok := d.(dialect.SchemaSelector)
+	if !ok {
+		return nil, fmt.Errorf("dialect %T can't select the schema of a tenant", d)
+	}
+
+	options := newExecAllOptions(opts)
+
+	schemas, err := tenants.Tenants(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	source = NewMemoryMigrationSource(migrations)
+
+	results := make([]TenantResult, len(schemas))
+
+	started := runParallel(ctx, len(schemas), options, func(ctx context.Context, i int) error {
+		applied, err := ex.execTenant(ctx, db, d, selector, schemas[i], source, dir, options.max)
+		results[i] = TenantResult{Schema: schemas[i], Applied: applied, Err: err}
+
+		return err
+	})
+
+	var errs []error
+
+	for i := range results {
+		if !started[i] {
+			results[i] = TenantResult{Schema: schemas[i], Skipped: true}
+		}
+
+		if results[i].Err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", schemas[i], results[i].Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+func (ex *MigrationExecutor) execTenant(
+	ctx context.Context,
+	db *sql.DB,
+	d dialect.Dialect,
+	selector dialect.SchemaSelector,
+	schema string,
+	source MigrationSource,
+	dir MigrationDirection,
+	max int,
+) (int, error) {
+	var applied int
+
+	err := ex.inSchema(ctx, db, d, selector, schema, func(tenant *MigrationExecutor, conn *sql.Conn) error {
+		var err error
+		applied, err = tenant.exec(ctx, db, conn, d, source, dir, max, -1)
+
+		return err
+	})
+
+	return applied, err
+}
+
+// inSchema calls fn with a copy of the executor whose SchemaName is schema
+// and a connection whose default schema is schema, created first when
+// CreateSchema is set. The default schema of the connection is reset
+// afterwards, or the connection is discarded when it can't be.
+func (ex *MigrationExecutor) inSchema(
+	ctx context.Context,
+	db *sql.DB,
+	d dialect.Dialect,
+	selector dialect.SchemaSelector,
+	schema string,
+	fn func(tenant *MigrationExecutor, conn *sql.Conn) error,
+) error {
+	tenant := *ex
+	tenant.SchemaName = schema
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if reset := selector.QueryResetSchema(); reset != "" {
+			_, err := conn.ExecContext(context.Background(), reset)
+			if err == nil {
+				_ = conn.Close()
+
+				return
+			}
+		}
+
+		// The session still points at the tenant schema, don't hand it back to the pool.
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		_ = conn.Close()
+	}()
+
+	if tenant.CreateSchema {
+		err = tenant.newRepository(conn, d).CreateSchema(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = conn.ExecContext(ctx, selector.QuerySelectSchema(schema))
+	if err != nil {
+		return err
+	}
+
+	return fn(&tenant, conn)
+}
@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	"github.com/kva3umoda/sql-migrate/dialect"
+)
+
+// tenantDialect emulates schemas on SQLite by prefixing the migration table with the schema name.
+type tenantDialect struct {
+	*dialect.SqliteDialect
+}
+
+func (d tenantDialect) QueryCreateMigrateTable(schemaName, tableName string) string {
+	return d.SqliteDialect.QueryCreateMigrateTable("", schemaName+"_"+tableName)
+}
+
+func (d tenantDialect) QuerySelectMigrate(schemaName, tableName string) string {
+	return d.SqliteDialect.QuerySelectMigrate("", schemaName+"_"+tableName)
+}
+
+func (d tenantDialect) QuerySelectMigrateIds(schemaName, tableName string) string {
+	return d.SqliteDialect.QuerySelectMigrateIds("", schemaName+"_"+tableName)
+}
+
+func (d tenantDialect) QuerySelectMigrateId(schemaName, tableName string) string {
+	return d.SqliteDialect.QuerySelectMigrateId("", schemaName+"_"+tableName)
+}
+
+func (d tenantDialect) QueryCreateMigrateIndex(schemaName, tableName string) string {
+	return d.SqliteDialect.QueryCreateMigrateIndex("", schemaName+"_"+tableName)
+}
+
+func (d tenantDialect) QuerySelectLastMigrate(schemaName, tableName string) string {
+	return d.SqliteDialect.QuerySelectLastMigrate("", schemaName+"_"+tableName)
+}
+
+func (d tenantDialect) QueryInsertMigrate(schemaName, tableName string) string {
+	return d.SqliteDialect.QueryInsertMigrate("", schemaName+"_"+tableName)
+}
+
+func (d tenantDialect) QueryDeleteMigrate(schemaName, tableName string) string {
+	return d.SqliteDialect.QueryDeleteMigrate("", schemaName+"_"+tableName)
+}
+
+func (d tenantDialect) QueryProbeMigrateColumn(schemaName, tableName, column string) string {
+	return d.SqliteDialect.QueryProbeMigrateColumn("", schemaName+"_"+tableName, column)
+}
+
+func (d tenantDialect) QueryAddMigrateColumn(schemaName, tableName, column string, columnType dialect.ColumnType) string {
+	return d.SqliteDialect.QueryAddMigrateColumn("", schemaName+"_"+tableName, column, columnType)
+}
+
+func (d tenantDialect) QuerySelectMigrateColumns(schemaName, tableName string, columns []string) string {
+	return d.SqliteDialect.QuerySelectMigrateColumns("", schemaName+"_"+tableName, columns)
+}
+
+func (d tenantDialect) QueryInsertMigrateColumns(schemaName, tableName string, columns []string) string {
+	return d.SqliteDialect.QueryInsertMigrateColumns("", schemaName+"_"+tableName, columns)
+}
+
+func (tenantDialect) QuerySelectSchema(string) string { return "SELECT 1" }
+func (tenantDialect) QueryResetSchema() string        { return "SELECT 1" }
+
+type TenantSuite struct {
+	db *sql.DB
+}
+
+var _ = Suite(&TenantSuite{})
+
+func (s *TenantSuite) SetUpTest(c *C) {
+	var err error
+	s.db, err = sql.Open("sqlite3", filepath.Join(c.MkDir(), "test.db"))
+	c.Assert(err, IsNil)
+
+	_, err = s.db.Exec("CREATE TABLE tenants (name text); INSERT INTO tenants VALUES ('acme'), ('globex')")
+	c.Assert(err, IsNil)
+}
+
+func (s *TenantSuite) TearDownTest(c *C) {
+	c.Assert(s.db.Close(), IsNil)
+}
+
+func (s *TenantSuite) TestTenantQuery(c *C) {
+	tenants, err := TenantQuery("SELECT name FROM tenants ORDER BY name").Tenants(context.Background(), s.db)
+	c.Assert(err, IsNil)
+	c.Assert(tenants, DeepEquals, []string{"acme", "globex"})
+}
+
+func (s *TenantSuite) TestExecTenants(c *C) {
+	ex := NewMigrationExecutor()
+	ex.CreateTable = true
+	ex.Logger = nullLogger{}
+
+	source := NewMemoryMigrationSource([]*Migration{
+		{Id: "1_initial", Up: []string{"CREATE TABLE IF NOT EXISTS people (id int)"}},
+	})
+
+	results, err := ex.ExecTenants(context.Background(), s.db, tenantDialect{dialect.NewSqliteDialect()},
+		TenantQuery("SELECT name FROM tenants ORDER BY name"), source, Up, WithConcurrency(2))
+	c.Assert(err, IsNil)
+	c.Assert(results, DeepEquals, []TenantResult{
+		{Schema: "acme", Applied: 1},
+		{Schema: "globex", Applied: 1},
+	})
+}
+
+func (s *TenantSuite) TestExecTenantsUnsupportedDialect(c *C) {
+	_, err := NewMigrationExecutor().ExecTenants(context.Background(), s.db, dialect.NewSqliteDialect(),
+		TenantList{"acme"}, NewMemoryMigrationSource(nil), Up)
+	c.Assert(err, ErrorMatches, ".*can't select the schema of a tenant")
+}
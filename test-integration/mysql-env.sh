@@ -15,6 +15,6 @@ set -ex
 
 sql-migrate status $OPTIONS
 sql-migrate up $OPTIONS
-sql-migrate down $OPTIONS
-sql-migrate redo $OPTIONS
+sql-migrate down -yes $OPTIONS
+sql-migrate redo -yes $OPTIONS
 sql-migrate status $OPTIONS
@@ -9,8 +9,8 @@ set -ex
 
 sql-migrate status $OPTIONS
 sql-migrate up $OPTIONS
-sql-migrate down $OPTIONS
-sql-migrate redo $OPTIONS
+sql-migrate down -yes $OPTIONS
+sql-migrate redo -yes $OPTIONS
 sql-migrate status $OPTIONS
 
 # Should have used the custom migrations table
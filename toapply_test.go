@@ -99,3 +99,18 @@ func (*ToApplyMigrateSuite) TestAlphaNumericMigrations(c *C) {
 	c.Assert(toApplyDown[0].Id, Equals, "2_cde")
 	c.Assert(toApplyDown[1].Id, Equals, "1_abc")
 }
+
+func (*ToApplyMigrateSuite) TestCatchup(c *C) {
+	migrations := byId([]*Migration{
+		{Id: "1_abc"},
+		{Id: "2_cde"},
+		{Id: "3_efg"},
+		{Id: "4_ghi"},
+	})
+
+	applied := map[string]struct{}{"1_abc": {}, "3_efg": {}}
+
+	missing := toCatchup(migrations, applied, migrations[2])
+	c.Assert(missing, HasLen, 1)
+	c.Assert(missing[0].Id, Equals, "2_cde")
+}
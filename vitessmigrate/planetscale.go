@@ -0,0 +1,232 @@
+package vitessmigrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+// PlanetScale applies the statements with a deploy request, for the branches
+// with safe migrations, which refuse direct DDL: a branch is created off
+// Branch, the statements are run on it, and a deploy request of the branch
+// into Branch is deployed and waited for. The branch is deleted afterwards.
+type PlanetScale struct {
+	Organization string
+	Database     string
+	// Branch is the branch deployed to, main when empty.
+	Branch string
+	// TokenId and Token are a service token with the create_branch,
+	// delete_branch, connect_branch, create_deploy_request and
+	// deploy_deploy_request permissions on the database.
+	TokenId string
+	Token   string
+	// PollInterval is the time between the checks of the branch and of the
+	// deploy request, 5s when zero.
+	PollInterval time.Duration
+	// BaseURL is the URL of the API, https://api.planetscale.com/v1 when
+	// empty.
+	BaseURL string
+	// Client sends the requests to the API, http.DefaultClient when nil.
+	Client *http.Client
+	// Open opens the branch with the DSN of go-sql-driver/mysql,
+	// sql.Open("mysql", dsn) when nil, which needs the mysql driver
+	// registered.
+	Open func(dsn string) (*sql.DB, error)
+}
+
+// branchName drops the characters PlanetScale refuses in branch names.
+var branchName = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Deploy runs the statements on a new branch and deploys it.
+func (p *PlanetScale) Deploy(ctx context.Context, _ Conn, migration *migrate.PlannedMigration, stmts []string) (int, error) {
+	branch := "migrate-" + strings.Trim(branchName.ReplaceAllString(strings.ToLower(migration.Id), "-"), "-")
+	into := p.Branch
+	if into == "" {
+		into = "main"
+	}
+
+	err := p.call(ctx, http.MethodPost, "branches", map[string]string{"name": branch, "parent_branch": into}, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() { _ = p.call(context.WithoutCancel(ctx), http.MethodDelete, "branches/"+branch, nil, nil) }()
+
+	for {
+		var state struct {
+			Ready bool `json:"ready"`
+		}
+
+		if err := p.call(ctx, http.MethodGet, "branches/"+branch, nil, &state); err != nil {
+			return 0, err
+		}
+
+		if state.Ready {
+			break
+		}
+
+		if err := sleep(ctx, p.PollInterval); err != nil {
+			return 0, err
+		}
+	}
+
+	if failed, err := p.apply(ctx, branch, stmts); err != nil {
+		return failed, err
+	}
+
+	var request struct {
+		Number int `json:"number"`
+	}
+
+	err = p.call(ctx, http.MethodPost, "deploy-requests", map[string]any{"branch": branch, "into_branch": into, "auto_cutover": true}, &request)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.wait(ctx, request.Number); err != nil {
+		return 0, err
+	}
+
+	return len(stmts), nil
+}
+
+// apply runs the statements on the branch, with a password created for it.
+func (p *PlanetScale) apply(ctx context.Context, branch string, stmts []string) (int, error) {
+	var password struct {
+		Username      string `json:"username"`
+		PlainText     string `json:"plain_text"`
+		AccessHostURL string `json:"access_host_url"`
+	}
+
+	if err := p.call(ctx, http.MethodPost, "branches/"+branch+"/passwords", map[string]string{"role": "admin"}, &password); err != nil {
+		return 0, err
+	}
+
+	open := p.Open
+	if open == nil {
+		open = func(dsn string) (*sql.DB, error) { return sql.Open("mysql", dsn) }
+	}
+
+	db, err := open(fmt.Sprintf("%s:%s@tcp(%s)/%s?tls=true", password.Username, password.PlainText, password.AccessHostURL, p.Database))
+	if err != nil {
+		return 0, err
+	}
+
+	defer db.Close()
+
+	for i, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return i, err
+		}
+	}
+
+	return len(stmts), nil
+}
+
+// wait deploys the deploy request once PlanetScale checked it, and waits for
+// the deployment to complete.
+func (p *PlanetScale) wait(ctx context.Context, number int) error {
+	path := fmt.Sprintf("deploy-requests/%d", number)
+	deployed := false
+
+	for {
+		var request struct {
+			DeploymentState string `json:"deployment_state"`
+		}
+
+		if err := p.call(ctx, http.MethodGet, path, nil, &request); err != nil {
+			return err
+		}
+
+		switch state := request.DeploymentState; state {
+		case "no_changes", "complete", "complete_pending_revert":
+			return nil
+		case "error", "complete_error", "cancelled", "complete_cancel":
+			return fmt.Errorf("vitessmigrate: deploy request %d of %s: %s", number, p.Database, state)
+		case "ready":
+			if !deployed {
+				if err := p.call(ctx, http.MethodPost, path+"/deploy", nil, nil); err != nil {
+					return err
+				}
+
+				deployed = true
+			}
+		}
+
+		if err := sleep(ctx, p.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// call sends a request to the API of the database, and decodes the response
+// into out.
+func (p *PlanetScale) call(ctx context.Context, method, path string, in, out any) error {
+	base := p.BaseURL
+	if base == "" {
+		base = "https://api.planetscale.com/v1"
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	endpoint := fmt.Sprintf("%s/organizations/%s/databases/%s/%s", strings.TrimSuffix(base, "/"),
+		url.PathEscape(p.Organization), url.PathEscape(p.Database), path)
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", p.TokenId+":"+p.Token)
+	req.Header.Set("Accept", "application/json")
+
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vitessmigrate: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+
+		return fmt.Errorf("vitessmigrate: %s %s: %s %s", method, path, resp.Status, apiErr.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
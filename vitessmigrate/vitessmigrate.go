@@ -0,0 +1,240 @@
+// Package vitessmigrate runs the migrations on Vitess and PlanetScale, where
+// the DDL can't run directly: the CREATE, ALTER and DROP of tables and views
+// are submitted as online schema changes, or as deploy requests on
+// PlanetScale, and waited for, while the other statements and the
+// bookkeeping of the migration table run on the connection as usual.
+//
+//	ex := migrate.NewMigrationExecutor()
+//	ex.Runner = vitessmigrate.Runner{Deployer: vitessmigrate.OnlineDDL{Strategy: "vitess"}}
+//	n, err := ex.ExecContext(ctx, db, &dialect.MySQLDialect{}, source, migrate.Up)
+//
+// The schema changes run outside of the transaction of the migration, so a
+// migration mixing DDL and DML is better split, or run without transaction.
+package vitessmigrate
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+var (
+	_ migrate.StatementRunner = Runner{}
+	_ Deployer                = OnlineDDL{}
+	_ Deployer                = (*PlanetScale)(nil)
+)
+
+// Conn is the driver connection of go-sql-driver/mysql the statements run on.
+type Conn interface {
+	driver.ExecerContext
+	driver.QueryerContext
+}
+
+// Deployer applies DDL statements as a schema change, and waits for the
+// change to complete.
+type Deployer interface {
+	// Deploy applies the consecutive DDL statements of the migration, and
+	// returns the index of the statement which failed, with its error.
+	Deploy(ctx context.Context, conn Conn, migration *migrate.PlannedMigration, stmts []string) (failed int, err error)
+}
+
+// Runner is a migrate.StatementRunner handing the DDL statements to the
+// Deployer, and running the others on the connection.
+type Runner struct {
+	// Deployer applies the DDL statements, OnlineDDL with the vitess
+	// strategy when nil.
+	Deployer Deployer
+}
+
+// RunStatements runs the statements on the MySQL connection raw.
+func (r Runner) RunStatements(ctx context.Context, raw any, migration *migrate.PlannedMigration, stmts []string) (int, error) {
+	conn, ok := raw.(Conn)
+	if !ok {
+		return 0, fmt.Errorf("vitessmigrate: the connection is a %T, not a MySQL connection", raw)
+	}
+
+	deployer := r.Deployer
+	if deployer == nil {
+		deployer = OnlineDDL{}
+	}
+
+	for i := 0; i < len(stmts); {
+		n := 0
+		for i+n < len(stmts) && IsDDL(stmts[i+n]) {
+			n++
+		}
+
+		if n == 0 {
+			if _, err := conn.ExecContext(ctx, stmts[i], nil); err != nil {
+				return i, err
+			}
+
+			i++
+
+			continue
+		}
+
+		if failed, err := deployer.Deploy(ctx, conn, migration, stmts[i:i+n]); err != nil {
+			return i + failed, err
+		}
+
+		i += n
+	}
+
+	return len(stmts), nil
+}
+
+// ddl matches the statements Vitess applies as online schema changes, after
+// the leading comments.
+var ddl = regexp.MustCompile(`(?is)^(?:\s+|--[^\n]*(?:\n|$)|/\*.*?\*/)*(?:CREATE|ALTER|DROP)\s+(?:OR\s+REPLACE\s+)?(?:TABLE|VIEW)\b`)
+
+// IsDDL tells whether the statement creates, alters or drops a table or a
+// view, which the Deployer applies. The other DDL, e.g. CREATE INDEX, runs
+// directly, write it as ALTER TABLE ... ADD INDEX to deploy it.
+func IsDDL(stmt string) bool {
+	return ddl.MatchString(stmt)
+}
+
+// OnlineDDL submits the statements to Vitess as online schema changes with
+// the ddl_strategy of the session, and polls SHOW VITESS_MIGRATIONS until
+// each completed. The statements are submitted one at a time, each once the
+// previous one completed.
+type OnlineDDL struct {
+	// Strategy is the ddl_strategy, e.g. "vitess --prefer-instant-ddl",
+	// vitess when empty. Options postponing the completion would wait
+	// forever.
+	Strategy string
+	// PollInterval is the time between the checks of a schema change, 5s
+	// when zero.
+	PollInterval time.Duration
+}
+
+// Deploy submits the statements and waits for them to complete. The
+// ddl_strategy of the session is restored afterwards.
+func (o OnlineDDL) Deploy(ctx context.Context, conn Conn, _ *migrate.PlannedMigration, stmts []string) (failed int, err error) {
+	strategy := o.Strategy
+	if strategy == "" {
+		strategy = "vitess"
+	}
+
+	row, err := queryRow(ctx, conn, "SELECT @@ddl_strategy")
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := conn.ExecContext(ctx, "SET @@ddl_strategy = "+quote(strategy), nil); err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		_, resetErr := conn.ExecContext(ctx, "SET @@ddl_strategy = "+quote(row["@@ddl_strategy"]), nil)
+		if err == nil && resetErr != nil {
+			failed, err = len(stmts)-1, resetErr
+		}
+	}()
+
+	for i, stmt := range stmts {
+		submitted, err := queryRow(ctx, conn, stmt)
+		if err != nil {
+			return i, err
+		}
+
+		uuid := submitted["uuid"]
+		if uuid == "" {
+			return i, fmt.Errorf("vitessmigrate: the statement wasn't submitted as an online schema change, is %q an online strategy?", strategy)
+		}
+
+		if err := o.wait(ctx, conn, uuid); err != nil {
+			return i, err
+		}
+	}
+
+	return len(stmts), nil
+}
+
+// wait polls the schema change until it completed.
+func (o OnlineDDL) wait(ctx context.Context, conn Conn, uuid string) error {
+	for {
+		row, err := queryRow(ctx, conn, "SHOW VITESS_MIGRATIONS LIKE "+quote(uuid))
+		if err != nil {
+			return err
+		}
+
+		switch status := row["migration_status"]; status {
+		case "complete":
+			return nil
+		case "failed", "cancelled":
+			return fmt.Errorf("vitessmigrate: schema change %s %s: %s", uuid, status, row["message"])
+		case "":
+			return fmt.Errorf("vitessmigrate: schema change %s not found", uuid)
+		}
+
+		if err := sleep(ctx, o.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// quote quotes the string as a MySQL string literal.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// queryRow runs the query and returns its first row by column, empty when it
+// has none.
+func queryRow(ctx context.Context, conn Conn, query string) (map[string]string, error) {
+	rows, err := conn.QueryContext(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	columns := rows.Columns()
+	values := make([]driver.Value, len(columns))
+	row := make(map[string]string, len(columns))
+
+	if err := rows.Next(values); errors.Is(err, io.EOF) {
+		return row, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for i, column := range columns {
+		switch v := values[i].(type) {
+		case nil:
+		case []byte:
+			row[column] = string(v)
+		default:
+			row[column] = fmt.Sprint(v)
+		}
+	}
+
+	return row, nil
+}
+
+// sleep waits for the poll interval, 5s when zero, or the end of ctx.
+func sleep(ctx context.Context, interval time.Duration) error {
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
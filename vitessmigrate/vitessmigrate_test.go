@@ -0,0 +1,229 @@
+package vitessmigrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type VitessSuite struct{}
+
+var _ = Suite(&VitessSuite{})
+
+// fakeConn plays vtgate: the DDL statements return a uuid, whose schema
+// change reports the statuses in turn.
+type fakeConn struct {
+	queries  []string
+	statuses []string
+	failExec string
+}
+
+func (f *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	f.queries = append(f.queries, query)
+	if query == f.failExec {
+		return nil, errors.New("exec failed")
+	}
+
+	return driver.RowsAffected(0), nil
+}
+
+func (f *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	f.queries = append(f.queries, query)
+
+	switch {
+	case query == "SELECT @@ddl_strategy":
+		return &fakeRows{columns: []string{"@@ddl_strategy"}, values: []driver.Value{[]byte("direct")}}, nil
+	case strings.HasPrefix(query, "SHOW VITESS_MIGRATIONS"):
+		status := f.statuses[0]
+		f.statuses = f.statuses[1:]
+
+		return &fakeRows{columns: []string{"migration_status", "message"}, values: []driver.Value{status, []byte("boom")}}, nil
+	default:
+		return &fakeRows{columns: []string{"uuid"}, values: []driver.Value{"u" + string(rune('0'+len(f.queries)))}}, nil
+	}
+}
+
+type fakeRows struct {
+	columns []string
+	values  []driver.Value
+	done    bool
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (*fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+
+	r.done = true
+	copy(dest, r.values)
+
+	return nil
+}
+
+func (*VitessSuite) TestIsDDL(c *C) {
+	c.Assert(IsDDL("ALTER TABLE people ADD COLUMN name text"), Equals, true)
+	c.Assert(IsDDL("-- people\n/* v2 */ create or replace view adults AS SELECT 1"), Equals, true)
+	c.Assert(IsDDL("DROP TABLE people"), Equals, true)
+	c.Assert(IsDDL("CREATE INDEX people_name ON people (name)"), Equals, false)
+	c.Assert(IsDDL("INSERT INTO people VALUES (1)"), Equals, false)
+	c.Assert(IsDDL("UPDATE tables SET n = 1 -- ALTER TABLE"), Equals, false)
+}
+
+func (*VitessSuite) TestOnlineDDL(c *C) {
+	conn := &fakeConn{statuses: []string{"running", "complete", "complete"}}
+	runner := Runner{Deployer: OnlineDDL{PollInterval: 1}}
+
+	n, err := runner.RunStatements(context.Background(), conn, &migrate.PlannedMigration{}, []string{
+		"CREATE TABLE people (id int)",
+		"ALTER TABLE people ADD COLUMN name text",
+		"INSERT INTO people VALUES (1, 'alice')",
+	})
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 3)
+	c.Assert(conn.queries, DeepEquals, []string{
+		"SELECT @@ddl_strategy",
+		"SET @@ddl_strategy = 'vitess'",
+		"CREATE TABLE people (id int)",
+		"SHOW VITESS_MIGRATIONS LIKE 'u3'",
+		"SHOW VITESS_MIGRATIONS LIKE 'u3'",
+		"ALTER TABLE people ADD COLUMN name text",
+		"SHOW VITESS_MIGRATIONS LIKE 'u6'",
+		"SET @@ddl_strategy = 'direct'",
+		"INSERT INTO people VALUES (1, 'alice')",
+	})
+}
+
+func (*VitessSuite) TestOnlineDDLFailure(c *C) {
+	conn := &fakeConn{statuses: []string{"complete", "failed"}}
+
+	failed, err := Runner{Deployer: OnlineDDL{Strategy: "vitess --prefer-instant-ddl"}}.RunStatements(context.Background(), conn, &migrate.PlannedMigration{}, []string{
+		"INSERT INTO people VALUES (1)",
+		"ALTER TABLE people ADD COLUMN name text",
+		"ALTER TABLE people ADD COLUMN age int",
+	})
+	c.Assert(err, ErrorMatches, "vitessmigrate: schema change u6 failed: boom")
+	c.Assert(failed, Equals, 2)
+	c.Assert(conn.queries[2], Equals, "SET @@ddl_strategy = 'vitess --prefer-instant-ddl'")
+	c.Assert(conn.queries[len(conn.queries)-1], Equals, "SET @@ddl_strategy = 'direct'")
+
+	conn = &fakeConn{failExec: "INSERT INTO people VALUES (1)"}
+	failed, err = Runner{}.RunStatements(context.Background(), conn, &migrate.PlannedMigration{}, []string{"INSERT INTO people VALUES (1)"})
+	c.Assert(err, ErrorMatches, "exec failed")
+	c.Assert(failed, Equals, 0)
+
+	_, err = Runner{}.RunStatements(context.Background(), "conn", &migrate.PlannedMigration{}, nil)
+	c.Assert(err, ErrorMatches, "vitessmigrate: the connection is a string, not a MySQL connection")
+}
+
+// fakePlanetScale plays the API of PlanetScale, the branch is ready on the
+// second check and the deploy request completes once deployed.
+type fakePlanetScale struct {
+	requests []string
+	checks   int
+	deployed bool
+}
+
+func (f *fakePlanetScale) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/organizations/acme/databases/shop/")
+	f.requests = append(f.requests, r.Method+" "+path)
+
+	if r.Header.Get("Authorization") != "id:secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "bad token"})
+
+		return
+	}
+
+	var body any
+
+	switch r.Method + " " + path {
+	case "GET branches/migrate-2-add-name-sql":
+		f.checks++
+		body = map[string]bool{"ready": f.checks > 1}
+	case "POST branches/migrate-2-add-name-sql/passwords":
+		body = map[string]string{"username": "user", "plain_text": "pass", "access_host_url": "aws.connect.psdb.cloud"}
+	case "POST deploy-requests":
+		body = map[string]int{"number": 7}
+	case "GET deploy-requests/7":
+		state := "pending"
+		if f.checks++; f.deployed {
+			state = "complete"
+		} else if f.checks > 3 {
+			state = "ready"
+		}
+
+		body = map[string]string{"deployment_state": state}
+	case "POST deploy-requests/7/deploy":
+		f.deployed = true
+	}
+
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (*VitessSuite) TestPlanetScale(c *C) {
+	api := &fakePlanetScale{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	branch, err := sql.Open("sqlite3", ":memory:")
+	c.Assert(err, IsNil)
+	defer branch.Close()
+
+	branch.SetMaxOpenConns(1)
+
+	var dsn string
+	deployer := &PlanetScale{
+		Organization: "acme",
+		Database:     "shop",
+		TokenId:      "id",
+		Token:        "secret",
+		PollInterval: 1,
+		BaseURL:      server.URL,
+		Open: func(s string) (*sql.DB, error) {
+			dsn = s
+			return branch, nil
+		},
+	}
+
+	migration := &migrate.PlannedMigration{Migration: &migrate.Migration{Id: "2_add_name.sql"}}
+	n, err := Runner{Deployer: deployer}.RunStatements(context.Background(), &fakeConn{}, migration, []string{"CREATE TABLE people (id int)"})
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+	c.Assert(dsn, Equals, "user:pass@tcp(aws.connect.psdb.cloud)/shop?tls=true")
+	c.Assert(api.requests, DeepEquals, []string{
+		"POST branches",
+		"GET branches/migrate-2-add-name-sql",
+		"GET branches/migrate-2-add-name-sql",
+		"POST branches/migrate-2-add-name-sql/passwords",
+		"POST deploy-requests",
+		"GET deploy-requests/7",
+		"GET deploy-requests/7",
+		"POST deploy-requests/7/deploy",
+		"GET deploy-requests/7",
+		"DELETE branches/migrate-2-add-name-sql",
+	})
+
+	deployer.Token = "wrong"
+	_, err = deployer.Deploy(context.Background(), nil, migration, nil)
+	c.Assert(err, ErrorMatches, "vitessmigrate: POST branches: 401 Unauthorized bad token")
+}
@@ -0,0 +1,96 @@
+// Package zapadapter logs the migrations of sql-migrate with zap.
+//
+//	ex := migrate.NewMigrationExecutor()
+//	ex.Logger = zapadapter.New(logger.Sugar())
+//
+// Traced queries are logged at the debug level, as zap has no trace level.
+// The structured fields of the executor become fields of the entries.
+package zapadapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+var (
+	_ migrate.FieldLogger  = (*Logger)(nil)
+	_ migrate.LevelEnabler = (*Logger)(nil)
+)
+
+// Logger implements migrate.FieldLogger over a zap.SugaredLogger.
+type Logger struct {
+	logger *zap.SugaredLogger
+}
+
+// New returns a Logger writing to logger.
+func New(logger *zap.SugaredLogger) *Logger {
+	return &Logger{logger: logger}
+}
+
+func (l *Logger) Tracef(format string, v ...any) {
+	l.logger.Debugf(format, v...)
+}
+
+func (l *Logger) Infof(format string, v ...any) {
+	l.logger.Infof(format, v...)
+}
+
+func (l *Logger) Errorf(format string, v ...any) {
+	l.logger.Errorf(format, v...)
+}
+
+// Log logs the message with the fields.
+func (l *Logger) Log(_ context.Context, level migrate.LogLevel, msg string, fields ...migrate.Field) {
+	keysAndValues := make([]any, 0, len(fields))
+	for _, f := range fields {
+		keysAndValues = append(keysAndValues, field(f))
+	}
+
+	switch level {
+	case migrate.LevelTrace:
+		l.logger.Debugw(msg, keysAndValues...)
+	case migrate.LevelWarn:
+		l.logger.Warnw(msg, keysAndValues...)
+	case migrate.LevelError:
+		l.logger.Errorw(msg, keysAndValues...)
+	default:
+		l.logger.Infow(msg, keysAndValues...)
+	}
+}
+
+// Enabled reports whether the zap logger logs the entries of the level.
+func (l *Logger) Enabled(_ context.Context, level migrate.LogLevel) bool {
+	return l.logger.Level().Enabled(zapLevel(level))
+}
+
+func zapLevel(level migrate.LogLevel) zapcore.Level {
+	switch level {
+	case migrate.LevelTrace:
+		return zapcore.DebugLevel
+	case migrate.LevelWarn:
+		return zapcore.WarnLevel
+	case migrate.LevelError:
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+func field(f migrate.Field) zap.Field {
+	switch v := f.Value.(type) {
+	case time.Duration:
+		return zap.Duration(f.Key, v)
+	case error:
+		return zap.String(f.Key, v.Error())
+	case fmt.Stringer:
+		return zap.Stringer(f.Key, v)
+	default:
+		return zap.Any(f.Key, v)
+	}
+}
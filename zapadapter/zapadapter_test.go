@@ -0,0 +1,48 @@
+package zapadapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	//revive:disable-next-line:dot-imports
+	. "gopkg.in/check.v1"
+
+	migrate "github.com/kva3umoda/sql-migrate"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ZapSuite struct{}
+
+var _ = Suite(&ZapSuite{})
+
+func (*ZapSuite) TestLog(c *C) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := New(zap.New(core).Sugar())
+
+	logger.Tracef("SELECT %d", 1)
+	logger.Log(context.Background(), migrate.LevelError, "Failed to apply migration 1_init.sql",
+		migrate.Field{Key: "migration_id", Value: "1_init.sql"},
+		migrate.Field{Key: "direction", Value: migrate.Up},
+		migrate.Field{Key: "duration", Value: 2 * time.Second},
+		migrate.Field{Key: "error", Value: errors.New("syntax error")},
+	)
+
+	entries := logs.AllUntimed()
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[0].Level, Equals, zapcore.DebugLevel)
+	c.Assert(entries[0].Message, Equals, "SELECT 1")
+	c.Assert(entries[1].Level, Equals, zapcore.ErrorLevel)
+	c.Assert(entries[1].ContextMap(), DeepEquals, map[string]any{
+		"migration_id": "1_init.sql",
+		"direction":    "up",
+		"duration":     2 * time.Second,
+		"error":        "syntax error",
+	})
+}